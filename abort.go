@@ -0,0 +1,59 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// AbortAfter is how long a quick match game can go without anybody moving
+// by default, before it's called off
+const AbortAfter = time.Minute
+
+// abortAfter is how long a quick match game can go without anybody moving
+// before it's called off and both players go back in the queue, set with
+// -abort-after. 0 never calls games off.
+var abortAfter = AbortAfter
+
+// scheduleAbort calls the match off if nobody has moved by abortAfter from
+// now. Call with the session locked, as the match starts.
+func (gs *GameSession) scheduleAbort() {
+	if abortAfter == 0 {
+		return
+	}
+	turn := gs.turn
+	time.AfterFunc(abortAfter, func() { gs.abortIdle(turn) })
+}
+
+// abortIdle calls off a quick match game nobody has moved in since the
+// given turn began, the first: there's no result, and both players go back
+// to their places in the queue, not to be paired together again. Rooms,
+// tournament and hill bouts and crowd games are left alone, and so is a
+// game a player's left, that's abandoning it.
+func (gs *GameSession) abortIdle(turn int) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if gs.turn != turn || gs.Moves > 0 || gs.Winner != Empty || gs.PlayerDisconnected ||
+		gs.Private || gs.Crowd || gs.tournament != nil || gs.hill != nil {
+		return
+	}
+	gs.Aborted = true
+	gs.journal.Record(Event{Type: EventAbort})
+	sessionManager.queue.Abort(gs.match)
+	gs.notify()
+}
+
+// leaveAborted leaves a game that was called off for the match the queue
+// put the player back in
+func (m model) leaveAborted() (tea.Model, tea.Cmd) {
+	to := m.gameSession.match.Requeued(m.playerID)
+	close(m.left)
+	f := m.fresh()
+	if to == nil {
+		f.lobby.err = "Nobody moved, so the game was called off"
+		return f.backToLobby()
+	}
+	session, seat := sessionManager.sit(to, f.player(), false)
+	f.requeued = true
+	return f.enterSession(session, seat, nil)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"tictactui/matchmaking"
+)
+
+func TestAbortIdle(t *testing.T) {
+	tests := []struct {
+		name      string
+		session   func(gs *GameSession)
+		wantAbort bool
+	}{
+		{"nobody moved", func(gs *GameSession) {}, true},
+		{"somebody moved", func(gs *GameSession) { gs.Moves = 1 }, false},
+		{"a later turn", func(gs *GameSession) { gs.turn++ }, false},
+		{"a player left", func(gs *GameSession) { gs.PlayerDisconnected = true }, false},
+		{"a room", func(gs *GameSession) { gs.Private = true }, false},
+		{"the crowd", func(gs *GameSession) { gs.Crowd = true }, false},
+		{"a tournament bout", func(gs *GameSession) { gs.tournament = matchmaking.NewTournament(4) }, false},
+		{"a hill bout", func(gs *GameSession) { gs.hill = matchmaking.NewHill() }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := sessionManager.queue
+			t.Cleanup(func() { sessionManager.queue = old })
+			sessionManager.queue = matchmaking.NewQueue()
+
+			match, err := sessionManager.queue.Join(matchmaking.Player{ID: "a"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := sessionManager.queue.Join(matchmaking.Player{ID: "b"}); err != nil {
+				t.Fatal(err)
+			}
+			gs := &GameSession{Winner: Empty, match: match}
+			turn := gs.turn
+			tt.session(gs)
+			gs.abortIdle(turn)
+
+			if gs.Aborted != tt.wantAbort {
+				t.Errorf("Aborted = %v, want %v", gs.Aborted, tt.wantAbort)
+			}
+			if requeued := match.Requeued("a") != nil; requeued != tt.wantAbort {
+				t.Errorf("requeued = %v, want %v", requeued, tt.wantAbort)
+			}
+		})
+	}
+}
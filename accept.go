@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// acceptTimeout is how long a quick match's players have to accept it, set
+// with -accept
+var acceptTimeout = AcceptTimeout
+
+const (
+	// AcceptTimeout is how long players have to accept a quick match by default
+	AcceptTimeout = 15 * time.Second
+
+	// The screen flashes when a match is found, every other frame inverted
+	FlashFrames        = 6
+	FlashFrameInterval = 150 * time.Millisecond
+)
+
+// accepting reports whether the player has been paired and the match is
+// waiting for both players to accept it. A tournament player can check in
+// before their opponent turns up.
+func (m model) accepting() bool {
+	return m.gameSession != nil && (!m.waitingForPlayer || m.tournament != nil) && !m.acceptBy.IsZero() && m.matchStarted.IsZero()
+}
+
+// matchFound gets the player's attention: the accept screen flashes and,
+// unless they've turned it off, the bell rings
+func (m *model) matchFound() tea.Cmd {
+	var cmd tea.Cmd
+	m.flash, cmd = m.flash.Start()
+	opponent := m.gameSession.match.Opponent(m.playerID)
+	return tea.Batch(cmd, m.alert(m.notifications.MatchBell, "Match found against "+opponent.Name))
+}
+
+// accept confirms the player wants to play their match, it starts once both
+// players have
+func (sm *SessionManager) accept(session *GameSession, playerID string) {
+	session.match.Accept(playerID)
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if session.match.IsConfirmed() && session.MatchStarted.IsZero() {
+		session.start()
+		sm.sessionsChanged()
+	}
+	session.notify()
+}
+
+// updateAccept handles keys while a match waits to be accepted
+func (m model) updateAccept(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "enter", " ", "y":
+		sessionManager.accept(m.gameSession, m.playerID)
+	case "esc", "n":
+		if m.tournament != nil {
+			break // there's no declining a bout, not checking in forfeits it
+		}
+		// the opponent goes back in the queue, we go back to the lobby
+		if err := sessionManager.queue.Decline(m.gameSession.match, m.playerID); err != nil {
+			log.Printf("declining match %s: %v", m.gameSession.ID, err)
+		}
+		close(m.left)
+		return m.fresh().backToLobby()
+	}
+	return m, nil
+}
+
+// acceptView asks the player to accept the match they've been paired in
+func (m model) acceptView() string {
+	banner := headerStyle.Render(m.bannerArt("match!", ""))
+	if m.flash.Running() && m.flash.Frame()%2 == 0 && !m.reducedMotion {
+		banner = headerStyle.Reverse(true).Render(m.bannerArt("match!", ""))
+	}
+	s := "\n\n\n"
+	s += banner
+	s += "\n\n"
+
+	opponent := m.gameSession.match.Opponent(m.playerID)
+	s += "  " + m.playerName + m.recordFor(m.playerID) + footerStyle.Render("  vs  ") + opponent.Name + m.recordFor(opponent.ID) + "\n"
+	s += "  " + footerStyle.Render(m.stakesView(opponent.ID)) + "\n\n"
+
+	if m.tournament != nil {
+		return s + m.checkInView()
+	}
+	if m.accepted {
+		s += lip.NewStyle().Foreground(lip.Color("#50FA7B")).Bold(true).Render("  Accepted! Waiting for your opponent to accept...") + "\n"
+	} else if m.opponentAccepted {
+		s += headerStyle.Render("  Your opponent accepted, are you in?") + "\n"
+	} else {
+		s += headerStyle.Render("  Are you in?") + "\n"
+	}
+	if m.reducedMotion {
+		s += footerStyle.Render(fmt.Sprintf("  Both players have %d seconds to accept", int(acceptTimeout.Seconds()))) + "\n"
+	} else {
+		left := max(time.Until(m.acceptBy), 0)
+		s += footerStyle.Render(fmt.Sprintf("  %d seconds left to accept", int(left.Seconds())+1)) + "\n"
+	}
+
+	if m.accepted {
+		s += footerStyle.Render("\n  Press esc to back out, q to quit") + "\n"
+	} else {
+		s += footerStyle.Render("\n  Press enter to accept, esc to decline, q to quit") + "\n"
+	}
+	return s
+}
+
+// checkInView asks a tournament player to check in for their bout, there's
+// no backing out of it
+func (m model) checkInView() string {
+	var s string
+	if m.accepted {
+		s += lip.NewStyle().Foreground(lip.Color("#50FA7B")).Bold(true).Render("  Checked in! Waiting for your opponent to check in...") + "\n"
+	} else if m.opponentAccepted {
+		s += headerStyle.Render("  Your opponent checked in, are you ready?") + "\n"
+	} else {
+		s += headerStyle.Render("  Are you ready?") + "\n"
+	}
+	if m.reducedMotion {
+		s += footerStyle.Render(fmt.Sprintf("  Both players have %d seconds to check in, whoever doesn't forfeits", int(checkInTimeout.Seconds()))) + "\n"
+	} else {
+		left := max(time.Until(m.acceptBy), 0)
+		s += footerStyle.Render(fmt.Sprintf("  %d seconds left to check in, whoever doesn't forfeits", int(left.Seconds())+1)) + "\n"
+	}
+
+	if m.accepted {
+		s += footerStyle.Render("\n  Press q to quit") + "\n"
+	} else {
+		s += footerStyle.Render("\n  Press enter to check in, q to quit") + "\n"
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package main
+
+import "tictactui/matchmaking"
+
+// achievement is a milestone a player reaches by playing
+type achievement struct {
+	name    string
+	reached func(r matchmaking.Results) bool
+}
+
+// achievements are worked out from players' results in every game added
+// up, so there's nothing extra to keep and each is unlocked once, by the
+// game that reaches it
+var achievements = []achievement{
+	{"First game", func(r matchmaking.Results) bool { return gamesPlayed(r) >= 1 }},
+	{"First win", func(r matchmaking.Results) bool { return r.Wins >= 1 }},
+	{"Ten wins", func(r matchmaking.Results) bool { return r.Wins >= 10 }},
+	{"Fifty wins", func(r matchmaking.Results) bool { return r.Wins >= 50 }},
+	{"Regular, 100 games", func(r matchmaking.Results) bool { return gamesPlayed(r) >= 100 }},
+}
+
+// gamesPlayed is how many games results count
+func gamesPlayed(r matchmaking.Results) int {
+	return r.Wins + r.Losses + r.Draws
+}
+
+// totalResults adds up a player's results in every game
+func totalResults(playerID string) matchmaking.Results {
+	var total matchmaking.Results
+	for _, g := range boardGames {
+		r := sessionManager.queue.Record(playerID, g.Name())
+		total.Wins += r.Wins
+		total.Losses += r.Losses
+		total.Draws += r.Draws
+	}
+	return total
+}
+
+// unlocked names the achievements a game took a player's results in every
+// game from before to after
+func unlocked(before, after matchmaking.Results) []string {
+	var names []string
+	for _, a := range achievements {
+		if a.reached(after) && !a.reached(before) {
+			names = append(names, a.name)
+		}
+	}
+	return names
+}
+
+// rankTitles are earned by rating, best first, once a player's played
+// NoviceGames games
+var rankTitles = []struct {
+	rating int
+	title  string
+}{
+	{1600, "Grandmaster of Noughts"},
+	{1450, "Master"},
+	{1300, "Expert"},
+	{1150, "Contender"},
+	{0, "Apprentice"},
+}
+
+// NoviceGames is how many games a player's a Novice for, their rating
+// says little before then
+const NoviceGames = 10
+
+// Unranked is the title of players still playing their placement matches,
+// their rating isn't shown until they're done
+const Unranked = "Unranked"
+
+// rankTitle is the title shown by a player's name
+func rankTitle(rating, games int) string {
+	switch {
+	case games < matchmaking.PlacementMatches:
+		return Unranked
+	case games < NoviceGames:
+		return "Novice"
+	}
+	for _, t := range rankTitles {
+		if rating >= t.rating {
+			return t.title
+		}
+	}
+	return rankTitles[len(rankTitles)-1].title
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// difficulty is how hard the computer tries
+type difficulty int
+
+const (
+	AIEasy   difficulty = iota // picks any free cell
+	AIMedium                   // takes wins, blocks, sets up forks
+	AIHard                     // plays perfectly, it can't be beaten
+)
+
+// Opponents for a local game, in menu order
+const (
+	opponentHuman = iota
+	opponentEasy
+	opponentMedium
+	opponentHard
+	opponentPractice
+	opponentPuzzles
+)
+
+var opponentOptions = []string{
+	opponentHuman:    "Two players - take turns on this keyboard",
+	opponentEasy:     "vs computer - easy",
+	opponentMedium:   "vs computer - medium",
+	opponentHard:     "vs computer - hard",
+	opponentPractice: "Practice - vs the computer, with takebacks, an eval bar and your own positions",
+	opponentPuzzles:  "Puzzles - X to move and win against the computer",
+}
+
+// opponentMenu is where a local game picks who X plays against
+type opponentMenu struct {
+	active bool
+	choice int // highlighted option
+}
+
+// aiMoveMsg is the computer's move, delivered after a short pause so it
+// looks like it's thinking
+type aiMoveMsg struct {
+	cell coord
+	game int // which game the move was for, moves for an earlier game are dropped
+}
+
+// updateOpponentMenu handles keys while picking an opponent
+func (m model) updateOpponentMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "up", "k":
+		m.opponentMenu.choice = (m.opponentMenu.choice + len(opponentOptions) - 1) % len(opponentOptions)
+	case "down", "j":
+		m.opponentMenu.choice = (m.opponentMenu.choice + 1) % len(opponentOptions)
+	case "enter", " ":
+		switch m.opponentMenu.choice {
+		case opponentPractice:
+			return m.startPractice()
+		case opponentPuzzles:
+			return m.startPuzzles()
+		}
+		if m.opponentMenu.choice != opponentHuman {
+			// the player goes first as X, the computer answers as O
+			m.computer = PlayerO
+			m.difficulty = difficulty(m.opponentMenu.choice - opponentEasy)
+		}
+		m.opponentMenu = opponentMenu{}
+		m.gameStarted = time.Now()
+		return m, m.clearScreen()
+	}
+	return m, nil
+}
+
+// opponentMenuView draws the opponent picker
+func (m model) opponentMenuView() string {
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+	s += footerStyle.Render("  Who do you want to play against?") + "\n\n"
+	for i, option := range opponentOptions {
+		if i == m.opponentMenu.choice {
+			s += headerStyle.Render("> "+option) + "\n"
+		} else {
+			s += "  " + option + "\n"
+		}
+	}
+	s += footerStyle.Render("\n  Press enter to pick, q to quit") + "\n"
+	return s
+}
+
+// computerTurn has the computer pick its move if it's up next
+func (m model) computerTurn() tea.Cmd {
+	if m.computer == Empty || m.currentPlayer != m.computer || m.winner != Empty {
+		return nil
+	}
+	// seeded by the move too, so a position taken back and played again
+	// gets the same answer
+	rng := rand.New(rand.NewPCG(m.aiSeed, uint64(m.moves)))
+	move := aiMoveMsg{cell: aiMove(m.board, m.computer, m.difficulty, rng), game: m.game}
+	return tea.Tick(AIMoveDelay, func(time.Time) tea.Msg {
+		return move
+	})
+}
+
+// aiMove picks the computer's next move on a board with at least one free
+// cell, making any random choices with rng
+func aiMove(board [][]string, me string, level difficulty, rng *rand.Rand) coord {
+	board = copyBoard(board)
+	switch level {
+	case AIHard:
+		return bestMove(board, me, rng)
+	case AIMedium:
+		if cell, ok := winningMove(board, me); ok {
+			return cell
+		}
+		if cell, ok := winningMove(board, opponentOf(me)); ok {
+			return cell // block
+		}
+		if cell, ok := forkMove(board, me); ok {
+			return cell
+		}
+		if board[1][1] == Empty {
+			return coord{1, 1}
+		}
+	}
+	free := freeCells(board)
+	return free[rng.IntN(len(free))]
+}
+
+// freeCells lists the empty cells
+func freeCells(board [][]string) []coord {
+	var free []coord
+	for y, row := range board {
+		for x, cell := range row {
+			if cell == Empty {
+				free = append(free, coord{y, x})
+			}
+		}
+	}
+	return free
+}
+
+// winningMove finds a cell that wins the game for player straight away
+func winningMove(board [][]string, player string) (coord, bool) {
+	for _, cell := range freeCells(board) {
+		board[cell.row][cell.col] = player
+		won := ticTacToe.checkWinner(board, player) != nil
+		board[cell.row][cell.col] = Empty
+		if won {
+			return cell, true
+		}
+	}
+	return coord{}, false
+}
+
+// forkMove finds a cell that leaves player two ways to win, the opponent
+// can only block one of them
+func forkMove(board [][]string, player string) (coord, bool) {
+	for _, cell := range freeCells(board) {
+		board[cell.row][cell.col] = player
+		threats := 0
+		for _, next := range freeCells(board) {
+			board[next.row][next.col] = player
+			if ticTacToe.checkWinner(board, player) != nil {
+				threats++
+			}
+			board[next.row][next.col] = Empty
+		}
+		board[cell.row][cell.col] = Empty
+		if threats >= 2 {
+			return cell, true
+		}
+	}
+	return coord{}, false
+}
+
+// bestMove searches the whole game tree with minimax and picks one of the
+// best moves at random with rng, so a perfect game doesn't always look the
+// same
+func bestMove(board [][]string, me string, rng *rand.Rand) coord {
+	var best []coord
+	bestScore := 0
+	for _, cell := range freeCells(board) {
+		board[cell.row][cell.col] = me
+		score := minimax(board, me, opponentOf(me), 1)
+		board[cell.row][cell.col] = Empty
+		switch {
+		case best == nil || score > bestScore:
+			best, bestScore = []coord{cell}, score
+		case score == bestScore:
+			best = append(best, cell)
+		}
+	}
+	return best[rng.IntN(len(best))]
+}
+
+// minimax scores a position for me with turn to move: positive if me wins,
+// negative if me loses, quicker results counting for more
+func minimax(board [][]string, me, turn string, depth int) int {
+	if ticTacToe.checkWinner(board, me) != nil {
+		return 10 - depth
+	}
+	if ticTacToe.checkWinner(board, opponentOf(me)) != nil {
+		return depth - 10
+	}
+	if isDraw(board) {
+		return 0
+	}
+
+	scores := make([]int, 0, BoardSize*BoardSize)
+	for _, cell := range freeCells(board) {
+		board[cell.row][cell.col] = turn
+		scores = append(scores, minimax(board, me, opponentOf(turn), depth+1))
+		board[cell.row][cell.col] = Empty
+	}
+	if turn == me {
+		return slices.Max(scores)
+	}
+	return slices.Min(scores)
+}
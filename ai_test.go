@@ -0,0 +1,18 @@
+package main
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+func TestAIMoveSeeded(t *testing.T) {
+	board := lineBoard("X..", "...", "...")
+	for _, level := range []difficulty{AIEasy, AIHard} {
+		first := aiMove(board, PlayerO, level, rand.New(rand.NewPCG(42, 1)))
+		for range 10 {
+			if again := aiMove(board, PlayerO, level, rand.New(rand.NewPCG(42, 1))); again != first {
+				t.Fatalf("difficulty %d: the same seed picked %v then %v", level, first, again)
+			}
+		}
+	}
+}
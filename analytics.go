@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// heatmapShades go from no activity to the busiest hour
+var heatmapShades = []rune(" ░▒▓█")
+
+// sparkBars go from no games to the busiest hour, for the lobby's sparkline
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws counts as bars, the tallest for the largest count. Any
+// count above 0 shows above the baseline.
+func sparkline(counts []int) string {
+	busiest := slices.Max(counts)
+	var bars strings.Builder
+	for _, n := range counts {
+		bar := 0
+		if busiest > 0 {
+			bar = (n*(len(sparkBars)-1) + busiest - 1) / busiest
+		}
+		bars.WriteRune(sparkBars[bar])
+	}
+	return bars.String()
+}
+
+// gameStats is what the analytics command adds up from the journals
+type gameStats struct {
+	journals      int
+	gamesPerDay   map[string]int  // finished games by UTC date
+	gamesPerHour  map[int64]int   // finished games by the Unix time of the hour they finished in
+	queueWaits    []time.Duration // first player joining until the second one does
+	gamesBegun    int             // games with at least one move
+	abandoned     int             // games a player disconnected from before the result
+	movesByHour   [24]int
+	finishedGames int
+	byGame        map[string]*gameTally // by game name
+}
+
+// gameTally is what's added up for each game, results and openings only
+// compare between games of the same kind
+type gameTally struct {
+	sessions   int            // game sessions that played it
+	finished   int            // finished games
+	results    map[string]int // finished games by result: "X", "O" or "draw"
+	firstMoves map[string]int // opening move, like "B2"
+}
+
+// newGameStats starts adding up from nothing
+func newGameStats() *gameStats {
+	return &gameStats{gamesPerDay: map[string]int{}, gamesPerHour: map[int64]int{}, byGame: map[string]*gameTally{}}
+}
+
+// tally is the tally for a game, started if it's the first of its kind
+func (st *gameStats) tally(g gameRules) *gameTally {
+	t, ok := st.byGame[g.Name()]
+	if !ok {
+		t = &gameTally{results: map[string]int{}, firstMoves: map[string]int{}}
+		st.byGame[g.Name()] = t
+	}
+	return t
+}
+
+// readJournal loads one journal file
+func readJournal(path string) ([]Event, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
+
+// analyze adds one game session's journal to the stats
+func (st *gameStats) analyze(events []Event) {
+	st.journals++
+
+	var first *Event
+	var game *gameTally // what the session played, from its first join
+	paired, inProgress := false, false
+	for _, e := range events {
+		if game == nil {
+			// the first event is the first join, which says what was
+			// played. Journals from before there was a choice are
+			// tic-tac-toe.
+			game = st.tally(rulesFor(e.Game))
+			game.sessions++
+		}
+		switch e.Type {
+		case EventJoin:
+			if first == nil {
+				first = &e
+			} else if !paired && e.Player != first.Player {
+				st.queueWaits = append(st.queueWaits, e.Time.Sub(first.Time))
+				paired = true
+			}
+		case EventMove:
+			if !inProgress {
+				st.gamesBegun++
+				game.firstMoves[e.Cell]++
+				inProgress = true
+			}
+			st.movesByHour[e.Time.UTC().Hour()]++
+		case EventResult:
+			st.finishedGames++
+			st.gamesPerDay[e.Time.UTC().Format(time.DateOnly)]++
+			st.gamesPerHour[e.Time.Truncate(time.Hour).Unix()]++
+			game.finished++
+			game.results[e.Result]++
+			inProgress = false
+		case EventRestart:
+			inProgress = false
+		case EventDisconnect:
+			if inProgress {
+				st.abandoned++
+				inProgress = false
+			}
+		}
+	}
+}
+
+// runAnalytics summarizes every journal in journalDir to w
+func runAnalytics(w io.Writer) error {
+	if journalDir == "" {
+		return fmt.Errorf("no journals to analyze, pass -journal <dir>")
+	}
+	st, err := analyzeJournals(journalDir)
+	if err != nil {
+		return err
+	}
+	st.print(w)
+	return nil
+}
+
+// analyzeJournals adds up every journal in dir
+func analyzeJournals(dir string) (*gameStats, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	st := newGameStats()
+	for _, path := range paths {
+		events, err := readJournal(path)
+		if err != nil {
+			return nil, err
+		}
+		st.analyze(events)
+	}
+	return st, nil
+}
+
+// gameSummary is one game's results and openings, for the web leaderboard
+type gameSummary struct {
+	Game     string    `json:"game"`
+	Finished int       `json:"finished"`
+	XWins    float64   `json:"x_wins"` // percentages of the finished games
+	OWins    float64   `json:"o_wins"`
+	Draws    float64   `json:"draws"`
+	Openings []opening `json:"openings"` // most common first
+}
+
+// opening is a first move and how many games started with it
+type opening struct {
+	Move  string `json:"move"`
+	Games int    `json:"games"`
+}
+
+// summaries sums up each game that's been played, in the lobby's order
+func (st *gameStats) summaries() []gameSummary {
+	summaries := []gameSummary{}
+	for _, g := range boardGames {
+		t, ok := st.byGame[g.Name()]
+		if !ok || t.finished+len(t.firstMoves) == 0 {
+			continue
+		}
+		s := gameSummary{
+			Game:     g.Title(),
+			Finished: t.finished,
+			XWins:    t.share(PlayerX),
+			OWins:    t.share(PlayerO),
+			Draws:    t.share(Draw),
+			Openings: []opening{},
+		}
+		for _, mv := range t.openings(3) {
+			s.Openings = append(s.Openings, opening{Move: mv, Games: t.firstMoves[mv]})
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// lastDay is how many games finished in each of the last 24 hours, oldest
+// first, the current hour last
+func (st *gameStats) lastDay(now time.Time) [24]int {
+	var hours [24]int
+	hour := now.Truncate(time.Hour)
+	for i := range hours {
+		hours[i] = st.gamesPerHour[hour.Add(time.Duration(i-len(hours)+1)*time.Hour).Unix()]
+	}
+	return hours
+}
+
+// resultLabel names a result, "X wins" or "draws"
+func resultLabel(result string) string {
+	if result == Draw {
+		return "draws"
+	}
+	return result + " wins"
+}
+
+// share is the percentage of finished games with a result
+func (t *gameTally) share(result string) float64 {
+	if t.finished == 0 {
+		return 0
+	}
+	return 100 * float64(t.results[result]) / float64(t.finished)
+}
+
+// openings are the n most common first moves, most common first
+func (t *gameTally) openings(n int) []string {
+	moves := make([]string, 0, len(t.firstMoves))
+	for mv := range t.firstMoves {
+		moves = append(moves, mv)
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		if t.firstMoves[moves[i]] != t.firstMoves[moves[j]] {
+			return t.firstMoves[moves[i]] > t.firstMoves[moves[j]]
+		}
+		return moves[i] < moves[j]
+	})
+	return moves[:min(n, len(moves))]
+}
+
+// print writes the summary as plain text
+func (st *gameStats) print(w io.Writer) {
+	fmt.Fprintf(w, "Game sessions: %d   finished games: %d\n\n", st.journals, st.finishedGames)
+
+	fmt.Fprintln(w, "Games per day (UTC):")
+	days := make([]string, 0, len(st.gamesPerDay))
+	for day := range st.gamesPerDay {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	for _, day := range days {
+		fmt.Fprintf(w, "  %s  %d\n", day, st.gamesPerDay[day])
+	}
+	if len(days) == 0 {
+		fmt.Fprintln(w, "  none yet")
+	}
+
+	fmt.Fprintln(w)
+	if len(st.queueWaits) > 0 {
+		var total time.Duration
+		for _, wait := range st.queueWaits {
+			total += wait
+		}
+		fmt.Fprintf(w, "Average queue wait: %s\n", (total / time.Duration(len(st.queueWaits))).Round(time.Second))
+	} else {
+		fmt.Fprintln(w, "Average queue wait: n/a")
+	}
+	if st.gamesBegun > 0 {
+		fmt.Fprintf(w, "Disconnect rate: %.1f%% (%d of %d games abandoned)\n",
+			100*float64(st.abandoned)/float64(st.gamesBegun), st.abandoned, st.gamesBegun)
+	} else {
+		fmt.Fprintln(w, "Disconnect rate: n/a")
+	}
+
+	popular, most := "n/a", 0
+	for _, g := range boardGames {
+		if t, ok := st.byGame[g.Name()]; ok && t.sessions > most {
+			popular, most = g.Title(), t.sessions
+		}
+	}
+	fmt.Fprintf(w, "Most popular game: %s\n\n", popular)
+
+	for _, g := range boardGames {
+		t, ok := st.byGame[g.Name()]
+		if !ok || t.finished+len(t.firstMoves) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", g.Title())
+		// first-move advantage: does going first (X) actually help?
+		if t.finished > 0 {
+			fmt.Fprintln(w, "  Results:")
+			for _, result := range []string{PlayerX, PlayerO, Draw} {
+				fmt.Fprintf(w, "    %-7s %5.1f%% (%d)\n", resultLabel(result), t.share(result), t.results[result])
+			}
+		}
+		if len(t.firstMoves) > 0 {
+			fmt.Fprintln(w, "  Most common first moves:")
+			for _, mv := range t.openings(3) {
+				fmt.Fprintf(w, "    %s  %d\n", mv, t.firstMoves[mv])
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "Moves by hour of day (UTC):")
+	busiest := 0
+	for _, moves := range st.movesByHour {
+		busiest = max(busiest, moves)
+	}
+	var shades strings.Builder
+	for _, moves := range st.movesByHour {
+		shade := 0
+		if busiest > 0 {
+			shade = (moves*(len(heatmapShades)-1) + busiest - 1) / busiest
+		}
+		shades.WriteRune(heatmapShades[shade])
+		shades.WriteRune(heatmapShades[shade])
+	}
+	fmt.Fprintf(w, "  |%s|\n", shades.String())
+	fmt.Fprintln(w, "   0     3     6     9     12    15    18    21")
+}
@@ -0,0 +1,87 @@
+// Package animation drives short frame-by-frame terminal animations from a
+// bubbletea Update loop. It knows nothing about what's being drawn, a model
+// asks its Sequence which frame it's on and renders that.
+package animation
+
+import (
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// lastID hands out unique sequence ids so frames from one sequence never advance another
+var lastID atomic.Int64
+
+// FrameMsg tells a running Sequence to advance a frame
+type FrameMsg struct {
+	id  int64
+	tag int
+}
+
+// Sequence plays a fixed number of frames at a steady interval
+type Sequence struct {
+	Frames   int
+	Interval time.Duration
+
+	id      int64
+	tag     int // bumped on every start/stop so stale frames are ignored
+	frame   int
+	running bool
+}
+
+// New creates a stopped sequence of frames played every interval
+func New(frames int, interval time.Duration) Sequence {
+	return Sequence{
+		Frames:   frames,
+		Interval: interval,
+		id:       lastID.Add(1),
+	}
+}
+
+// Start (re)starts the sequence from the first frame
+func (s Sequence) Start() (Sequence, tea.Cmd) {
+	s.tag++
+	s.frame = 0
+	s.running = true
+	return s, s.next()
+}
+
+// Stop ends the sequence early, any frames already scheduled are dropped
+func (s Sequence) Stop() Sequence {
+	s.tag++
+	s.running = false
+	return s
+}
+
+// Update advances the sequence when one of its frames is due
+func (s Sequence) Update(msg tea.Msg) (Sequence, tea.Cmd) {
+	frame, ok := msg.(FrameMsg)
+	if !ok || frame.id != s.id || frame.tag != s.tag || !s.running {
+		return s, nil
+	}
+
+	s.frame++
+	if s.frame >= s.Frames {
+		s.running = false
+		return s, nil
+	}
+	return s, s.next()
+}
+
+// Running reports whether the sequence is still playing
+func (s Sequence) Running() bool {
+	return s.running
+}
+
+// Frame is the index of the current frame
+func (s Sequence) Frame() int {
+	return s.frame
+}
+
+func (s Sequence) next() tea.Cmd {
+	id, tag := s.id, s.tag
+	return tea.Tick(s.Interval, func(time.Time) tea.Msg {
+		return FrameMsg{id: id, tag: tag}
+	})
+}
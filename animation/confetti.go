@@ -0,0 +1,38 @@
+package animation
+
+import "math/rand/v2"
+
+// confettiPieces are the shapes falling confetti is drawn with
+var confettiPieces = []rune{'*', '+', '.', 'o', '~', '\'', ','}
+
+// Confetti returns one frame of falling confetti as height rows of width
+// runes, with ' ' wherever there's no confetti. The same seed always gives the
+// same fall, so a frame can be redrawn at any time.
+func Confetti(seed uint64, width, height, frame int) [][]rune {
+	layer := make([][]rune, height)
+	for y := range layer {
+		layer[y] = make([]rune, width)
+		for x := range layer[y] {
+			layer[y][x] = ' '
+		}
+	}
+
+	// a few pieces per 4 columns, each starting somewhere above the top
+	// edge and falling at its own speed with a little sideways drift
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	pieces := max(width/4, 1) * 3
+	for range pieces {
+		x := rng.IntN(max(width, 1))
+		start := -rng.IntN(3*height + 1)
+		speed := 1 + rng.IntN(2)
+		drift := rng.IntN(3) - 1
+		piece := confettiPieces[rng.IntN(len(confettiPieces))]
+
+		y := start + frame*speed
+		x += drift * (frame / 3)
+		if y >= 0 && y < height && x >= 0 && x < width {
+			layer[y][x] = piece
+		}
+	}
+	return layer
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	bolt "go.etcd.io/bbolt"
+)
+
+// annotationsBucket holds the notes on each annotated game as a JSON list of
+// annotations, by replayKey
+var annotationsBucket = []byte("annotations")
+
+// MaxAnnotationLength is how many terminal cells a note on a move can take up
+const MaxAnnotationLength = 100
+
+// annotation is a note a player left on one move of a game, for whoever
+// watches it back, like a coach going over it
+type annotation struct {
+	Step     int       `json:"step"` // the move it's on, from 1, 0 is before the first
+	Author   string    `json:"author"`
+	AuthorID string    `json:"author_id"`
+	Text     string    `json:"text"`
+	At       time.Time `json:"at"`
+}
+
+// replayKey is what a game's notes are kept under: its session's ID and
+// when its first move was made, as a session can have several games
+func replayKey(r replay) []byte {
+	return []byte(r.id + "/" + r.played.UTC().Format(time.RFC3339Nano))
+}
+
+// annotations returns the notes on a game, in the order its moves were made
+func (ps *playerStore) annotations(r replay) ([]annotation, error) {
+	if ps == nil {
+		return nil, nil
+	}
+	var notes []annotation
+	err := ps.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(annotationsBucket).Get(replayKey(r))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &notes)
+	})
+	return notes, err
+}
+
+// annotate puts a player's note on a move of a game, in place of the one
+// they had there. An empty note takes theirs off.
+func (ps *playerStore) annotate(r replay, note annotation) error {
+	return ps.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(annotationsBucket)
+		var notes []annotation
+		if data := bucket.Get(replayKey(r)); data != nil {
+			if err := json.Unmarshal(data, &notes); err != nil {
+				return err
+			}
+		}
+		notes = slices.DeleteFunc(notes, func(a annotation) bool { return a.Step == note.Step && a.AuthorID == note.AuthorID })
+		if note.Text != "" {
+			notes = append(notes, note)
+		}
+		slices.SortStableFunc(notes, func(a, b annotation) int { return a.Step - b.Step })
+		if len(notes) == 0 {
+			return bucket.Delete(replayKey(r))
+		}
+		data, err := json.Marshal(notes)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(replayKey(r), data)
+	})
+}
+
+// forgetAnnotations takes a player's notes off every game
+func (ps *playerStore) forgetAnnotations(playerID string) error {
+	if ps == nil || !persistent(playerID) {
+		return nil
+	}
+	return ps.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(annotationsBucket)
+		changed := map[string][]annotation{}
+		err := bucket.ForEach(func(key, data []byte) error {
+			var notes []annotation
+			if err := json.Unmarshal(data, &notes); err != nil {
+				return err
+			}
+			if kept := slices.DeleteFunc(slices.Clone(notes), func(a annotation) bool { return a.AuthorID == playerID }); len(kept) < len(notes) {
+				changed[string(key)] = kept
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// a bucket can't change while it's being gone through
+		for key, notes := range changed {
+			if len(notes) == 0 {
+				err = bucket.Delete([]byte(key))
+			} else {
+				var data []byte
+				if data, err = json.Marshal(notes); err == nil {
+					err = bucket.Put([]byte(key), data)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// notesOn picks out the notes on one move
+func notesOn(notes []annotation, step int) []annotation {
+	var on []annotation
+	for _, a := range notes {
+		if a.Step == step {
+			on = append(on, a)
+		}
+	}
+	return on
+}
+
+// canAnnotate reports whether the player can leave notes on the game
+// they're watching back: it takes a key to know them by and a database to
+// keep them in, and in a replay room it's up to whoever's driving
+func (m model) canAnnotate() bool {
+	return playerDB != nil && persistent(m.playerID) && !m.replays.file && (m.replayRoom == nil || m.driving)
+}
+
+// loadAnnotations reads the notes on the game being watched back
+func (m *model) loadAnnotations() {
+	notes, err := playerDB.annotations(m.replays.replays[m.replays.choice])
+	m.annotationErr = ""
+	if err != nil {
+		m.annotationErr = "Couldn't read the notes: " + err.Error()
+	}
+	m.annotations = notes
+}
+
+// startAnnotating opens the note on the move on the board, with whatever
+// the player already had there
+func (m model) startAnnotating() model {
+	m.annotating = true
+	m.annotationInput = ""
+	m.annotationErr = ""
+	for _, a := range notesOn(m.annotations, m.replays.step) {
+		if a.AuthorID == m.playerID {
+			m.annotationInput = a.Text
+		}
+	}
+	return m
+}
+
+// updateAnnotation handles keys while typing a note on a move
+func (m model) updateAnnotation(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.annotating = false
+		m.annotationInput = ""
+	case tea.KeyEnter:
+		r := m.replays.replays[m.replays.choice]
+		note := annotation{
+			Step:     m.replays.step,
+			Author:   m.playerName,
+			AuthorID: m.playerID,
+			Text:     sanitize(m.annotationInput, MaxAnnotationLength),
+			At:       time.Now().UTC(),
+		}
+		if err := playerDB.annotate(r, note); err != nil {
+			m.annotationErr = "Couldn't save the note: " + err.Error()
+			return m, nil
+		}
+		m.annotating = false
+		m.annotationInput = ""
+		m.loadAnnotations()
+		if m.replayRoom != nil {
+			m.replayRoom.annotated()
+		}
+	case tea.KeyBackspace:
+		if m.annotationInput != "" {
+			_, size := utf8.DecodeLastRuneInString(m.annotationInput)
+			m.annotationInput = m.annotationInput[:len(m.annotationInput)-size]
+		}
+	case tea.KeySpace, tea.KeyRunes:
+		for _, r := range msg.Runes {
+			if !unicode.IsControl(r) && ansi.StringWidth(m.annotationInput+string(r)) <= MaxAnnotationLength {
+				m.annotationInput += string(r)
+			}
+		}
+	}
+	return m, nil
+}
+
+// annotationsView draws the notes on the move on the board, and the note
+// being typed
+func (m model) annotationsView() string {
+	var s string
+	for _, a := range notesOn(m.annotations, m.replays.step) {
+		s += m.fit(headerStyle.Render("Note from "+a.Author+": ")+a.Text) + "\n"
+	}
+	if m.annotating {
+		s += m.fit(headerStyle.Render("Note on this move: ")+m.annotationInput+"_") + "\n"
+		s += footerStyle.Render("Press enter to save it (empty takes yours off), esc to cancel") + "\n"
+	}
+	if m.annotationErr != "" {
+		s += lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render(m.annotationErr) + "\n"
+	}
+	return s
+}
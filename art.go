@@ -0,0 +1,128 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// artDir is where operators keep custom art, set with -art. Empty means the
+// built-in art is used everywhere.
+var artDir string
+
+// Art files an operator can put in <artDir>/<game>/, each one replaces a
+// piece of built-in art. They're plain text, the game colors them in.
+const (
+	artTitleFile = "banner.txt" // heads the board and the menus
+	artWinFile   = "win.txt"    // the win screen, unless there's one for the side that won
+	artXWinsFile = "x-wins.txt"
+	artOWinsFile = "o-wins.txt"
+	artDrawFile  = "draw.txt"
+)
+
+const (
+	// MaxTitleLines is as tall as a custom title can be, the board and
+	// menus are laid out around the built-in one
+	MaxTitleLines = 4
+
+	// MaxBannerLines is as tall as custom win and draw art can be
+	MaxBannerLines = 10
+
+	// maxArtFileSize stops a stray big file being read in as art
+	maxArtFileSize = 64 << 10
+)
+
+// Art is what a game's screens are decorated with
+type Art struct {
+	Title string // the title, wrapped in a blank line above and below like titleArt
+	XWins string // win and draw banners, empty ones are drawn in the banner font
+	OWins string
+	Draw  string
+}
+
+// gameArt is the custom art for each game that has some, see loadArt
+var gameArt = map[string]Art{}
+
+// artFor returns a game's art, with the built-in title if it has no custom one
+func artFor(game gameRules) Art {
+	art := gameArt[game.Name()]
+	if art.Title == "" {
+		art.Title = game.Art()
+	}
+	return art
+}
+
+// loadArt reads the custom art for every game from dir. Missing files just
+// keep the built-in art, art that's unreadable or too big is an error.
+func loadArt(dir string) error {
+	for _, g := range boardGames {
+		game := g.Name()
+		var art Art
+		var errs []error
+		read := func(file string, maxLines int) string {
+			s, err := readArt(filepath.Join(dir, game, file), maxLines)
+			errs = append(errs, err)
+			return s
+		}
+		if title := read(artTitleFile, MaxTitleLines); title != "" {
+			art.Title = "\n" + title + "\n"
+		}
+		win := read(artWinFile, MaxBannerLines)
+		art.XWins = cmp.Or(read(artXWinsFile, MaxBannerLines), win)
+		art.OWins = cmp.Or(read(artOWinsFile, MaxBannerLines), win)
+		art.Draw = read(artDrawFile, MaxBannerLines)
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+		gameArt[game] = art
+	}
+	return nil
+}
+
+// readArt reads an art file and cleans it up, it's empty if there's no file
+func readArt(path string, maxLines int) (string, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if info.Size() > maxArtFileSize {
+		return "", fmt.Errorf("%s: art can be at most %d bytes", path, maxArtFileSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	art := cleanArt(string(data))
+	if lines := strings.Count(art, "\n") + 1; lines > maxLines {
+		return "", fmt.Errorf("%s: art is %d lines, at most %d fit", path, lines, maxLines)
+	}
+	return art, nil
+}
+
+// cleanArt keeps art to plain characters, so the game's colors apply and
+// nothing in the file can mess with players' terminals. Tabs become spaces,
+// trailing space and blank lines around the art are dropped.
+func cleanArt(s string) string {
+	s = ansi.Strip(s)
+	s = strings.ReplaceAll(s, "\t", "    ")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		line = strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r) {
+				return -1
+			}
+			return r
+		}, line)
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
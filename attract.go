@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// How long the lobby sits untouched before the attract mode starts
+	AttractDelay = 30 * time.Second
+
+	// How long each attract mode page stays up
+	AttractPageDuration = 8 * time.Second
+
+	// How often the attract mode redraws, so the featured game is live
+	AttractRefresh = time.Second
+
+	// How many players and results the attract mode lists
+	AttractListSize = 5
+
+	// How long the attract mode reuses the top players before reading them
+	// from the database again
+	AttractLeaderboardTTL = AttractPageDuration
+)
+
+// Attract mode pages, in the order they're cycled through. Pages with
+// nothing to show are skipped.
+const (
+	attractLeaderboard = iota
+	attractFeatured
+	attractResults
+	attractPages
+)
+
+// attractMsg is the lobby's idle timer going off, it's dropped if there's
+// been a key press since it was set
+type attractMsg struct {
+	activity int
+}
+
+// attractTickMsg moves the attract mode along
+type attractTickMsg struct {
+	activity int
+}
+
+// result is a finished game, for the attract mode's recent results
+type result struct {
+	names  [2]string // X and O
+	winner string    // "X", "O" or Draw
+	at     time.Time
+}
+
+// resultLog keeps the last few results on the server
+type resultLog struct {
+	results []result // newest last
+	mutex   sync.Mutex
+}
+
+// recentResults is every game finished on the server, well, the last few
+var recentResults = &resultLog{}
+
+// add remembers a result, forgetting the oldest once there are enough
+func (l *resultLog) add(r result) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.results = append(l.results, r)
+	if len(l.results) > AttractListSize {
+		l.results = l.results[1:]
+	}
+}
+
+// list returns the results, newest first
+func (l *resultLog) list() []result {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	results := slices.Clone(l.results)
+	slices.Reverse(results)
+	return results
+}
+
+// topPlayersCache keeps the attract mode's leaderboard for a while, it's a
+// scan of the whole database and every idle lobby redraws once a second
+type topPlayersCache struct {
+	board []publicPlayer
+	at    time.Time
+	mutex sync.Mutex
+}
+
+// topPlayers is the leaderboard shared by every attract mode
+var topPlayers = &topPlayersCache{}
+
+// get returns the top players, reading them again if they're older than
+// AttractLeaderboardTTL
+func (c *topPlayersCache) get() ([]publicPlayer, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.board != nil && time.Since(c.at) < AttractLeaderboardTTL {
+		return c.board, nil
+	}
+	board, err := playerDB.leaderboard(enabledGames[0].Name(), AttractListSize)
+	if err != nil {
+		return nil, err
+	}
+	c.board, c.at = board, time.Now()
+	return board, nil
+}
+
+// idle starts the lobby's idle timer, it goes off unless there's a key
+// press (which bumps lobby.activity) in the meantime
+func (m model) idle() tea.Cmd {
+	activity := m.lobby.activity
+	return tea.Tick(AttractDelay, func(time.Time) tea.Msg {
+		return attractMsg{activity: activity}
+	})
+}
+
+// attractTick waits for the next attract mode redraw
+func (m model) attractTick() tea.Cmd {
+	activity := m.lobby.activity
+	return tea.Tick(AttractRefresh, func(time.Time) tea.Msg {
+		return attractTickMsg{activity: activity}
+	})
+}
+
+// startAttract starts the attract mode if the lobby is still idle and
+// there's something to show
+func (m model) startAttract(msg attractMsg) (tea.Model, tea.Cmd) {
+	if !m.lobby.active || m.lobby.enteringCode || msg.activity != m.lobby.activity {
+		return m, nil
+	}
+	m.lobby.attractStarted = time.Now()
+	m.lobby.shownPage = m.attractPage()
+	if m.lobby.shownPage < 0 {
+		// nothing to show yet, look again in a while
+		return m, m.idle()
+	}
+	m.lobby.attract = true
+	return m, tea.Batch(m.clearScreen(), m.attractTick())
+}
+
+// updateAttract redraws the attract mode, clearing the screen when the page
+// changes
+func (m model) updateAttract(msg attractTickMsg) (tea.Model, tea.Cmd) {
+	if !m.lobby.attract || msg.activity != m.lobby.activity {
+		return m, nil
+	}
+	page := m.attractPage()
+	if page < 0 {
+		return m.stopAttract()
+	}
+	if page != m.lobby.shownPage {
+		m.lobby.shownPage = page
+		return m, tea.Batch(m.clearScreen(), m.attractTick())
+	}
+	return m, m.attractTick()
+}
+
+// stopAttract goes back to the lobby menu
+func (m model) stopAttract() (tea.Model, tea.Cmd) {
+	m.lobby.attract = false
+	return m, tea.Batch(m.clearScreen(), m.idle())
+}
+
+// attractPage is the page to show now, cycling through the ones with
+// something on them, -1 if none of them have
+func (m model) attractPage() int {
+	var pages []int
+	for page := range attractPages {
+		if m.attractView(page) != "" {
+			pages = append(pages, page)
+		}
+	}
+	if len(pages) == 0 {
+		return -1
+	}
+	n := int(time.Since(m.lobby.attractStarted) / AttractPageDuration)
+	return pages[n%len(pages)]
+}
+
+// featuredGame is the game in progress with the most spectators
+func featuredGame() *GameSession {
+	var featured *GameSession
+	most := -1
+	for _, game := range sessionManager.watchable() {
+		game.mutex.RLock()
+		if game.Spectators > most {
+			featured, most = game, game.Spectators
+		}
+		game.mutex.RUnlock()
+	}
+	return featured
+}
+
+// attractView draws one attract mode page, or nothing if there's nothing
+// on it to show
+func (m model) attractView(page int) string {
+	var s string
+	switch page {
+	case attractLeaderboard:
+		if playerDB == nil {
+			return ""
+		}
+		board, err := topPlayers.get()
+		if err != nil || len(board) == 0 {
+			return ""
+		}
+		s += headerStyle.Render("  Top "+enabledGames[0].Title()+" players") + "\n\n"
+		for _, p := range board {
+			s += fmt.Sprintf("  %d. %s", p.Rank, p.Name) + footerStyle.Render(fmt.Sprintf(" %s %d (%d-%d-%d)", p.Title, p.Rating, p.Wins, p.Losses, p.Draws)) + "\n"
+		}
+
+	case attractFeatured:
+		game := featuredGame()
+		if game == nil {
+			return ""
+		}
+		game.mutex.RLock()
+		board := game.Board
+		if f, ok := game.delayedFrame(); ok {
+			board = f.board // lobby players count as spectators
+		}
+		s += headerStyle.Render("  Now playing") + footerStyle.Render("  game "+game.ID+watchers(game.Spectators)) + "\n\n"
+		names := game.shownNames()
+		s += "  " + styledPlayer(PlayerX) + " " + names[0] + footerStyle.Render("  vs  ") + styledPlayer(PlayerO) + " " + names[1] + "\n\n"
+		for y, row := range board {
+			s += boardIndent
+			for x, cell := range row {
+				switch {
+				case !game.Rules.playable(y, x):
+					s += "   "
+				case cell == Empty:
+					s += cellStyle.Render("[ ]")
+				case crowned(cell):
+					s += cellStyle.Render("{") + styledPlayer(owner(cell)) + cellStyle.Render("}")
+				default:
+					s += cellStyle.Render("[") + styledPlayer(cell) + cellStyle.Render("]")
+				}
+			}
+			s += "\n"
+		}
+		game.mutex.RUnlock()
+
+	case attractResults:
+		results := recentResults.list()
+		if len(results) == 0 {
+			return ""
+		}
+		s += headerStyle.Render("  Recent results") + "\n\n"
+		for _, r := range results {
+			var line string
+			switch r.winner {
+			case PlayerX:
+				line = r.names[0] + footerStyle.Render(" beat ") + r.names[1]
+			case PlayerO:
+				line = r.names[1] + footerStyle.Render(" beat ") + r.names[0]
+			default:
+				line = r.names[0] + footerStyle.Render(" drew with ") + r.names[1]
+			}
+			s += "  " + line + footerStyle.Render("  "+formatAgo(time.Since(r.at))) + "\n"
+		}
+	}
+	return s
+}
+
+// formatAgo says roughly how long ago something happened
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh ago", int(d.Hours()))
+}
+
+// attractModeView draws the attract mode's current page
+func (m model) attractModeView() string {
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+	s += m.attractView(max(m.attractPage(), 0))
+	s += footerStyle.Render("\n  Press any key for the menu") + "\n"
+	return s
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"tictactui/matchmaking"
+)
+
+// autoTournamentSize is how many players waiting in the queue at once call
+// an impromptu tournament, set with -auto-tournament. 0 never does.
+var autoTournamentSize int
+
+const (
+	// TournamentCallDuration is how long lobby players have to sign up for
+	// an impromptu tournament
+	TournamentCallDuration = 60 * time.Second
+
+	// TournamentCallSize caps an impromptu tournament's field, the bracket
+	// is drawn straight away if it fills
+	TournamentCallSize = 16
+)
+
+// tournamentCall is an impromptu tournament taking sign ups in the lobby
+type tournamentCall struct {
+	tournament *matchmaking.Tournament
+	closes     time.Time
+}
+
+// lobbyMsg says there's something new to show in the lobby
+type lobbyMsg struct{}
+
+// watchLobby waits for the next change worth redrawing the lobby for
+func watchLobby() tea.Cmd {
+	changed := sessionManager.watchSessions()
+	return func() tea.Msg {
+		<-changed
+		return lobbyMsg{}
+	}
+}
+
+// checkLobby looks for a tournament call straight away, to start its
+// countdown
+func checkLobby() tea.Msg {
+	return tickMsg(time.Now())
+}
+
+// updateLobbyCall keeps watching for tournament calls, and counts down the
+// one taking sign ups while the player's in the lobby
+func (m model) updateLobbyCall() (tea.Model, tea.Cmd) {
+	return m, tea.Batch(watchLobby(), m.startTicking())
+}
+
+// maybeCallTournament calls an impromptu tournament if enough players are
+// waiting in the queue and there isn't one taking sign ups already
+func (sm *SessionManager) maybeCallTournament() {
+	if autoTournamentSize == 0 || sm.queue.Waiting() < autoTournamentSize {
+		return
+	}
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if sm.call != nil {
+		return
+	}
+	closes := time.Now().Add(TournamentCallDuration)
+	t := newTournament(TournamentCallSize, closes)
+	sm.call = &tournamentCall{tournament: t, closes: closes}
+	time.AfterFunc(TournamentCallDuration, func() { sm.closeCall(t) })
+	sm.sessionsChanged()
+}
+
+// closeCall stops taking sign ups for an impromptu tournament and draws its
+// bracket, or calls it off if too few signed up
+func (sm *SessionManager) closeCall(t *matchmaking.Tournament) {
+	sm.mutex.Lock()
+	if sm.call != nil && sm.call.tournament == t {
+		sm.call = nil
+		sm.sessionsChanged()
+	}
+	sm.mutex.Unlock()
+	if err := t.Close(); err != nil && !errors.Is(err, matchmaking.ErrTooFewPlayers) {
+		log.Printf("closing tournament sign ups: %v", err)
+	}
+}
+
+// tournamentCall returns the impromptu tournament taking sign ups, if there is one
+func (sm *SessionManager) tournamentCall() (tournamentCall, bool) {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	if sm.call == nil {
+		return tournamentCall{}, false
+	}
+	return *sm.call, true
+}
+
+// signUp enters the player in the impromptu tournament taking sign ups
+func (m model) signUp() (tea.Model, tea.Cmd) {
+	call, ok := sessionManager.tournamentCall()
+	if !ok {
+		return m, nil
+	}
+	t := call.tournament
+	if err := t.Register(m.player()); err != nil {
+		m.lobby.err = "Couldn't sign up: " + strings.TrimPrefix(err.Error(), "matchmaking: ")
+		return m, nil
+	}
+	playerID, done := m.playerID, m.done
+	go func() {
+		<-done
+		t.Withdraw(playerID)
+	}()
+	m.lobby = lobby{}
+	m.tournament = t
+	tick := m.startTicking() // the countdown to sign ups closing
+	return m, tea.Batch(m.clearScreen(), checkBracket, tick)
+}
+
+// leaveTournament goes back to the lobby from an impromptu tournament,
+// forfeiting if it's still going
+func (m model) leaveTournament() (tea.Model, tea.Cmd) {
+	if _, over := m.tournament.Champion(); !over {
+		m.tournament.Withdraw(m.playerID)
+	}
+	f := m.fresh()
+	f.tournament = nil
+	return f.backToLobby()
+}
+
+// tournamentCallView invites lobby players to sign up for the impromptu
+// tournament, if there is one
+func (m model) tournamentCallView() string {
+	call, ok := sessionManager.tournamentCall()
+	if !ok {
+		return ""
+	}
+	left := int(max(time.Until(call.closes), 0).Seconds())
+	signedUp := len(call.tournament.Players())
+	return headerStyle.Render(fmt.Sprintf("  A tournament starts in %ds, %d signed up. Press t to join!", left, signedUp)) + "\n\n"
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	"slices"
+)
+
+// avatars are what players can pick to show next to their name, in the
+// lobby and on the versus screen. Each is a short bit of ASCII or one
+// emoji, so it fits wherever a name does. The first is none at all.
+var avatars = []string{"", "(^_^)", "(o_o)", "[-_-]", "(>_<)", "=^.^=", `\o/`, "<:3", "🐱", "🐶", "🦊", "🐸", "🐙", "🤖", "👾", "🌵"}
+
+// avatarNeedsKey says why a guest can't pick an avatar
+const avatarNeedsKey = "Avatars are kept with your key, connect with an SSH key to pick one"
+
+// avatarOf is the avatar in a player's record, none for players without a
+// key or with one that's since been taken off the list
+func avatarOf(playerID string) string {
+	rec, _ := playerDB.lookup(playerID)
+	if !slices.Contains(avatars, rec.Avatar) {
+		return ""
+	}
+	return rec.Avatar
+}
+
+// withAvatar puts an avatar in front of a name, if there is one
+func withAvatar(avatar, name string) string {
+	if avatar == "" {
+		return name
+	}
+	return avatar + " " + name
+}
+
+// nextAvatar is the avatar step places on from this one, round the list
+// either way
+func nextAvatar(avatar string, step int) string {
+	i := slices.Index(avatars, avatar) + step
+	return avatars[(i%len(avatars)+len(avatars))%len(avatars)]
+}
+
+// setAvatar picks the player's avatar and saves it to their profile
+func (m *model) setAvatar(avatar string) {
+	m.avatar = avatar
+	err := playerDB.change(m.playerID, func(rec *PlayerRecord) {
+		rec.Avatar = avatar
+	})
+	if err != nil {
+		log.Printf("players: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestAvatars picks an avatar in the lobby and finds it on the versus
+// screen, on both sides of the board
+func TestAvatars(t *testing.T) {
+	ps, err := openPlayerStore(filepath.Join(t.TempDir(), "players.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.db.Close()
+	old := playerDB
+	t.Cleanup(func() { playerDB = old })
+	playerDB = ps
+
+	press := func(m model, key string) model {
+		t.Helper()
+		next, _ := m.updateLobbyMenu(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		return next.(model)
+	}
+
+	// a guest has nowhere to keep one
+	guest := initialModel()
+	guest.playerID, guest.playerName, guest.lobby.active = "session:b", "bea", true
+	if guest = press(guest, "a"); guest.avatar != "" || guest.lobby.err == "" {
+		t.Errorf("a guest picked %q, want an error saying why not", guest.avatar)
+	}
+
+	host := initialModel()
+	host.playerID, host.playerName, host.lobby.active = "SHA256:a", "ann", true
+	host = press(press(host, "a"), "a")
+	if host.avatar != avatars[2] || avatarOf("SHA256:a") != avatars[2] {
+		t.Fatalf("picked %q, saved %q, want %q", host.avatar, avatarOf("SHA256:a"), avatars[2])
+	}
+	if !strings.Contains(host.lobbyView(), avatars[2]+" ann") {
+		t.Error("the lobby doesn't show their avatar")
+	}
+
+	// one that's been taken off the list isn't shown
+	if err := ps.change("SHA256:c", func(rec *PlayerRecord) { rec.Avatar = "(gone)" }); err != nil {
+		t.Fatal(err)
+	}
+	if got := avatarOf("SHA256:c"); got != "" {
+		t.Errorf("an avatar off the list came back as %q", got)
+	}
+
+	// both players see it before their game
+	session, seat, err := sessionManager.createRoom(host.player(), false, timeControl{}, handicapNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		sessionManager.mutex.Lock()
+		delete(sessionManager.sessions, session.ID)
+		sessionManager.mutex.Unlock()
+	})
+	host.sitDown(session, seat)
+	joined, seat, err := sessionManager.joinRoom(session.ID, guest.player())
+	if err != nil {
+		t.Fatal(err)
+	}
+	guest.sitDown(joined, seat)
+	for _, m := range []model{host, guest} {
+		m.syncSession()
+		if !strings.Contains(m.versusScreen(), avatars[2]+" ann") {
+			t.Errorf("%s's versus screen doesn't show ann's avatar", m.playerName)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// A backup is a gzipped tar of the player database, as players.db, and the
+// game journals the replays and stats come from, under journal/. It's taken
+// with `tictactui backup`, or by the server every -backup-every, and put
+// back with `tictactui restore`.
+
+// backupEvery is how often the server backs itself up, set with
+// -backup-every. 0 means it doesn't.
+var backupEvery time.Duration
+
+// backupDir is where the server's scheduled backups go, set with
+// -backup-dir. Empty means they only go to S3.
+var backupDir string
+
+// backupS3 is an S3-compatible bucket backups are uploaded to as well, like
+// https://s3.example.com/bucket/prefix, set with -backup-s3. Empty means
+// they aren't. The keys come from AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY.
+var backupS3 string
+
+// backupS3Region is the region backups are signed for, set with
+// -backup-s3-region
+var backupS3Region = "us-east-1"
+
+// BackupsKept is how many scheduled backups stay in backupDir, the oldest
+// go first
+const BackupsKept = 7
+
+// backupDBName and backupJournalDir are where the database and journals
+// sit in a backup
+const (
+	backupDBName     = "players.db"
+	backupJournalDir = "journal"
+)
+
+// writeBackup writes a backup of the database and journals to w. The
+// database is copied in one read transaction, so it's consistent even
+// while the server's using it.
+func writeBackup(w io.Writer) error {
+	if playerDB == nil && journalDir == "" {
+		return errors.New("nothing to back up, pass -db or -journal")
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	if playerDB != nil {
+		err := playerDB.db.View(func(tx *bolt.Tx) error {
+			hdr := &tar.Header{Name: backupDBName, Mode: 0o600, Size: tx.Size(), ModTime: now}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			_, err := tx.WriteTo(tw)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("backing up the database: %w", err)
+		}
+	}
+
+	if journalDir != "" {
+		paths, err := filepath.Glob(filepath.Join(journalDir, "*.jsonl"))
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			// journals are still being written to, so take what's there now
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			hdr := &tar.Header{Name: path.Join(backupJournalDir, filepath.Base(p)), Mode: 0o644, Size: int64(len(data)), ModTime: now}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if _, err := tw.Write(data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// backupName names a backup by when it was taken, so they sort oldest first
+func backupName(t time.Time) string {
+	return "tictactui-" + t.UTC().Format("20060102-150405") + ".tar.gz"
+}
+
+// saveBackup takes a backup to the file at dest, and uploads it to S3 as
+// well if there's a bucket for it
+func saveBackup(dest string) error {
+	var buf bytes.Buffer
+	if err := writeBackup(&buf); err != nil {
+		return err
+	}
+	// written next to it and moved in place so a half-written backup never
+	// replaces a good one
+	tmp := dest + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return err
+	}
+	if backupS3 != "" {
+		return putS3(filepath.Base(dest), buf.Bytes())
+	}
+	return nil
+}
+
+// runBackup is `tictactui backup`, for admins. The server keeps the
+// database locked, so it has to be stopped; a running server backs itself
+// up with -backup-every.
+func runBackup(dest string) error {
+	if dest == "" {
+		dest = backupName(time.Now())
+	}
+	if err := saveBackup(dest); err != nil {
+		return err
+	}
+	fmt.Println("Backed up to", dest)
+	return nil
+}
+
+// scheduleBackups backs the server up every backupEvery, in backupDir and
+// S3, until it stops
+func scheduleBackups() {
+	for range time.Tick(backupEvery) {
+		if err := scheduledBackup(); err != nil {
+			log.Printf("backing up: %v", err)
+		}
+	}
+}
+
+// scheduledBackup takes one scheduled backup and clears out the old ones
+func scheduledBackup() error {
+	name := backupName(time.Now())
+	if backupDir == "" {
+		var buf bytes.Buffer
+		if err := writeBackup(&buf); err != nil {
+			return err
+		}
+		return putS3(name, buf.Bytes())
+	}
+	if err := os.MkdirAll(backupDir, 0o700); err != nil {
+		return err
+	}
+	if err := saveBackup(filepath.Join(backupDir, name)); err != nil {
+		return err
+	}
+	old, err := filepath.Glob(filepath.Join(backupDir, "tictactui-*.tar.gz"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(old)
+	for _, p := range old[:max(len(old)-BackupsKept, 0)] {
+		if err := os.Remove(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runRestore is `tictactui restore`, for admins: it puts a backup's
+// database at -db and its journals in -journal, over what's there. The
+// server has to be stopped, as it keeps the database locked. Nothing is
+// replaced until the whole backup has been read, so a damaged one leaves
+// the server as it was.
+func runRestore(src string) error {
+	if src == "" {
+		return errors.New("usage: tictactui -db players.db [-journal dir] restore backup.tar.gz")
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	staged, err := stageBackup(src, f)
+	if err != nil {
+		for _, s := range staged {
+			os.Remove(s.tmp)
+		}
+		return err
+	}
+	if playerDB != nil {
+		// let go of the lock so the restored file can replace it
+		playerDB.db.Close()
+		playerDB = nil
+	}
+
+	journals := 0
+	for _, s := range staged {
+		if err := os.Rename(s.tmp, s.dest); err != nil {
+			return err
+		}
+		if s.dest == dbPath {
+			fmt.Println("Restored the player database to", dbPath)
+		} else {
+			journals++
+		}
+	}
+	if journals > 0 {
+		fmt.Printf("Restored %d game journals to %s\n", journals, journalDir)
+	}
+	return nil
+}
+
+// stagedFile is a file from a backup written next to where it goes, to be
+// moved in place once the whole backup has been read
+type stagedFile struct {
+	tmp, dest string
+}
+
+// stageBackup reads a backup, checking every entry in it, and writes its
+// files next to where they go. It returns what it staged even when it
+// fails, for clearing up.
+func stageBackup(src string, r io.Reader) ([]stagedFile, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	var staged []stagedFile
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return staged, err
+		}
+		var dest string
+		var perm os.FileMode
+		dir, name := path.Split(hdr.Name)
+		switch {
+		case hdr.Name == backupDBName:
+			if dbPath == "" {
+				return staged, errors.New("the backup has a player database, pass -db for where it goes")
+			}
+			dest, perm = dbPath, 0o600
+		case dir == backupJournalDir+"/" && strings.HasSuffix(name, ".jsonl"):
+			if journalDir == "" {
+				return staged, errors.New("the backup has game journals, pass -journal for where they go")
+			}
+			if err := os.MkdirAll(journalDir, 0o755); err != nil {
+				return staged, err
+			}
+			dest, perm = filepath.Join(journalDir, name), 0o644
+		default:
+			return staged, fmt.Errorf("%s isn't a tictactui backup: it has %s in it", src, hdr.Name)
+		}
+		tmp, err := stageFile(dest, tr, perm)
+		if err != nil {
+			return staged, err
+		}
+		staged = append(staged, stagedFile{tmp, dest})
+	}
+	// the gzip checksum is only checked at the very end
+	if _, err := io.Copy(io.Discard, gz); err != nil {
+		return staged, err
+	}
+	return staged, nil
+}
+
+// stageFile writes one file from a backup next to dest, in full or not at
+// all, and returns where it went
+func stageFile(dest string, r io.Reader, perm os.FileMode) (string, error) {
+	tmp := dest + ".restore"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
+
+// putS3 uploads a backup to the backupS3 bucket under name
+func putS3(name string, body []byte) error {
+	u, err := url.Parse(backupS3)
+	if err != nil {
+		return err
+	}
+	key, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if key == "" || secret == "" {
+		return errors.New("uploading to S3 needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	u.Path = path.Join("/", u.Path, name)
+
+	req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	signS3(req, backupS3Region, key, secret, time.Now())
+
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		why, _ := io.ReadAll(io.LimitReader(resp.Body, 500))
+		return fmt.Errorf("uploading %s to S3: %s: %s", name, resp.Status, strings.TrimSpace(string(why)))
+	}
+	return nil
+}
+
+// signS3 signs a request for S3 with AWS signature version 4, over the
+// host and every header already set. X-Amz-Content-Sha256 has to be set
+// to the body's hash.
+func signS3(req *http.Request, region, key, secret string, t time.Time) {
+	t = t.UTC()
+	stamp, day := t.Format("20060102T150405Z"), t.Format("20060102")
+	req.Header.Set("X-Amz-Date", stamp)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signed,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := day + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	k := []byte("AWS4" + secret)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		k = hmacSHA256(k, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		key, scope, signed, hex.EncodeToString(hmacSHA256(k, toSign))))
+}
+
+// hmacSHA256 is the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
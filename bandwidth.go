@@ -0,0 +1,64 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// slowLink is the round trip time from which a session switches to low
+// bandwidth rendering, set with -slow-link. 0 never switches.
+var slowLink = SlowLink
+
+const (
+	// SlowLink is the round trip time from which a session renders for a
+	// slow link by default
+	SlowLink = 400 * time.Millisecond
+
+	// LowBandwidthFPS caps how often a slow link is redrawn, a burst of
+	// changes is sent as one frame instead of several
+	LowBandwidthFPS = 10
+)
+
+// isSlowLink reports whether a connection with the given round trip time
+// should get low bandwidth rendering. 0 means it couldn't be measured.
+func isSlowLink(rtt time.Duration) bool {
+	return slowLink > 0 && rtt >= slowLink
+}
+
+// lite takes the styling out of a frame for low bandwidth rendering, which
+// is most of its bytes. The screen still makes sense without color: cells
+// mark the cursor and wins with their brackets instead, see liteCell.
+func (m model) lite(frame string) string {
+	if !m.lowBandwidth {
+		return frame
+	}
+	return ansi.Strip(frame)
+}
+
+// liteCell draws a board cell without styling, fullCell is how it's drawn
+// when nothing marks it out
+func (m model) liteCell(x, y int, content, fullCell string, winning, held bool) string {
+	cursorY, cursorX := m.cursorCell()
+	switch {
+	case winning:
+		return "*" + content + "*"
+	case m.hasCursor() && cursorX == x && cursorY == y:
+		return ">" + content + "<"
+	case !m.spectating && m.opponentSharing && m.opponentCursor.row == y && m.opponentCursor.col == x:
+		return "(" + content + ")"
+	case held:
+		return "|" + content + "|"
+	}
+	return fullCell
+}
+
+// clearScreen wipes the screen before a new one is drawn, except on a slow
+// link where a full redraw costs more than the odd leftover line
+func (m model) clearScreen() tea.Cmd {
+	if m.lowBandwidth {
+		return nil
+	}
+	return tea.ClearScreen
+}
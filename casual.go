@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Casual games are quick matches that don't count: no rating at stake, no
+// clocks, no penalty for leaving early, and a move can be taken back if
+// both players agree. Their results are kept apart from rated ones.
+
+// sessionPosition is a casual game as it stood before a move, to take the
+// move back to
+type sessionPosition struct {
+	board         [][]string
+	currentPlayer int
+	chain         *coord
+	moves         int
+}
+
+// remember keeps the position before a move in a casual game. Call with the
+// session locked.
+func (gs *GameSession) remember() {
+	if gs.Casual {
+		gs.history = append(gs.history, sessionPosition{copyBoard(gs.Board), gs.CurrentPlayer, gs.Chain, gs.Moves})
+	}
+}
+
+// offerTakeback records that a player wants the last move taken back, and
+// takes it back once both players do. Offers lapse with the next move.
+func (gs *GameSession) offerTakeback(player string) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if !gs.Casual || gs.Winner != Empty || len(gs.history) == 0 {
+		return
+	}
+	gs.TakebackOffered[playerIndex(player)] = true
+	if gs.TakebackOffered[0] && gs.TakebackOffered[1] {
+		p := gs.history[len(gs.history)-1]
+		gs.history = gs.history[:len(gs.history)-1]
+		gs.Board, gs.CurrentPlayer, gs.Chain, gs.Moves = p.board, p.currentPlayer, p.chain, p.moves
+		gs.TakebackOffered = [2]bool{}
+		gs.TurnStarted = time.Now()
+		gs.journal.Record(Event{Type: EventTakeback, Player: player})
+	}
+	gs.notify()
+}
+
+// takebackOfferView says who wants the last move taken back, if anyone does
+func (m model) takebackOfferView() string {
+	switch {
+	case m.takebackOffered[playerIndex(opponentOf(m.playerSymbol))]:
+		return headerStyle.Render("Your opponent wants to take the last move back, press u to agree") + "\n"
+	case m.takebackOffered[playerIndex(m.playerSymbol)]:
+		return footerStyle.Render("Waiting for your opponent to agree to take the last move back") + "\n"
+	}
+	return ""
+}
+
+// formatCasualRecord shows a player's casual results in a game, if they
+// have any
+func formatCasualRecord(playerID, game string) string {
+	r := sessionManager.queue.Record(playerID, game)
+	if r.PlayedCasual() == 0 {
+		return ""
+	}
+	return footerStyle.Render(fmt.Sprintf(" casual (%d-%d-%d)", r.CasualWins, r.CasualLosses, r.CasualDraws))
+}
+
+// recordFor shows a player's results next to their name in a game: their
+// rating and rated results, or their casual results in a casual game
+func (m model) recordFor(playerID string) string {
+	if m.casual {
+		return formatCasualRecord(playerID, m.rules.Name())
+	}
+	return formatRecord(playerID, m.rules.Name())
+}
+
+// stakesView says what the game means for the player's rating
+func (m model) stakesView(opponentID string) string {
+	if m.casual {
+		return "Casual game, no rating at stake"
+	}
+	return formatStakes(sessionManager.queue.Stakes(m.playerID, opponentID, m.rules.Name()))
+}
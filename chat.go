@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// MaxChatLength is how many terminal cells a chat message can take up
+	MaxChatLength = 60
+
+	// MaxChatHistory is how many messages a session keeps
+	MaxChatHistory = 20
+
+	// ChatLines is how many of the latest messages are shown under the board
+	ChatLines = 2
+)
+
+// mergeSpectatorChat shows spectators' chat to the players once their game
+// is over, set with -merge-spectator-chat
+var mergeSpectatorChat bool
+
+// chatMessage is something a player, or a spectator, said in a game
+type chatMessage struct {
+	Player string // "X" or "O", empty for spectators
+	Name   string
+	Text   string
+	At     time.Time
+}
+
+// say adds a chat message to the session and lets everyone know, the text
+// is sanitized first since it's going on someone else's screen
+func (gs *GameSession) say(player, name, text string) {
+	text = sanitize(text, MaxChatLength)
+	if text == "" {
+		return
+	}
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.Chat = append(gs.Chat, chatMessage{Player: player, Name: name, Text: text, At: time.Now()})
+	if len(gs.Chat) > MaxChatHistory {
+		gs.Chat = gs.Chat[len(gs.Chat)-MaxChatHistory:]
+	}
+	gs.journal.Record(Event{Type: EventChat, Player: player, Text: text})
+	gs.notify()
+}
+
+// spectate adds a message to the spectators' own chat, which the players
+// can't see while they play so nobody can tip them off
+func (gs *GameSession) spectate(playerID, name, text string) {
+	text = sanitize(text, MaxChatLength)
+	if text == "" {
+		return
+	}
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.SpectatorChat = append(gs.SpectatorChat, chatMessage{Name: name, Text: text, At: time.Now()})
+	if len(gs.SpectatorChat) > MaxChatHistory {
+		gs.SpectatorChat = gs.SpectatorChat[len(gs.SpectatorChat)-MaxChatHistory:]
+	}
+	said := Event{Type: EventSpectatorChat, Name: name, Text: text}
+	if persistent(playerID) {
+		said.ID = playerID // so it can go if they delete their data
+	}
+	gs.journal.Record(said)
+	gs.notify()
+}
+
+// updateChat handles keys while typing a chat message, none of them reach
+// the board
+func (m model) updateChat(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.chatting = false
+		m.chatInput = ""
+	case tea.KeyEnter:
+		if sanitize(m.chatInput, MaxChatLength) == "" {
+			// nothing to send, so nothing to count against them
+		} else if muted, ok := chatLimits.allow(m.playerID, time.Now()); !ok {
+			m.mutedUntil = time.Now().Add(muted)
+		} else if m.replayRoom != nil {
+			m.replayRoom.say(m.playerName, m.chatInput)
+		} else if m.spectating {
+			m.gameSession.spectate(m.playerID, m.playerName, m.chatInput)
+		} else {
+			m.gameSession.say(m.playerSymbol, m.playerName, m.chatInput)
+		}
+		m.chatting = false
+		m.chatInput = ""
+	case tea.KeyBackspace:
+		if m.chatInput != "" {
+			_, size := utf8.DecodeLastRuneInString(m.chatInput)
+			m.chatInput = m.chatInput[:len(m.chatInput)-size]
+		}
+	case tea.KeySpace, tea.KeyRunes:
+		// pastes are fine, it's all sanitized before anyone else sees it
+		for _, r := range msg.Runes {
+			if !unicode.IsControl(r) && ansi.StringWidth(m.chatInput+string(r)) <= MaxChatLength {
+				m.chatInput += string(r)
+			}
+		}
+	}
+	return m, nil
+}
+
+// chatView draws the latest messages, and the message being typed
+func (m model) chatView() string {
+	var s string
+	for _, msg := range m.chat[max(len(m.chat)-ChatLines, 0):] {
+		if msg.Player == "" {
+			// a replay room's, nobody in it is playing
+			s += m.fit(msg.Name+footerStyle.Render(": ")+msg.Text) + "\n"
+			continue
+		}
+		s += m.fit(styledPlayer(msg.Player)+" "+msg.Name+footerStyle.Render(": ")+msg.Text) + "\n"
+	}
+	for _, msg := range m.spectatorChat[max(len(m.spectatorChat)-ChatLines, 0):] {
+		s += m.fit(footerStyle.Render("watching ")+msg.Name+footerStyle.Render(": ")+msg.Text) + "\n"
+	}
+	if m.chatting {
+		s += m.fit(headerStyle.Render("Say: ")+m.chatInput+"_") + "\n"
+	}
+	if left := time.Until(m.mutedUntil); left > 0 {
+		s += m.fit(footerStyle.Render(fmt.Sprintf("Too many messages too fast, you're muted for %s", formatDuration(left.Round(time.Second))))) + "\n"
+	}
+	return s
+}
+
+// fit cuts a line to the terminal's width, so it can't wrap onto another
+func (m model) fit(line string) string {
+	if m.width == 0 {
+		return line
+	}
+	return ansi.Truncate(line, m.width, "…")
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// ChatBurst is how many chat messages a player can send in ChatWindow
+	// before they're muted for spamming
+	ChatBurst = 5
+
+	// ChatWindow is the stretch of time ChatBurst is counted over
+	ChatWindow = 10 * time.Second
+
+	// ChatForgiveAfter is how long a player has to go without being muted
+	// for their next mute to be a short one again
+	ChatForgiveAfter = time.Hour
+)
+
+// chatMutes is how long each mute lasts, longer every time a player spams
+// again before they're forgiven, the last one over and over
+var chatMutes = []time.Duration{30 * time.Second, 2 * time.Minute, 10 * time.Minute, time.Hour}
+
+// chatLimits keeps track of every player's chatting, by player ID, so a mute
+// follows them from game to game, and for key users across reconnects
+var chatLimits = &chatLimiter{players: map[string]*chatRecord{}}
+
+// chatLimiter mutes players who send too many chat messages too fast
+type chatLimiter struct {
+	players map[string]*chatRecord
+	swept   time.Time // when players who've been quiet a while were last forgotten
+	mutex   sync.Mutex
+}
+
+// chatRecord is one player's recent chatting
+type chatRecord struct {
+	sent       []time.Time // their messages in the last ChatWindow
+	mutes      int         // how many times they've been muted since they were last forgiven
+	mutedUntil time.Time
+}
+
+// allow records a chat message from a player if they can send it, or
+// reports how much longer they're muted for. The message that takes them
+// over ChatBurst isn't sent, it mutes them.
+func (cl *chatLimiter) allow(playerID string, now time.Time) (time.Duration, bool) {
+	cl.mutex.Lock()
+	defer cl.mutex.Unlock()
+	cl.sweep(now)
+
+	r, ok := cl.players[playerID]
+	if !ok {
+		r = &chatRecord{}
+		cl.players[playerID] = r
+	}
+	if now.Before(r.mutedUntil) {
+		return r.mutedUntil.Sub(now), false
+	}
+	if r.mutes > 0 && now.Sub(r.mutedUntil) >= ChatForgiveAfter {
+		r.mutes = 0
+	}
+	for len(r.sent) > 0 && now.Sub(r.sent[0]) >= ChatWindow {
+		r.sent = r.sent[1:]
+	}
+	if len(r.sent) >= ChatBurst {
+		mute := chatMutes[min(r.mutes, len(chatMutes)-1)]
+		r.mutes++
+		r.mutedUntil = now.Add(mute)
+		r.sent = nil
+		return mute, false
+	}
+	r.sent = append(r.sent, now)
+	return 0, true
+}
+
+// sweep forgets players who've nothing left to remember, every so often.
+// Call with cl locked.
+func (cl *chatLimiter) sweep(now time.Time) {
+	if now.Sub(cl.swept) < ChatWindow {
+		return
+	}
+	cl.swept = now
+	for id, r := range cl.players {
+		quiet := len(r.sent) == 0 || now.Sub(r.sent[len(r.sent)-1]) >= ChatWindow
+		if quiet && now.Sub(r.mutedUntil) >= ChatForgiveAfter {
+			delete(cl.players, id)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+// GameCheckers is checkers, as recorded in journals
+const GameCheckers = "checkers"
+
+// checkersTitle heads checkers' screens, like titleArt does Tic-Tac-Toe's
+const checkersTitle = `
+    ___  _              _
+   / __|| |_   ___  __ | |__ ___  _ _  ___
+  | (__ | ' \ / -_)/ _|| / // -_)| '_|(_-<
+   \___||_||_|\___|\__||_\_\\___||_|  /__/
+`
+
+const (
+	// CheckersSize is how many rows and columns a checkers board has
+	CheckersSize = 8
+
+	// CheckersRows is how many rows of men each player starts with
+	CheckersRows = 3
+)
+
+// checkers is played with men on the dark squares. X's start at the bottom
+// of the board and O's at the top, both move diagonally forwards: a square
+// at a time, or jumping an opponent's piece to capture it. Capturing is
+// compulsory and a piece keeps jumping while it can. A man that reaches the
+// far side is crowned king, kings move backwards too. A player who can't
+// move, with no pieces left or all of them blocked, loses.
+type checkers struct{}
+
+func (checkers) Name() string           { return GameCheckers }
+func (checkers) Title() string          { return "Checkers" }
+func (checkers) Art() string            { return checkersTitle }
+func (checkers) Size() (rows, cols int) { return CheckersSize, CheckersSize }
+
+// playable reports whether a square is dark, pieces never go on light ones
+func (checkers) playable(row, col int) bool {
+	return (row+col)%2 == 1
+}
+
+// newBoard sets out both players' men
+func (c checkers) newBoard() [][]string {
+	board := make([][]string, CheckersSize)
+	for y := range board {
+		board[y] = make([]string, CheckersSize)
+		for x := range board[y] {
+			switch {
+			case !c.playable(y, x):
+				board[y][x] = Empty
+			case y < CheckersRows:
+				board[y][x] = PlayerO
+			case y >= CheckersSize-CheckersRows:
+				board[y][x] = PlayerX
+			default:
+				board[y][x] = Empty
+			}
+		}
+	}
+	return board
+}
+
+// aim is the cursor's cell, pieces go where they're put
+func (checkers) aim(_ [][]string, row, col int) coord {
+	return coord{row, col}
+}
+
+// crown makes a king of the player's man
+func crown(player string) string {
+	return player + player
+}
+
+// crowned reports whether a cell holds a king
+func crowned(cell string) bool {
+	return len(cell) > 1
+}
+
+// forward is the way up or down the board a player's men move
+func forward(player string) int {
+	if player == PlayerX {
+		return -1
+	}
+	return 1
+}
+
+// diagonals are the directions the piece in a cell can move in
+func diagonals(cell string) []coord {
+	f := forward(owner(cell))
+	if crowned(cell) {
+		return []coord{{f, -1}, {f, 1}, {-f, -1}, {-f, 1}}
+	}
+	return []coord{{f, -1}, {f, 1}}
+}
+
+// onBoard reports whether a cell is on the board
+func onBoard(board [][]string, c coord) bool {
+	return c.row >= 0 && c.row < len(board) && c.col >= 0 && c.col < len(board[c.row])
+}
+
+// pieceMoves lists where the piece at from can jump to and step to
+func pieceMoves(board [][]string, from coord) (jumps, steps []move) {
+	cell := board[from.row][from.col]
+	for _, d := range diagonals(cell) {
+		next := coord{from.row + d.row, from.col + d.col}
+		if !onBoard(board, next) {
+			continue
+		}
+		if board[next.row][next.col] == Empty {
+			steps = append(steps, move{from, next})
+			continue
+		}
+		landing := coord{next.row + d.row, next.col + d.col}
+		if owner(board[next.row][next.col]) != owner(cell) && onBoard(board, landing) && board[landing.row][landing.col] == Empty {
+			jumps = append(jumps, move{from, landing})
+		}
+	}
+	return jumps, steps
+}
+
+// moves are the player's jumps if they have any, capturing is compulsory,
+// otherwise their steps
+func (checkers) moves(board [][]string, player string, from *coord) []move {
+	var jumps, steps []move
+	for y := range board {
+		for x := range board[y] {
+			if owner(board[y][x]) != player || (from != nil && *from != coord{y, x}) {
+				continue
+			}
+			j, s := pieceMoves(board, coord{y, x})
+			jumps, steps = append(jumps, j...), append(steps, s...)
+		}
+	}
+	// a piece in the middle of a multi-jump can only jump
+	if len(jumps) > 0 || from != nil {
+		return jumps
+	}
+	return steps
+}
+
+// play moves the piece, capturing the piece it jumped and crowning a man
+// that reaches the far side. A piece that jumped has to go again if it can
+// jump again, unless it was just crowned.
+func (checkers) play(board [][]string, player string, mv move) bool {
+	piece := board[mv.from.row][mv.from.col]
+	board[mv.from.row][mv.from.col] = Empty
+	jumped := mv.to.row-mv.from.row == 2 || mv.to.row-mv.from.row == -2
+	if jumped {
+		board[(mv.from.row+mv.to.row)/2][(mv.from.col+mv.to.col)/2] = Empty
+	}
+	far := len(board) - 1
+	if player == PlayerX {
+		far = 0
+	}
+	if mv.to.row == far && !crowned(piece) {
+		board[mv.to.row][mv.to.col] = crown(player)
+		return false
+	}
+	board[mv.to.row][mv.to.col] = piece
+	if !jumped {
+		return false
+	}
+	jumps, _ := pieceMoves(board, mv.to)
+	return len(jumps) > 0
+}
+
+// result is a win once the opponent can't move
+func (c checkers) result(board [][]string, player string) (string, []coord) {
+	if len(c.moves(board, opponentOf(player), nil)) == 0 {
+		return player, nil
+	}
+	return Empty, nil
+}
@@ -0,0 +1,514 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// checkersBoard reads a board drawn a row to a string, top row first: x and
+// o are men, X and O kings, anything else an empty cell
+func checkersBoard(rows ...string) [][]string {
+	board := make([][]string, len(rows))
+	for y, row := range rows {
+		board[y] = make([]string, len(row))
+		for x, c := range row {
+			switch c {
+			case 'x':
+				board[y][x] = PlayerX
+			case 'o':
+				board[y][x] = PlayerO
+			case 'X':
+				board[y][x] = crown(PlayerX)
+			case 'O':
+				board[y][x] = crown(PlayerO)
+			default:
+				board[y][x] = Empty
+			}
+		}
+	}
+	return board
+}
+
+// moveNames names moves the way they're journaled, sorted
+func moveNames(moves []move) []string {
+	names := make([]string, len(moves))
+	for i, mv := range moves {
+		names[i] = mv.String()
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestCheckersMoves(t *testing.T) {
+	tests := []struct {
+		name   string
+		board  []string
+		player string
+		from   *coord
+		want   []string
+	}{
+		{
+			name: "X's opening",
+			board: []string{
+				".o.o.o.o",
+				"o.o.o.o.",
+				".o.o.o.o",
+				"........",
+				"........",
+				"x.x.x.x.",
+				".x.x.x.x",
+				"x.x.x.x.",
+			},
+			player: PlayerX,
+			want:   []string{"A6-B5", "C6-B5", "C6-D5", "E6-D5", "E6-F5", "G6-F5", "G6-H5"},
+		},
+		{
+			name: "O's men move down",
+			board: []string{
+				"........",
+				"........",
+				".o......",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+			},
+			player: PlayerO,
+			want:   []string{"B3-A4", "B3-C4"},
+		},
+		{
+			name: "capturing is compulsory",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"...o....",
+				"..x.....",
+				".......x",
+				"........",
+			},
+			player: PlayerX,
+			want:   []string{"C6-E4"},
+		},
+		{
+			name: "men don't move backwards",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"...x....",
+				"........",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			want:   []string{"D5-C4", "D5-E4"},
+		},
+		{
+			name: "men don't jump backwards",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"....x...",
+				"...o....",
+				"........",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			want:   []string{"E4-D3", "E4-F3"},
+		},
+		{
+			name: "kings move both ways",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"...X....",
+				"........",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			want:   []string{"D5-C4", "D5-C6", "D5-E4", "D5-E6"},
+		},
+		{
+			name: "can't jump off the board or onto a piece",
+			board: []string{
+				"........",
+				"........",
+				".....o..",
+				"........",
+				".o......",
+				"x.x.....",
+				"...x....",
+				"........",
+			},
+			player: PlayerO,
+			want:   []string{"F3-E4", "F3-G4"},
+		},
+		{
+			name: "any piece that can jump",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"...o.o..",
+				"..x...x.",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			want:   []string{"C6-E4", "G6-E4"},
+		},
+		{
+			name: "in the middle of a multi-jump only that piece goes",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"...o.o..",
+				"..x...x.",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			from:   &coord{5, 2},
+			want:   []string{"C6-E4"},
+		},
+		{
+			name: "in the middle of a multi-jump it can't step",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"..x.....",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			from:   &coord{5, 2},
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := moveNames(checkers{}.moves(checkersBoard(tt.board...), tt.player, tt.from))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("moves = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckersNewBoard(t *testing.T) {
+	want := checkersBoard(
+		".o.o.o.o",
+		"o.o.o.o.",
+		".o.o.o.o",
+		"........",
+		"........",
+		"x.x.x.x.",
+		".x.x.x.x",
+		"x.x.x.x.",
+	)
+	got := checkers{}.newBoard()
+	for y := range want {
+		if !slices.Equal(got[y], want[y]) {
+			t.Errorf("row %d = %q, want %q", y+1, got[y], want[y])
+		}
+	}
+}
+
+func TestCheckersPlay(t *testing.T) {
+	tests := []struct {
+		name      string
+		board     []string
+		player    string
+		move      string
+		wantAgain bool
+		want      []string
+	}{
+		{
+			name: "step",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"..x.....",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			move:   "C6-D5",
+			want: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"...x....",
+				"........",
+				"........",
+				"........",
+			},
+		},
+		{
+			name: "jump captures",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"...o....",
+				"..x.....",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			move:   "C6-E4",
+			want: []string{
+				"........",
+				"........",
+				"........",
+				"....x...",
+				"........",
+				"........",
+				"........",
+				"........",
+			},
+		},
+		{
+			name: "jump that can go on",
+			board: []string{
+				"........",
+				"........",
+				".....o..",
+				"........",
+				"...o....",
+				"..x.....",
+				"........",
+				"........",
+			},
+			player:    PlayerX,
+			move:      "C6-E4",
+			wantAgain: true,
+			want: []string{
+				"........",
+				"........",
+				".....o..",
+				"....x...",
+				"........",
+				"........",
+				"........",
+				"........",
+			},
+		},
+		{
+			name: "king jumping on backwards",
+			board: []string{
+				"........",
+				"........",
+				"...X....",
+				"....o...",
+				"........",
+				"....o...",
+				"........",
+				"........",
+			},
+			player:    PlayerX,
+			move:      "D3-F5",
+			wantAgain: true,
+			want: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				".....X..",
+				"....o...",
+				"........",
+				"........",
+			},
+		},
+		{
+			name: "reaching the far side crowns",
+			board: []string{
+				"........",
+				"..x.....",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			move:   "C2-B1",
+			want: []string{
+				".X......",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+			},
+		},
+		{
+			name: "O is crowned at the bottom",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				".o......",
+				"........",
+			},
+			player: PlayerO,
+			move:   "B7-A8",
+			want: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"O.......",
+			},
+		},
+		{
+			name: "being crowned ends a multi-jump",
+			board: []string{
+				"........",
+				"....o.o.",
+				"...x....",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			move:   "D3-F1",
+			want: []string{
+				".....X..",
+				"......o.",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board := checkersBoard(tt.board...)
+			mv, ok := parseMove(tt.move, CheckersSize, CheckersSize)
+			if !ok {
+				t.Fatalf("bad move %q", tt.move)
+			}
+			if again := (checkers{}).play(board, tt.player, mv); again != tt.wantAgain {
+				t.Errorf("again = %v, want %v", again, tt.wantAgain)
+			}
+			want := checkersBoard(tt.want...)
+			for y := range want {
+				if !slices.Equal(board[y], want[y]) {
+					t.Errorf("row %d = %q, want %q", y+1, board[y], want[y])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckersResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		board  []string
+		player string
+		want   string
+	}{
+		{
+			name: "opponent can move",
+			board: []string{
+				".o.o.o.o",
+				"o.o.o.o.",
+				".o.o.o.o",
+				"........",
+				"........",
+				"x.x.x.x.",
+				".x.x.x.x",
+				"x.x.x.x.",
+			},
+			player: PlayerX,
+			want:   Empty,
+		},
+		{
+			name: "opponent has no pieces left",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"...x....",
+				"........",
+				"........",
+				"........",
+			},
+			player: PlayerX,
+			want:   PlayerX,
+		},
+		{
+			name: "opponent is blocked in",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				".o......",
+				"x.x.....",
+			},
+			player: PlayerX,
+			want:   PlayerX,
+		},
+		{
+			name: "a king isn't blocked from behind",
+			board: []string{
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				"........",
+				".O......",
+				"x.x.....",
+			},
+			player: PlayerX,
+			want:   Empty,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := (checkers{}).result(checkersBoard(tt.board...), tt.player); got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// shotClock is how long a player has for each move in a multiplayer game,
+// set with -move-clock. 0 means there's no shot clock. Rooms can be opened
+// with other clocks, see timeControl.
+var shotClock time.Duration
+
+// gameClock is how long each player has for all their moves in a game, set
+// with -game-clock. 0 means there's no game clock.
+var gameClock time.Duration
+
+// ClockWarning is when a clock turns red
+const ClockWarning = 10 * time.Second
+
+// turnTime is how long a turn that started at started has taken so far.
+// Turns that haven't started yet, behind the versus screen, take nothing.
+func turnTime(started time.Time) time.Duration {
+	return max(time.Since(started), 0)
+}
+
+// startClocks winds both players' game clocks back up for a new game and
+// starts the first player's, from TurnStarted. Call with the session
+// locked.
+func (gs *GameSession) startClocks() {
+	gs.Clocks = [2]time.Duration{gs.TimeControl.Game, gs.TimeControl.Game}
+	gs.handicapClocks()
+	gs.TimedOut = false
+	gs.runClock()
+}
+
+// chargeClock takes the time the current turn took off the current
+// player's game clock. Call with the session locked.
+func (gs *GameSession) chargeClock() {
+	gs.Clocks[gs.CurrentPlayer] = max(gs.Clocks[gs.CurrentPlayer]-turnTime(gs.TurnStarted), 0)
+}
+
+// passTurn hands the turn to the other player and starts their clock, after
+// adding the increment to the clock of the player who moved. Call with the
+// session locked.
+func (gs *GameSession) passTurn() {
+	if gs.TimeControl.Game > 0 {
+		gs.Clocks[gs.CurrentPlayer] += gs.TimeControl.Increment
+	}
+	gs.CurrentPlayer = 1 - gs.CurrentPlayer
+	gs.TurnStarted = time.Now()
+	gs.runClock()
+}
+
+// runClock makes the current player lose on time unless they move before
+// the shot clock or their game clock runs out. Call with the session locked.
+func (gs *GameSession) runClock() {
+	gs.turn++
+	tc := gs.TimeControl
+	if !tc.on() || gs.Casual {
+		return
+	}
+	limit := time.Duration(math.MaxInt64)
+	if tc.Move > 0 {
+		limit = tc.Move
+	}
+	if tc.Game > 0 {
+		limit = min(limit, gs.Clocks[gs.CurrentPlayer])
+	}
+	turn := gs.turn
+	time.AfterFunc(time.Until(gs.TurnStarted)+limit, func() { gs.flag(turn) })
+}
+
+// flag ends the game on time if the given turn is still being taken
+func (gs *GameSession) flag(turn int) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if gs.turn != turn || gs.Winner != Empty {
+		return
+	}
+	gs.chargeClock()
+	gs.TimedOut = true
+	gs.finish(opponentOf([]string{PlayerX, PlayerO}[gs.CurrentPlayer]))
+	gs.notify()
+}
+
+// gameClockLeft is how long a player has left on their game clock, it runs
+// down during their turn
+func (m model) gameClockLeft(player string) time.Duration {
+	left := m.clocks[playerIndex(player)]
+	if player == m.currentPlayer && m.winner == Empty {
+		left -= turnTime(m.turnStarted)
+	}
+	return max(left, 0)
+}
+
+// clockView shows a player's game clock next to their name: bright while
+// it's running, red when it's nearly out
+func (m model) clockView(player string) string {
+	if m.timeControl.Game == 0 || m.casual {
+		return ""
+	}
+	left := m.gameClockLeft(player)
+	style := footerStyle
+	switch {
+	case left < ClockWarning:
+		style = lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true)
+	case player == m.currentPlayer && m.winner == Empty:
+		style = headerStyle
+	}
+	return " " + style.Render("⏱ "+formatDuration(left))
+}
+
+// shotClockView shows how long the current player has left to move
+func (m model) shotClockView() string {
+	if m.timeControl.Move == 0 || m.gameSession == nil || m.casual {
+		return ""
+	}
+	left := max(m.timeControl.Move-turnTime(m.turnStarted), 0)
+	style := footerStyle
+	if left < ClockWarning {
+		style = lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true)
+	}
+	return style.Render("  " + formatDuration(left) + " to move")
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"tictactui/matchmaking"
+)
+
+// CrowdVoteTime is how long the crowd has to vote on each of its moves
+const CrowdVoteTime = 15 * time.Second
+
+// CrowdName is what the crowd's called, as O's player
+const CrowdName = "The crowd"
+
+// crowdPlayer is the crowd to the matchmaker, one for every game with a
+// record in each, like a player's. It has a session's ID so it isn't stored like
+// a player, see persistent.
+var crowdPlayer = matchmaking.Player{ID: "session:crowd", Name: CrowdName, Casual: true}
+
+// playCrowd starts a game against everyone watching it: the player is X,
+// and each of O's moves is the one most spectators vote for. Crowd games
+// are casual, there's no rating for a crowd.
+func (sm *SessionManager) playCrowd(p matchmaking.Player) (*GameSession, int, error) {
+	p.Casual = true
+	crowd := crowdPlayer
+	crowd.Game = p.Game
+	session, seat := sm.sit(matchmaking.Versus(p, crowd), p, false)
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.Crowd = true
+	session.PlayerNames[1] = CrowdName
+	session.PlayerCount++ // the crowd's here already
+	session.start()
+	session.notify()
+	sm.sessionsChanged()
+	return session, seat, nil
+}
+
+// callVote opens the vote on the crowd's next move, if it's the crowd's
+// turn, and counts it once CrowdVoteTime is up. Call with the session
+// locked.
+func (gs *GameSession) callVote() {
+	if !gs.Crowd || gs.CurrentPlayer != 1 || gs.Winner != Empty {
+		return
+	}
+	gs.vote++
+	gs.Votes = map[string]move{}
+	gs.VoteEnds = time.Now().Add(CrowdVoteTime)
+	vote := gs.vote
+	time.AfterFunc(CrowdVoteTime, func() { gs.countVotes(vote) })
+}
+
+// castVote records a spectator's vote for the crowd's next move, they can
+// change their mind until the vote's counted
+func (gs *GameSession) castVote(voterID string, mv move) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if gs.Votes == nil || gs.CurrentPlayer != 1 || gs.Winner != Empty ||
+		!slices.Contains(gs.Rules.moves(gs.Board, PlayerO, gs.Chain), mv) {
+		return
+	}
+	gs.Votes[voterID] = mv
+	gs.notify()
+}
+
+// countVotes makes the crowd's move once the given vote is up, unless the
+// game's moved on without it: restarted or over
+func (gs *GameSession) countVotes(vote int) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if gs.vote != vote || gs.CurrentPlayer != 1 || gs.Winner != Empty {
+		return
+	}
+	mv := crowdMove(gs.Rules.moves(gs.Board, PlayerO, gs.Chain), gs.Votes, gs.rng)
+	gs.Votes = nil
+	gs.makeMove(PlayerO, mv)
+}
+
+// crowdMove is the legal move with the most votes, the one listed first on
+// a tie, or any of them picked with rng if nobody voted
+func crowdMove(legal []move, votes map[string]move, rng *rand.Rand) move {
+	tally := map[move]int{}
+	for _, mv := range votes {
+		tally[mv]++
+	}
+	best := -1
+	for i, mv := range legal {
+		if tally[mv] > 0 && (best < 0 || tally[mv] > tally[legal[best]]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return legal[rng.IntN(len(legal))]
+	}
+	return legal[best]
+}
+
+// tallies adds the votes up by the cell each move ends in, which is where
+// the board shows them
+func tallies(votes map[string]move) map[coord]int {
+	counts := map[coord]int{}
+	for _, mv := range votes {
+		counts[mv.to]++
+	}
+	return counts
+}
+
+// voting reports whether this spectator can vote on the crowd's move right
+// now
+func (m model) voting() bool {
+	return m.spectating && m.crowd && m.currentPlayer == PlayerO && m.winner == Empty
+}
+
+// hasCursor reports whether the board shows this model's cursor: players
+// always have one, spectators only while voting for the crowd
+func (m model) hasCursor() bool {
+	return !m.spectating || m.voting()
+}
+
+// tallyMark is how a cell shows the crowd's votes for it, a digit, or a +
+// for 10 or more
+func tallyMark(n int) string {
+	if n > 9 {
+		return "+"
+	}
+	return fmt.Sprint(n)
+}
+
+// crowdView shows the vote on the crowd's move under the board: the time
+// left, the leading moves, and what this spectator voted for
+func (m model) crowdView() string {
+	if !m.crowd || m.currentPlayer != PlayerO || m.winner != Empty {
+		return ""
+	}
+	left := int(max(time.Until(m.voteEnds), 0).Seconds()) + 1
+	s := footerStyle.Render(fmt.Sprintf("The crowd is voting, %ds left", left))
+
+	cells := make([]coord, 0, len(m.tallies))
+	for c := range m.tallies {
+		cells = append(cells, c)
+	}
+	slices.SortFunc(cells, func(a, b coord) int {
+		return cmp.Or(m.tallies[b]-m.tallies[a], a.row-b.row, a.col-b.col)
+	})
+	leading := make([]string, 0, 3)
+	for _, c := range cells[:min(len(cells), 3)] {
+		leading = append(leading, fmt.Sprintf("%s %d", cellName(c.row, c.col), m.tallies[c]))
+	}
+	if len(leading) > 0 {
+		s += footerStyle.Render(" · " + strings.Join(leading, ", "))
+	} else {
+		s += footerStyle.Render(" · no votes yet, it plays at random without any")
+	}
+	s += "\n"
+	if m.myVote != "" {
+		s += footerStyle.Render("You voted "+m.myVote) + "\n"
+	}
+	return s
+}
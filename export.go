@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+)
+
+// Export formats, the argument to `ssh host export`
+const (
+	ExportJSON     = "json"      // the player's stats and matches, the default
+	ExportCSV      = "csv"       // the player's matches, one row each
+	ExportStatsCSV = "stats.csv" // the player's stats, one row per game
+)
+
+// exportFiles are the files a player's export is downloaded to, one per
+// format
+var exportFiles = []struct{ format, file, what string }{
+	{ExportJSON, "tictactui.json", "everything, as JSON"},
+	{ExportCSV, "matches.csv", "your matches"},
+	{ExportStatsCSV, "stats.csv", "your stats"},
+}
+
+// ExportPreview is how many of their latest matches the export screen shows
+const ExportPreview = 5
+
+// exportedMatch is one of a player's games, from their side of the board
+type exportedMatch struct {
+	ID       string    `json:"id"`
+	Game     string    `json:"game"`
+	Played   time.Time `json:"played"`
+	Side     string    `json:"side"` // "X" or "O"
+	Opponent string    `json:"opponent"`
+	Result   string    `json:"result"` // "win", "loss", "draw" or "unfinished"
+	OnTime   bool      `json:"on_time,omitempty"`
+	Moves    int       `json:"moves"`
+	Notation string    `json:"notation"`
+	Seed     uint64    `json:"seed,omitempty,string"` // what the game's random choices were made from, see EventSeed
+	Draw     uint64    `json:"draw,omitempty,string"` // what its tournament's bracket was drawn from
+}
+
+// playerExport is everything a player can export about themselves
+type playerExport struct {
+	Games   []publicPlayer  `json:"games"`   // their stats in each game they've played, in the lobby's order
+	Matches []exportedMatch `json:"matches"` // oldest first
+}
+
+// exportFor gathers a player's stats and matches. Their matches come from
+// the journals with -journal, otherwise from the last games kept for them
+// to rewatch.
+func exportFor(playerID, name string) (playerExport, error) {
+	rec, ok := playerDB.lookup(playerID)
+	if !ok {
+		// no database, what the queue knows about them this run
+		rec = PlayerRecord{Fingerprint: playerID, Name: name, Games: map[string]GameRecord{}}
+		for _, g := range boardGames {
+			rec.Games[g.Name()] = GameRecord(sessionManager.queue.Record(playerID, g.Name()).Results)
+		}
+	}
+	export := playerExport{Matches: []exportedMatch{}}
+	for _, g := range boardGames {
+		if r := rec.Games[g.Name()]; r.played()+r.CasualWins+r.CasualLosses+r.CasualDraws > 0 {
+			export.Games = append(export.Games, rec.public(g.Name()))
+		}
+	}
+	if len(export.Games) == 0 {
+		export.Games = []publicPlayer{rec.public(enabledGames[0].Name())}
+	}
+
+	replays, err := playerReplays(playerID)
+	if err != nil {
+		return playerExport{}, err
+	}
+	for _, r := range replays {
+		if m, ok := exportMatch(r, playerID); ok {
+			export.Matches = append(export.Matches, m)
+		}
+	}
+	slices.SortFunc(export.Matches, func(a, b exportedMatch) int { return a.Played.Compare(b.Played) })
+	return export, nil
+}
+
+// playerReplays is every game a player played that's on record, from the
+// journals with -journal, otherwise the last ones kept for them to rewatch
+func playerReplays(playerID string) ([]replay, error) {
+	if journalDir == "" {
+		return recentReplays.list(playerID), nil
+	}
+	return journalReplays(playerID)
+}
+
+// journalReplays reads every game a player played from the journals
+func journalReplays(playerID string) ([]replay, error) {
+	paths, err := filepath.Glob(filepath.Join(journalDir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	var replays []replay
+	for _, path := range paths {
+		events, err := readJournal(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range replaysIn(journalID(path), events) {
+			if slices.Contains(r.ids[:], playerID) {
+				replays = append(replays, r)
+			}
+		}
+	}
+	return replays, nil
+}
+
+// exportMatch is a game as the player saw it, false if they didn't play in it
+func exportMatch(r replay, playerID string) (exportedMatch, bool) {
+	seat := slices.Index(r.ids[:], playerID)
+	if seat < 0 {
+		// kept to rewatch from before journals had player IDs
+		return exportedMatch{}, false
+	}
+	side := []string{PlayerX, PlayerO}[seat]
+	m := exportedMatch{
+		ID:       r.id,
+		Game:     r.rules.Name(),
+		Played:   r.played,
+		Side:     side,
+		Opponent: r.names[1-seat],
+		Moves:    len(r.moves),
+		Notation: r.notation(),
+		OnTime:   r.result.Reason == ReasonTime,
+		Seed:     r.seed,
+		Draw:     r.draw,
+	}
+	switch r.result.Result {
+	case "":
+		m.Result = "unfinished"
+	case Draw:
+		m.Result = "draw"
+	case side:
+		m.Result = "win"
+	default:
+		m.Result = "loss"
+	}
+	return m, true
+}
+
+// writeExport writes a player's export in one of the export formats
+func writeExport(w io.Writer, format string, export playerExport) error {
+	switch format {
+	case ExportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(export)
+	case ExportCSV:
+		out := csv.NewWriter(w)
+		out.Write([]string{"id", "game", "played", "side", "opponent", "result", "on_time", "moves", "notation"})
+		for _, m := range export.Matches {
+			out.Write([]string{m.ID, m.Game, m.Played.UTC().Format(time.RFC3339), m.Side, m.Opponent, m.Result,
+				strconv.FormatBool(m.OnTime), strconv.Itoa(m.Moves), m.Notation})
+		}
+		out.Flush()
+		return out.Error()
+	case ExportStatsCSV:
+		out := csv.NewWriter(w)
+		out.Write([]string{"fingerprint", "name", "game", "title", "rating", "wins", "losses", "draws", "win_rate", "dodged", "abandoned", "last_seen"})
+		for _, p := range export.Games {
+			out.Write([]string{p.Fingerprint, p.Name, p.Game, p.Title, strconv.Itoa(p.Rating), strconv.Itoa(p.Wins), strconv.Itoa(p.Losses), strconv.Itoa(p.Draws),
+				strconv.FormatFloat(p.WinRate, 'f', 1, 64), strconv.Itoa(p.Dodged), strconv.Itoa(p.Abandoned), p.LastSeen.UTC().Format(time.RFC3339)})
+		}
+		out.Flush()
+		return out.Error()
+	}
+	return fmt.Errorf("unknown export format %q, use %s, %s or %s", format, ExportJSON, ExportCSV, ExportStatsCSV)
+}
+
+// exportCommand is the command that downloads a player's export
+func exportCommand(format string) string {
+	return "ssh -p " + SSHPort + " " + hostName + " export " + format
+}
+
+// serveExport answers `ssh host export [format]` with the player's export
+// instead of the game, it's meant to be redirected to a file
+func serveExport(next ssh.Handler) ssh.Handler {
+	return func(s ssh.Session) {
+		cmd := s.Command()
+		if len(cmd) == 0 || cmd[0] != "export" {
+			next(s)
+			return
+		}
+		playerID := playerID(s)
+		if !persistent(playerID) {
+			wish.Errorln(s, "Connect with an SSH key to export your games, guests start afresh every time.")
+			_ = s.Exit(1)
+			return
+		}
+		format := ExportJSON
+		if len(cmd) > 1 {
+			format = cmd[1]
+		}
+		export, err := exportFor(playerID, displayName(s))
+		if err == nil {
+			err = writeExport(s, format, export)
+		}
+		if err != nil {
+			wish.Errorln(s, "Couldn't export:", err)
+			_ = s.Exit(1)
+			return
+		}
+		_ = s.Exit(0)
+	}
+}
+
+// exportScreen shows what a player can export and how to download it
+type exportScreen struct {
+	active bool
+	export playerExport
+	err    error
+}
+
+// openExport shows the export screen
+func (m model) openExport() (tea.Model, tea.Cmd) {
+	m.lobby.active = false
+	export, err := exportFor(m.playerID, m.playerName)
+	m.export = exportScreen{active: true, export: export, err: err}
+	return m, m.clearScreen()
+}
+
+// updateExport handles keys on the export screen
+func (m model) updateExport(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.export = exportScreen{}
+		return m.backToLobby()
+	}
+	return m, nil
+}
+
+// exportView draws the export screen
+func (m model) exportView() string {
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+	s += headerStyle.Render("  Export your games") + "\n\n"
+
+	switch {
+	case !persistent(m.playerID):
+		s += "  Connect with an SSH key to export your games, guests start afresh every time\n"
+	case m.export.err != nil:
+		s += "  Couldn't read your games: " + m.export.err.Error() + "\n"
+	default:
+		matches := m.export.export.Matches
+		s += "  " + m.playerName + formatRecord(m.playerID, m.rules.Name()) + footerStyle.Render(fmt.Sprintf(", games on record: %d", len(matches))) + "\n\n"
+		for _, match := range matches[max(len(matches)-ExportPreview, 0):] {
+			s += fmt.Sprintf("  %s  %s vs %s", match.Played.Format("2006-01-02 15:04"), match.Side, match.Opponent) +
+				footerStyle.Render(fmt.Sprintf("  %s, %s, %d moves", rulesFor(match.Game).Title(), match.Result, match.Moves)) + "\n"
+		}
+		s += "\n  Download them from your own terminal:\n\n"
+		for _, f := range exportFiles {
+			s += "    " + exportCommand(f.format) + " > " + f.file + footerStyle.Render("  "+f.what) + "\n"
+		}
+		s += "\n  Or browse them and your replays over SFTP:\n\n    " + sftpCommand() + "\n"
+		if journalDir == "" {
+			s += footerStyle.Render(fmt.Sprintf("\n  This server only keeps your last %d games", MaxReplays)) + "\n"
+		}
+	}
+	s += footerStyle.Render("\n  Press esc to go back, q to quit") + "\n"
+	return s
+}
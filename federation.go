@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	gossh "golang.org/x/crypto/ssh"
+
+	"tictactui/matchmaking"
+)
+
+// Servers listed in each other's -peers file are federated: they connect to
+// each other over SSH, signing in with their host keys, and
+//   - a player who's waited PeerWait in the quick match queue with nobody to
+//     play here is sent on to a peer with someone waiting for the same game,
+//     and plays there in the same terminal until they quit back here
+//   - every rated game is sent to every peer, signed like -push-results, so
+//     each of them has the combined leaderboard over all of them
+// Players are only sent on to peers whose host key fingerprint sorts after
+// this server's, so two servers never swap their waiting players.
+
+// peersPath lists the servers this one is federated with, set with -peers.
+// Empty means it isn't.
+var peersPath string
+
+// peer is another server this one is federated with
+type peer struct {
+	addr string // its SSH server, host:port
+	key  gossh.PublicKey
+}
+
+// peers are the servers this one is federated with, from -peers
+var peers []peer
+
+// PeerWait is how long a player waits in the quick match queue before
+// they're sent on to a peer, and between looks for one with someone waiting
+const PeerWait = 15 * time.Second
+
+// PeerTimeout is how long a peer gets to answer
+const PeerTimeout = 5 * time.Second
+
+// Peer commands, what one server runs on another
+const (
+	PeerQueue  = "peer-queue"  // how many are waiting in the quick match queue, by game
+	PeerResult = "peer-result" // takes a signed result on stdin for the leaderboard
+	PeerPlay   = "peer-play"   // <player ID> <game> [casual]: the player's terminal, straight into the queue
+)
+
+// federating reports whether the server has peers
+func federating() bool {
+	return len(peers) > 0
+}
+
+// loadPeers reads the servers this one is federated with. Each line is a
+// peer's address and host key, like known_hosts: `host:port ssh-ed25519
+// AAAA...`. Their results count on the web's /api/network leaderboard.
+func loadPeers(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	peers = nil
+	lines := bufio.NewScanner(f)
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addr, key, _ := strings.Cut(line, " ")
+		pub, _, _, _, err := gossh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if !strings.Contains(addr, ":") {
+			addr += ":" + SSHPort
+		}
+		peers = append(peers, peer{addr: addr, key: pub})
+	}
+	if err := lines.Err(); err != nil {
+		return err
+	}
+	if len(peers) == 0 {
+		return fmt.Errorf("%s: no peers", path)
+	}
+	if trustedServers == nil {
+		trustedServers = map[string]gossh.PublicKey{}
+	}
+	for _, p := range peers {
+		trustedServers[gossh.FingerprintSHA256(p.key)] = p.key
+	}
+	return nil
+}
+
+// trustSelf counts the server's own games on the combined leaderboard, once
+// its host key's loaded
+func trustSelf() {
+	key := resultSigner.PublicKey()
+	trustedServers[gossh.FingerprintSHA256(key)] = key
+}
+
+// peerFor is the peer a session comes from, if it does
+func peerFor(s ssh.Session) (peer, bool) {
+	key := s.PublicKey()
+	if key == nil {
+		return peer{}, false
+	}
+	for _, p := range peers {
+		if ssh.KeysEqual(p.key, key) {
+			return p, true
+		}
+	}
+	return peer{}, false
+}
+
+// after reports whether players waiting here can be sent on to the peer,
+// see the top of the file
+func (p peer) after() bool {
+	return gossh.FingerprintSHA256(p.key) > gossh.FingerprintSHA256(resultSigner.PublicKey())
+}
+
+// dial connects to a peer as this server, under the user given
+func (p peer) dial(user string) (*gossh.Client, error) {
+	return gossh.Dial("tcp", p.addr, &gossh.ClientConfig{
+		User:            user,
+		Auth:            []gossh.AuthMethod{gossh.PublicKeys(resultSigner)},
+		HostKeyCallback: gossh.FixedHostKey(p.key),
+		Timeout:         PeerTimeout,
+	})
+}
+
+// run runs a peer command on the peer, with the input given, and returns
+// what it wrote
+func (p peer) run(cmd string, stdin io.Reader) ([]byte, error) {
+	client, err := p.dial(hostName)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	session.Stdin = stdin
+	var stderr strings.Builder
+	session.Stderr = &stderr
+	out, err := session.Output(cmd)
+	var exit *gossh.ExitError
+	if errors.As(err, &exit) {
+		return nil, rejectedError(strings.TrimSpace(stderr.String()))
+	}
+	return out, err
+}
+
+// queue is how many are waiting in the peer's quick match queue, by game
+func (p peer) queue() (map[string]int, error) {
+	out, err := p.run(PeerQueue, nil)
+	if err != nil {
+		return nil, err
+	}
+	waiting := map[string]int{}
+	return waiting, json.Unmarshal(out, &waiting)
+}
+
+// peerWaiting finds a peer with someone waiting for a game, that players
+// can be sent on to
+func peerWaiting(game string) (peer, bool) {
+	for _, p := range peers {
+		if !p.after() {
+			continue
+		}
+		waiting, err := p.queue()
+		if err != nil {
+			log.Printf("peer %s: %v", p.addr, err)
+			continue
+		}
+		if waiting[game] > 0 {
+			return p, true
+		}
+	}
+	return peer{}, false
+}
+
+// shareResult keeps a finished rated game for the combined leaderboard and
+// sends it to every peer
+func shareResult(r networkResult, body []byte) {
+	if !federating() {
+		return
+	}
+	if err := playerDB.addResult(r); err != nil {
+		log.Printf("keeping result of game %s: %v", r.ID, err)
+	}
+	for _, p := range peers {
+		err := deliver(func() error {
+			_, err := p.run(PeerResult, bytes.NewReader(body))
+			return err
+		})
+		if err != nil {
+			log.Printf("sending result of game %s to %s: %v", r.ID, p.addr, err)
+		}
+	}
+}
+
+// visit is a player a peer sent here to play
+type visit struct {
+	id     string // their player ID on the peer, fingerprints are the same everywhere
+	game   string
+	casual bool
+}
+
+// visitKey is where a session's visit is kept in its context
+type visitKey struct{}
+
+// visiting is the visit a session is, if it's one
+func visiting(s ssh.Session) (visit, bool) {
+	v, ok := s.Context().Value(visitKey{}).(visit)
+	return v, ok
+}
+
+// servePeers answers the peer commands, for peers only: anyone else could
+// pass themselves off as any player
+func servePeers(next ssh.Handler) ssh.Handler {
+	return func(s ssh.Session) {
+		cmd := s.Command()
+		if len(cmd) == 0 || !strings.HasPrefix(cmd[0], "peer-") {
+			next(s)
+			return
+		}
+		if _, ok := peerFor(s); !ok {
+			wish.Errorln(s, "Only federated servers can do that.")
+			_ = s.Exit(1)
+			return
+		}
+		switch {
+		case cmd[0] == PeerQueue:
+			waiting := map[string]int{}
+			for _, g := range enabledGames {
+				waiting[g.Name()] = sessionManager.queue.WaitingFor(g.Name())
+			}
+			_ = json.NewEncoder(s).Encode(waiting)
+		case cmd[0] == PeerResult:
+			var signed signedResult
+			if err := json.NewDecoder(io.LimitReader(s, MaxResultSize)).Decode(&signed); err != nil {
+				wish.Errorln(s, "not a signed result")
+				_ = s.Exit(1)
+				return
+			}
+			r, err := signed.verify()
+			if err == nil {
+				err = playerDB.addResult(r)
+			}
+			if err != nil {
+				wish.Errorln(s, err)
+				_ = s.Exit(1)
+				return
+			}
+		case cmd[0] == PeerPlay && (len(cmd) == 3 || len(cmd) == 4 && cmd[3] == "casual"):
+			if !gameEnabled(enabledGames, cmd[2]) {
+				wish.Errorln(s, "This server doesn't play "+cmd[2]+".")
+				_ = s.Exit(1)
+				return
+			}
+			s.Context().SetValue(visitKey{}, visit{id: cmd[1], game: cmd[2], casual: len(cmd) == 4})
+			next(s)
+			return
+		default:
+			wish.Errorln(s, "No such peer command.")
+			_ = s.Exit(1)
+			return
+		}
+		_ = s.Exit(0)
+	}
+}
+
+// peerFoundMsg says whether a peer has someone waiting for the game the
+// player's been waiting for in match
+type peerFoundMsg struct {
+	match *matchmaking.Match
+	peer  peer
+	found bool
+}
+
+// peerBackMsg says the player's back from a peer
+type peerBackMsg struct{ err error }
+
+// lookForPeers looks for a peer with someone waiting for the game, once the
+// player's waited PeerWait
+func lookForPeers(match *matchmaking.Match, game string) tea.Cmd {
+	return tea.Tick(PeerWait, func(time.Time) tea.Msg {
+		if match.IsReady() {
+			return nil // found someone here
+		}
+		p, found := peerWaiting(game)
+		return peerFoundMsg{match: match, peer: p, found: found}
+	})
+}
+
+// visitPeer leaves the queue for the peer that has someone waiting, if
+// the player's still waiting here
+func (m model) visitPeer(msg peerFoundMsg) (tea.Model, tea.Cmd) {
+	if m.gameSession == nil || m.gameSession.match != msg.match || msg.match.IsReady() {
+		return m, nil // found someone here, or left
+	}
+	if !msg.found {
+		return m, lookForPeers(msg.match, m.rules.Name())
+	}
+	v := &peerVisit{peer: msg.peer, player: m.player(), width: m.width, height: m.height}
+	close(m.left)
+	f := m.fresh()
+	f.lobby.active = true // what's left on the screen until they're there
+	return f, tea.Exec(v, func(err error) tea.Msg { return peerBackMsg{err} })
+}
+
+// backFromPeer returns to the lobby once the player's quit the peer
+func (m model) backFromPeer(msg peerBackMsg) (tea.Model, tea.Cmd) {
+	m.lobby.err = ""
+	if msg.err != nil {
+		m.lobby.err = "Couldn't play on the other server: " + msg.err.Error()
+	}
+	return m.backToLobby()
+}
+
+// peerVisit is a player's terminal handed over to a peer, which puts them
+// straight in its quick match queue
+type peerVisit struct {
+	peer          peer
+	player        matchmaking.Player
+	width, height int
+	in            io.Reader
+	out           io.Writer
+}
+
+func (v *peerVisit) SetStdin(r io.Reader)  { v.in = r }
+func (v *peerVisit) SetStdout(w io.Writer) { v.out = w }
+func (v *peerVisit) SetStderr(io.Writer)   {}
+
+// Run plays on the peer until the player quits there
+func (v *peerVisit) Run() error {
+	client, err := v.peer.dial(v.player.Name)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	if err := session.RequestPty("xterm-256color", v.height, v.width, gossh.TerminalModes{}); err != nil {
+		return err
+	}
+	session.Stdout = v.out
+	var stderr strings.Builder
+	session.Stderr = &stderr
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+	cmd := PeerPlay + " " + v.player.ID + " " + v.player.Game
+	if v.player.Casual {
+		cmd += " casual"
+	}
+	if err := session.Start(cmd); err != nil {
+		return err
+	}
+
+	// the player's keys go to the peer until they're back, see sessionInput
+	back := make(chan struct{})
+	go func() {
+		in, ok := v.in.(*sessionInput)
+		if !ok {
+			io.Copy(stdin, v.in)
+			return
+		}
+		in.interrupt() // the game's last read, it's given up on them
+		buf := make([]byte, 256)
+		for {
+			n, err := in.readUntil(buf, back)
+			if n > 0 {
+				stdin.Write(buf[:n])
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	err = session.Wait()
+	close(back)
+	var exit *gossh.ExitError
+	if errors.As(err, &exit) && stderr.Len() > 0 {
+		return errors.New(strings.TrimSpace(stderr.String())) // the peer turned them away
+	}
+	return err
+}
+
+// sessionInput hands a player's keys to whoever's reading them: the game
+// or, while they're on a peer, the peer. The game can't give up a read
+// that's waiting for a key, so it's interrupted rather than let it swallow
+// the first key meant for the peer, and so is the peer's once they're back.
+type sessionInput struct {
+	keys  chan []byte   // from the session, one read at a time
+	eof   chan struct{} // closed when the session's out of input
+	stop  chan struct{} // closed to interrupt the reads waiting, then replaced
+	rest  []byte        // what's left of the last read, for the next
+	mutex sync.Mutex
+}
+
+// newSessionInput starts reading a session's input, until it's done
+func newSessionInput(s io.Reader, done <-chan struct{}) *sessionInput {
+	in := &sessionInput{keys: make(chan []byte), eof: make(chan struct{}), stop: make(chan struct{})}
+	go func() {
+		defer close(in.eof)
+		for {
+			buf := make([]byte, 256)
+			n, err := s.Read(buf)
+			if n > 0 {
+				select {
+				case in.keys <- buf[:n]:
+				case <-done:
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return in
+}
+
+// Read reads the player's keys, returning nothing if it's interrupted
+func (in *sessionInput) Read(p []byte) (int, error) {
+	in.mutex.Lock()
+	stop := in.stop
+	in.mutex.Unlock()
+	return in.readUntil(p, stop)
+}
+
+// readUntil reads the player's keys, returning nothing once stop is closed
+func (in *sessionInput) readUntil(p []byte, stop <-chan struct{}) (int, error) {
+	in.mutex.Lock()
+	if len(in.rest) > 0 {
+		n := copy(p, in.rest)
+		in.rest = in.rest[n:]
+		in.mutex.Unlock()
+		return n, nil
+	}
+	in.mutex.Unlock()
+	select {
+	case <-stop:
+		return 0, io.EOF // a key waiting too is the next reader's
+	default:
+	}
+	select {
+	case keys := <-in.keys:
+		n := copy(p, keys)
+		in.mutex.Lock()
+		in.rest = keys[n:]
+		in.mutex.Unlock()
+		return n, nil
+	case <-in.eof:
+		return 0, io.EOF
+	case <-stop:
+		return 0, io.EOF
+	}
+}
+
+// interrupt ends the reads waiting for a key
+func (in *sessionInput) interrupt() {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+	close(in.stop)
+	in.stop = make(chan struct{})
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestLoadPeers(t *testing.T) {
+	oldPeers, oldTrusted := peers, trustedServers
+	t.Cleanup(func() { peers, trustedServers = oldPeers, oldTrusted })
+	key := strings.TrimSpace(string(gossh.MarshalAuthorizedKey(testServerKey(t).PublicKey())))
+
+	tests := []struct {
+		name    string
+		file    string
+		want    []string
+		wantErr bool
+	}{
+		{"with a port", "ttt.example.com:2200 " + key + "\n", []string{"ttt.example.com:2200"}, false},
+		{"on the game's port", "# the other server\n\n10.0.0.2 " + key + "\n", []string{"10.0.0.2:" + SSHPort}, false},
+		{"no key", "10.0.0.2\n", nil, true},
+		{"no peers", "# nobody yet\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peers, trustedServers = nil, nil
+			path := filepath.Join(t.TempDir(), "peers")
+			if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			err := loadPeers(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadPeers: %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(peers) != len(tt.want) {
+				t.Fatalf("peers = %v, want %v", peers, tt.want)
+			}
+			for i, p := range peers {
+				if p.addr != tt.want[i] {
+					t.Errorf("peer %d at %s, want %s", i, p.addr, tt.want[i])
+				}
+				// their results count on the combined leaderboard
+				if _, ok := trustedServers[gossh.FingerprintSHA256(p.key)]; !ok {
+					t.Errorf("peer %s isn't trusted", p.addr)
+				}
+			}
+		})
+	}
+}
+
+// TestSessionInputHandoff hands the player's keys from the game to a peer
+// and back, without either swallowing one meant for the other
+func TestSessionInputHandoff(t *testing.T) {
+	keys, player := io.Pipe()
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		player.Close()
+	})
+	in := newSessionInput(keys, done)
+
+	read := func(reader func([]byte) (int, error)) chan string {
+		got := make(chan string, 1)
+		go func() {
+			buf := make([]byte, 16)
+			n, _ := reader(buf)
+			got <- string(buf[:n])
+		}()
+		return got
+	}
+
+	// the game's waiting for a key when the player's sent off
+	in.mutex.Lock()
+	stop := in.stop // what Read waits on, had it got there first
+	in.mutex.Unlock()
+	game := read(func(p []byte) (int, error) { return in.readUntil(p, stop) })
+	in.interrupt()
+	if got := <-game; got != "" {
+		t.Fatalf("interrupted game read %q, want nothing", got)
+	}
+
+	// so the first key goes to the peer
+	back := make(chan struct{})
+	peer := read(func(p []byte) (int, error) { return in.readUntil(p, back) })
+	player.Write([]byte("x"))
+	if got := <-peer; got != "x" {
+		t.Errorf("peer read %q, want x", got)
+	}
+
+	// and once they're back, the next goes to the game
+	peer = read(func(p []byte) (int, error) { return in.readUntil(p, back) })
+	close(back)
+	if got := <-peer; got != "" {
+		t.Errorf("peer read %q after they're back, want nothing", got)
+	}
+	game = read(in.Read)
+	player.Write([]byte("q"))
+	if got := <-game; got != "q" {
+		t.Errorf("game read %q, want q", got)
+	}
+}
@@ -0,0 +1,92 @@
+// Package figlet renders text as large ASCII-art banners, figlet style.
+// It has no idea about games, so any TUI can use it for title and win screens.
+package figlet
+
+import "strings"
+
+// Height is the number of lines every rendered banner takes up
+const Height = 5
+
+// Font decides how the pixels of the shared bitmap glyphs are drawn
+type Font struct {
+	Name   string
+	on     string // drawn for an inked pixel
+	off    string // drawn for an empty pixel
+	shadow string // drawn for an empty pixel just left of an inked one, if set
+}
+
+// fonts in the order they're cycled through, the first one is the default
+var fonts = []Font{
+	{Name: "shadow", on: "██", off: "  ", shadow: " ░"},
+	{Name: "block", on: "██", off: "  "},
+	{Name: "hash", on: "#", off: " "},
+}
+
+// Default is the font used when none is picked
+var Default = fonts[0]
+
+// Lookup finds a font by name
+func Lookup(name string) (Font, bool) {
+	for _, f := range fonts {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Font{}, false
+}
+
+// Names lists the available fonts
+func Names() []string {
+	names := make([]string, len(fonts))
+	for i, f := range fonts {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Next returns the font after f, wrapping around, handy for letting players cycle fonts
+func Next(f Font) Font {
+	for i, font := range fonts {
+		if font.Name == f.Name {
+			return fonts[(i+1)%len(fonts)]
+		}
+	}
+	return Default
+}
+
+// Render draws text in this font. Letters are upper-cased and characters
+// the font doesn't know are drawn as '?'.
+func (f Font) Render(text string) string {
+	// lay the glyphs out side by side as one big bitmap, one blank column apart
+	rows := make([]string, Height)
+	for i, r := range []rune(strings.ToUpper(text)) {
+		glyph, ok := glyphs[r]
+		if !ok {
+			glyph = glyphs['?']
+		}
+		for y := range rows {
+			if i > 0 {
+				rows[y] += " "
+			}
+			rows[y] += glyph[y]
+		}
+	}
+
+	// then ink it
+	lines := make([]string, Height)
+	for y, row := range rows {
+		var b strings.Builder
+		for x, pixel := range row {
+			switch {
+			case pixel == '#':
+				b.WriteString(f.on)
+			case f.shadow != "" && x+1 < len(row) && row[x+1] == '#':
+				b.WriteString(f.shadow)
+			default:
+				b.WriteString(f.off)
+			}
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,321 @@
+package figlet
+
+// glyphs is a 5-row bitmap font, '#' marks an inked pixel. Every font in this
+// package is drawn from these shapes, they only differ in how pixels are inked.
+var glyphs = map[rune][]string{
+	'A': {
+		" ### ",
+		"#   #",
+		"#####",
+		"#   #",
+		"#   #",
+	},
+	'B': {
+		"#### ",
+		"#   #",
+		"#### ",
+		"#   #",
+		"#### ",
+	},
+	'C': {
+		" ####",
+		"#    ",
+		"#    ",
+		"#    ",
+		" ####",
+	},
+	'D': {
+		"#### ",
+		"#   #",
+		"#   #",
+		"#   #",
+		"#### ",
+	},
+	'E': {
+		"#####",
+		"#    ",
+		"#### ",
+		"#    ",
+		"#####",
+	},
+	'F': {
+		"#####",
+		"#    ",
+		"#### ",
+		"#    ",
+		"#    ",
+	},
+	'G': {
+		" ####",
+		"#    ",
+		"#  ##",
+		"#   #",
+		" ####",
+	},
+	'H': {
+		"#   #",
+		"#   #",
+		"#####",
+		"#   #",
+		"#   #",
+	},
+	'I': {
+		"###",
+		" # ",
+		" # ",
+		" # ",
+		"###",
+	},
+	'J': {
+		"    #",
+		"    #",
+		"    #",
+		"#   #",
+		" ### ",
+	},
+	'K': {
+		"#   #",
+		"#  # ",
+		"###  ",
+		"#  # ",
+		"#   #",
+	},
+	'L': {
+		"#    ",
+		"#    ",
+		"#    ",
+		"#    ",
+		"#####",
+	},
+	'M': {
+		"#   #",
+		"## ##",
+		"# # #",
+		"#   #",
+		"#   #",
+	},
+	'N': {
+		"#   #",
+		"##  #",
+		"# # #",
+		"#  ##",
+		"#   #",
+	},
+	'O': {
+		" ### ",
+		"#   #",
+		"#   #",
+		"#   #",
+		" ### ",
+	},
+	'P': {
+		"#### ",
+		"#   #",
+		"#### ",
+		"#    ",
+		"#    ",
+	},
+	'Q': {
+		" ### ",
+		"#   #",
+		"# # #",
+		"#  # ",
+		" ## #",
+	},
+	'R': {
+		"#### ",
+		"#   #",
+		"#### ",
+		"#  # ",
+		"#   #",
+	},
+	'S': {
+		" ####",
+		"#    ",
+		" ### ",
+		"    #",
+		"#### ",
+	},
+	'T': {
+		"#####",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+	},
+	'U': {
+		"#   #",
+		"#   #",
+		"#   #",
+		"#   #",
+		" ### ",
+	},
+	'V': {
+		"#   #",
+		"#   #",
+		"#   #",
+		" # # ",
+		"  #  ",
+	},
+	'W': {
+		"#   #",
+		"#   #",
+		"# # #",
+		"## ##",
+		"#   #",
+	},
+	'X': {
+		"#   #",
+		" # # ",
+		"  #  ",
+		" # # ",
+		"#   #",
+	},
+	'Y': {
+		"#   #",
+		" # # ",
+		"  #  ",
+		"  #  ",
+		"  #  ",
+	},
+	'Z': {
+		"#####",
+		"   # ",
+		"  #  ",
+		" #   ",
+		"#####",
+	},
+	'0': {
+		" ### ",
+		"#  ##",
+		"# # #",
+		"##  #",
+		" ### ",
+	},
+	'1': {
+		" # ",
+		"## ",
+		" # ",
+		" # ",
+		"###",
+	},
+	'2': {
+		"#### ",
+		"    #",
+		" ### ",
+		"#    ",
+		"#####",
+	},
+	'3': {
+		"#### ",
+		"    #",
+		" ### ",
+		"    #",
+		"#### ",
+	},
+	'4': {
+		"#   #",
+		"#   #",
+		"#####",
+		"    #",
+		"    #",
+	},
+	'5': {
+		"#####",
+		"#    ",
+		"#### ",
+		"    #",
+		"#### ",
+	},
+	'6': {
+		" ### ",
+		"#    ",
+		"#### ",
+		"#   #",
+		" ### ",
+	},
+	'7': {
+		"#####",
+		"    #",
+		"   # ",
+		"  #  ",
+		"  #  ",
+	},
+	'8': {
+		" ### ",
+		"#   #",
+		" ### ",
+		"#   #",
+		" ### ",
+	},
+	'9': {
+		" ### ",
+		"#   #",
+		" ####",
+		"    #",
+		" ### ",
+	},
+	' ': {
+		"   ",
+		"   ",
+		"   ",
+		"   ",
+		"   ",
+	},
+	'!': {
+		"#",
+		"#",
+		"#",
+		" ",
+		"#",
+	},
+	'?': {
+		"#### ",
+		"    #",
+		"  ## ",
+		"     ",
+		"  #  ",
+	},
+	'.': {
+		" ",
+		" ",
+		" ",
+		" ",
+		"#",
+	},
+	',': {
+		"  ",
+		"  ",
+		"  ",
+		" #",
+		"# ",
+	},
+	'\'': {
+		"#",
+		"#",
+		" ",
+		" ",
+		" ",
+	},
+	'-': {
+		"    ",
+		"    ",
+		"####",
+		"    ",
+		"    ",
+	},
+	'_': {
+		"    ",
+		"    ",
+		"    ",
+		"    ",
+		"####",
+	},
+	':': {
+		" ",
+		"#",
+		" ",
+		"#",
+		" ",
+	},
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"tictactui/animation"
+)
+
+// GameConnectFour is Connect Four, as recorded in journals
+const GameConnectFour = "connect-four"
+
+// connectFourTitle heads Connect Four's screens, like titleArt does
+// Tic-Tac-Toe's
+const connectFourTitle = `
+    ___                            _      _ _
+   / __| ___  _ _   _ _   ___  __ | |_   | | |
+  | (__ / _ \| ' \ | ' \ / -_)/ _||  _|  |_  _|
+   \___|\___/|_||_||_||_|\___|\__| \__|    |_|
+`
+
+// A piece dropped into a Connect Four column falls a row every frame
+const DropFrameInterval = 50 * time.Millisecond
+
+// gameRules is a game played by taking turns on a grid. lineGame is every
+// game where marks are put down to make a line, checkers moves pieces.
+type gameRules interface {
+	Name() string  // as recorded in journals, like GameTicTacToe
+	Title() string // as shown to players
+	Art() string   // the built-in title, see artFor
+	Size() (rows, cols int)
+
+	// newBoard sets a board up for the start of a game
+	newBoard() [][]string
+
+	// playable reports whether anything ever goes in a cell
+	playable(row, col int) bool
+
+	// aim is the cell a move made with the cursor at row, col is about: with
+	// gravity the one a mark would land in. The row is -1 if there's none.
+	aim(board [][]string, row, col int) coord
+
+	// moves lists the player's legal moves. from is the piece that has to
+	// keep moving, in the middle of a multi-jump, nil the rest of the time.
+	moves(board [][]string, player string, from *coord) []move
+
+	// play makes one of the player's legal moves on the board, reporting
+	// whether the piece has to move again
+	play(board [][]string, player string, mv move) (again bool)
+
+	// result is how the game stands once the player has moved: the winner,
+	// Draw, or Empty while it goes on, with the cells that won it
+	result(board [][]string, player string) (winner string, cells []coord)
+}
+
+// move is a player's move: a mark put down in a cell, or a piece moved from
+// one cell to another
+type move struct {
+	from, to coord // from is to when a mark is put down
+}
+
+// String names the move the way it's journaled, "B2" or "C3-D4"
+func (mv move) String() string {
+	if mv.from == mv.to {
+		return cellName(mv.to.row, mv.to.col)
+	}
+	return cellName(mv.from.row, mv.from.col) + "-" + cellName(mv.to.row, mv.to.col)
+}
+
+// parseMove reads a move the way String names it, on a board of the given size
+func parseMove(s string, rows, cols int) (move, bool) {
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		to = from
+	}
+	fromRow, fromCol, ok := parseCell(from)
+	if !ok {
+		return move{}, false
+	}
+	toRow, toCol, ok := parseCell(to)
+	if !ok || max(fromRow, toRow) >= rows || max(fromCol, toCol) >= cols {
+		return move{}, false
+	}
+	return move{coord{fromRow, fromCol}, coord{toRow, toCol}}, true
+}
+
+// owner is whose piece, or mark, is in a cell. Kings are the player's
+// symbol twice, see crown.
+func owner(cell string) string {
+	if cell == Empty {
+		return Empty
+	}
+	return cell[:1]
+}
+
+// lineGame is a game played by taking turns to put marks on a grid, the
+// first to get enough in a line wins
+type lineGame struct {
+	name    string
+	title   string
+	rows    int
+	cols    int
+	inARow  int  // marks in a line that win
+	gravity bool // marks are dropped into a column and fall to the lowest free cell
+	art     string
+}
+
+var (
+	ticTacToe   = lineGame{name: GameTicTacToe, title: "Tic-Tac-Toe", rows: BoardSize, cols: BoardSize, inARow: BoardSize, art: titleArt}
+	connectFour = lineGame{name: GameConnectFour, title: "Connect Four", rows: 6, cols: 7, inARow: 4, gravity: true, art: connectFourTitle}
+)
+
+// boardGames are all the games there are, the first is the default
+var boardGames = []gameRules{ticTacToe, connectFour, checkers{}}
+
+// enabledGames are the games players can pick from on this server, the
+// first is the default, set with -games. The rest still show in replays
+// and stats from before they were turned off.
+var enabledGames = boardGames
+
+// enableGames limits players to the games named, comma separated, in the
+// order they're named
+func enableGames(names string) error {
+	var games []gameRules
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(boardGames, func(g gameRules) bool { return g.Name() == name })
+		if i < 0 {
+			return fmt.Errorf("there's no game %q, pick from %s", name, strings.Join(gameNames(boardGames), ", "))
+		}
+		if !gameEnabled(games, name) {
+			games = append(games, boardGames[i])
+		}
+	}
+	enabledGames = games
+	return nil
+}
+
+// gameNames lists games' names, as -games takes them
+func gameNames(games []gameRules) []string {
+	names := make([]string, len(games))
+	for i, g := range games {
+		names[i] = g.Name()
+	}
+	return names
+}
+
+// gameEnabled reports whether a game is one of games
+func gameEnabled(games []gameRules, name string) bool {
+	return slices.ContainsFunc(games, func(g gameRules) bool { return g.Name() == name })
+}
+
+// rulesFor looks a game up by name, unknown and empty names are the default
+func rulesFor(name string) gameRules {
+	for _, g := range boardGames {
+		if g.Name() == name {
+			return g
+		}
+	}
+	return boardGames[0]
+}
+
+// nextGame is the enabled game after g, for cycling through them
+func nextGame(g gameRules) gameRules {
+	for i, other := range enabledGames {
+		if other.Name() == g.Name() {
+			return enabledGames[(i+1)%len(enabledGames)]
+		}
+	}
+	return enabledGames[0]
+}
+
+// largestBoard is how many rows and columns the biggest board has
+func largestBoard() (rows, cols int) {
+	for _, g := range boardGames {
+		r, c := g.Size()
+		rows, cols = max(rows, r), max(cols, c)
+	}
+	return rows, cols
+}
+
+// hasGravity reports whether marks fall down their column in a game
+func hasGravity(g gameRules) bool {
+	line, ok := g.(lineGame)
+	return ok && line.gravity
+}
+
+func (g lineGame) Name() string           { return g.name }
+func (g lineGame) Title() string          { return g.title }
+func (g lineGame) Art() string            { return g.art }
+func (g lineGame) Size() (rows, cols int) { return g.rows, g.cols }
+func (g lineGame) playable(int, int) bool { return true }
+
+// newBoard makes an empty board for the game
+func (g lineGame) newBoard() [][]string {
+	board := make([][]string, g.rows)
+	for i := range board {
+		board[i] = make([]string, g.cols)
+		for j := range board[i] {
+			board[i][j] = Empty
+		}
+	}
+	return board
+}
+
+// aim is the cell a mark put down at the cursor ends up in: the cursor's,
+// or with gravity the lowest free one in its column
+func (g lineGame) aim(board [][]string, row, col int) coord {
+	return coord{g.landing(board, row, col), col}
+}
+
+// landing is the row a mark put in the given column ends up in: the cursor's
+// row, or with gravity the lowest free one. It's -1 if the cell, or the
+// column, is taken.
+func (g lineGame) landing(board [][]string, row, col int) int {
+	if !g.gravity {
+		if board[row][col] != Empty {
+			return -1
+		}
+		return row
+	}
+	for y := len(board) - 1; y >= 0; y-- {
+		if board[y][col] == Empty {
+			return y
+		}
+	}
+	return -1
+}
+
+// moves are the cells a mark can go in
+func (g lineGame) moves(board [][]string, _ string, _ *coord) []move {
+	var moves []move
+	for y := range board {
+		for x := range board[y] {
+			if g.landing(board, y, x) == y {
+				moves = append(moves, move{coord{y, x}, coord{y, x}})
+			}
+		}
+	}
+	return moves
+}
+
+// play puts the player's mark down
+func (g lineGame) play(board [][]string, player string, mv move) bool {
+	board[mv.to.row][mv.to.col] = player
+	return false
+}
+
+// result is a win for a line, a draw once the board is full
+func (g lineGame) result(board [][]string, player string) (string, []coord) {
+	if cells := g.checkWinner(board, player); cells != nil {
+		return player, cells
+	}
+	if isDraw(board) {
+		return Draw, nil
+	}
+	return Empty, nil
+}
+
+// checkWinner returns the cells of a line of the player's marks long enough
+// to win, nil if there isn't one
+func (g lineGame) checkWinner(board [][]string, player string) []coord {
+	for y := range board {
+		for x := range board[y] {
+			// right, down, down-right and down-left
+			for _, d := range []coord{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+				n := 0
+				for r, c := y, x; r >= 0 && r < len(board) && c >= 0 && c < len(board[r]) && board[r][c] == player; r, c = r+d.row, c+d.col {
+					n++
+				}
+				if n < g.inARow {
+					continue
+				}
+				line := make([]coord, n)
+				for i := range line {
+					line[i] = coord{y + i*d.row, x + i*d.col}
+				}
+				return line
+			}
+		}
+	}
+	return nil
+}
+
+// cursorCell is the cell a move made with the cursor is about, with gravity
+// the cell the mark would land in. The row is -1 if the column is full.
+func (m model) cursorCell() (row, col int) {
+	c := m.rules.aim(m.board, m.cursorY, m.cursorX)
+	return c.row, c.col
+}
+
+// pick works out what pressing enter at the cursor does on the current
+// player's turn: make a move, or pick up the piece to move, or put it back
+func (m *model) pick() (move, bool) {
+	row, col := m.cursorCell()
+	at := coord{row, col}
+	legal := m.rules.moves(m.board, m.currentPlayer, m.chain)
+	for _, mv := range legal {
+		if mv.to == at && (mv.from == at || (m.held != nil && mv.from == *m.held)) {
+			return mv, true
+		}
+	}
+	switch {
+	case m.held != nil && *m.held == at && m.chain == nil:
+		m.held = nil
+	case slices.ContainsFunc(legal, func(mv move) bool { return mv.from == at && mv.to != at }):
+		m.held = &at
+	}
+	return move{}, false
+}
+
+// targets are where the piece that's been picked up can go
+func (m model) targets() []coord {
+	if m.held == nil || m.winner != Empty {
+		return nil
+	}
+	var cells []coord
+	for _, mv := range m.rules.moves(m.board, m.currentPlayer, m.chain) {
+		if mv.from == *m.held && mv.to != mv.from {
+			cells = append(cells, mv.to)
+		}
+	}
+	return cells
+}
+
+// dropIn starts a mark that just landed falling down its column, if the
+// game has gravity. prev is the board before it landed.
+func (m *model) dropIn(prev [][]string) tea.Cmd {
+	if !hasGravity(m.rules) || m.reducedMotion || len(prev) != len(m.board) {
+		return nil
+	}
+	for y, row := range m.board {
+		for x, cell := range row {
+			if cell != Empty && prev[y][x] == Empty {
+				m.dropCell = coord{y, x}
+				var cmd tea.Cmd
+				m.drop, cmd = animation.New(y+1, DropFrameInterval).Start()
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// boardCell is what a cell shows right now: a falling mark shows in the
+// cell it's fallen to so far, not the one it'll land in
+func (m model) boardCell(row, col int) string {
+	if !m.drop.Running() || m.dropCell.col != col {
+		return m.board[row][col]
+	}
+	switch {
+	case row == m.drop.Frame():
+		return m.board[m.dropCell.row][col]
+	case row == m.dropCell.row:
+		return Empty
+	}
+	return m.board[row][col]
+}
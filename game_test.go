@@ -0,0 +1,215 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// lineBoard reads a board drawn a row to a string, top row first: X and O
+// are marks, anything else an empty cell
+func lineBoard(rows ...string) [][]string {
+	board := make([][]string, len(rows))
+	for y, row := range rows {
+		board[y] = make([]string, len(row))
+		for x, c := range row {
+			switch c {
+			case 'X':
+				board[y][x] = PlayerX
+			case 'O':
+				board[y][x] = PlayerO
+			default:
+				board[y][x] = Empty
+			}
+		}
+	}
+	return board
+}
+
+func TestLineGameResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     lineGame
+		board     []string
+		player    string
+		want      string
+		wantCells []coord
+	}{
+		{
+			name:      "row",
+			rules:     ticTacToe,
+			board:     []string{"XXX", "OO.", "..."},
+			player:    PlayerX,
+			want:      PlayerX,
+			wantCells: []coord{{0, 0}, {0, 1}, {0, 2}},
+		},
+		{
+			name:      "column",
+			rules:     ticTacToe,
+			board:     []string{"XO.", "XO.", ".OX"},
+			player:    PlayerO,
+			want:      PlayerO,
+			wantCells: []coord{{0, 1}, {1, 1}, {2, 1}},
+		},
+		{
+			name:      "diagonal",
+			rules:     ticTacToe,
+			board:     []string{"X.O", ".XO", "..X"},
+			player:    PlayerX,
+			want:      PlayerX,
+			wantCells: []coord{{0, 0}, {1, 1}, {2, 2}},
+		},
+		{
+			name:      "other diagonal",
+			rules:     ticTacToe,
+			board:     []string{"X.O", ".OX", "O.X"},
+			player:    PlayerO,
+			want:      PlayerO,
+			wantCells: []coord{{0, 2}, {1, 1}, {2, 0}},
+		},
+		{
+			name:   "only the player's own lines count",
+			rules:  ticTacToe,
+			board:  []string{"XXX", "OO.", "..."},
+			player: PlayerO,
+			want:   Empty,
+		},
+		{
+			name:   "two isn't enough",
+			rules:  ticTacToe,
+			board:  []string{"XX.", "O..", "O.."},
+			player: PlayerX,
+			want:   Empty,
+		},
+		{
+			name:   "full board",
+			rules:  ticTacToe,
+			board:  []string{"XOX", "XOO", "OXX"},
+			player: PlayerX,
+			want:   Draw,
+		},
+		{
+			name:      "a line on the last cell isn't a draw",
+			rules:     ticTacToe,
+			board:     []string{"XOX", "OXO", "OXX"},
+			player:    PlayerX,
+			want:      PlayerX,
+			wantCells: []coord{{0, 0}, {1, 1}, {2, 2}},
+		},
+		{
+			name:  "four across",
+			rules: connectFour,
+			board: []string{
+				".......",
+				".......",
+				".......",
+				".......",
+				"OOO....",
+				"XXXX...",
+			},
+			player:    PlayerX,
+			want:      PlayerX,
+			wantCells: []coord{{5, 0}, {5, 1}, {5, 2}, {5, 3}},
+		},
+		{
+			name:  "four down",
+			rules: connectFour,
+			board: []string{
+				".......",
+				".......",
+				"......O",
+				"X.....O",
+				"X.....O",
+				"XX....O",
+			},
+			player:    PlayerO,
+			want:      PlayerO,
+			wantCells: []coord{{2, 6}, {3, 6}, {4, 6}, {5, 6}},
+		},
+		{
+			name:  "four on a diagonal",
+			rules: connectFour,
+			board: []string{
+				".......",
+				".......",
+				"...X...",
+				"..XO...",
+				".XOO...",
+				"XOOOX..",
+			},
+			player:    PlayerX,
+			want:      PlayerX,
+			wantCells: []coord{{2, 3}, {3, 2}, {4, 1}, {5, 0}},
+		},
+		{
+			name:  "three isn't enough",
+			rules: connectFour,
+			board: []string{
+				".......",
+				".......",
+				".......",
+				".......",
+				"OOO....",
+				"XXX.X..",
+			},
+			player: PlayerX,
+			want:   Empty,
+		},
+		{
+			name:  "five in a row is all of it",
+			rules: connectFour,
+			board: []string{
+				".......",
+				".......",
+				".......",
+				".......",
+				"OOOO...",
+				"XXXXX..",
+			},
+			player:    PlayerX,
+			want:      PlayerX,
+			wantCells: []coord{{5, 0}, {5, 1}, {5, 2}, {5, 3}, {5, 4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cells := tt.rules.result(lineBoard(tt.board...), tt.player)
+			if got != tt.want {
+				t.Errorf("result = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(cells, tt.wantCells) {
+				t.Errorf("cells = %v, want %v", cells, tt.wantCells)
+			}
+		})
+	}
+}
+
+func TestLineGameAim(t *testing.T) {
+	board := lineBoard(
+		"..X....",
+		"..O....",
+		"..X....",
+		"..O....",
+		"..X....",
+		"..OX...",
+	)
+	tests := []struct {
+		name     string
+		rules    lineGame
+		board    [][]string
+		row, col int
+		want     coord
+	}{
+		{"empty column", connectFour, board, 0, 0, coord{5, 0}},
+		{"on top of the others", connectFour, board, 0, 3, coord{4, 3}},
+		{"full column", connectFour, board, 3, 2, coord{-1, 2}},
+		{"without gravity it's the cursor's", ticTacToe, lineBoard("...", "...", "..."), 0, 1, coord{0, 1}},
+		{"taken cell", ticTacToe, lineBoard(".X.", "...", "..."), 0, 1, coord{-1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.aim(tt.board, tt.row, tt.col); got != tt.want {
+				t.Errorf("aim(%d, %d) = %v, want %v", tt.row, tt.col, got, tt.want)
+			}
+		})
+	}
+}
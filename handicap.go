@@ -0,0 +1,118 @@
+package main
+
+import (
+	"time"
+)
+
+// A room can be opened with a handicap, to even out a game between friends
+// of different strength. The weaker player is the one with the lower
+// rating when the game starts; players with the same rating play level.
+
+// handicap is what a room gives the weaker player
+type handicap int
+
+const (
+	handicapNone  handicap = iota
+	handicapMark           // their mark is in the middle before the game starts, and the stronger player moves first
+	handicapClock          // the stronger player's game clock is cut, see HandicapClockShare
+)
+
+var handicapNames = []string{
+	handicapNone:  "none",
+	handicapMark:  "a mark placed for the weaker player",
+	handicapClock: "a shorter clock for the stronger player",
+}
+
+func (h handicap) String() string {
+	return handicapNames[h]
+}
+
+// HandicapClockShare is how much of the game clock the stronger player gets
+// with a clock handicap
+const HandicapClockShare = 0.5
+
+// handicapFits reports whether a handicap can be given in a game, with its
+// time control: marks only go down in the games played with marks, and
+// there's no cutting a clock that isn't there
+func handicapFits(h handicap, rules gameRules, tc timeControl) bool {
+	switch h {
+	case handicapMark:
+		_, ok := rules.(lineGame)
+		return ok
+	case handicapClock:
+		return tc.Game > 0
+	}
+	return true
+}
+
+// stronger is the seat of the player with the higher rating, false if
+// they're level. Call with the session locked for reading.
+func (gs *GameSession) stronger() (int, bool) {
+	ratings := [2]int{}
+	for i, p := range gs.match.Players {
+		ratings[i] = sessionManager.queue.Record(p.ID, gs.Rules.Name()).Rating
+	}
+	switch {
+	case ratings[0] > ratings[1]:
+		return 0, true
+	case ratings[1] > ratings[0]:
+		return 1, true
+	}
+	return 0, false
+}
+
+// placeHandicap puts the weaker player's mark on the cell nearest the
+// middle of a new game's board, with the stronger player to move, in a room
+// with a mark handicap. Call with the session locked, before the clocks
+// start.
+func (gs *GameSession) placeHandicap() {
+	if gs.Handicap != handicapMark {
+		return
+	}
+	strong, ok := gs.stronger()
+	if !ok {
+		return
+	}
+	symbol := []string{PlayerX, PlayerO}[1-strong]
+	moves := gs.Rules.moves(gs.Board, symbol, nil)
+	if len(moves) == 0 {
+		return
+	}
+	rows, cols := gs.Rules.Size()
+	best := moves[0]
+	for _, mv := range moves[1:] {
+		if offCentre(mv.to, rows, cols) < offCentre(best.to, rows, cols) {
+			best = mv
+		}
+	}
+	gs.Rules.play(gs.Board, symbol, best)
+	gs.journal.Record(Event{Type: EventMove, Player: symbol, Cell: best.String()})
+	gs.CurrentPlayer = strong
+}
+
+// handicapClocks cuts the stronger player's game clock in a room with a
+// clock handicap. Call with the session locked, as the clocks are wound up.
+func (gs *GameSession) handicapClocks() {
+	if gs.Handicap != handicapClock {
+		return
+	}
+	if strong, ok := gs.stronger(); ok {
+		gs.Clocks[strong] = time.Duration(float64(gs.Clocks[strong]) * HandicapClockShare)
+	}
+}
+
+// offCentre is how far a cell is from the middle of a board, squared
+func offCentre(c coord, rows, cols int) int {
+	dy, dx := 2*c.row-(rows-1), 2*c.col-(cols-1)
+	return dy*dy + dx*dx
+}
+
+// handicapView shows the handicap rooms the player opens are played with,
+// in the games it can be given in
+func (m model) handicapView() string {
+	h := m.roomHandicap
+	if !handicapFits(h, m.rules, roomTimeControl(m.roomClock)) {
+		h = handicapNone
+	}
+	return footerStyle.Render("  Handicap for new rooms: ") + headerStyle.Render(h.String()) + footerStyle.Render(" (h to change)") + "\n"
+}
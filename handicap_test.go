@@ -0,0 +1,119 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"tictactui/matchmaking"
+)
+
+func TestHandicap(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      gameRules
+		handicap   handicap
+		ratings    [2]int // X's and O's
+		wantBoard  []string
+		wantFirst  int
+		wantClocks [2]time.Duration
+	}{
+		{"none", ticTacToe, handicapNone, [2]int{1400, 1200},
+			[]string{"...", "...", "..."}, 0, [2]time.Duration{time.Minute, time.Minute}},
+		{"mark for O", ticTacToe, handicapMark, [2]int{1400, 1200},
+			[]string{"...", ".O.", "..."}, 0, [2]time.Duration{time.Minute, time.Minute}},
+		{"mark for X", ticTacToe, handicapMark, [2]int{1200, 1400},
+			[]string{"...", ".X.", "..."}, 1, [2]time.Duration{time.Minute, time.Minute}},
+		{"mark falls in connect four", connectFour, handicapMark, [2]int{1400, 1200},
+			[]string{".......", ".......", ".......", ".......", ".......", "...O..."}, 0, [2]time.Duration{time.Minute, time.Minute}},
+		{"level players", ticTacToe, handicapMark, [2]int{1300, 1300},
+			[]string{"...", "...", "..."}, 0, [2]time.Duration{time.Minute, time.Minute}},
+		{"clock", ticTacToe, handicapClock, [2]int{1200, 1400},
+			[]string{"...", "...", "..."}, 0, [2]time.Duration{time.Minute, 30 * time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old := sessionManager.queue
+			t.Cleanup(func() { sessionManager.queue = old })
+			sessionManager.queue = matchmaking.NewQueue()
+			sessionManager.queue.SetStore(testRatings{"x": tt.ratings[0], "o": tt.ratings[1]})
+
+			gs := &GameSession{
+				Rules:       tt.rules,
+				Board:       tt.rules.newBoard(),
+				TimeControl: timeControl{Game: time.Minute},
+				Handicap:    tt.handicap,
+				TurnStarted: time.Now(),
+				match:       matchmaking.Versus(matchmaking.Player{ID: "x"}, matchmaking.Player{ID: "o"}),
+			}
+			gs.placeHandicap()
+			gs.startClocks()
+
+			want := lineBoard(tt.wantBoard...)
+			for y := range want {
+				for x := range want[y] {
+					if gs.Board[y][x] != want[y][x] {
+						t.Errorf("%s = %q, want %q", cellName(y, x), gs.Board[y][x], want[y][x])
+					}
+				}
+			}
+			if gs.CurrentPlayer != tt.wantFirst {
+				t.Errorf("CurrentPlayer = %d, want %d", gs.CurrentPlayer, tt.wantFirst)
+			}
+			if gs.Clocks != tt.wantClocks {
+				t.Errorf("Clocks = %v, want %v", gs.Clocks, tt.wantClocks)
+			}
+		})
+	}
+}
+
+// testRatings is a record store with only ratings in it, by player ID,
+// the same in every game
+type testRatings map[string]int
+
+func (r testRatings) LoadResults(playerID, _ string) (matchmaking.Results, bool) {
+	rating, ok := r[playerID]
+	return matchmaking.Results{Rating: rating}, ok
+}
+
+func (r testRatings) SaveResults(string, string, matchmaking.Results) error {
+	return nil
+}
+
+func (r testRatings) LoadPenalties(string) (matchmaking.Penalties, bool) {
+	return matchmaking.Penalties{}, false
+}
+
+func (r testRatings) SavePenalties(string, matchmaking.Penalties) error {
+	return nil
+}
+
+// TestHandicapAfterRestart checks a restarted game's handicap mark is in
+// its replay, not the game before's
+func TestHandicapAfterRestart(t *testing.T) {
+	old := sessionManager.queue
+	t.Cleanup(func() { sessionManager.queue = old })
+	sessionManager.queue = matchmaking.NewQueue()
+	sessionManager.queue.SetStore(testRatings{"x": 1400, "o": 1200})
+
+	gs := &GameSession{
+		ID:       "abc123",
+		Rules:    ticTacToe,
+		Board:    ticTacToe.newBoard(),
+		Handicap: handicapMark,
+		match:    matchmaking.Versus(matchmaking.Player{ID: "x"}, matchmaking.Player{ID: "o"}),
+		journal:  &Journal{},
+	}
+	gs.placeHandicap()
+	m := model{gameSession: gs, rules: ticTacToe, playerSymbol: PlayerX}
+	m.resetGame()
+
+	replays := replaysIn(gs.ID, gs.journal.Events())
+	if len(replays) != 2 {
+		t.Fatalf("%d replays, want the game before the restart and the one after", len(replays))
+	}
+	for i, r := range replays {
+		if len(r.moves) != 1 || r.moves[0].Cell != "B2" {
+			t.Errorf("game %d moves = %+v, want O's mark on B2", i+1, r.moves)
+		}
+	}
+}
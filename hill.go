@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"tictactui/matchmaking"
+)
+
+// hillMsg says something changed on the hill
+type hillMsg struct{}
+
+// checkHill looks at the hill straight away
+func checkHill() tea.Msg {
+	return hillMsg{}
+}
+
+// watchHill waits for the next change on the hill
+func watchHill(h *matchmaking.Hill) tea.Cmd {
+	changed := h.Changed()
+	return func() tea.Msg {
+		<-changed
+		return hillMsg{}
+	}
+}
+
+// playHillMatch seats a player in the bout they're in on the hill
+func (sm *SessionManager) playHillMatch(match *matchmaking.Match, p matchmaking.Player) (*GameSession, int, error) {
+	session, seat := sm.sit(match, p, false)
+	session.mutex.Lock()
+	session.hill = sm.hill
+	session.mutex.Unlock()
+	return session, seat, nil
+}
+
+// joinHill puts the player on the hill, to hold it or line up to challenge
+// whoever does
+func (m model) joinHill() (tea.Model, tea.Cmd) {
+	h := sessionManager.hill
+	m.casual = false // bouts are rated
+	if err := h.Join(m.player()); err != nil {
+		m.lobby.err = "Couldn't get on the hill: " + strings.TrimPrefix(err.Error(), "matchmaking: ")
+		return m, nil
+	}
+	if m.hill == nil {
+		playerID, done := m.playerID, m.done
+		go func() {
+			<-done
+			h.Leave(playerID)
+		}()
+	}
+	m.lobby = lobby{}
+	m.hill = h
+	return m, tea.Batch(m.clearScreen(), checkHill)
+}
+
+// leaveHill goes back to the lobby, giving up the hill or a place in line
+func (m model) leaveHill() (tea.Model, tea.Cmd) {
+	m.hill.Leave(m.playerID)
+	f := m.fresh()
+	f.hill = nil
+	return f.backToLobby()
+}
+
+// updateHill sends the player to their bout once it's ready, otherwise
+// keeps waiting on the hill
+func (m model) updateHill() (tea.Model, tea.Cmd) {
+	if m.gameSession != nil && !m.spectating {
+		// playing, the hill is checked again after the bout
+		return m, nil
+	}
+	// start watching before looking so no change slips through in between
+	watch := watchHill(m.hill)
+	if match, ok := m.hill.NextMatch(m.playerID); ok {
+		if m.spectating {
+			// stop watching, it's their turn to play
+			close(m.left)
+			m = m.fresh()
+		}
+		return m.enterSession(sessionManager.playHillMatch(match, m.player()))
+	}
+	return m, watch
+}
+
+// updateHillKeys handles keys while waiting on the hill
+func (m model) updateHillKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "w":
+		return m.openGameList()
+	case "c":
+		// knocked off, back in line for another go
+		if !m.hill.On(m.playerID) {
+			return m.joinHill()
+		}
+	case "esc":
+		return m.leaveHill()
+	}
+	return m, nil
+}
+
+// backToHill leaves a settled bout for the hill, to defend it or to line up
+// again
+func (m model) backToHill() (tea.Model, tea.Cmd) {
+	close(m.left)
+
+	return m.fresh(), tea.Batch(m.clearScreen(), checkHill)
+}
+
+// hillView draws who holds the hill, who's challenging them, the line and
+// the longest reigns
+func (m model) hillView() string {
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+	s += headerStyle.Render("  King of the hill") + "\n\n"
+
+	h := m.hill
+	king, streak, held := h.King()
+	if !held {
+		s += "  Nobody holds the hill\n"
+	} else {
+		s += "  " + footerStyle.Render("King: ") + king.Name + footerStyle.Render(" · "+rulesFor(king.Game).Title()) + "\n"
+		s += "  " + footerStyle.Render("Streak: ") + headerStyle.Render(fmt.Sprintf("%d", streak)) + "\n"
+	}
+	if challenger, ok := h.Challenger(); ok {
+		s += "  " + footerStyle.Render("Playing now: ") + king.Name + footerStyle.Render(" vs ") + challenger.Name + "\n"
+	}
+
+	line := h.Line()
+	s += "\n" + footerStyle.Render(fmt.Sprintf("  In line (%d)", len(line))) + "\n"
+	for i, p := range line {
+		s += fmt.Sprintf("    %d. %s\n", i+1, p.Name)
+	}
+
+	if hall := h.HallOfFame(); len(hall) > 0 {
+		s += "\n" + footerStyle.Render("  Hall of fame") + "\n"
+		for i, r := range hall {
+			s += fmt.Sprintf("    %d. %s", i+1, r.King.Name) + footerStyle.Render(fmt.Sprintf("  %d in a row, %s", r.Streak, r.Ended.Format("Jan 2 15:04"))) + "\n"
+		}
+	}
+
+	s += "\n"
+	switch {
+	case held && king.ID == m.playerID:
+		s += headerStyle.Render("  You hold the hill, waiting for a challenger...") + "\n"
+	case !h.On(m.playerID):
+		s += footerStyle.Render("  You're off the hill") + "\n"
+	default:
+		for i, p := range line {
+			if p.ID == m.playerID {
+				s += footerStyle.Render(fmt.Sprintf("  You're number %d in line", i+1)) + "\n"
+			}
+		}
+	}
+	s += footerStyle.Render("\n  Press "+m.hillKeys()) + "\n"
+	return s
+}
+
+// hillKeys says what the keys do while waiting on the hill
+func (m model) hillKeys() string {
+	if !m.hill.On(m.playerID) {
+		return "c to challenge again, w to watch a game, esc to go back to the lobby, q to quit"
+	}
+	return "w to watch a game, esc to leave the hill, q to quit"
+}
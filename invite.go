@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"tictactui/matchmaking"
+)
+
+// hostName is the host players reach the server at, set with -host. It goes
+// in invitations, the server can't tell what name it was reached by.
+var hostName = "localhost"
+
+// connectCommand is what players run to reach the server
+func connectCommand() string {
+	return "ssh -t -p " + SSHPort + " " + hostName
+}
+
+const (
+	// InviteTTL is how long an invitation to a room can be used for
+	InviteTTL = 5 * time.Minute
+
+	// InvitePrefix starts the command an invitation runs, before its token
+	InvitePrefix = "join-"
+)
+
+// inviteCommand is what a friend runs to use an invitation token
+func inviteCommand(token string) string {
+	return connectCommand() + " " + InvitePrefix + token
+}
+
+// inviteToken finds the invitation token in the command a player connected
+// with, if they used one
+func inviteToken(command []string) (string, bool) {
+	for _, arg := range command {
+		if token, ok := strings.CutPrefix(arg, InvitePrefix); ok {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// redeemInvite seats a player in the room an invitation token is for
+func (sm *SessionManager) redeemInvite(token string, p matchmaking.Player) (*GameSession, int, error) {
+	match, err := sm.rooms.Redeem(token, p)
+	if err != nil {
+		return nil, 0, err
+	}
+	session, seat := sm.sit(match, p, true)
+	return session, seat, nil
+}
+
+// newInvite makes a fresh invitation to the player's room, the last one
+// stops working
+func (m *model) newInvite(code string) {
+	token, err := sessionManager.rooms.Invite(code, InviteTTL)
+	if err != nil {
+		log.Printf("inviting to room %s: %v", code, err)
+		m.invite = ""
+		return
+	}
+	m.invite = inviteCommand(token)
+	m.inviteExpires = time.Now().Add(InviteTTL)
+}
+
+// inviteView shows the invitation to the room the player is waiting in
+func (m model) inviteView() string {
+	switch {
+	case m.invite == "":
+		return footerStyle.Render("Press i for a command that brings them straight here") + "\n"
+	case time.Now().After(m.inviteExpires):
+		return footerStyle.Render("Their invitation expired, press i for a new one") + "\n"
+	}
+	s := footerStyle.Render(fmt.Sprintf("Or have them run this, it works once in the next %d minutes (i for a new one):", int(InviteTTL.Minutes()))) + "\n"
+	s += "  " + m.invite + "\n"
+	return s
+}
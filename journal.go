@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// journalDir is where game journals are written, set with -journal. Empty
+// means games aren't journaled.
+var journalDir string
+
+// Journal event types
+const (
+	EventJoin          = "join"
+	EventMove          = "move"
+	EventRestart       = "restart"
+	EventTakeback      = "takeback" // the last move was taken back, in a casual game
+	EventSwap          = "swap"     // O took X's first move as their own, under the swap rule
+	EventAbort         = "abort"    // the game was called off because nobody moved, see -abort-after
+	EventDisconnect    = "disconnect"
+	EventResult        = "result"
+	EventChat          = "chat"
+	EventSpectatorChat = "spectator-chat" // said by a spectator, Name says who
+	EventSeed          = "seed"           // what the game's random choices are made from, or its tournament's draw, see Event.Seed
+)
+
+// ReasonTime is the reason given for a result when the game was won on time
+const ReasonTime = "time"
+
+// ReasonAbandoned is the reason given for a result when the loser left a
+// rated game and didn't come back in time
+const ReasonAbandoned = "abandoned"
+
+// Event is one thing that happened in a game
+type Event struct {
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	Player string    `json:"player,omitempty"` // "X" or "O"
+	Name   string    `json:"name,omitempty"`   // player's display name, on join
+	ID     string    `json:"id,omitempty"`     // key fingerprint of whoever joined or, for spectator chat, spoke; key users only
+	Game   string    `json:"game,omitempty"`   // which game is being played, on join
+	Cell   string    `json:"cell,omitempty"`   // board coordinate like "B2", or a piece moved like "C3-D4", on move
+	Result string    `json:"result,omitempty"` // "X", "O" or "draw", on result
+	Text   string    `json:"text,omitempty"`   // what was said, on chat
+	Reason string    `json:"reason,omitempty"` // ReasonTime or ReasonAbandoned if the game wasn't played out, on result
+
+	// on seed, so a game's random choices can be made again to settle a
+	// dispute: the seed of the game that follows, or with Tournament set,
+	// the one its tournament's bracket was drawn with
+	Seed       uint64 `json:"seed,omitempty,string"`
+	Tournament bool   `json:"tournament,omitempty"`
+}
+
+// Journal keeps a game's events for its replays and, with journaling on,
+// appends them to <journalDir>/<game id>-<start time>.jsonl as they happen,
+// so there's a record to replay, moderate or debug even if the server dies
+// mid-game. The start time keeps a game whose ID was used before from adding
+// to the older game's file.
+type Journal struct {
+	path   string // empty if journaling is off
+	events []Event
+	mutex  sync.Mutex
+}
+
+// openJournal starts the journal for a game. It only keeps the events in
+// memory if journaling is off or the directory can't be created.
+func openJournal(gameID string) *Journal {
+	if journalDir == "" {
+		return &Journal{}
+	}
+	if err := os.MkdirAll(journalDir, 0o755); err != nil {
+		log.Printf("journal: %v", err)
+		return &Journal{}
+	}
+	started := time.Now().UTC().Format("20060102T150405Z")
+	return &Journal{path: filepath.Join(journalDir, gameID+"-"+started+".jsonl")}
+}
+
+// Events returns everything recorded so far, oldest first
+func (j *Journal) Events() []Event {
+	if j == nil {
+		return nil
+	}
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return slices.Clone(j.events)
+}
+
+// Record appends an event, stamping it with the current time
+func (j *Journal) Record(e Event) {
+	if j == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.events = append(j.events, e)
+	if j.path == "" {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("journal: %v", err)
+		return
+	}
+
+	// open per event: games don't have a clear end (players can restart
+	// forever) so there's no good moment to close a long-lived file
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		log.Printf("journal: %v", err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		log.Printf("journal: %v", err)
+	}
+}
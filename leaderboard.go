@@ -0,0 +1,321 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// LeaderboardScreenSize is how many players the leaderboard screen lists at
+// once, the rest are scrolled to. It fits a MinTerminalHeight terminal.
+const LeaderboardScreenSize = 8
+
+// leaderboardScreen is the top players, from the lobby or straight from
+// `ssh leaderboard@host`
+type leaderboardScreen struct {
+	active bool
+	off    bool           // no player database, so no ratings to rank
+	game   gameRules      // each game has its own leaderboard
+	season bool           // just the players who've played a rated game this season
+	board  []publicPlayer // every ranked player, read when the screen opens and when the game or season changes
+	top    int            // the first of the players listed that's shown
+	me     string         // the player looking, picked out and jumped to with m
+
+	searching bool           // typing a name to look for
+	search    string         // the name typed so far
+	found     []publicPlayer // the players named like it, listed instead of everyone
+
+	combined bool                  // showing the combined leaderboard over the server and its peers instead
+	across   []networkPlayerRecord // read when it's shown
+}
+
+// MaxSearchLength is how many letters of a name the leaderboard can be
+// searched for, as long as the longest name
+const MaxSearchLength = MaxNameWidth
+
+// openLeaderboard shows the top players in the game picked in the lobby
+func (m model) openLeaderboard() (tea.Model, tea.Cmd) {
+	m.lobby.active = false
+	m.leaderboard = newLeaderboardScreen(m.rules, m.playerID)
+	return m, m.clearScreen()
+}
+
+// newLeaderboardScreen ranks the players in a game for the player looking,
+// there's nobody to show without a player database
+func newLeaderboardScreen(game gameRules, me string) leaderboardScreen {
+	return leaderboardScreen{active: true, game: game, me: me}.read()
+}
+
+// read ranks the players in the screen's game and season, from the top
+func (l leaderboardScreen) read() leaderboardScreen {
+	l.board, l.top, l.search, l.found = nil, 0, "", nil
+	if playerDB == nil {
+		l.off = true
+		return l
+	}
+	var since time.Time
+	if l.season {
+		since = seasonStart(time.Now())
+	}
+	board, err := playerDB.ranking(l.game.Name(), since)
+	if err == nil {
+		l.board = board
+	}
+	return l
+}
+
+// seasonStart is when the season t falls in began. Seasons are the quarters
+// of the year, in UTC; ratings carry over from one to the next.
+func seasonStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// seasonName is what a season's called, like "2025 Q3"
+func seasonName(t time.Time) string {
+	t = seasonStart(t)
+	return fmt.Sprintf("%d Q%d", t.Year(), (int(t.Month())+2)/3)
+}
+
+// listed is the players the screen lists: the ones named like the search,
+// or everyone ranked
+func (l leaderboardScreen) listed() []publicPlayer {
+	if l.search != "" {
+		return l.found
+	}
+	return l.board
+}
+
+// find lists the players whose name starts with the search, by the name
+// they're shown under or any other they've claimed. They keep their rank.
+func (l leaderboardScreen) find() leaderboardScreen {
+	l.top, l.found = 0, nil
+	if l.search == "" {
+		return l
+	}
+	claimed := playerDB.namesStarting(l.search)
+	for _, p := range l.board {
+		if claimed[p.Fingerprint] || strings.HasPrefix(strings.ToLower(p.Name), strings.ToLower(l.search)) {
+			l.found = append(l.found, p)
+		}
+	}
+	return l
+}
+
+// scroll moves the players shown by n, staying on the list
+func (l leaderboardScreen) scroll(n int) leaderboardScreen {
+	l.top = max(0, min(l.top+n, len(l.listed())-LeaderboardScreenSize))
+	return l
+}
+
+// mine is where the player looking is ranked, -1 if they aren't
+func (l leaderboardScreen) mine() int {
+	return slices.IndexFunc(l.board, func(p publicPlayer) bool { return p.Fingerprint == l.me })
+}
+
+// jumpToMe shows everyone ranked, with the player looking in the middle
+func (l leaderboardScreen) jumpToMe() leaderboardScreen {
+	i := l.mine()
+	if i < 0 {
+		return l
+	}
+	l.search, l.found, l.top = "", nil, 0
+	return l.scroll(i - LeaderboardScreenSize/2)
+}
+
+// updateLeaderboardSearch handles keys while typing a name to look for,
+// the list follows as it's typed
+func (m model) updateLeaderboardSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	l := &m.leaderboard
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		l.searching, l.search = false, ""
+	case tea.KeyEnter:
+		l.searching = false
+		return m, nil
+	case tea.KeyBackspace:
+		if l.search != "" {
+			runes := []rune(l.search)
+			l.search = string(runes[:len(runes)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		// printable ones only, it's drawn back to them
+		for _, r := range msg.Runes {
+			if unicode.IsPrint(r) && utf8.RuneCountInString(l.search) < MaxSearchLength {
+				l.search += string(r)
+			}
+		}
+	}
+	*l = l.find()
+	return m, nil
+}
+
+// newCombinedLeaderboard reads the top players over the server and its
+// peers, see federation.go
+func newCombinedLeaderboard(game gameRules) leaderboardScreen {
+	board, err := playerDB.networkLeaderboard(LeaderboardScreenSize)
+	if err != nil {
+		return leaderboardScreen{active: true, game: game, combined: true}
+	}
+	return leaderboardScreen{active: true, game: game, combined: true, across: board}
+}
+
+// updateLeaderboard handles keys on the leaderboard screen
+func (m model) updateLeaderboard(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.leaderboard.searching {
+		return m.updateLeaderboardSearch(msg)
+	}
+	l := &m.leaderboard
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		if l.search != "" {
+			l.search, l.found, l.top = "", nil, 0
+			return m, m.clearScreen()
+		}
+		m.leaderboard = leaderboardScreen{}
+		return m.backToLobby()
+	case "g":
+		if len(enabledGames) > 1 && !l.combined {
+			l.game = nextGame(l.game)
+			*l = l.read()
+			return m, m.clearScreen()
+		}
+	case "s":
+		if !l.off && !l.combined {
+			l.season = !l.season
+			*l = l.read()
+			return m, m.clearScreen()
+		}
+	case "/":
+		if !l.off && !l.combined {
+			l.searching = true
+		}
+	case "m":
+		if !l.combined {
+			*l = l.jumpToMe()
+		}
+	case "up", "k":
+		*l = l.scroll(-1)
+	case "down", "j":
+		*l = l.scroll(1)
+	case "pgup":
+		*l = l.scroll(-LeaderboardScreenSize)
+	case "pgdown":
+		*l = l.scroll(LeaderboardScreenSize)
+	case "n":
+		switch {
+		case m.leaderboard.combined:
+			m.leaderboard = newLeaderboardScreen(m.leaderboard.game, m.playerID)
+		case federating() && playerDB != nil:
+			m.leaderboard = newCombinedLeaderboard(m.leaderboard.game)
+		default:
+			return m, nil
+		}
+		return m, m.clearScreen()
+	}
+	return m, nil
+}
+
+// leaderboardView draws the top players
+func (m model) leaderboardView() string {
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+	if m.leaderboard.combined {
+		return s + m.combinedLeaderboardView()
+	}
+	l := m.leaderboard
+	when := "of all time"
+	if l.season {
+		when = "this season, " + seasonName(time.Now())
+	}
+	if l.search != "" && !l.searching {
+		when += ", named " + l.search + "..."
+	}
+	s += headerStyle.Render("  Top "+l.game.Title()+" players "+when) + "\n\n"
+
+	listed := l.listed()
+	switch {
+	case l.off:
+		s += "  This server doesn't keep ratings\n"
+	case l.search != "" && len(listed) == 0:
+		s += "  Nobody ranked goes by that name\n"
+	case len(listed) == 0 && l.season:
+		s += "  Nobody yet, players show up here once they've played a rated game this season\n"
+	case len(listed) == 0:
+		s += "  Nobody yet, players show up here once they've played a game\n"
+	}
+	for _, p := range listed[l.top:min(l.top+LeaderboardScreenSize, len(listed))] {
+		row := fmt.Sprintf("  %2d. %s", p.Rank, p.Name)
+		if p.Fingerprint == l.me {
+			row = headerStyle.Render(row)
+		}
+		s += row + footerStyle.Render(fmt.Sprintf(" %s %d (%d-%d-%d)", p.Title, p.Rating, p.Wins, p.Losses, p.Draws)) + "\n"
+	}
+	var where []string
+	if len(listed) > LeaderboardScreenSize {
+		where = append(where, fmt.Sprintf("%d-%d of %d, up and down to scroll", l.top+1, l.top+LeaderboardScreenSize, len(listed)))
+	}
+	if i := l.mine(); i >= 0 && !l.searching {
+		where = append(where, fmt.Sprintf("you're #%d", l.board[i].Rank))
+	}
+	if len(where) > 0 {
+		s += footerStyle.Render("  "+strings.Join(where, ", ")) + "\n"
+	}
+
+	s += "\n"
+	if l.searching {
+		s += "  Find: " + l.search + "_\n"
+		s += footerStyle.Render("  Type a name, enter to keep what it finds, esc to stop looking") + "\n"
+		return s
+	}
+	if !l.off {
+		help := "  Press / to find a player, "
+		if l.mine() >= 0 {
+			help += "m to jump to you, "
+		}
+		if l.season {
+			help += "s for all time"
+		} else {
+			help += "s for this season"
+		}
+		s += footerStyle.Render(help) + "\n"
+	}
+	help := "  Press "
+	if len(enabledGames) > 1 {
+		help += "g for another game, "
+	}
+	if federating() && playerDB != nil {
+		help += "n for every server's, "
+	}
+	if l.search != "" {
+		help += "esc to see everyone, "
+	} else {
+		help += "esc to go back, "
+	}
+	s += footerStyle.Render(help+"q to quit") + "\n"
+	return s
+}
+
+// combinedLeaderboardView draws the top players over the server and its
+// peers, by wins in every game
+func (m model) combinedLeaderboardView() string {
+	s := headerStyle.Render("  Top players on every server") + "\n\n"
+	if len(m.leaderboard.across) == 0 {
+		s += "  Nobody yet, players show up here once they've played a rated game\n"
+	}
+	for _, p := range m.leaderboard.across {
+		s += fmt.Sprintf("  %2d. %s", p.Rank, p.Name) + footerStyle.Render(fmt.Sprintf(" (%d-%d-%d), servers played on: %d", p.Wins, p.Losses, p.Draws, p.Servers)) + "\n"
+	}
+	s += footerStyle.Render("\n  Press n for this server's, esc to go back, q to quit") + "\n"
+	return s
+}
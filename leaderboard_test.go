@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+
+	"tictactui/matchmaking"
+)
+
+func TestSeasons(t *testing.T) {
+	tests := []struct {
+		at    time.Time
+		start time.Time
+		name  string
+	}{
+		{time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), "2025 Q1"},
+		{time.Date(2025, 6, 30, 23, 59, 0, 0, time.UTC), time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), "2025 Q2"},
+		{time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC), time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC), "2025 Q3"},
+		{time.Date(2025, 12, 31, 12, 0, 0, 0, time.UTC), time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC), "2025 Q4"},
+		// it's still the last day of the year in New York, but not in UTC
+		{time.Date(2025, 12, 31, 20, 0, 0, 0, time.FixedZone("EST", -5*60*60)), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), "2026 Q1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seasonStart(tt.at); !got.Equal(tt.start) {
+				t.Errorf("seasonStart(%v) = %v, want %v", tt.at, got, tt.start)
+			}
+			if got := seasonName(tt.at); got != tt.name {
+				t.Errorf("seasonName(%v) = %q, want %q", tt.at, got, tt.name)
+			}
+		})
+	}
+}
+
+// TestLeaderboardScreen searches, filters and jumps around a leaderboard
+// longer than the screen
+func TestLeaderboardScreen(t *testing.T) {
+	ps, err := openPlayerStore(filepath.Join(t.TempDir(), "players.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.db.Close()
+	old := playerDB
+	t.Cleanup(func() { playerDB = old })
+	playerDB = ps
+
+	// player00 is rated best, player19 worst; the even ones played last
+	// season, the odd ones this one
+	now := time.Now()
+	for i := range 20 {
+		id := fmt.Sprintf("SHA256:%02d", i)
+		played := seasonStart(now).AddDate(0, 0, -1)
+		if i%2 == 1 {
+			played = now
+		}
+		results := matchmaking.Results{Wins: matchmaking.PlacementMatches, Rating: 1500 - 10*i, LastPlayed: played}
+		if err := ps.SaveResults(id, GameTicTacToe, results); err != nil {
+			t.Fatal(err)
+		}
+		if err := ps.change(id, func(rec *PlayerRecord) { rec.Name = fmt.Sprintf("player%02d", i) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// player14 plays under another name they've claimed too
+	if err := ps.setNameOwner("zed", "SHA256:14"); err != nil {
+		t.Fatal(err)
+	}
+
+	names := func(players []publicPlayer) []string {
+		var got []string
+		for _, p := range players {
+			got = append(got, p.Name)
+		}
+		return got
+	}
+	keys := func(m model, keys ...tea.KeyMsg) model {
+		t.Helper()
+		for _, k := range keys {
+			next, _ := m.updateLeaderboard(k)
+			m = next.(model)
+		}
+		return m
+	}
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	m := model{rules: ticTacToe, playerID: "SHA256:17", leaderboard: newLeaderboardScreen(ticTacToe, "SHA256:17")}
+	if got := len(m.leaderboard.board); got != 20 {
+		t.Fatalf("ranked %d players, want all 20", got)
+	}
+	if height := lip.Height(m.leaderboardView()); height > MinTerminalHeight {
+		t.Errorf("the leaderboard is %d lines, taller than a %d line terminal", height, MinTerminalHeight)
+	}
+
+	// they're 18th, so jumping to them shows the bottom of the list
+	m = keys(m, runes("m"))
+	if got := m.leaderboard.top; got != 20-LeaderboardScreenSize {
+		t.Errorf("jumped to %d, want the last %d players", got, LeaderboardScreenSize)
+	}
+
+	// a search finds shown and claimed names, in any case, and the players
+	// keep their rank
+	m = keys(m, runes("/"), runes("Z"))
+	if got := names(m.leaderboard.listed()); !slices.Equal(got, []string{"player14"}) {
+		t.Errorf("found %v for z, want player14 by their claimed name", got)
+	}
+	m = keys(m, tea.KeyMsg{Type: tea.KeyBackspace}, runes("PLAYER1"), tea.KeyMsg{Type: tea.KeyEnter})
+	found := m.leaderboard.listed()
+	if len(found) != 10 || found[0].Name != "player10" || found[0].Rank != 11 {
+		t.Errorf("found %v for player1, want player10 to player19 at their ranks", names(found))
+	}
+	if m.leaderboard.searching {
+		t.Error("still typing after enter")
+	}
+	m = keys(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.leaderboard.search != "" || len(m.leaderboard.listed()) != 20 || !m.leaderboard.active {
+		t.Errorf("esc left search %q and %d players, want everyone back", m.leaderboard.search, len(m.leaderboard.listed()))
+	}
+
+	// this season's only has the odd ones, ranked among themselves
+	m = keys(m, runes("s"))
+	board := m.leaderboard.listed()
+	if len(board) != 10 || board[0].Name != "player01" || board[0].Rank != 1 {
+		t.Errorf("this season's leaderboard is %v, want player01 to player19's odd ones", names(board))
+	}
+	if i := m.leaderboard.mine(); i < 0 || board[i].Rank != 9 {
+		t.Errorf("they're at %d this season, want 9th", i)
+	}
+}
@@ -0,0 +1,524 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+
+	"tictactui/matchmaking"
+)
+
+// Lobby options, in menu order
+const (
+	lobbyQuickMatch = iota
+	lobbyCasualMatch
+	lobbyHill
+	lobbyCrowd
+	lobbyCreateRoom
+	lobbyJoinRoom
+	lobbyWatch
+	lobbyReplays
+	lobbyPractice
+	lobbyPuzzles
+	lobbyProfile
+	lobbyLeaderboard
+	lobbyExport
+	lobbyDelete
+)
+
+var lobbyOptions = []string{
+	lobbyQuickMatch:  "Quick match - play the next person who connects, for rating",
+	lobbyCasualMatch: "Casual match - unrated, no clocks, takebacks allowed",
+	lobbyHill:        "King of the hill - the winner stays on, challengers line up",
+	lobbyCrowd:       "Play the crowd - everyone watching votes on O's moves",
+	lobbyCreateRoom:  "Create a room - get a join code for a friend",
+	lobbyJoinRoom:    "Join a room - enter a friend's join code",
+	lobbyWatch:       "Watch a game - follow a game in progress",
+	lobbyReplays:     "Replays - step through your last games",
+	lobbyPractice:    "Practice - Tic-Tac-Toe vs the computer, with takebacks and an eval bar",
+	lobbyPuzzles:     "Puzzles - X to move and win, forks and all",
+	lobbyProfile:     "Profile - your name, avatar, board colours, macros and stats",
+	lobbyLeaderboard: "Leaderboard - the top rated players",
+	lobbyExport:      "Export - download your matches and stats as JSON or CSV",
+	lobbyDelete:      "Delete my data - forget your record, rating and games",
+}
+
+// MinLobbyOptions is how few of the options the lobby lists at once, when it
+// has to scroll them to fit the terminal
+const MinLobbyOptions = 5
+
+// lobby is where SSH players pick how to find an opponent
+type lobby struct {
+	active       bool
+	choice       int    // highlighted option
+	enteringCode bool   // typing a join code
+	code         string // the join code typed so far
+	err          string // why the last attempt to get into a game failed
+
+	// the attract mode takes over the lobby when nobody's touched it for a while
+	activity       int       // counts key presses, so stale idle timers can be told apart
+	attract        bool      // showing the attract mode instead of the menu
+	attractStarted time.Time // when the attract mode started, pages cycle from here
+	shownPage      int       // the attract mode page on screen
+}
+
+// activity is how busy the server is, for the lobby's footer
+type activity struct {
+	online  int   // SSH sessions connected
+	playing int   // players in a game under way
+	queued  []int // players waiting for a quick match, in each of enabledGames
+}
+
+func (a activity) String() string {
+	s := fmt.Sprintf("%d online, %d playing", a.online, a.playing)
+	if len(a.queued) == 1 {
+		return s + fmt.Sprintf(", %d in queue", a.queued[0])
+	}
+	queues := make([]string, len(a.queued))
+	for i, n := range a.queued {
+		queues[i] = fmt.Sprintf("%s %d", enabledGames[i].Title(), n)
+	}
+	return s + ", in queue: " + strings.Join(queues, ", ")
+}
+
+// connect counts an SSH session until it's done
+func (sm *SessionManager) connect(done <-chan struct{}) {
+	sm.mutex.Lock()
+	sm.online++
+	sm.sessionsChanged()
+	sm.mutex.Unlock()
+	go func() {
+		<-done
+		sm.mutex.Lock()
+		sm.online--
+		sm.sessionsChanged()
+		sm.mutex.Unlock()
+	}()
+}
+
+// activity counts who's on the server and what they're doing
+func (sm *SessionManager) activity() activity {
+	a := activity{playing: 2 * len(sm.games())}
+	for _, g := range enabledGames {
+		a.queued = append(a.queued, sm.queue.WaitingFor(g.Name()))
+	}
+	sm.mutex.RLock()
+	a.online = sm.online
+	sm.mutex.RUnlock()
+	return a
+}
+
+// busyHoursView shows how many games finished in each of the last 24
+// hours, from the journals, so players can see when the server's busy
+func busyHoursView() string {
+	st, err := journalStats.load()
+	if err != nil {
+		return ""
+	}
+	hours := st.lastDay(time.Now())
+	total := 0
+	for _, n := range hours {
+		total += n
+	}
+	if total == 0 {
+		return ""
+	}
+	return footerStyle.Render("  Games per hour, last 24h ") + headerStyle.Render(sparkline(hours[:])) +
+		footerStyle.Render(fmt.Sprintf(" %d in all", total)) + "\n"
+}
+
+// player is who this model plays as, to the matchmaker
+func (m model) player() matchmaking.Player {
+	return matchmaking.Player{ID: m.playerID, Name: m.playerName, Game: m.rules.Name(), Casual: m.casual}
+}
+
+// updateLobby handles keys while picking how to find an opponent
+func (m model) updateLobby(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.lobby.activity++
+	// any key wakes the lobby up from the attract mode, and does nothing else
+	if m.lobby.attract {
+		if msg.Type == tea.KeyCtrlC {
+			return m, tea.Quit
+		}
+		return m.stopAttract()
+	}
+
+	next, cmd := m.updateLobbyMenu(msg)
+	if still, ok := next.(model); ok && still.lobby.active {
+		// still here, wait for the lobby to go idle again
+		return still, tea.Batch(cmd, still.idle())
+	}
+	return next, cmd
+}
+
+// backToLobby returns to the lobby menu from one of its screens
+func (m model) backToLobby() (tea.Model, tea.Cmd) {
+	m.lobby.active = true
+	m.lobby.activity++
+	tick := m.startTicking() // a tournament call's countdown
+	return m, tea.Batch(m.clearScreen(), m.idle(), tick)
+}
+
+// updateLobbyMenu handles keys in the lobby menu and the join code prompt
+func (m model) updateLobbyMenu(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.lobby.enteringCode {
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		case tea.KeyEsc:
+			m.lobby.enteringCode = false
+			m.lobby.code = ""
+		case tea.KeyEnter:
+			if rr, ok := joinReplayRoom(m.lobby.code, m.playerID, m.playerName); ok {
+				return m.enterReplayRoom(rr)
+			}
+			return m.enterSession(sessionManager.joinRoom(m.lobby.code, m.player()))
+		case tea.KeyBackspace:
+			if m.lobby.code != "" {
+				m.lobby.code = m.lobby.code[:len(m.lobby.code)-1]
+			}
+		case tea.KeyRunes:
+			// pastes are welcome here, codes get copied out of chat
+			for _, r := range msg.Runes {
+				if len(m.lobby.code) < matchmaking.CodeLength && r < unicode.MaxASCII &&
+					(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+					m.lobby.code += strings.ToUpper(string(r))
+				}
+			}
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "up", "k":
+		m.lobby.choice = (m.lobby.choice + len(lobbyOptions) - 1) % len(lobbyOptions)
+	case "down", "j":
+		m.lobby.choice = (m.lobby.choice + 1) % len(lobbyOptions)
+	case "t":
+		return m.signUp()
+	case "s":
+		if swappable(m.rules) {
+			m.swapRule = !m.swapRule
+		}
+	case "c":
+		m.roomClock = (m.roomClock + 1) % (len(timeControlPresets) + 1)
+	case "h":
+		m.roomHandicap = (m.roomHandicap + 1) % handicap(len(handicapNames))
+	case "?":
+		return m.startTutorial(), m.clearScreen()
+	case "a":
+		if !persistent(m.playerID) {
+			m.lobby.err = avatarNeedsKey
+			break
+		}
+		m.lobby.err = ""
+		m.setAvatar(nextAvatar(m.avatar, 1))
+	case "g":
+		// quick match and new rooms play this, joining a room plays the room's
+		m.rules = nextGame(m.rules)
+		m.board = m.rules.newBoard()
+		return m, m.clearScreen()
+	case "enter", " ":
+		m.lobby.err = ""
+		m.casual = m.lobby.choice == lobbyCasualMatch // rooms are rated
+		switch m.lobby.choice {
+		case lobbyQuickMatch, lobbyCasualMatch:
+			return m.enterSession(sessionManager.join(m.player()))
+		case lobbyHill:
+			return m.joinHill()
+		case lobbyCrowd:
+			return m.enterSession(sessionManager.playCrowd(m.player()))
+		case lobbyCreateRoom:
+			session, seat, err := sessionManager.createRoom(m.player(), m.swapRule, roomTimeControl(m.roomClock), m.roomHandicap)
+			if err == nil {
+				m.newInvite(session.ID)
+			}
+			return m.enterSession(session, seat, err)
+		case lobbyJoinRoom:
+			m.lobby.enteringCode = true
+		case lobbyWatch:
+			return m.openGameList()
+		case lobbyReplays:
+			return m.openReplays()
+		case lobbyPractice:
+			return m.startPractice()
+		case lobbyPuzzles:
+			return m.startPuzzles()
+		case lobbyProfile:
+			return m.openProfile()
+		case lobbyLeaderboard:
+			return m.openLeaderboard()
+		case lobbyExport:
+			return m.openExport()
+		case lobbyDelete:
+			return m.openDelete()
+		}
+	}
+	return m, nil
+}
+
+// enterSession leaves the lobby for the game session the player got a seat
+// in, or stays in the lobby to explain why they didn't get one
+func (m model) enterSession(session *GameSession, seat int, err error) (tea.Model, tea.Cmd) {
+	if errors.Is(err, matchmaking.ErrCoolingDown) {
+		m.lobby.err = "You can't quick match for another " + formatCooldown(sessionManager.queue.Cooldown(m.playerID)) + ", for dodging or abandoning games"
+		return m, nil
+	} else if err != nil {
+		m.lobby.err = "Couldn't join a game: " + strings.TrimPrefix(err.Error(), "matchmaking: ")
+		return m, nil
+	}
+	return m, m.sitDown(session, seat)
+}
+
+// sitDown takes the player's seat in a game session and follows it until
+// they leave or disconnect
+func (m *model) sitDown(session *GameSession, seat int) tea.Cmd {
+	m.lobby = lobby{}
+	m.gameList = gameList{}
+	m.left = make(chan struct{})
+	m.gameSession = session
+	m.rules, m.board = session.Rules, session.Rules.newBoard()
+	m.updates = session.subscribe()
+	if seat == 0 {
+		// First player waits for an opponent
+		m.playerSymbol = PlayerX
+		m.isMyTurn = true
+		m.waitingForPlayer = true
+	} else {
+		// Second player starts the game
+		m.playerSymbol = PlayerO
+		m.isMyTurn = false
+		m.waitingForPlayer = false
+	}
+	join := Event{Type: EventJoin, Player: m.playerSymbol, Name: m.playerName, Game: session.Rules.Name()}
+	if persistent(m.playerID) {
+		join.ID = m.playerID
+	}
+	session.journal.Record(join)
+
+	// Set up disconnect detection
+	player, symbol, updates, done, left := m.player(), m.playerSymbol, m.updates, m.done, m.left
+	go func() {
+		select {
+		case <-done:
+			// Simple disconnect detection - if session ends, mark as disconnected
+			session.unsubscribe(updates)
+			session.mutex.Lock()
+			seat := playerIndex(symbol)
+			session.leftAt[seat] = time.Now()
+			// leaving once the opponent's gone doesn't abandon anyone, and
+			// they keep their time to come back
+			if session.leftAt[1-seat].IsZero() {
+				session.PlayerDisconnected = true
+				session.ReconnectBy = time.Now().Add(DisconnectTimeout)
+				if session.tournament == nil && session.hill == nil && persistent(player.ID) {
+					// the same key can pick up where they left off, see resume
+					session.ReconnectBy = time.Now().Add(reconnectGrace)
+				}
+				if !session.MatchStarted.IsZero() && session.Winner == Empty {
+					time.AfterFunc(time.Until(session.ReconnectBy), func() { sessionManager.abandon(session, player, seat) })
+				}
+				session.notify()
+			}
+			session.mutex.Unlock()
+			session.journal.Record(Event{Type: EventDisconnect, Player: symbol})
+		case <-left:
+			// went back to the tournament bracket or the hill, the opponent isn't kept waiting
+			session.unsubscribe(updates)
+		}
+		sessionManager.leave(player, session)
+	}()
+
+	cmds := []tea.Cmd{m.clearScreen(), waitForUpdate(m.updates)}
+	if !session.Private {
+		// from the queue, which may move them to another match
+		cmds = append(cmds, waitForMove(session.match, m.playerID))
+	} else if seat == 0 {
+		// or their room may expire before anyone joins
+		cmds = append(cmds, waitForGuest(session.match))
+	}
+	if seat == 0 && !session.Private && !session.Crowd && federating() && !m.visitor && m.tournament == nil && m.hill == nil {
+		// or a peer may have someone for them, see federation.go
+		cmds = append(cmds, lookForPeers(session.match, session.Rules.Name()))
+	}
+	return tea.Batch(cmds...)
+}
+
+// matchMovedMsg says the queue moved a player to another match
+type matchMovedMsg struct {
+	from     *matchmaking.Match
+	to       *matchmaking.Match // nil if they're out of the queue, cooling down
+	requeued bool               // their match wasn't accepted, they're back in the queue
+}
+
+// waitForMove follows the player's queued match until it's under way, and
+// reports it if the queue moves them to another match on the way: pairing
+// them with someone else waiting, or putting them back in the queue when
+// the match isn't accepted
+func waitForMove(match *matchmaking.Match, playerID string) tea.Cmd {
+	return func() tea.Msg {
+		<-match.Waited()
+		if to := match.MovedTo(); to != nil {
+			return matchMovedMsg{from: match, to: to}
+		}
+		if !match.IsReady() {
+			return nil // left the queue
+		}
+		select {
+		case <-match.Confirmed():
+		case <-match.FellThrough():
+			return matchMovedMsg{from: match, to: match.Requeued(playerID), requeued: true}
+		}
+		return nil
+	}
+}
+
+// roomExpiredMsg says nobody joined the player's room in time
+type roomExpiredMsg struct {
+	match *matchmaking.Match
+}
+
+// waitForGuest follows the room a player opened until someone joins it, and
+// reports it if it expires first. Its code is free for a new room then, so
+// they can't wait on in it.
+func waitForGuest(match *matchmaking.Match) tea.Cmd {
+	return func() tea.Msg {
+		<-match.Waited()
+		if !match.IsReady() && match.Released() {
+			return roomExpiredMsg{match: match}
+		}
+		return nil
+	}
+}
+
+// moveMatch leaves the session the player was in for the match the queue
+// moved them to, or for the lobby if it didn't put them back in the queue
+func (m model) moveMatch(msg matchMovedMsg) (tea.Model, tea.Cmd) {
+	if m.gameSession == nil || m.gameSession.match != msg.from {
+		return m, nil // already left it, e.g. by declining it
+	}
+	if m.tournament != nil {
+		// a bout that fell through was settled by who checked in
+		return m.backToBracket()
+	}
+	if m.hill != nil {
+		return m.backToHill()
+	}
+	close(m.left)
+	if msg.to == nil {
+		f := m.fresh()
+		f.lobby.err = "You didn't accept the match, you can't quick match for another " + formatCooldown(sessionManager.queue.Cooldown(m.playerID))
+		return f.backToLobby()
+	}
+	session, seat := sessionManager.sit(msg.to, m.player(), false)
+	f := m.fresh()
+	f.requeued = msg.requeued
+	return f.enterSession(session, seat, nil)
+}
+
+// leaveGame goes back to the lobby from a game session, saying why if
+// there's more to it than the player asking to
+func (m model) leaveGame(why string) (tea.Model, tea.Cmd) {
+	close(m.left)
+	f := m.fresh()
+	f.lobby.err = why
+	return f.backToLobby()
+}
+
+// requeue leaves a finished game for the queue, to play someone new at the
+// same game
+func (m model) requeue() (tea.Model, tea.Cmd) {
+	close(m.left)
+	f := m.fresh()
+	f.lobby.active = true // to explain if they can't get back in the queue
+	next, cmd := f.enterSession(sessionManager.join(f.player()))
+	return next, tea.Batch(f.clearScreen(), cmd)
+}
+
+// lobbyView draws the lobby menu, or the join code prompt
+func (m model) lobbyView() string {
+	if m.lobby.attract {
+		return m.attractModeView()
+	}
+	top := ""
+	if m.visitor {
+		top += footerStyle.Render("  Visiting from another server as ") + m.playerName + formatRecord(m.playerID, m.rules.Name()) + footerStyle.Render(", q takes you back") + "\n"
+	} else if m.returning {
+		top += footerStyle.Render("  Welcome back, ") + withAvatar(m.avatar, m.playerName) + formatRecord(m.playerID, m.rules.Name()) + formatCasualRecord(m.playerID, m.rules.Name()) + "\n"
+	} else {
+		top += footerStyle.Render("  Playing as ") + withAvatar(m.avatar, m.playerName) + formatRecord(m.playerID, m.rules.Name()) + formatCasualRecord(m.playerID, m.rules.Name()) + "\n"
+	}
+	top += m.penaltiesView()
+	top += footerStyle.Render("  Game: ") + headerStyle.Render(m.rules.Title()) + "\n"
+	top += m.swapRuleView()
+	top += m.timeControlView()
+	top += m.handicapView()
+	top += "\n"
+	top += m.newsView()
+	top += m.tournamentCallView()
+
+	var options []string
+	bottom := ""
+	if m.lobby.enteringCode {
+		code := m.lobby.code + strings.Repeat("_", matchmaking.CodeLength-len(m.lobby.code))
+		bottom += "  Join code: " + headerStyle.Render(code) + "\n\n"
+		bottom += footerStyle.Render("  Press enter to join, esc to go back") + "\n"
+	} else {
+		options = lobbyOptions
+		if len(enabledGames) > 1 {
+			bottom += footerStyle.Render("\n  Press enter to pick, g to change game, a for an avatar, ? for the tutorial, q to quit") + "\n"
+		} else {
+			bottom += footerStyle.Render("\n  Press enter to pick, a for an avatar, ? for the tutorial, q to quit") + "\n"
+		}
+	}
+	bottom += footerStyle.Render("  "+sessionManager.activity().String()) + "\n"
+	bottom += busyHoursView()
+	if m.lobby.err != "" {
+		bottom += "\n" + lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render("  "+m.lobby.err) + "\n"
+	}
+
+	// the title art goes when the lobby doesn't fit the terminal with it,
+	// and if it still doesn't the options scroll
+	room := m.height
+	if room == 0 {
+		room = MinTerminalHeight
+	}
+	title := "\n" + headerStyle.Render(m.art().Title) + "\n\n"
+	if lip.Height(title+top+strings.Repeat("\n", len(options))+bottom) > room {
+		title = "\n" + headerStyle.Render("  "+strings.ToUpper(m.rules.Title())) + "\n\n"
+	}
+	over := lip.Height(title+top+strings.Repeat("\n", len(options))+bottom) - room
+	return title + top + m.lobbyOptionsView(options, max(len(options)-over, MinLobbyOptions)) + bottom
+}
+
+// lobbyOptionsView lists the options, rows of them at a time around the one
+// picked, with arrows where there are more to scroll to
+func (m model) lobbyOptionsView(options []string, rows int) string {
+	first := 0
+	if rows < len(options) {
+		first = min(max(m.lobby.choice-rows/2, 0), len(options)-rows)
+	} else {
+		rows = len(options)
+	}
+	s := ""
+	for i := first; i < first+rows; i++ {
+		switch {
+		case i == m.lobby.choice:
+			s += headerStyle.Render("> "+options[i]) + "\n"
+		case i == first && first > 0:
+			s += footerStyle.Render("↑ ") + options[i] + "\n"
+		case i == first+rows-1 && i < len(options)-1:
+			s += footerStyle.Render("↓ ") + options[i] + "\n"
+		default:
+			s += "  " + options[i] + "\n"
+		}
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+
+	"tictactui/matchmaking"
+)
+
+// TestLobbyFits checks the lobby fits the terminal, with the news up and an
+// error showing, and keeps the option picked on screen when it scrolls
+func TestLobbyFits(t *testing.T) {
+	news.mutex.Lock()
+	old := news.lines
+	news.lines = []string{"New: board themes", "Tournament on Friday", "Connect Four is back"}
+	news.mutex.Unlock()
+	t.Cleanup(func() {
+		news.mutex.Lock()
+		news.lines = old
+		news.mutex.Unlock()
+	})
+
+	m := initialModel()
+	m.playerID, m.playerName, m.lobby.active = "session:a", "ann", true
+	m.lobby.err = "Can't find a room with that code"
+	for _, height := range []int{0, MinTerminalHeight, MinTerminalHeight + 5} {
+		m.height = height // 0 until the terminal says
+		for _, choice := range []int{lobbyQuickMatch, lobbyPuzzles, lobbyDelete} {
+			m.lobby.choice = choice
+			view := m.lobbyView()
+			if got := lip.Height(view); got > max(height, MinTerminalHeight) {
+				t.Errorf("in a %d line terminal the lobby is %d lines", height, got)
+			}
+			if !strings.Contains(view, "> "+lobbyOptions[choice]) {
+				t.Errorf("in a %d line terminal option %d isn't shown", height, choice)
+			}
+		}
+	}
+
+	// there's room for the whole lobby in a tall terminal
+	m.height = 60
+	art := strings.Fields(m.art().Title)[0]
+	if view := m.lobbyView(); !strings.Contains(view, art) || strings.Contains(view, "↓") {
+		t.Error("a tall terminal doesn't get the title art and every option")
+	}
+}
+
+// TestRoomExpires sends a room's creator back to the lobby when nobody joins
+// in time, its code is free for another room then
+func TestRoomExpires(t *testing.T) {
+	old := sessionManager.rooms
+	sessionManager.rooms = matchmaking.NewRooms(10 * time.Millisecond)
+	t.Cleanup(func() { sessionManager.rooms = old })
+
+	m := initialModel()
+	m.playerID, m.playerName, m.lobby.active = "session:a", "ann", true
+	session, seat, err := sessionManager.createRoom(m.player(), false, timeControl{}, handicapNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, _ := m.enterSession(session, seat, nil)
+	m = next.(model)
+	expired := make(chan tea.Msg)
+	go func() { expired <- waitForGuest(session.match)() }()
+
+	time.Sleep(30 * time.Millisecond)
+	if sessionManager.rooms.Open(session.ID) {
+		t.Fatal("the room's still open")
+	}
+	msg := <-expired
+	if _, ok := msg.(roomExpiredMsg); !ok {
+		t.Fatalf("got %#v, want a roomExpiredMsg", msg)
+	}
+	next, _ = m.Update(msg)
+	if m = next.(model); !m.lobby.active || m.gameSession != nil || !strings.Contains(m.lobby.err, "Nobody joined") {
+		t.Fatalf("in the lobby %v, saying %q, want back in the lobby told why", m.lobby.active, m.lobby.err)
+	}
+	for start := time.Now(); ; time.Sleep(time.Millisecond) {
+		sessionManager.mutex.RLock()
+		_, ok := sessionManager.sessions[session.ID]
+		sessionManager.mutex.RUnlock()
+		if !ok {
+			break
+		}
+		if time.Since(start) > time.Second {
+			t.Fatal("the room's session is still there")
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// macroKeys are the keys a macro can be bound to, none of them do anything
+// in a game on their own
+const macroKeys = "123456789"
+
+// MaxMacroSteps keeps macros to a handful of moves, they're shortcuts not scripts
+const MaxMacroSteps = 8
+
+// macroKeySteps are the steps that press a key, a macro step can also be a
+// cell like "B2" that moves the cursor straight there
+var macroKeySteps = map[string]tea.KeyType{
+	"up":    tea.KeyUp,
+	"down":  tea.KeyDown,
+	"left":  tea.KeyLeft,
+	"right": tea.KeyRight,
+	"enter": tea.KeyEnter,
+}
+
+// macroEditor is where players bind number keys to a string of moves
+type macroEditor struct {
+	active  bool
+	choice  int    // highlighted key, index into macroKeys
+	editing bool   // typing the highlighted key's steps
+	text    string // the steps typed so far
+	err     string // why the last macro was rejected
+}
+
+// parseMacro checks a macro's steps, separated by spaces or commas, and
+// returns them tidied up: key names lowercase, cells uppercase
+func parseMacro(text string) ([]string, error) {
+	steps := strings.Fields(strings.ReplaceAll(text, ",", " "))
+	if len(steps) > MaxMacroSteps {
+		return nil, fmt.Errorf("a macro can have at most %d steps", MaxMacroSteps)
+	}
+	for i, step := range steps {
+		if _, ok := macroKeySteps[strings.ToLower(step)]; ok {
+			steps[i] = strings.ToLower(step)
+		} else if _, _, ok := parseCell(step); ok {
+			steps[i] = strings.ToUpper(step)
+		} else {
+			return nil, errors.New("unknown step " + step + ", use up, down, left, right, enter or a cell like B2")
+		}
+	}
+	return steps, nil
+}
+
+// parseCell reads a board coordinate like "B2", that's on the biggest board
+// of any game. Check it's on the board at hand too.
+func parseCell(s string) (row, col int, ok bool) {
+	if len(s) != 2 {
+		return 0, 0, false
+	}
+	col = int(unicode.ToUpper(rune(s[0])) - 'A')
+	row = int(s[1] - '1')
+	rows, cols := largestBoard()
+	if col < 0 || col >= cols || row < 0 || row >= rows {
+		return 0, 0, false
+	}
+	return row, col, true
+}
+
+// runMacro plays a macro's steps as if each had been typed on its own
+func (m model) runMacro(text string) (tea.Model, tea.Cmd) {
+	steps, _ := parseMacro(text) // checked when it was saved
+	var cmds []tea.Cmd
+	for _, step := range steps {
+		if row, col, ok := parseCell(step); ok {
+			// cells off this game's board are skipped
+			if row < len(m.board) && col < len(m.board[row]) {
+				m.cursorY, m.cursorX = row, col
+				m.shareCursor()
+			}
+			continue
+		}
+		// deliberate, so it's never mistaken for a burst of pasted keys
+		m.lastKey = time.Time{}
+		next, cmd := m.Update(tea.KeyMsg{Type: macroKeySteps[step]})
+		m = next.(model)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// openMacroEditor shows the macros, from the profile
+func (m model) openMacroEditor() (tea.Model, tea.Cmd) {
+	m.profile.active = false
+	m.macroEditor = macroEditor{active: true}
+	return m, m.clearScreen()
+}
+
+// updateMacroEditor handles keys while editing macros
+func (m model) updateMacroEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	key := string(macroKeys[m.macroEditor.choice])
+	if m.macroEditor.editing {
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		case tea.KeyEsc:
+			m.macroEditor.editing = false
+			m.macroEditor.err = ""
+		case tea.KeyEnter:
+			steps, err := parseMacro(m.macroEditor.text)
+			if err != nil {
+				m.macroEditor.err = err.Error()
+				break
+			}
+			m.setMacro(key, strings.Join(steps, " "))
+			m.macroEditor.editing = false
+			m.macroEditor.err = ""
+		case tea.KeyBackspace:
+			if m.macroEditor.text != "" {
+				m.macroEditor.text = m.macroEditor.text[:len(m.macroEditor.text)-1]
+			}
+		case tea.KeySpace:
+			m.macroEditor.text += " "
+		case tea.KeyRunes:
+			for _, r := range msg.Runes {
+				// room for MaxMacroSteps of the longest step, "right"
+				if len(m.macroEditor.text) < MaxMacroSteps*len("right ") && r < unicode.MaxASCII &&
+					(unicode.IsLetter(r) || unicode.IsDigit(r) || r == ',' || r == ' ') {
+					m.macroEditor.text += string(r)
+				}
+			}
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.macroEditor = macroEditor{}
+		m.profile.active = true
+		return m, m.clearScreen()
+	case "up", "k":
+		m.macroEditor.choice = (m.macroEditor.choice + len(macroKeys) - 1) % len(macroKeys)
+	case "down", "j":
+		m.macroEditor.choice = (m.macroEditor.choice + 1) % len(macroKeys)
+	case "enter", " ":
+		m.macroEditor.editing = true
+		m.macroEditor.text = m.macros[key]
+	case "backspace", "delete":
+		m.setMacro(key, "")
+	}
+	return m, nil
+}
+
+// setMacro binds key to the given steps, or unbinds it if there are none,
+// and saves the player's macros to their profile
+func (m *model) setMacro(key, steps string) {
+	m.macros = maps.Clone(m.macros)
+	if m.macros == nil {
+		m.macros = map[string]string{}
+	}
+	if steps == "" {
+		delete(m.macros, key)
+	} else {
+		m.macros[key] = steps
+	}
+	macros := m.macros
+	err := playerDB.change(m.playerID, func(rec *PlayerRecord) {
+		rec.Macros = macros
+	})
+	if err != nil {
+		log.Printf("players: %v", err)
+	}
+}
+
+// macroEditorView draws the macro list, or the prompt for one macro's steps
+func (m model) macroEditorView() string {
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+	s += headerStyle.Render("  Macros") + footerStyle.Render(", a number key plays its moves in one go") + "\n\n"
+
+	for i, key := range macroKeys {
+		steps := m.macros[string(key)]
+		if m.macroEditor.editing && i == m.macroEditor.choice {
+			steps = m.macroEditor.text + "_"
+		} else if steps == "" {
+			steps = footerStyle.Render("unbound")
+		}
+		if i == m.macroEditor.choice {
+			s += headerStyle.Render(fmt.Sprintf("> %c  ", key)) + steps + "\n"
+		} else {
+			s += fmt.Sprintf("  %c  ", key) + steps + "\n"
+		}
+	}
+
+	// the error takes the hint's place, it says what's allowed anyway
+	if m.macroEditor.err != "" {
+		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render("  "+m.macroEditor.err) + "\n"
+	} else {
+		s += footerStyle.Render("\n  Steps: up, down, left, right, enter, or a cell like B2 to jump there") + "\n"
+	}
+	if m.macroEditor.editing {
+		s += footerStyle.Render("  Press enter to save, esc to cancel") + "\n"
+	} else {
+		s += footerStyle.Render("  Press enter to edit, backspace to clear, esc for your profile") + "\n"
+	}
+	return s
+}
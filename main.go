@@ -1,9 +1,16 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math/rand/v2"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,19 +19,30 @@ import (
 	"github.com/charmbracelet/ssh"
 	"github.com/charmbracelet/wish"
 	"github.com/charmbracelet/wish/bubbletea"
+	"github.com/muesli/termenv"
+
+	"tictactui/animation"
+	"tictactui/figlet"
+	"tictactui/matchmaking"
 )
 
 /*
    TODO:
-       1: Create a matchmaking service that keeps track of:
-           a: Who is looking for a match
-           b: Assigns players to each other
-           c: Records results (wins/losses)
-       2: Extract that matchmaking service into its own package so it can be used for creating/hosting other multiplayer TUI apps
-       3: Future Ideas:
-           a: Tournament Mode: An even number of players >=4 join a Tournament Mode Server and are randomly paired up then compete tournament style
-           b: More board games! (checkers, chess, solitaire, connect-4 (this one would be VERY cool if we could figure out the dropping mechanic))
-           c: Add a web leaderboard so people can show off
+       1: Future Ideas:
+           a: More board games! (chess, solitaire)
+       2: Requested, but blocked until the pieces they build on exist:
+           - Daily challenge puzzle with solve tracking and a daily solver leaderboard (needs the puzzle mode, persistent players and a leaderboard)
+           - Append-only, tamper-evident audit log of admin actions, reviewable from an admin TUI (needs admin tooling)
+           - Simultaneous exhibition: one player on many boards with a board switcher and per-board clocks (needs multiple sessions per player and clocks)
+           - 2v2 consultation: two players share a side and alternate moves with a private suggest/confirm step (needs a queue that can pair teams)
+           - Mutual pause and adjournment with resume from the lobby (needs clocks, a lobby and persisted games)
+           - Authenticated admin web dashboard (sessions, games, queue, bans, broadcasts, tournaments) (needs admin tooling and an HTTP listener)
+           - Embedded schema migrations with `tictactui migrate` (needs a SQL-backed store)
+           - Smurf detection: flag profiles sharing an IP with suspicious rating patterns, exclude flagged games, admin review (needs profiles, ratings and admin tooling)
+           - Store timestamps in UTC and render history, tournaments and dailies in the player's profile time zone (needs stored history and profiles)
+           - Analytics page in an admin TUI, next to `tictactui analytics` (needs admin tooling)
+           - Scoped API tokens for non-SSH APIs (leaderboard writes, admin API, bots), created and revoked from an admin interface (needs an HTTP/gRPC API and admin tooling)
+           - Admin review of dodge/abandon penalties with pardons and manual cooldowns (penalties are on /api/player and in -db, the review screen needs admin tooling)
 */
 
 // Game constants
@@ -34,16 +52,65 @@ const (
 	Draw    = "draw"
 	Empty   = ""
 
+	// Game type, as recorded in journals
+	GameTicTacToe = "tic-tac-toe"
+
 	// Board dimensions
 	BoardSize = 3
 
-	// Ticker frequency for real-time updates (100ms)
-	TickerInterval = time.Millisecond * 100
+	// How often live counters (thinking timer, countdowns) refresh. Everything
+	// else is redrawn when the session changes, not on a timer.
+	TickerInterval = time.Second
+
+	// The slowest we'll refresh live counters when adapting to a laggy connection
+	SlowestTickerInterval = 2 * time.Second
+
+	// How long the versus screen shows before the board appears
+	VersusDuration = 3 * time.Second
 
-	// Disconnect timeout
+	// Confetti animation played behind the win banner
+	ConfettiFrames        = 30
+	ConfettiFrameInterval = 80 * time.Millisecond
+
+	// Keys arriving closer together than this came from a paste or a stuck
+	// key rather than a person, so they can't place marks
+	KeyBurstWindow = 20 * time.Millisecond
+
+	// How long to wait for a latency probe reply before giving up
+	LatencyProbeTimeout = 2 * time.Second
+
+	// How long the opponent waits when a player who can't come back (a
+	// guest, or in a tournament) disconnects
 	DisconnectTimeout = 5 * time.Second
+
+	// How long a key user who disconnects has to come back, by default
+	ReconnectGrace = 60 * time.Second
+
+	// How long a room waits for the friend it was created for
+	RoomTTL = 10 * time.Minute
+
+	// How long the computer takes over its move in a local game
+	AIMoveDelay = 600 * time.Millisecond
+
+	// The smallest terminal the game fits in: the title and lobby menu set
+	// the width, the board with its footer and chat sets the height
+	MinTerminalWidth  = 50
+	MinTerminalHeight = 24
 )
 
+// boardIndent lines the board up under the title. It's spaces rather than
+// tabs because a tab skips over whatever the previous screen left behind.
+const boardIndent = "                "
+
+// titleArt heads the board and the lobby, unless the operator has their own
+// (see loadArt)
+const titleArt = `
+  _____ _       _____           _____         
+ |_   _(_)__ __|_   _|_ _ __ __|_   _|___  ___ 
+   | | | / _'___|| |/ _' / _'___|| | / _ \/ -_)
+   |_| |_\__|    |_|\__,_\__|    |_| \___/\___|
+`
+
 // style colors
 var (
 	winStyle    = lip.NewStyle().Foreground(lip.Color("#50FA7B")).Bold(true) // dracula green green & bold
@@ -52,6 +119,17 @@ var (
 	headerStyle = lip.NewStyle().Foreground(lip.Color("#F1FA8C")).Bold(true) // dracula yellow
 	footerStyle = lip.NewStyle().Foreground(lip.Color("#6272A4")).Bold(true) // dracula comment blue
 	cellStyle   = lip.NewStyle().Foreground(lip.Color("#BD93F9"))            // dracula purple
+
+	// confetti pieces cycle through the rest of the dracula palette
+	confettiStyles = []lip.Style{
+		lip.NewStyle().Foreground(lip.Color("#FF5555")), // red
+		lip.NewStyle().Foreground(lip.Color("#FFB86C")), // orange
+		lip.NewStyle().Foreground(lip.Color("#F1FA8C")), // yellow
+		lip.NewStyle().Foreground(lip.Color("#50FA7B")), // green
+		lip.NewStyle().Foreground(lip.Color("#8BE9FD")), // cyan
+		lip.NewStyle().Foreground(lip.Color("#BD93F9")), // purple
+		lip.NewStyle().Foreground(lip.Color("#FF79C6")), // pink
+	}
 )
 
 type coord struct {
@@ -59,55 +137,554 @@ type coord struct {
 	col int
 }
 
+// tickInterval is the minimum live counter refresh interval, set with -tick
+var tickInterval = TickerInterval
+
+// reconnectGrace is how long a key user who disconnects mid-game has to
+// reconnect and carry on, set with -reconnect
+var reconnectGrace = ReconnectGrace
+
+// bannerFont is the starting font for win/draw banners, set with -font
+var bannerFont = figlet.Default
+
 // Global session manager
 var (
 	sessionManager = &SessionManager{
-		waitingSession: nil,
-		mutex:          sync.RWMutex{},
+		queue:    matchmaking.NewQueue(),
+		rooms:    matchmaking.NewRooms(RoomTTL),
+		sessions: map[string]*GameSession{},
+		changed:  make(chan struct{}),
+		hill:     matchmaking.NewHill(),
 	}
 )
 
+// SessionManager pairs players through the matchmaking queue or a room and
+// keeps the GameSession each match is played in
 type SessionManager struct {
-	waitingSession *GameSession
+	queue    *matchmaking.Queue
+	rooms    *matchmaking.Rooms
+	sessions map[string]*GameSession // by match ID
+	changed  chan struct{}           // closed and replaced whenever a game starts or ends, or a player comes or goes
+
+	// tournamentSize is the field for tournament mode, 0 when the server
+	// isn't running tournaments
+	tournamentSize int
+	tournament     *matchmaking.Tournament // the one taking registrations or being played
+	call           *tournamentCall         // an impromptu tournament taking sign ups in the lobby, see -auto-tournament
+	hill           *matchmaking.Hill       // king of the hill, from the lobby
+	online         int                     // SSH sessions connected, for the lobby's counts
 	mutex          sync.RWMutex
 }
 
 type GameSession struct {
+	ID                 string    // match ID, short enough to quote in bug reports
+	Private            bool      // opened as a room, the ID is its join code
+	Rules              gameRules // the game being played, the waiting player's pick
 	Board              [][]string
 	CurrentPlayer      int
 	Winner             string
 	WinningCells       []coord
+	Chain              *coord  // the piece that has to keep jumping, in the middle of a multi-jump
+	Moves              int     // moves made in the current game, a multi-jump's jumps count one each
+	RestartOffered     [2]bool // which players want to call the game under way off and start over, see offerRestart
+	Casual             bool    // unrated, without clocks, moves can be taken back, see casual.go
+	TakebackOffered    [2]bool // which players want the last move of a casual game taken back, see offerTakeback
+	PreferSame         [2]bool // which players would like to play each other again once the game's over, see rematch.go
+	history            []sessionPosition
+	Swap               bool            // O can take X's first move as their own, see swap.go
+	Crowd              bool            // O is played by the spectators' votes, see crowd.go
+	Votes              map[string]move // the spectators' votes for the crowd's next move, by player ID
+	VoteEnds           time.Time       // when the crowd's votes are counted
+	vote               int             // counts votes, so a count can tell if its vote is still open
 	PlayerCount        int
+	PlayerNames        [2]string // display names, indexed like CurrentPlayer (0 = X, 1 = O)
+	Avatars            [2]string // the avatars from the players' profiles, indexed like PlayerNames
+	Anonymous          [2]bool   // which players spectators don't see the names of, see shownNames
 	PlayerDisconnected bool
-	RestartRequested   bool
+	leftAt             [2]time.Time     // when each seat's player disconnected, zero while they're here; the first to go can resume from another device
+	ReconnectBy        time.Time        // the game ends unless the player who disconnected is back by then
+	Spectators         int              // connections watching the game
+	Chat               []chatMessage    // what the players said, oldest first, see say
+	SpectatorChat      []chatMessage    // what the spectators said, hidden from the players, see spectate
+	frames             []frame          // the game as spectators will see it, with -spectator-delay
+	TurnStarted        time.Time        // when the current player's turn began
+	TimeControl        timeControl      // how long the players have to move, see timecontrol.go
+	Handicap           handicap         // what the weaker player's given, see handicap.go
+	Clocks             [2]time.Duration // time left on each player's game clock at the start of the turn, see clock.go
+	TimedOut           bool             // the game was won on time
+	Forfeited          bool             // the game was won by the opponent leaving, see abandon
+	Aborted            bool             // called off because nobody moved, see abort.go
+	turn               int              // counts turns, so a clock can tell if its turn is over
+	MatchStarted       time.Time        // when the second player joined, the versus screen counts down from here
+	GameStarted        time.Time        // when the current game's first turn began
+	GameEnded          time.Time        // when the current game was won or drawn
+	Cursors            [2]coord         // where each player is hovering, indexed like CurrentPlayer
+	ShareCursors       [2]bool          // which players let their opponent see their cursor, indexed like Cursors
+	RatingChanges      [2]int           // how much the last game moved each player's rating, X first
+	Unlocked           [2][]string      // the achievements each player unlocked with the last game, X first
+	match              *matchmaking.Match
+	tournament         *matchmaking.Tournament // the tournament the match is a bout in, if any
+	hill               *matchmaking.Hill       // the hill the match is a bout for, if it is one
+	journal            *Journal
+	rng                *rand.Rand      // the game's random choices, from the seed journaled as it starts, see seedGame
+	subscribers        []chan struct{} // signalled whenever the session changes
 	mutex              sync.RWMutex
 }
 
+// subscribe returns a channel that's signalled whenever the session changes.
+// It starts out signalled so a new subscriber syncs straight away.
+func (gs *GameSession) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	ch <- struct{}{}
+	gs.mutex.Lock()
+	gs.subscribers = append(gs.subscribers, ch)
+	gs.mutex.Unlock()
+	return ch
+}
+
+// unsubscribe stops updates to ch and closes it
+func (gs *GameSession) unsubscribe(ch chan struct{}) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.subscribers = slices.DeleteFunc(gs.subscribers, func(c chan struct{}) bool { return c == ch })
+	close(ch)
+}
+
+// notify tells every subscriber the session changed. Call with the session
+// locked. Signals coalesce: a subscriber that hasn't caught up yet just
+// syncs once.
+func (gs *GameSession) notify() {
+	gs.recordFrame()
+	for _, ch := range gs.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// join queues a player and returns the session for their match along with
+// the seat they play in (0 = X, 1 = O)
+func (sm *SessionManager) join(p matchmaking.Player) (*GameSession, int, error) {
+	match, err := sm.queue.Join(p)
+	if err != nil {
+		return nil, 0, err
+	}
+	session, seat := sm.sit(match, p, false)
+	sm.maybeCallTournament()
+	return session, seat, nil
+}
+
+// createRoom opens a private room for a player to wait in, played with the
+// time control and handicap they picked. The session's ID is the join code their friend
+// needs.
+func (sm *SessionManager) createRoom(p matchmaking.Player, swap bool, tc timeControl, h handicap) (*GameSession, int, error) {
+	session, seat := sm.sit(sm.rooms.Create(p), p, true)
+	session.mutex.Lock()
+	session.TimeControl = tc
+	if handicapFits(h, session.Rules, tc) {
+		session.Handicap = h
+	}
+	// a handicap mark isn't a first move to take
+	session.Swap = swap && swappable(session.Rules) && session.Handicap != handicapMark
+	session.mutex.Unlock()
+	return session, seat, nil
+}
+
+// joinRoom seats a player in the room with the given join code
+func (sm *SessionManager) joinRoom(code string, p matchmaking.Player) (*GameSession, int, error) {
+	match, err := sm.rooms.Join(code, p)
+	if err != nil {
+		return nil, 0, err
+	}
+	session, seat := sm.sit(match, p, true)
+	return session, seat, nil
+}
+
+// enterTournament registers a player for the current tournament, opening a
+// new one once the last has crowned its champion
+func (sm *SessionManager) enterTournament(p matchmaking.Player) (*matchmaking.Tournament, error) {
+	sm.mutex.Lock()
+	if sm.tournament == nil {
+		sm.tournament = newTournament(sm.tournamentSize, time.Time{})
+	} else if _, over := sm.tournament.Champion(); over {
+		sm.tournament = newTournament(sm.tournamentSize, time.Time{})
+	}
+	t := sm.tournament
+	sm.mutex.Unlock()
+	return t, t.Register(p)
+}
+
+// playTournamentMatch seats a player in the match for their current bout
+func (sm *SessionManager) playTournamentMatch(t *matchmaking.Tournament, match *matchmaking.Match, p matchmaking.Player) (*GameSession, int, error) {
+	session, seat := sm.sit(match, p, false)
+	session.mutex.Lock()
+	if session.tournament == nil {
+		session.journal.Record(Event{Type: EventSeed, Seed: t.Seed(), Tournament: true})
+	}
+	session.tournament = t
+	session.mutex.Unlock()
+	return session, seat, nil
+}
+
+// sit puts a player in the session for their match and returns the seat they
+// play in. The first player in a match creates the session, the second one
+// starts the game.
+func (sm *SessionManager) sit(match *matchmaking.Match, p matchmaking.Player, private bool) (*GameSession, int) {
+	seat := match.Seat(p.ID)
+	// from the store, before the sessions are locked
+	avatar, anonymous := avatarOf(p.ID), privacyOf(p.ID).Anonymous
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	session, ok := sm.sessions[match.ID]
+	if !ok || session.match != match {
+		// or the session left under an ID that was released early, see leave
+		rules := rulesFor(match.Players[0].Game)
+		session = &GameSession{
+			ID:          match.ID,
+			Private:     private,
+			Casual:      match.Players[0].Casual,
+			Rules:       rules,
+			Board:       rules.newBoard(),
+			TimeControl: serverTimeControl(),
+			match:       match,
+			journal:     openJournal(match.ID),
+		}
+		sm.sessions[match.ID] = session
+	}
+
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.PlayerNames[seat] = p.Name
+	session.Avatars[seat] = avatar
+	session.Anonymous[seat] = anonymous
+	session.PlayerCount++
+	if session.PlayerCount == 2 && match.IsConfirmed() {
+		session.start()
+	}
+	session.notify()
+	sm.sessionsChanged()
+	return session, seat
+}
+
+// start begins a match both players are in, with the versus screen. Call
+// with the session locked.
+func (gs *GameSession) start() {
+	gs.MatchStarted = time.Now()
+	// X's thinking timer and clock start once the versus screen is gone
+	gs.TurnStarted = time.Now().Add(VersusDuration)
+	gs.GameStarted = gs.TurnStarted
+	gs.seedGame()
+	gs.placeHandicap()
+	gs.startClocks()
+	gs.scheduleAbort()
+}
+
+// seedGame picks the seed a new game's random choices are made from, and
+// journals it. Call with the session locked, as the game starts.
+func (gs *GameSession) seedGame() {
+	seed := rand.Uint64()
+	gs.rng = rand.New(rand.NewPCG(seed, 0))
+	gs.journal.Record(Event{Type: EventSeed, Seed: seed})
+}
+
+// resume puts a player who dropped out of a game back in their seat, so they
+// can carry on after a dropped connection, or from another device, with the
+// same key before their opponent gives up on them. Tournament bouts are
+// forfeited on disconnect instead.
+func (sm *SessionManager) resume(p matchmaking.Player) (*GameSession, int, bool) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	for _, session := range sm.sessions {
+		session.mutex.Lock()
+		seat := session.match.Seat(p.ID)
+		if seat >= 0 && session.PlayerDisconnected && !session.leftAt[seat].IsZero() && session.PlayerCount == 1 &&
+			session.tournament == nil && session.hill == nil && time.Now().Before(session.ReconnectBy) {
+			session.PlayerDisconnected = false
+			session.leftAt[seat] = time.Time{}
+			session.PlayerCount++
+			session.notify()
+			session.mutex.Unlock()
+			sm.sessionsChanged()
+			return session, seat, true
+		}
+		session.mutex.Unlock()
+	}
+	return nil, 0, false
+}
+
+// games lists the games under way, rooms too
+func (sm *SessionManager) games() []*GameSession {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	var games []*GameSession
+	for _, session := range sm.sessions {
+		session.mutex.RLock()
+		if session.PlayerCount == 2 && !session.MatchStarted.IsZero() {
+			games = append(games, session)
+		}
+		session.mutex.RUnlock()
+	}
+	slices.SortFunc(games, func(a, b *GameSession) int { return strings.Compare(a.ID, b.ID) })
+	return games
+}
+
+// watchable lists the games under way that spectators can pick from, on the
+// server and on the web. Games in rooms aren't among them, they're private
+// and their IDs are the join codes.
+func (sm *SessionManager) watchable() []*GameSession {
+	return slices.DeleteFunc(sm.games(), func(gs *GameSession) bool { return gs.Private })
+}
+
+// watchSessions is closed the next time a game starts or ends, or a
+// player connects, disconnects or queues
+func (sm *SessionManager) watchSessions() <-chan struct{} {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.changed
+}
+
+// sessionsChanged lets everyone watching the list of games know it changed.
+// Call with sm locked.
+func (sm *SessionManager) sessionsChanged() {
+	close(sm.changed)
+	sm.changed = make(chan struct{})
+}
+
+// leave takes a player who disconnected or left the session out of the
+// queue if they were still waiting, or out of the match they hadn't
+// accepted yet, and forgets their session once nobody is left in it
+func (sm *SessionManager) leave(p matchmaking.Player, session *GameSession) {
+	if session.match.IsReady() {
+		// they may be back in the queue for another match by now
+		if err := sm.queue.Decline(session.match, p.ID); err != nil {
+			log.Printf("declining match %s: %v", session.ID, err)
+		}
+	} else {
+		sm.queue.Leave(p)
+	}
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	session.PlayerCount--
+	if session.PlayerCount <= 0 || !session.match.IsReady() || session.Crowd {
+		if sm.sessions[session.ID] == session {
+			delete(sm.sessions, session.ID)
+		}
+		// the queue lets go of a match's ID when it moves its player, and
+		// the rooms of a room's code when it expires, a new match may have
+		// it by now
+		if !session.match.Released() {
+			sm.rooms.Close(session.ID)
+			matchmaking.Release(session.ID)
+		}
+	} else if !session.MatchStarted.IsZero() && !session.PlayerDisconnected {
+		// went back to the lobby or on to another game, the opponent
+		// follows once they've had a moment to see it
+		session.PlayerDisconnected = true
+		session.ReconnectBy = time.Now().Add(DisconnectTimeout)
+	}
+	session.notify()
+	sm.sessionsChanged()
+}
+
+// addSpectator counts a spectator arriving (1) or leaving (-1) so the
+// players can see they're being watched
+func (gs *GameSession) addSpectator(delta int) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	gs.Spectators += delta
+	gs.notify()
+}
+
+// finish ends the current game with the given winner ("X", "O" or Draw),
+// journals it and reports it to the matchmaker. Call with the session locked.
+func (gs *GameSession) finish(winner string) {
+	gs.Winner = winner
+	gs.GameEnded = time.Now()
+	event := Event{Type: EventResult, Result: winner}
+	if gs.TimedOut {
+		event.Reason = ReasonTime
+	}
+	if gs.Forfeited {
+		event.Reason = ReasonAbandoned
+	}
+	gs.journal.Record(event)
+	recentResults.add(result{names: gs.shownNames(), winner: winner, at: gs.GameEnded})
+	gs.saveReplay()
+
+	winnerID := ""
+	if winner != Draw {
+		winnerID = gs.match.Players[playerIndex(winner)].ID
+	}
+	var before [2]matchmaking.Results
+	for i, p := range gs.match.Players {
+		before[i] = totalResults(p.ID)
+	}
+	changes, err := sessionManager.queue.Report(gs.match, winnerID)
+	if err != nil {
+		log.Printf("reporting result of game %s: %v", gs.ID, err)
+	}
+	gs.RatingChanges = changes
+	gs.pushResult()
+	if !gs.Crowd {
+		ids, winnerIndex := [2]string{gs.match.Players[0].ID, gs.match.Players[1].ID}, -1
+		if winner != Draw {
+			winnerIndex = playerIndex(winner)
+		}
+		if err := playerDB.recordMeeting(ids, winnerIndex); err != nil {
+			log.Printf("recording head-to-head of game %s: %v", gs.ID, err)
+		}
+	}
+	for i, p := range gs.match.Players {
+		gs.Unlocked[i] = unlocked(before[i], totalResults(p.ID))
+	}
+	if gs.tournament != nil {
+		if err := gs.tournament.Report(gs.match, winnerID); err != nil {
+			log.Printf("reporting result of tournament game %s: %v", gs.ID, err)
+		}
+	}
+	if gs.hill != nil {
+		if err := gs.hill.Report(gs.match, winnerID); err != nil {
+			log.Printf("reporting result of hill game %s: %v", gs.ID, err)
+		}
+	}
+}
+
 type tickMsg time.Time
 
-type model struct {
-	board            [][]string   // game board
-	cursorX, cursorY int          // which cell our cursor is currently on
-	currentPlayer    string       //"X" or "O"
-	winner           string       // "", "X", or "O"
-	winningCells     []coord      // allows us to highlight winning cells at win
-	playerSymbol     string       // "X" or "O" - which player this is
-	isMyTurn         bool         // whether it's this player's turn
-	waitingForPlayer bool         // whether waiting for another player
-	gameSession      *GameSession // shared game session
-	disconnectTimer  time.Time    // when disconnect was detected
+// sessionUpdateMsg says the shared game session changed
+type sessionUpdateMsg struct {
+	updates <-chan struct{} // the subscription it came from
 }
 
-// createEmptyBoard creates a new empty 3x3 board
-func createEmptyBoard() [][]string {
-	board := make([][]string, BoardSize)
-	for i := range board {
-		board[i] = make([]string, BoardSize)
-		for j := range board[i] {
-			board[i][j] = Empty
+// waitForUpdate waits for the next change to the session
+func waitForUpdate(updates <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-updates; !ok {
+			return nil
 		}
+		return sessionUpdateMsg{updates: updates}
 	}
-	return board
+}
+
+type model struct {
+	rules            gameRules               // the game being played, or picked in the lobby
+	board            [][]string              // game board
+	cursorX, cursorY int                     // which cell our cursor is currently on
+	currentPlayer    string                  //"X" or "O"
+	winner           string                  // "", "X", or "O"
+	winningCells     []coord                 // allows us to highlight winning cells at win
+	held             *coord                  // the piece picked up to move, in games with pieces
+	chain            *coord                  // the piece that has to keep jumping, see GameSession.Chain
+	moves            int                     // moves made this game, for the post-game summary
+	restartOffered   [2]bool                 // which players want to start the game over, X first
+	casual           bool                    // playing, or queueing for, a casual game, see casual.go
+	takebackOffered  [2]bool                 // which players want the last move of a casual game taken back, X first
+	preferSame       [2]bool                 // which players would like to play each other again, X first
+	swapRule         bool                    // rooms the player opens use the swap rule, picked in the lobby
+	roomClock        int                     // the time control rooms the player opens are played with, see roomTimeControl
+	roomHandicap     handicap                // the handicap rooms the player opens are played with
+	swap             bool                    // the game's played with the swap rule
+	canSwap          bool                    // O can swap now, see GameSession.canSwap
+	crowd            bool                    // O is played by the spectators' votes, see crowd.go
+	tallies          map[coord]int           // the crowd's votes so far, by the cell each move ends in
+	voteEnds         time.Time               // when the crowd's votes are counted
+	myVote           string                  // the move this spectator voted for, empty if they haven't
+	aborted          bool                    // the game was called off because nobody moved, see abort.go
+	ratingChange     int                     // how much the last game moved our rating, for the post-game summary
+	unlocked         []string                // the achievements we unlocked with the last game
+	notation         string                  // the last game's moves, for the post-game summary, read from the journal once it's over
+	playerSymbol     string                  // "X" or "O" - which player this is
+	playerID         string                  // matchmaking identity, what results are recorded against
+	playerName       string                  // display name shown to the opponent
+	returning        bool                    // the player has been here before, per the player database
+	visitor          bool                    // a peer sent the player here, quitting takes them back, see federation.go
+	lobby            lobby                   // picking how to find an opponent, before joining a session
+	tournament       *matchmaking.Tournament // the tournament being played, nil outside tournament mode
+	hill             *matchmaking.Hill       // the hill the player's on or was knocked off, nil elsewhere
+	gameList         gameList                // picking a game to watch
+	macroEditor      macroEditor             // binding number keys to moves, from the profile
+	replays          replayViewer            // rewatching past games, from the lobby or the replay command
+	leaderboard      leaderboardScreen       // the top players, from the lobby
+	export           exportScreen            // how to download your games and stats, from the lobby
+	deleting         deleteScreen            // deleting your own data, from the lobby
+	practice         practice                // a sandbox game against the computer, from the lobby or the opponent menu
+	puzzle           puzzleMode              // positions to win against the computer, from the lobby or the opponent menu
+	tutorial         tutorial                // the keys and the ideas of the game, for players new here
+	profile          profile                 // the player's name, avatar, theme and macros, from the lobby
+	macros           map[string]string       // key to the steps it plays, from the player's profile
+	avatar           string                  // shown next to their name, from the player's profile
+	theme            boardTheme              // the board's colours, from the player's profile
+	notifications    notifications           // which bells ring, from the player's profile
+	privacy          privacy                 // what the player keeps to themselves, from their profile
+	alerts           io.Writer               // where bells and desktop notifications go, the SSH session
+	chat             []chatMessage           // the session's chat, oldest first
+	spectatorChat    []chatMessage           // the spectators' chat, for spectators and, with -merge-spectator-chat, players whose game is over
+	behind           bool                    // a spectator's delayed feed hasn't reached the game's result yet, see -spectator-delay
+	chatting         bool                    // typing a chat message, keys go to it rather than the board
+	chatInput        string                  // the chat message typed so far
+	replayRoom       *replayRoom             // the replay room the player's watching together in, if they are
+	roomUpdates      chan struct{}           // signalled when the replay room changes
+	driving          bool                    // stepping through the replay room's game for everyone
+	roomMembers      []string                // who's in the replay room, in the order they came in
+	annotations      []annotation            // the notes on the game being watched back
+	annotating       bool                    // typing a note on the move on the board
+	annotationInput  string                  // the note typed so far
+	annotationErr    string                  // why a note couldn't be read or saved
+	mutedUntil       time.Time               // when the player can chat again after spamming, see chatLimits
+	spectating       bool                    // watching gameSession rather than playing in it
+	names            [2]string               // both players' names, X first, for spectators
+	avatars          [2]string               // both players' avatars, X first
+	anonymous        [2]bool                 // which players spectators see as AnonymousName, X first
+	spectators       int                     // how many are watching the game
+	left             chan struct{}           // closed when leaving the session for the tournament bracket or the hill
+	done             <-chan struct{}         // closed when the SSH connection ends
+	opponentName     string                  // display name of the other player
+	isMyTurn         bool                    // whether it's this player's turn
+	waitingForPlayer bool                    // whether waiting for another player
+	gameSession      *GameSession            // shared game session
+	updates          chan struct{}           // signalled when the shared session changes
+	opponentLeft     bool                    // whether the opponent disconnected
+	reconnectBy      time.Time               // when the game ends unless the opponent who disconnected is back
+	turnStarted      time.Time               // when the current turn began, for the thinking timer
+	invite           string                  // the command that brings a friend to this player's room, see invite.go
+	inviteExpires    time.Time               // when the invitation stops working
+	timeControl      timeControl             // how long the players have to move
+	handicap         handicap                // what the weaker player's given, in a room
+	clocks           [2]time.Duration        // each player's game clock at the start of the turn, X first
+	timedOut         bool                    // the game was won on time
+	forfeited        bool                    // the game was won by the opponent leaving
+	matchStarted     time.Time               // when both players were paired, for the versus screen
+	acceptBy         time.Time               // when the match has to be accepted by, zero if it needn't be
+	accepted         bool                    // this player accepted the match
+	opponentAccepted bool                    // the opponent accepted the match
+	requeued         bool                    // back in the queue because the last match wasn't accepted
+	flash            animation.Sequence      // the accept screen flashing when a match is found
+	drop             animation.Sequence      // a mark falling down its column, with gravity
+	dropCell         coord                   // where the falling mark lands
+	gameStarted      time.Time               // when this game began, for the post-game summary
+	gameEnded        time.Time               // when this game was won or drawn
+	lastKey          time.Time               // when the previous key arrived, to spot bursts
+	width, height    int                     // terminal size, 0 until the terminal reports it
+	opponentMenu     opponentMenu            // picking an opponent for a local game
+	computer         string                  // the symbol the computer plays in a local game, "" for two players
+	difficulty       difficulty              // how hard the computer tries
+	aiSeed           uint64                  // what the computer's random choices in this game are made from, shown once it's over
+	game             int                     // counts restarts, so a late computer move can't land in a new game
+	opponentCursor   coord                   // where the opponent is hovering
+	sharingCursor    bool                    // whether we let the opponent see our cursor
+	opponentSharing  bool                    // whether the opponent lets us see theirs
+	reducedMotion    bool                    // accessibility: no live counters or animations
+	lowBandwidth     bool                    // slow link: plain text, small art and no full redraws, see bandwidth.go
+	tickInterval     time.Duration           // how often live counters refresh, adapted to the connection
+	ticking          bool                    // whether a tick is already on its way
+	font             figlet.Font             // font for the win/draw banners
+	confetti         animation.Sequence      // confetti falling behind the win banner
+	confettiSeed     uint64                  // so every frame of one celebration falls the same way
 }
 
 // copyBoard creates a deep copy of the board
@@ -123,33 +700,131 @@ func copyBoard(board [][]string) [][]string {
 func initialModel() model {
 	return model{
 		currentPlayer: PlayerX,
-		board:         createEmptyBoard(),
+		rules:         ticTacToe,
+		board:         ticTacToe.newBoard(),
+		tickInterval:  tickInterval,
+		theme:         themes[0],
+		notifications: defaultNotifications,
+		font:          bannerFont,
+		confetti:      animation.New(ConfettiFrames, ConfettiFrameInterval),
+		flash:         animation.New(FlashFrames, FlashFrameInterval),
+		gameStarted:   time.Now(),
+		aiSeed:        rand.Uint64(),
 	}
 }
 
 // resetGame resets the game to initial state
 func (m *model) resetGame() {
-	m.board = createEmptyBoard()
+	m.board = m.rules.newBoard()
 	m.currentPlayer = PlayerX
 	m.winner = Empty
 	m.winningCells = nil
+	m.held, m.chain = nil, nil
+	m.moves = 0
 	m.cursorX, m.cursorY = 0, 0
 	m.isMyTurn = m.playerSymbol == m.currentPlayer
-	m.disconnectTimer = time.Time{} // Reset disconnect timer
+	m.confetti = m.confetti.Stop()
+	m.gameStarted = time.Now()
+	m.gameEnded = time.Time{}
+	m.game++
+	m.aiSeed = rand.Uint64()
+	m.practice.history = nil
 
 	// Reset shared session if in multiplayer mode
 	if m.gameSession != nil {
 		m.gameSession.mutex.Lock()
-		m.gameSession.Board = createEmptyBoard()
+		// ahead of anything the new game starts with, like a handicap mark
+		m.gameSession.journal.Record(Event{Type: EventRestart, Player: m.playerSymbol})
+		m.gameSession.seedGame()
+		m.gameSession.Board = m.gameSession.Rules.newBoard()
 		m.gameSession.CurrentPlayer = 0
+		m.gameSession.TurnStarted = time.Now()
+		m.gameSession.GameStarted = time.Now()
+		m.gameSession.GameEnded = time.Time{}
 		m.gameSession.Winner = Empty
 		m.gameSession.WinningCells = nil
+		m.gameSession.RestartOffered = [2]bool{}
+		m.gameSession.TakebackOffered = [2]bool{}
+		m.gameSession.PreferSame = [2]bool{}
+		m.gameSession.Forfeited = false
+		m.gameSession.history = nil
+		m.gameSession.Votes = nil
+		m.gameSession.Chain = nil
+		m.gameSession.Moves = 0
+		m.gameSession.RatingChanges = [2]int{}
+		m.gameSession.Unlocked = [2][]string{}
+		m.gameSession.placeHandicap()
+		m.gameSession.startClocks()
 		m.gameSession.PlayerDisconnected = false // Reset disconnect status
-		m.gameSession.RestartRequested = false   // Reset restart status
+		m.gameSession.notify()
 		m.gameSession.mutex.Unlock()
 	}
 }
 
+// canMove reports whether a move is the player's to make: it's their turn
+// in a game under way, and the move is legal on the session's board. Call
+// with the session locked.
+func (gs *GameSession) canMove(player string, mv move) bool {
+	if gs.Winner != Empty || gs.CurrentPlayer != playerIndex(player) || time.Now().Before(gs.TurnStarted) {
+		return false
+	}
+	return slices.Contains(gs.Rules.moves(gs.Board, player, gs.Chain), mv)
+}
+
+// makeMove makes a player's move on the session's board if it's still
+// theirs to make, and moves the game on: a win, a draw, the same piece
+// jumping again, or the next turn. It reports whether the move was made and
+// whether the piece has to move again. Call with the session locked.
+func (gs *GameSession) makeMove(player string, mv move) (made, again bool) {
+	if !gs.canMove(player, mv) {
+		return false, false
+	}
+	gs.remember()
+	again = gs.Rules.play(gs.Board, player, mv)
+	gs.Moves++
+	gs.RestartOffered = [2]bool{}
+	gs.TakebackOffered = [2]bool{}
+	gs.journal.Record(Event{Type: EventMove, Player: player, Cell: mv.String()})
+
+	if again {
+		// the same piece jumps again, on the same turn and clock
+		gs.Chain = &mv.to
+	} else {
+		gs.Chain = nil
+		gs.chargeClock()
+		winner, cells := gs.Rules.result(gs.Board, player)
+		if winner != Empty {
+			gs.WinningCells = cells
+			gs.finish(winner)
+		} else {
+			// Switch to next player
+			gs.passTurn()
+		}
+	}
+	gs.callVote()
+	gs.notify()
+	return true, again
+}
+
+// offerRestart records that a player wants to start a game under way over.
+// It reports whether their opponent wants to too, the game can be restarted
+// then. Offers lapse with the next move. Rated games can't be started over,
+// the result has to stand for the ratings to mean anything.
+func (gs *GameSession) offerRestart(player string) bool {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if !gs.Casual {
+		return false
+	}
+	gs.RestartOffered[playerIndex(player)] = true
+	if gs.RestartOffered[0] && gs.RestartOffered[1] {
+		gs.RestartOffered = [2]bool{}
+		return true
+	}
+	gs.notify()
+	return false
+}
+
 // switchPlayer toggles between X and O
 func (m *model) switchPlayer() {
 	if m.currentPlayer == PlayerX {
@@ -159,41 +834,195 @@ func (m *model) switchPlayer() {
 	}
 }
 
+// playLocal makes the current player's move in a local game and moves the
+// game on: a win, a draw, the same piece jumping again, or the next turn
+// (maybe the computer's)
+func (m *model) playLocal(mv move) tea.Cmd {
+	m.record()
+	m.moves++
+	if m.rules.play(m.board, m.currentPlayer, mv) {
+		m.chain, m.held = &mv.to, &mv.to
+		return nil
+	}
+	m.chain, m.held = nil, nil
+	winner, cells := m.rules.result(m.board, m.currentPlayer)
+	switch winner {
+	case m.currentPlayer:
+		m.winner = m.currentPlayer
+		m.winningCells = cells
+		m.gameEnded = time.Now()
+		// no confetti for losing to the computer
+		if m.winner == m.computer {
+			return nil
+		}
+		if m.puzzle.active {
+			m.solvePuzzle()
+		}
+		return m.celebrate(Empty)
+	case Draw:
+		m.winner = Draw
+		m.gameEnded = time.Now()
+		return nil
+	}
+	m.switchPlayer()
+	return m.computerTurn()
+}
+
 func (m model) Init() tea.Cmd {
-	// Start ticker for real-time updates if in multiplayer mode
+	// SSH players keep the lobby's live counts and tournament calls up to
+	// date from here on, for whenever they're in it
+	var lobby tea.Cmd
+	if m.done != nil && m.tournament == nil {
+		lobby = watchLobby()
+	}
+	// Follow the shared session if in multiplayer mode
+	if m.gameSession != nil && m.visitor {
+		// sent by a peer into the queue, see sitDown
+		return tea.Batch(lobby, waitForUpdate(m.updates), waitForMove(m.gameSession.match, m.playerID))
+	}
 	if m.gameSession != nil {
-		return tea.Tick(TickerInterval, func(t time.Time) tea.Msg {
-			return tickMsg(t)
-		})
+		return tea.Batch(lobby, waitForUpdate(m.updates))
+	}
+	if m.tournament != nil {
+		return checkBracket
 	}
-	return nil
+	if m.lobby.active && autoTournamentSize > 0 {
+		return tea.Batch(m.idle(), lobby, checkLobby)
+	}
+	if m.lobby.active {
+		return tea.Batch(m.idle(), lobby)
+	}
+	if m.gameList.active {
+		return tea.Batch(refreshGameList, lobby)
+	}
+	return lobby
 }
 
-func checkWinner(board [][]string, player string) []coord {
-	// rows
-	for y := 0; y < BoardSize; y++ {
-		if board[y][0] == player && board[y][1] == player && board[y][2] == player {
-			return []coord{{y, 0}, {y, 1}, {y, 2}}
-		}
+// startTicking keeps a tick going while something on screen counts by itself
+func (m *model) startTicking() tea.Cmd {
+	if m.ticking || !m.needsTick() {
+		return nil
 	}
+	m.ticking = true
+	return tea.Tick(m.tickInterval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
 
-	// cols
-	for x := 0; x < BoardSize; x++ {
-		if board[0][x] == player && board[1][x] == player && board[2][x] == player {
-			return []coord{{0, x}, {1, x}, {2, x}}
-		}
+// needsTick reports whether anything on screen changes with time alone:
+// the accept and versus countdowns, the disconnect countdown, the thinking
+// timer, the clocks or the lobby's tournament call
+func (m model) needsTick() bool {
+	if m.gameSession == nil {
+		call, calling := sessionManager.tournamentCall()
+		return calling && (m.lobby.active || m.tournament == call.tournament)
+	}
+	if m.opponentLeft || m.showingVersus() || (m.accepting() && !m.reducedMotion) {
+		return true
+	}
+	if m.spectating && spectatorDelay > 0 {
+		return true // for the delayed feed
 	}
+	return !m.reducedMotion && m.winner == Empty && !m.waitingForPlayer && (!m.isMyTurn || (m.timeControl.on() && !m.casual))
+}
+
+// syncSession copies the shared session state into the model
+func (m *model) syncSession() {
+	m.gameSession.mutex.RLock()
+	defer m.gameSession.mutex.RUnlock()
 
-	// diagonals
-	if board[0][0] == player && board[1][1] == player && board[2][2] == player {
-		return []coord{{0, 0}, {1, 1}, {2, 2}}
+	m.rules = m.gameSession.Rules
+	m.board = copyBoard(m.gameSession.Board)
+	if m.gameSession.CurrentPlayer == 0 {
+		m.currentPlayer = PlayerX
+	} else {
+		m.currentPlayer = PlayerO
+	}
+	m.winner = m.gameSession.Winner
+	m.winningCells = m.gameSession.WinningCells
+	m.restartOffered = m.gameSession.RestartOffered
+	m.casual = m.gameSession.Casual
+	m.takebackOffered = m.gameSession.TakebackOffered
+	m.preferSame = m.gameSession.PreferSame
+	m.swap = m.gameSession.Swap
+	m.canSwap = m.gameSession.canSwap()
+	m.crowd = m.gameSession.Crowd
+	m.tallies = tallies(m.gameSession.Votes)
+	m.voteEnds = m.gameSession.VoteEnds
+	m.myVote = ""
+	if mv, ok := m.gameSession.Votes[m.playerID]; ok && m.spectating {
+		m.myVote = mv.String()
+	}
+	m.chain = m.gameSession.Chain
+	m.moves = m.gameSession.Moves
+	if !m.spectating {
+		m.ratingChange = m.gameSession.RatingChanges[playerIndex(m.playerSymbol)]
+		m.unlocked = m.gameSession.Unlocked[playerIndex(m.playerSymbol)]
+	}
+	// Fix: Calculate isMyTurn directly from session state
+	m.isMyTurn = (m.gameSession.CurrentPlayer == 0 && m.playerSymbol == PlayerX) ||
+		(m.gameSession.CurrentPlayer == 1 && m.playerSymbol == PlayerO)
+	m.waitingForPlayer = m.gameSession.PlayerCount < 2
+	// a piece in the middle of a multi-jump stays picked up, nothing does
+	// once the turn's over
+	switch {
+	case (!m.isMyTurn && !m.voting()) || m.winner != Empty:
+		m.held = nil
+	case m.chain != nil:
+		m.held = m.chain
+	}
+	m.turnStarted = m.gameSession.TurnStarted
+	m.timeControl = m.gameSession.TimeControl
+	m.handicap = m.gameSession.Handicap
+	m.clocks = m.gameSession.Clocks
+	m.timedOut = m.gameSession.TimedOut
+	m.forfeited = m.gameSession.Forfeited
+	m.aborted = m.gameSession.Aborted
+	m.matchStarted = m.gameSession.MatchStarted
+	m.acceptBy = m.gameSession.match.AcceptBy()
+	m.accepted = m.gameSession.match.HasAccepted(m.playerID)
+	m.opponentAccepted = m.gameSession.match.HasAccepted(m.gameSession.match.Opponent(m.playerID).ID)
+	m.gameStarted = m.gameSession.GameStarted
+	m.gameEnded = m.gameSession.GameEnded
+	if m.winner == Empty {
+		m.notation = ""
+	} else if m.notation == "" {
+		// just the once, the summary's drawn every frame
+		if replays := replaysIn(m.gameSession.ID, m.gameSession.journal.Events()); len(replays) > 0 {
+			m.notation = replays[len(replays)-1].notation()
+		}
+	}
+	m.sharingCursor = m.gameSession.ShareCursors[playerIndex(m.playerSymbol)]
+	m.opponentSharing = m.gameSession.ShareCursors[playerIndex(opponentOf(m.playerSymbol))]
+	m.opponentCursor = m.gameSession.Cursors[playerIndex(opponentOf(m.playerSymbol))]
+	m.opponentName = m.gameSession.PlayerNames[playerIndex(opponentOf(m.playerSymbol))]
+	m.names = m.gameSession.PlayerNames
+	m.anonymous = m.gameSession.Anonymous
+	if m.spectating {
+		m.names = m.gameSession.shownNames()
+	}
+	m.avatars = m.gameSession.Avatars
+	m.spectators = m.gameSession.Spectators
+	m.chat = slices.Clone(m.gameSession.Chat)
+	for i, msg := range m.chat {
+		if m.spectating && msg.Player != "" && m.anonymous[playerIndex(msg.Player)] {
+			m.chat[i].Name = AnonymousName
+		}
 	}
-	if board[0][2] == player && board[1][1] == player && board[2][0] == player {
-		return []coord{{0, 2}, {1, 1}, {2, 0}}
+	m.spectatorChat = nil
+	if m.spectating || (mergeSpectatorChat && m.winner != Empty) {
+		m.spectatorChat = slices.Clone(m.gameSession.SpectatorChat)
 	}
 
-	// no current winner
-	return nil
+	// Start the disconnect countdown
+	m.opponentLeft = m.gameSession.PlayerDisconnected
+	m.reconnectBy = m.gameSession.ReconnectBy
+
+	m.behind = false
+	if f, ok := m.gameSession.delayedFrame(); ok && m.spectating {
+		m.showFrame(f)
+		m.behind = f.winner != m.gameSession.Winner
+	}
 }
 
 // check for draw
@@ -211,53 +1040,219 @@ func isDraw(board [][]string) bool {
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
-	// Handle tick messages for real-time updates
+	// the terminal was resized: wipe whatever the terminal reflowed and
+	// draw the layout again for the new size
+	case tea.WindowSizeMsg:
+		resized := m.width != 0 && (m.width != msg.Width || m.height != msg.Height)
+		m.width, m.height = msg.Width, msg.Height
+		if resized {
+			return m, m.clearScreen()
+		}
+		return m, nil
+
+	// the shared session changed, catch up with it
+	case sessionUpdateMsg:
+		if msg.updates != m.updates {
+			return m, nil // from a session we've since left
+		}
+		prevWinner, prevBoard, wasAccepting, wasMyTurn := m.winner, m.board, m.accepting(), m.isMyTurn
+		var lastChat time.Time
+		if len(m.chat) > 0 {
+			lastChat = m.chat[len(m.chat)-1].At
+		}
+		m.syncSession()
+		if m.aborted {
+			// spectators find another game, the players another opponent
+			if m.spectating {
+				return m.stopWatching()
+			}
+			return m.leaveAborted()
+		}
+		cmds := []tea.Cmd{waitForUpdate(m.updates), m.celebrate(prevWinner), m.startTicking(), m.dropIn(prevBoard), m.alertChanges(wasMyTurn, lastChat)}
+		if !wasAccepting && m.accepting() {
+			cmds = append(cmds, m.matchFound())
+		}
+		if prevWinner != Empty && m.winner == Empty {
+			// the game was restarted, wipe the win screen
+			cmds = append(cmds, m.clearScreen())
+		}
+		return m, tea.Batch(cmds...)
+
+	// keep live counters and countdowns moving
 	case tickMsg:
-		if m.gameSession != nil {
-			// Sync with session state
-			m.gameSession.mutex.RLock()
-			m.board = copyBoard(m.gameSession.Board)
-			if m.gameSession.CurrentPlayer == 0 {
-				m.currentPlayer = PlayerX
-			} else {
-				m.currentPlayer = PlayerO
-			}
-			m.winner = m.gameSession.Winner
-			m.winningCells = m.gameSession.WinningCells
-			// Fix: Calculate isMyTurn directly from session state
-			m.isMyTurn = (m.gameSession.CurrentPlayer == 0 && m.playerSymbol == PlayerX) ||
-				(m.gameSession.CurrentPlayer == 1 && m.playerSymbol == PlayerO)
-			m.waitingForPlayer = m.gameSession.PlayerCount < 2
-
-			// Check for restart request
-			if m.gameSession.RestartRequested {
-				// Clear screen and reset local state to match shared state
-				m.winner = m.gameSession.Winner
-				m.winningCells = m.gameSession.WinningCells
-				m.gameSession.mutex.RUnlock()
-				return m, tea.ClearScreen
-			}
-
-			// Check for disconnect
-			if m.gameSession.PlayerDisconnected {
-				if m.disconnectTimer.IsZero() {
-					m.disconnectTimer = time.Now()
-				} else if time.Since(m.disconnectTimer) > DisconnectTimeout {
-					// Disconnect timeout reached, quit the game
-					m.gameSession.mutex.RUnlock()
-					return m, tea.Quit
+		m.ticking = false
+		if m.spectating && spectatorDelay > 0 {
+			// the delayed feed catches up with the game by itself
+			m.syncSession()
+		}
+		if m.opponentLeft && time.Now().After(m.reconnectBy) {
+			// spectators just go and find another game
+			if m.spectating {
+				return m.stopWatching()
+			}
+			// the opponent forfeits a tournament bout, no need to quit
+			if m.tournament != nil {
+				return m.backToBracket()
+			}
+			// nor a bout on the hill
+			if m.hill != nil {
+				return m.backToHill()
+			}
+			// SSH players go back to the lobby to find another game, once
+			// a rated game's been settled as a forfeit, see abandon
+			if m.done != nil {
+				if m.winner == Empty && !m.casual && time.Since(m.reconnectBy) < ForfeitWait {
+					return m, m.startTicking()
 				}
+				return m.leaveGame(m.opponentLeftWhy())
 			}
-			m.gameSession.mutex.RUnlock()
+			// Disconnect timeout reached, quit the game
+			return m, tea.Quit
+		}
+		return m, m.startTicking()
+
+	// someone connected, queued or sat down, or a tournament's been called
+	// or its sign ups closed
+	case lobbyMsg:
+		return m.updateLobbyCall()
+
+	// someone stepped through the replay room's game, came, went or chatted
+	case replayRoomMsg:
+		return m.syncReplayRoom(msg)
+
+	// a peer has someone waiting for the game, or doesn't
+	case peerFoundMsg:
+		return m.visitPeer(msg)
+
+	// back from playing on a peer
+	case peerBackMsg:
+		return m.backFromPeer(msg)
+
+	// a game started or ended, refresh the list of games to watch
+	case gameListMsg:
+		return m.updateGameListGames()
+
+	// the computer's move is in
+	case aiMoveMsg:
+		if msg.game != m.game || m.winner != Empty || m.currentPlayer != m.computer {
+			return m, nil
+		}
+		return m, m.playLocal(move{msg.cell, msg.cell})
+
+	// the lobby has been idle a while, or the attract mode moves along
+	case attractMsg:
+		return m.startAttract(msg)
+	case attractTickMsg:
+		return m.updateAttract(msg)
+
+	// the queue paired us with someone else who was waiting
+	case matchMovedMsg:
+		return m.moveMatch(msg)
+
+	// nobody came to our room
+	case roomExpiredMsg:
+		if m.gameSession != nil && m.gameSession.match == msg.match {
+			return m.leaveGame(fmt.Sprintf("Nobody joined your room in %d minutes, so it's closed", int(RoomTTL.Minutes())))
 		}
+		return m, nil
+
+	// the tournament bracket changed, maybe our next match is ready
+	case bracketMsg:
+		return m.updateBracket()
+
+	// something changed on the hill, maybe it's our turn to play
+	case hillMsg:
+		return m.updateHill()
 
-		// Continue ticking
-		return m, tea.Tick(TickerInterval, func(t time.Time) tea.Msg {
-			return tickMsg(t)
-		})
+	case animation.FrameMsg:
+		var confettiCmd, flashCmd, dropCmd tea.Cmd
+		m.confetti, confettiCmd = m.confetti.Update(msg)
+		m.flash, flashCmd = m.flash.Update(msg)
+		m.drop, dropCmd = m.drop.Update(msg)
+		return m, tea.Batch(confettiCmd, flashCmd, dropCmd)
 
 	// is it a key press?
 	case tea.KeyMsg:
+		if m.lobby.active {
+			return m.updateLobby(msg)
+		}
+		if m.opponentMenu.active {
+			return m.updateOpponentMenu(msg)
+		}
+		if m.gameList.active {
+			return m.updateGameList(msg)
+		}
+		if m.macroEditor.active {
+			return m.updateMacroEditor(msg)
+		}
+		if m.profile.active {
+			return m.updateProfile(msg)
+		}
+		if m.replayRoom != nil {
+			return m.updateReplayRoom(msg)
+		}
+		if m.replays.active {
+			return m.updateReplays(msg)
+		}
+		if m.leaderboard.active {
+			return m.updateLeaderboard(msg)
+		}
+		if m.tutorial.active {
+			return m.updateTutorial(msg)
+		}
+		if m.export.active {
+			return m.updateExport(msg)
+		}
+		if m.deleting.active {
+			return m.updateDelete(msg)
+		}
+		if m.tournament != nil && m.gameSession == nil {
+			return m.updateBracketKeys(msg)
+		}
+		if m.hill != nil && m.gameSession == nil {
+			return m.updateHillKeys(msg)
+		}
+		if m.spectating {
+			return m.updateSpectating(msg)
+		}
+		if m.accepting() {
+			return m.updateAccept(msg)
+		}
+		if m.practice.settingUp {
+			return m.updateSetUp(msg)
+		}
+		if m.chatting {
+			return m.updateChat(msg)
+		}
+
+		// ignore pasted text outright: bracketed pastes are flagged, and
+		// terminals without bracketed paste deliver it as one multi-rune key
+		if msg.Paste || (msg.Type == tea.KeyRunes && len(msg.Runes) > 1) {
+			return m, nil
+		}
+		burst := time.Since(m.lastKey) < KeyBurstWindow
+		m.lastKey = time.Now()
+
+		// a macro key plays its steps as if they'd been typed
+		if steps, ok := m.macros[msg.String()]; ok {
+			return m.runMacro(steps)
+		}
+
+		// any key skips the confetti
+		if m.confetti.Running() {
+			m.confetti = m.confetti.Stop()
+		}
+
+		if m.practice.active {
+			if next, cmd, ok := m.updatePractice(msg); ok {
+				return next, cmd
+			}
+		}
+		if m.puzzle.active {
+			if next, cmd, ok := m.updatePuzzle(msg); ok {
+				return next, cmd
+			}
+		}
 
 		// cool, what key was pressed?
 		switch msg.String() {
@@ -266,49 +1261,122 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c", "q":
 			return m, tea.Quit
 
-		// the "up" and "k" keys move the cursor up
-		case "up", "k":
-			if m.cursorY > 0 {
-				m.cursorY--
+		// the arrow keys and h, j, k and l move the cursor
+		case "up", "k", "down", "j", "right", "l", "left", "h":
+			m.moveCursor(msg.String())
+
+		// toggle sharing our cursor with the opponent (it leaks intent, so
+		// it's up to each player whether to give theirs away)
+		case "c":
+			if m.gameSession != nil {
+				m.gameSession.mutex.Lock()
+				seat := playerIndex(m.playerSymbol)
+				m.gameSession.ShareCursors[seat] = !m.gameSession.ShareCursors[seat]
+				m.sharingCursor = m.gameSession.ShareCursors[seat]
+				m.gameSession.notify()
+				m.gameSession.mutex.Unlock()
 			}
 
-		// the "down" and "j" keys move the cursor down
-		case "down", "j":
-			if m.cursorY < len(m.board)-1 {
-				m.cursorY++
-				if m.cursorX >= len(m.board[m.cursorY]) {
-					m.cursorX = len(m.board[m.cursorY]) - 1
-				}
+		// say something to the opponent
+		case "t":
+			if m.gameSession != nil {
+				m.chatting = true
+			}
+
+		// invite a friend to the room with a command to run, instead of a join code
+		case "i":
+			if m.waitingForPlayer && m.gameSession != nil && m.gameSession.Private {
+				m.newInvite(m.gameSession.ID)
 			}
 
-		// the "right" and "l" keys move the cursor right
-		case "right", "l":
-			if m.cursorX < len(m.board[m.cursorY])-1 {
-				m.cursorX++
+		// cycle through the banner fonts
+		case "f":
+			m.font = figlet.Next(m.font)
+
+		// toggle reduced-motion mode
+		case "m":
+			m.reducedMotion = !m.reducedMotion
+
+		// toggle low bandwidth rendering, for when the link was misjudged
+		case "b":
+			m.lowBandwidth = !m.lowBandwidth
+			return m, tea.ClearScreen
+
+		// ask to take the last move of a casual game back
+		case "u":
+			if m.gameSession != nil && !m.spectating && m.casual && !m.crowd {
+				m.gameSession.offerTakeback(m.playerSymbol)
+			}
+
+		// find someone new to play, at the same game and in the same
+		// queue, rated or casual
+		case "n":
+			if m.requeueable() {
+				return m.requeue()
+			}
+
+		// ask to play the same opponent again next time
+		case "p":
+			if m.requeueable() {
+				m.gameSession.togglePreferSame(m.playerSymbol)
 			}
 
-		// the "left" and "h" keys move the cursor right
-		case "left", "h":
-			if m.cursorX > 0 {
-				m.cursorX--
+		// back to the lobby once the game's over, to find another
+		case "esc":
+			if m.gameSession != nil && !m.spectating && m.tournament == nil && m.hill == nil && m.winner != Empty {
+				return m.leaveGame("")
+			}
+
+		// step back through the game that just finished
+		case "v":
+			if m.gameSession != nil && !m.spectating && m.hill == nil && m.winner != Empty {
+				return m.rewatch()
+			}
+
+		// take X's first move, under the swap rule
+		case "s":
+			if m.gameSession != nil && !m.spectating && m.playerSymbol == PlayerO {
+				m.gameSession.swap()
 			}
 
 		// reset the game
 		case "r":
-			m.resetGame()
-			// In multiplayer, mark restart requested for other player
-			if m.gameSession != nil {
-				m.gameSession.mutex.Lock()
-				m.gameSession.RestartRequested = true
-				m.gameSession.mutex.Unlock()
+			// a won tournament bout is settled, only draws get replayed
+			if m.tournament != nil && m.winner != Draw {
+				break
 			}
-			return m, tea.ClearScreen
+			// and so is a bout on the hill, drawn or not
+			if m.hill != nil && m.winner != Empty {
+				break
+			}
+			// the board is kept as it was for the opponent to come back to
+			if m.opponentLeft {
+				break
+			}
+			// a game under way is only called off if both players want to,
+			// a player who's losing can't wipe the result
+			if m.gameSession != nil && m.winner == Empty && !m.waitingForPlayer && !m.gameSession.offerRestart(m.playerSymbol) {
+				break
+			}
+			m.resetGame()
+			return m, m.clearScreen()
 
 		// the "enter" and the spacebar (a literal space) toggle
 		// the selected state for the item that the cursor is pointing at.
 		case "enter", " ":
 			// ignore moves if the game is already over
 			if m.winner != Empty {
+				if m.tournament != nil && m.winner != Draw {
+					return m.backToBracket()
+				}
+				if m.hill != nil {
+					return m.backToHill()
+				}
+				break
+			}
+
+			// a burst of keys is a paste or a stuck key, not a move
+			if burst {
 				break
 			}
 
@@ -317,52 +1385,333 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				break
 			}
 
-			// only place on empty cells
-			if m.board[m.cursorY][m.cursorX] != Empty {
+			// no moves while the versus screen is still up
+			if m.showingVersus() {
 				break
 			}
 
-			// place the move - use player's symbol, not current player
-			if m.gameSession != nil {
-				m.board[m.cursorY][m.cursorX] = m.playerSymbol
-			} else {
-				m.board[m.cursorY][m.cursorX] = m.currentPlayer
+			// not while the computer is still thinking
+			if m.gameSession == nil && m.currentPlayer == m.computer {
+				break
 			}
 
-			// Update shared session if in multiplayer mode
-			if m.gameSession != nil {
-				m.gameSession.mutex.Lock()
-				m.gameSession.Board[m.cursorY][m.cursorX] = m.playerSymbol
-
-				cells := checkWinner(m.gameSession.Board, m.playerSymbol)
-				if cells != nil {
-					m.gameSession.Winner = m.playerSymbol
-					m.gameSession.WinningCells = cells
-				} else if isDraw(m.gameSession.Board) {
-					m.gameSession.Winner = Draw
-				} else {
-					// Switch to next player
-					m.gameSession.CurrentPlayer = 1 - m.gameSession.CurrentPlayer
-				}
-				m.gameSession.mutex.Unlock()
-			} else {
-				// Single player mode
-				cells := checkWinner(m.board, m.currentPlayer)
-				if cells != nil {
-					m.winner = m.currentPlayer
-					m.winningCells = cells
-				} else if isDraw(m.board) {
-					m.winner = Draw
-				} else {
-					m.switchPlayer()
-				}
+			// only legal moves: marks on empty cells or in columns with
+			// room, pieces picked up first and then put down
+			mv, ok := m.pick()
+			if !ok {
+				break
+			}
+
+			// Single player mode
+			if m.gameSession == nil {
+				return m, m.playLocal(mv)
+			}
+
+			// Update shared session in multiplayer mode. The model's copy
+			// of it can be behind: the game lost on time, or the other
+			// player moved while the key was on its way. The move's only
+			// made if it's still legal on the session's board.
+			m.gameSession.mutex.Lock()
+			made, again := m.gameSession.makeMove(m.playerSymbol, mv)
+			m.gameSession.mutex.Unlock()
+			if !made {
+				break
+			}
+
+			// and on our board - use player's symbol, not current player
+			m.rules.play(m.board, m.playerSymbol, mv)
+			m.held = nil
+			if again {
+				m.held = &mv.to
 			}
 		}
+
+		m.shareCursor()
+	}
+
+	// return the updated model to the Bubble Tea runtime for processing,
+	// along with a tick if something on screen now counts by itself
+	return m, m.startTicking()
+}
+
+// moveCursor moves the cursor one cell with the arrow keys or h, j, k and l
+func (m *model) moveCursor(key string) {
+	switch key {
+	case "up", "k":
+		if m.cursorY > 0 {
+			m.cursorY--
+		}
+	case "down", "j":
+		if m.cursorY < len(m.board)-1 {
+			m.cursorY++
+			if m.cursorX >= len(m.board[m.cursorY]) {
+				m.cursorX = len(m.board[m.cursorY]) - 1
+			}
+		}
+	case "right", "l":
+		if m.cursorX < len(m.board[m.cursorY])-1 {
+			m.cursorX++
+		}
+	case "left", "h":
+		if m.cursorX > 0 {
+			m.cursorX--
+		}
+	}
+}
+
+// shareCursor lets the opponent see where we're hovering, if we're sharing
+// our cursor
+func (m *model) shareCursor() {
+	if m.gameSession == nil {
+		return
+	}
+	m.gameSession.mutex.Lock()
+	defer m.gameSession.mutex.Unlock()
+	row, col := m.cursorCell()
+	m.gameSession.Cursors[playerIndex(m.playerSymbol)] = coord{row, col}
+	if m.gameSession.ShareCursors[playerIndex(m.playerSymbol)] {
+		m.gameSession.notify()
+	}
+}
+
+// art is the art for the game being played
+func (m model) art() Art {
+	if m.lowBandwidth {
+		// just the words, the banners fall back to them too
+		return Art{Title: "\n  " + strings.ToUpper(m.rules.Title()) + "\n"}
+	}
+	return artFor(m.rules)
+}
+
+// columnLabel returns the letter used for a board column (0 = A)
+func columnLabel(col int) string {
+	return string(rune('A' + col))
+}
+
+// cellName names a cell the way the board labels it, e.g. "B2"
+func cellName(row, col int) string {
+	return fmt.Sprintf("%s%d", columnLabel(col), row+1)
+}
+
+// showingVersus reports whether the pre-game versus screen is still counting down
+func (m model) showingVersus() bool {
+	return m.gameSession != nil && !m.matchStarted.IsZero() && time.Since(m.matchStarted) < VersusDuration
+}
+
+// versusScreen introduces the players and the rules before the board appears
+func (m model) versusScreen() string {
+	you := m.theme.player(m.playerSymbol) + " " + withAvatar(m.avatars[playerIndex(m.playerSymbol)], m.playerName)
+	them := m.theme.player(opponentOf(m.playerSymbol)) + " " + withAvatar(m.avatars[playerIndex(opponentOf(m.playerSymbol))], m.opponentName)
+	countdown := int((VersusDuration - time.Since(m.matchStarted)).Seconds()) + 1
+
+	s := "\n\n\n"
+	s += headerStyle.Render(m.bannerArt("vs", ""))
+	s += "\n\n"
+	s += "  " + you + footerStyle.Render("  vs  ") + them + "\n\n"
+	rules := m.rules.Title()
+	if m.casual {
+		rules += " (casual)"
+	}
+	if m.swap {
+		rules += " with the swap rule"
+	}
+	if m.timeControl.on() && !m.casual {
+		rules += ", " + m.timeControl.String()
+	}
+	if m.handicap != handicapNone {
+		rules += ", handicap: " + m.handicap.String()
+	}
+	s += footerStyle.Render("Rules: "+rules+", "+m.currentPlayer+" moves first, sharing your cursor "+onOff(m.sharingCursor)) + "\n"
+	// room games start without an accept screen, so this is the first look
+	opponentID := m.gameSession.match.Opponent(m.playerID).ID
+	s += footerStyle.Render(m.stakesView(opponentID)) + "\n"
+	if rivalry := rivalryView(m.playerID, opponentID); rivalry != "" {
+		s += headerStyle.Render(rivalry) + "\n"
+	}
+	s += headerStyle.Render(fmt.Sprintf("Game starts in %d...", countdown)) + "\n"
+	return s
+}
+
+// celebrate starts the confetti if the game has just been won
+func (m *model) celebrate(prevWinner string) tea.Cmd {
+	if m.reducedMotion || prevWinner != Empty || (m.winner != PlayerX && m.winner != PlayerO) {
+		return nil
+	}
+	m.confettiSeed = rand.Uint64()
+	var cmd tea.Cmd
+	m.confetti, cmd = m.confetti.Start()
+	return cmd
+}
+
+// tooSmall reports whether the terminal is too small to draw the game in.
+// Until the terminal reports its size we assume it's fine.
+func (m model) tooSmall() bool {
+	return m.width > 0 && (m.width < MinTerminalWidth || m.height < m.minHeight())
+}
+
+// minHeight is how tall the terminal needs to be, bigger boards need more
+func (m model) minHeight() int {
+	rows, _ := m.rules.Size()
+	return MinTerminalHeight + max(rows-BoardSize, 0)
+}
+
+// tooSmallView asks for a bigger terminal, it goes away by itself once the
+// terminal is resized
+func (m model) tooSmallView() string {
+	msg := headerStyle.Render("Please enlarge your terminal") + "\n" +
+		footerStyle.Render(fmt.Sprintf("need %dx%d, have %dx%d", MinTerminalWidth, m.minHeight(), m.width, m.height)) + "\n\n" +
+		footerStyle.Render("q to quit")
+	return lip.Place(m.width, m.height, lip.Center, lip.Center, lip.JoinVertical(lip.Center, strings.Split(msg, "\n")...))
+}
+
+// bannerArt uses the operator's custom art if there is any, otherwise renders
+// text in the banner font. Either falls back to plain text when it's wider
+// than the terminal.
+func (m model) bannerArt(text, custom string) string {
+	art := custom
+	if art == "" {
+		art = m.font.Render(text)
+	}
+	if m.lowBandwidth || (m.width > 0 && lip.Width(art) > m.width) {
+		return strings.ToUpper(text)
+	}
+	return art
+}
+
+// confettiBanner draws the banner with the current confetti frame falling
+// behind it, taking up the same lines as the plain banner does
+func (m model) confettiBanner(style lip.Style, banner string) string {
+	const above, below = 3, 2
+	lines := strings.Split(banner, "\n")
+	width := max(lip.Width(banner), 40)
+	if m.width > 0 {
+		width = min(width, m.width)
+	}
+	height := above + len(lines) + below
+	layer := animation.Confetti(m.confettiSeed, width, height, m.confetti.Frame())
+
+	var s strings.Builder
+	for y, row := range layer {
+		var bannerRow []rune
+		if y >= above && y < above+len(lines) {
+			bannerRow = []rune(lines[y-above])
+		}
+		for x, piece := range row {
+			switch {
+			case x < len(bannerRow) && bannerRow[x] != ' ':
+				s.WriteString(style.Render(string(bannerRow[x])))
+			case piece != ' ':
+				s.WriteString(confettiStyles[(x+y)%len(confettiStyles)].Render(string(piece)))
+			default:
+				s.WriteByte(' ')
+			}
+		}
+		s.WriteByte('\n')
+	}
+	return s.String()
+}
+
+// winnerLine announces the winner under the win banner
+func (m model) winnerLine() string {
+	if m.computer != Empty {
+		if m.winner == m.computer {
+			return "The computer takes the game!"
+		}
+		if m.puzzle.active {
+			return "Solved!"
+		}
+		return "You take the game!"
+	}
+	onTime := "!"
+	if m.timedOut {
+		onTime = " on time!"
+	}
+	if m.gameSession != nil && m.winner == m.playerSymbol {
+		return "You take the game" + onTime
+	}
+	return m.winnerName() + " takes the game" + onTime
+}
+
+// winnerName names the winner on the win screen: "You" or the opponent in
+// multiplayer, just the symbol when playing locally
+func (m model) winnerName() string {
+	switch {
+	case m.gameSession == nil:
+		return m.winner
+	case m.spectating:
+		return m.names[playerIndex(m.winner)]
+	case m.winner == m.playerSymbol:
+		return "You"
+	case m.opponentName != "":
+		return m.opponentName
 	}
+	return "Your opponent"
+}
+
+// formatRecord shows a player's rating in a game next to their name, and
+// their wins-losses-draws once they've played it. New players' ratings
+// aren't shown until they've played their placement matches.
+func formatRecord(playerID, game string) string {
+	r := sessionManager.queue.Record(playerID, game)
+	title := rankTitle(r.Rating, r.Played())
+	if !r.Placed() {
+		return footerStyle.Render(fmt.Sprintf(" %s, placement %d/%d", title, r.Played(), matchmaking.PlacementMatches))
+	}
+	if r.Played() == 0 {
+		return footerStyle.Render(fmt.Sprintf(" %s %d", title, r.Rating))
+	}
+	return footerStyle.Render(fmt.Sprintf(" %s %d (%d-%d-%d)", title, r.Rating, r.Wins, r.Losses, r.Draws))
+}
+
+// formatStakes shows what the game means for the player's rating
+func formatStakes(s matchmaking.Stakes) string {
+	return fmt.Sprintf("Rating at stake: win %+d · draw %+d · loss %+d", s.Win, s.Draw, s.Loss)
+}
+
+// onOff renders a toggle's state for the footer
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// formatDuration renders a duration as m:ss
+func formatDuration(d time.Duration) string {
+	secs := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// reconnectCountdown is how long a disconnected opponent has left to come back
+func (m model) reconnectCountdown() string {
+	return formatDuration(max(time.Until(m.reconnectBy), 0).Round(time.Second))
+}
+
+// restartOfferView says who wants to start the game over, if anyone does
+func (m model) restartOfferView() string {
+	switch {
+	case m.restartOffered[playerIndex(opponentOf(m.playerSymbol))]:
+		return headerStyle.Render("Your opponent wants to start over, press r to agree") + "\n"
+	case m.restartOffered[playerIndex(m.playerSymbol)]:
+		return footerStyle.Render("Waiting for your opponent to agree to start over") + "\n"
+	}
+	return ""
+}
+
+// playerIndex maps a player's symbol to its index in the session (0 = X, 1 = O)
+func playerIndex(player string) int {
+	if player == PlayerX {
+		return 0
+	}
+	return 1
+}
 
-	// return the updated model to the Bubble Tea runtime for processing.
-	// Note: we're not returning a command
-	return m, nil
+// opponentOf returns the other player's symbol
+func opponentOf(player string) string {
+	if player == PlayerX {
+		return PlayerO
+	}
+	return PlayerX
 }
 
 func styledPlayer(player string) string {
@@ -374,17 +1723,27 @@ func styledPlayer(player string) string {
 
 // renderCell creates a styled cell for the game board
 func (m model) renderCell(x, y int, cell string) string {
-	// build cell content
-	var content string
-	switch cell {
-	case PlayerX:
-		content = PlayerX
-	case PlayerO:
-		content = PlayerO
-	default:
-		content = " "
+	// build cell content: where the piece picked up can go is dotted, kings
+	// get braces and cells nothing goes in stay blank
+	content := " "
+	if cell != Empty {
+		content = owner(cell)
+	}
+	held := m.held != nil && *m.held == coord{y, x}
+	target := slices.Contains(m.targets(), coord{y, x})
+	if target {
+		content = "·"
+	}
+	if n := m.tallies[coord{y, x}]; n > 0 && m.crowd {
+		content = tallyMark(n) // the crowd's votes for it
 	}
 	fullCell := "[" + content + "]"
+	switch {
+	case !m.rules.playable(y, x):
+		fullCell = "   "
+	case crowned(cell):
+		fullCell = "{" + content + "}"
+	}
 
 	// check if this cell is part of a winning combo
 	highlight := false
@@ -394,240 +1753,684 @@ func (m model) renderCell(x, y int, cell string) string {
 			break
 		}
 	}
+	if m.lowBandwidth {
+		return m.liteCell(x, y, content, fullCell, highlight, held)
+	}
 
 	// apply styles
+	cursorY, cursorX := m.cursorCell()
 	if highlight {
-		return winStyle.Render(fullCell)
-	} else if m.cursorX == x && m.cursorY == y {
+		return m.theme.win.Render(fullCell)
+	} else if m.hasCursor() && cursorX == x && cursorY == y {
 		// cursor takes priority over normal colors
-		cursorStyle := lip.NewStyle().Background(lip.Color("#44475a")).Foreground(lip.Color("#f8f8f2")).Bold(true)
-		var styled lip.Style
-		switch cell {
-		case PlayerX:
-			styled = cursorStyle.Foreground(lip.Color("#8BE9FD"))
-		case PlayerO:
-			styled = cursorStyle.Foreground(lip.Color("#FF79C6"))
-		default:
-			styled = cursorStyle
+		styled := m.theme.cursor
+		if p := owner(cell); p == PlayerX || p == PlayerO {
+			styled = styled.Foreground(m.theme.mark(p).GetForeground())
 		}
 		return styled.Render(fullCell)
+	} else if !m.spectating && m.opponentSharing && m.opponentCursor.row == y && m.opponentCursor.col == x {
+		// opponent's cursor is underlined in their color
+		return m.theme.mark(opponentOf(m.playerSymbol)).Underline(true).Render(fullCell)
+	} else if held || target {
+		return headerStyle.Render(fullCell)
 	} else {
-		switch cell {
-		case PlayerX:
-			return xStyle.Render(fullCell)
-		case PlayerO:
-			return oStyle.Render(fullCell)
+		switch owner(cell) {
+		case PlayerX, PlayerO:
+			return m.theme.mark(owner(cell)).Render(fullCell)
 		default:
-			return cellStyle.Render(fullCell)
+			return m.theme.cell.Render(fullCell)
+		}
+	}
+}
+
+// boardView draws the board with its column and row labels
+func (m model) boardView() string {
+	// column labels (A, B, C, ...) line up with the middle of each cell
+	s := boardIndent
+	for x := range m.board[0] {
+		if hasGravity(m.rules) && m.hasCursor() && x == m.cursorX {
+			// marks are dropped in from above, point at the column
+			s += headerStyle.Render(" ▼ ")
+		} else {
+			s += footerStyle.Render(" " + columnLabel(x) + " ")
+		}
+	}
+	s += "\n"
+
+	for y, row := range m.board {
+		// row labels (1, 2, 3, ...) sit just left of the board
+		s += footerStyle.Render(fmt.Sprintf("%*d ", len(boardIndent)-1, y+1))
+		for x := range row {
+			s += m.renderCell(x, y, m.boardCell(y, x))
 		}
+		s += "\n"
 	}
+	return s
 }
 
 func (m model) View() string {
-	// If there's a winner, show full screen ASCII art
-	switch m.winner {
-	case PlayerX:
-		return showXWinScreen()
-	case PlayerO:
-		return showOWinScreen()
-	case Draw:
-		return showDrawScreen()
+	return m.lite(m.view())
+}
+
+// view draws the current screen, see View
+func (m model) view() string {
+	if m.tooSmall() {
+		return m.tooSmallView()
+	}
+
+	// If there's a winner, show full screen ASCII art, once the last mark has landed
+	switch {
+	case m.drop.Running():
+	case m.winner == PlayerX || m.winner == PlayerO:
+		style, custom := m.theme.x, m.art().XWins
+		if m.winner == PlayerO {
+			style, custom = m.theme.o, m.art().OWins
+		}
+		return m.endScreen(style, m.bannerArt(m.winner+" wins", custom), m.winnerLine())
+	case m.winner == Draw:
+		return m.endScreen(headerStyle, m.bannerArt("draw", m.art().Draw), "It's a draw!")
+	}
+
+	if m.accepting() {
+		return m.acceptView()
+	}
+	if m.showingVersus() {
+		return m.versusScreen()
+	}
+
+	if m.lobby.active {
+		return m.lobbyView()
+	}
+	if m.opponentMenu.active {
+		return m.opponentMenuView()
+	}
+	if m.gameList.active {
+		return m.gameListView()
+	}
+	if m.macroEditor.active {
+		return m.macroEditorView()
+	}
+	if m.profile.active {
+		return m.profileView()
+	}
+	if m.replays.active {
+		return m.replaysView()
+	}
+	if m.leaderboard.active {
+		return m.leaderboardView()
+	}
+	if m.tutorial.active {
+		return m.tutorialView()
+	}
+	if m.export.active {
+		return m.exportView()
+	}
+	if m.deleting.active {
+		return m.deleteView()
+	}
+	if m.tournament != nil && m.gameSession == nil {
+		return m.bracketView()
+	}
+	if m.hill != nil && m.gameSession == nil {
+		return m.hillView()
 	}
 
 	// Normal game view
 	// header
 	s := "\n"
-	s += headerStyle.Render(`
-  _____ _       _____           _____         
- |_   _(_)__ __|_   _|_ _ __ __|_   _|___  ___ 
-   | | | / _'___|| |/ _' / _'___|| | / _ \/ -_)
-   |_| |_\__|    |_|\__,_\__|    |_| \___/\___|
-`)
+	s += headerStyle.Render(m.art().Title)
 	s += "\n\n"
-
-	for y, row := range m.board {
-		s += "\t\t"
-		for x, cell := range row {
-			s += m.renderCell(x, y, cell)
-		}
-		s += "\n"
-	}
+	s += m.boardView()
 
 	// footer
-	if m.gameSession != nil && m.gameSession.PlayerDisconnected {
-		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render("⚠️  Opponent disconnected! Game will end in 5 seconds...") + "\n"
+	if m.opponentLeft && m.spectating {
+		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render("⚠️  A player disconnected! Back to the game list in "+m.reconnectCountdown()+" unless they reconnect") + "\n"
+	} else if m.opponentLeft {
+		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render("⚠️  Opponent left! Game ends in "+m.reconnectCountdown()+" unless they reconnect") + "\n"
+	} else if m.waitingForPlayer && m.gameSession.Private {
+		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FFB86C")).Bold(true).Render("Waiting for your friend, give them the join code "+m.gameSession.ID) + "\n"
+		s += footerStyle.Render("Clock: "+m.timeControl.String()+", handicap: "+m.handicap.String()) + "\n"
+		s += m.inviteView()
+	} else if m.waitingForPlayer && m.requeued {
+		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FFB86C")).Bold(true).Render("That match fell through, waiting for another player...") + "\n"
 	} else if m.waitingForPlayer {
 		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FFB86C")).Bold(true).Render("Waiting for another player to join...") + "\n"
+	} else if m.practice.settingUp {
+		s += m.practiceView()
+	} else {
+		s += footerStyle.Render("\nCurrent turn: ") + m.theme.player(m.currentPlayer) + m.shotClockView()
+		if m.computer != Empty && m.currentPlayer == m.computer {
+			s += footerStyle.Render(" (the computer is thinking...)")
+		}
+		s += "\n"
+		// show how long the opponent has been thinking so a slow move doesn't look like a hung connection
+		s += m.crowdView()
+		if m.gameSession != nil && !m.isMyTurn && time.Now().After(m.turnStarted) && !(m.crowd && m.currentPlayer == PlayerO) {
+			thinker := "Opponent"
+			if m.spectating {
+				thinker = m.names[playerIndex(m.currentPlayer)]
+			}
+			if m.reducedMotion {
+				s += footerStyle.Render(thinker+" is thinking...") + "\n"
+			} else {
+				s += footerStyle.Render(thinker+" has been thinking for "+formatDuration(time.Since(m.turnStarted))) + "\n"
+			}
+		}
+		if m.gameSession != nil && !m.spectating {
+			s += m.restartOfferView()
+			s += m.takebackOfferView()
+			s += m.swapView()
+		}
+		s += m.practiceView()
+	}
+	if m.puzzle.active {
+		s += m.puzzleView()
+	}
+	if m.gameSession != nil && m.spectating {
+		s += footerStyle.Render("\nWatching game "+m.gameSession.ID+watchers(m.spectators)) + "\n"
+		records := [2]string{m.recordFor(m.gameSession.match.Players[0].ID), m.recordFor(m.gameSession.match.Players[1].ID)}
+		if m.behind {
+			records = [2]string{} // they'd give the result away
+		}
+		for i, anonymous := range m.anonymous {
+			if anonymous {
+				records[i] = "" // a rating could say who they are
+			}
+		}
+		s += m.theme.player(PlayerX) + " " + m.names[0] + records[0] + m.clockView(PlayerX)
+		s += footerStyle.Render("  vs  ") + m.theme.player(PlayerO) + " " + m.names[1] + records[1] + m.clockView(PlayerO) + "\n"
+	} else if m.gameSession != nil {
+		s += footerStyle.Render("\nGame "+m.gameSession.ID+watchers(m.spectators)) + "\n"
+		s += footerStyle.Render("You: ") + m.theme.player(m.playerSymbol) + " " + m.playerName + m.recordFor(m.playerID)
+		if m.opponentName != "" {
+			opponent := m.gameSession.match.Opponent(m.playerID)
+			s += m.clockView(m.playerSymbol)
+			s += footerStyle.Render("  vs  ") + m.theme.player(opponentOf(m.playerSymbol)) + " " + m.opponentName + m.recordFor(opponent.ID) + m.clockView(opponentOf(m.playerSymbol))
+			s += "\n" + footerStyle.Render(m.stakesView(opponent.ID))
+		}
+		s += "\n"
+	}
+	s += m.chatView()
+	if m.chatting {
+		s += footerStyle.Render("\nPress enter to send, esc to cancel") + "\n"
+	} else if m.spectating && m.crowd {
+		s += footerStyle.Render("\nPress enter to vote, t to chat with the other spectators, esc to pick another game, q to quit") + "\n"
+	} else if m.spectating {
+		s += footerStyle.Render("\nPress t to chat with the other spectators, esc to pick another game, q to quit") + "\n"
+	} else if m.tournament != nil {
+		s += footerStyle.Render("\nPress t to chat, q to quit (you'll forfeit the tournament)") + "\n"
+	} else if m.hill != nil {
+		s += footerStyle.Render("\nPress t to chat, q to quit (you'll lose the bout)") + "\n"
+	} else if m.crowd {
+		s += footerStyle.Render("\nPress t to chat, q to quit") + "\n"
+	} else if m.gameSession != nil && m.casual {
+		s += footerStyle.Render("\nPress u to offer a takeback, r to offer a restart, t to chat, q to quit") + "\n"
+	} else if m.gameSession != nil && m.waitingForPlayer {
+		s += footerStyle.Render("\nPress t to chat, q to quit") + "\n"
+	} else if m.gameSession != nil {
+		s += footerStyle.Render("\nPress t to chat, q to quit (rated, leaving counts as a loss)") + "\n"
+	} else if m.practice.active {
+		s += footerStyle.Render("\n"+m.practiceKeys()) + "\n"
+	} else if m.puzzle.active {
+		s += footerStyle.Render("\n"+m.puzzleKeys()) + "\n"
 	} else {
-		s += footerStyle.Render("\nCurrent turn: ") + styledPlayer(m.currentPlayer) + "\n"
+		s += footerStyle.Render("\nPress r to restart, q to quit") + "\n"
 	}
-	s += footerStyle.Render("\nPress r to restart, q to quit\n")
+	settings := "m reduced motion: " + onOff(m.reducedMotion) + "  b low bandwidth: " + onOff(m.lowBandwidth)
+	if m.gameSession != nil && !m.spectating {
+		settings = "c share your cursor: " + onOff(m.sharingCursor) + "  " + settings
+	}
+	s += footerStyle.Render(settings + "\n")
 
 	return s
 }
 
-func showXWinScreen() string {
-	s := "\n\n\n"
-	s += xStyle.Render(`
-░██    ░██    ░██       ░██ ░██
- ░██  ░██     ░██       ░██
-  ░██░██      ░██  ░██  ░██ ░██░████████   ░███████
-   ░███       ░██ ░████ ░██ ░██░██    ░██ ░██
-  ░██░██      ░██░██ ░██░██ ░██░██    ░██  ░███████
- ░██  ░██     ░████   ░████ ░██░██    ░██        ░██
-░██    ░██    ░███     ░███ ░██░██    ░██  ░███████
-`)
-	s += "\n\n"
-	s += footerStyle.Render("Press r to restart, q to quit\n")
+// endScreen shows a full screen banner once the game is over
+func (m model) endScreen(style lip.Style, banner, footer string) string {
+	var s string
+	if m.confetti.Running() {
+		s = m.confettiBanner(style, banner)
+	} else {
+		s = "\n\n\n"
+		s += style.Render(banner)
+		s += "\n\n"
+	}
+	s += footerStyle.Render(footer) + "\n"
+	s += m.summary() + "\n"
+	if chat := m.chatView(); chat != "" {
+		s += "\n" + chat
+	}
+	switch {
+	case m.chatting:
+		s += footerStyle.Render("Press enter to send, esc to cancel") + "\n"
+	case m.spectating:
+		s += footerStyle.Render("Press t to chat, esc to pick another game, f to change font, q to quit") + "\n"
+	case m.tournament != nil && m.winner == Draw:
+		s += footerStyle.Render("Draws don't count in a tournament, press r to replay, q to quit") + "\n"
+	case m.tournament != nil:
+		s += footerStyle.Render("Press enter to return to the bracket, f to change font, q to quit") + "\n"
+	case m.hill != nil:
+		s += footerStyle.Render("Press enter to return to the hill, f to change font, q to quit") + "\n"
+	case m.practice.active:
+		s += footerStyle.Render(m.practiceKeys()) + "\n"
+	case m.puzzle.active:
+		s += footerStyle.Render(m.puzzleKeys()) + "\n"
+	case m.crowd:
+		s += footerStyle.Render("Press r to play the crowd again, v to watch it back, esc for the lobby, q to quit") + "\n"
+	case m.gameSession == nil:
+		// a local game, there's no queue to go back to or journal to watch
+		s += footerStyle.Render("Press r to play again, f to change font, q to quit") + "\n"
+	default:
+		s += m.preferSameView()
+		s += footerStyle.Render("Press r for a rematch, n for a new opponent, v to watch it back, esc for the lobby, q to quit") + "\n"
+	}
 	return s
 }
 
-func showOWinScreen() string {
-	s := "\n\n\n"
-	s += oStyle.Render(`
-  ░██████      ░██       ░██ ░██
- ░██   ░██     ░██       ░██
-░██     ░██    ░██  ░██  ░██ ░██░████████   ░███████
-░██     ░██    ░██ ░████ ░██ ░██░██    ░██ ░██
-░██     ░██    ░██░██ ░██░██ ░██░██    ░██  ░███████
- ░██   ░██     ░████   ░████ ░██░██    ░██        ░██
-  ░██████      ░███     ░███ ░██░██    ░██  ░█████
-`)
-	s += "\n\n"
-	s += footerStyle.Render("Press r to restart, q to quit\n")
+// summary sums up the finished game under the banner: how long it took,
+// what it did to our rating and the moves, for copying out
+func (m model) summary() string {
+	s := footerStyle.Render("Moves: ") + fmt.Sprint(m.moves)
+	if !m.gameStarted.IsZero() && m.gameEnded.After(m.gameStarted) {
+		s += footerStyle.Render("   Duration: ") + formatDuration(m.gameEnded.Sub(m.gameStarted))
+	}
+	if m.computer != Empty {
+		// the same seed makes the same choices from the same positions
+		s += footerStyle.Render("   Computer's seed: ") + fmt.Sprint(m.aiSeed)
+	}
+	if m.gameSession == nil {
+		return s
+	}
+	if !m.spectating && !m.casual {
+		s += footerStyle.Render("   Rating: ") + fmt.Sprintf("%+d", m.ratingChange)
+	}
+	for _, name := range m.unlocked {
+		s += "\n" + footerStyle.Render("Achievement unlocked: ") + headerStyle.Render(name)
+	}
+	if m.notation != "" {
+		s += "\n" + footerStyle.Render("Record: ") + m.notation
+	}
 	return s
 }
 
-func showDrawScreen() string {
-	s := "\n\n\n"
-	s += headerStyle.Render(`
-░███████                                         
-░██   ░██                                        
-░██    ░██ ░██░████  ░██████   ░██    ░██    ░██ 
-░██    ░██ ░███           ░██  ░██    ░██    ░██ 
-░██    ░██ ░██       ░███████   ░██  ░████  ░██  
-░██   ░██  ░██      ░██   ░██    ░██░██ ░██░██   
-░███████   ░██       ░█████░██    ░███   ░███    
-`)
-	s += "\n\n"
-	s += footerStyle.Render("It's a draw! Press r to restart, q to quit\n")
-	return s
+// measureLatency times a round trip to the client with a keepalive request.
+// Clients answer unknown requests with a failure, which is all we need.
+// Returns 0 if the client didn't answer in time.
+func measureLatency(s ssh.Session) time.Duration {
+	done := make(chan time.Duration, 1)
+	go func() {
+		start := time.Now()
+		if _, err := s.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+			done <- 0
+			return
+		}
+		done <- time.Since(start)
+	}()
+
+	select {
+	case rtt := <-done:
+		return rtt
+	case <-time.After(LatencyProbeTimeout):
+		return 0
+	}
+}
+
+// adaptTickInterval slows refreshes down on laggy links: redrawing faster than
+// the round trip just wastes bandwidth and causes flicker
+func adaptTickInterval(base, rtt time.Duration) time.Duration {
+	return min(max(base, 2*rtt), max(base, SlowestTickerInterval))
+}
+
+// requirePTY turns away sessions without a terminal (scripts, scp probes,
+// `ssh host` from a pipe) with a plain-text explanation instead of trying
+// to draw a TUI into a pipe
+func requirePTY(next ssh.Handler) ssh.Handler {
+	return func(s ssh.Session) {
+		if _, _, active := s.Pty(); !active {
+			wish.Println(s, "tictactui is a terminal game and needs an interactive terminal.")
+			wish.Println(s, "Connect with: "+connectCommand())
+			_ = s.Exit(1)
+			return
+		}
+		next(s)
+	}
 }
 
 // SSH handler - sets up multiplayer sessions
 func handleSSHSession(s ssh.Session) (tea.Model, []tea.ProgramOption) {
-	// Check for PTY allocation
-	_, _, active := s.Pty()
-	if !active {
-		// If no PTY, we can't run the TUI properly
-		return model{}, []tea.ProgramOption{}
+	// requirePTY has turned away sessions without a terminal
+	model := initialModel()
+	model.rules, model.board = enabledGames[0], enabledGames[0].newBoard()
+	model.playerName = holdDisplayName(s)
+	rtt := measureLatency(s)
+	model.tickInterval = adaptTickInterval(tickInterval, rtt)
+	if isSlowLink(rtt) {
+		// every byte counts, animations and live counters can go too
+		model.lowBandwidth = true
+		model.reducedMotion = true
+	}
+	model.playerID = playerID(s)
+	var profile PlayerRecord
+	profile, model.returning = playerDB.seen(model.playerID, model.playerName)
+	model.macros = profile.Macros
+	model.avatar = avatarOf(model.playerID)
+	model.theme = themeNamed(profile.Theme)
+	model.notifications = profile.notificationSettings()
+	model.privacy = profile.Privacy
+	model.alerts = s
+	model.done = s.Context().Done()
+	sessionManager.connect(model.done)
+
+	if sessionManager.tournamentSize > 0 {
+		// Sign them up, they wait in the bracket until their first match
+		t, err := sessionManager.enterTournament(model.player())
+		switch {
+		case errors.Is(err, matchmaking.ErrTournamentStarted):
+			// too late to play, but not to watch
+			model.tournament = t
+		case err != nil:
+			wish.Println(s, "Couldn't join the tournament:", strings.TrimPrefix(err.Error(), "matchmaking: "))
+			return nil, nil
+		default:
+			model.tournament = t
+			go func() {
+				<-s.Context().Done()
+				t.Withdraw(model.playerID)
+			}()
+		}
+	} else if session, seat, ok := sessionManager.resume(model.player()); ok {
+		// handed off from another device, straight back into the same seat
+		model.sitDown(session, seat)
+	} else if v, ok := visiting(s); ok {
+		// sent by a peer, straight into the queue for the game they were waiting for
+		model.rules, model.casual, model.visitor = rulesFor(v.game), v.casual, true
+		session, seat, err := sessionManager.join(model.player())
+		if err != nil {
+			wish.Errorln(s, "Couldn't join the queue:", strings.TrimPrefix(err.Error(), "matchmaking: "))
+			_ = s.Exit(1)
+			return nil, nil
+		}
+		model.sitDown(session, seat)
+	} else if token, ok := inviteToken(s.Command()); ok {
+		// invited, straight into their friend's room
+		session, seat, err := sessionManager.redeemInvite(token, model.player())
+		if err != nil {
+			wish.Println(s, "Couldn't join the game:", strings.TrimPrefix(err.Error(), "matchmaking: "))
+			return nil, nil
+		}
+		model.sitDown(session, seat)
+	} else if user := s.User(); sessionManager.rooms.Open(user) {
+		// `ssh K7QF@host`, straight into the room with that code
+		session, seat, err := sessionManager.joinRoom(user, model.player())
+		if err != nil {
+			wish.Println(s, "Couldn't join the game:", strings.TrimPrefix(err.Error(), "matchmaking: "))
+			return nil, nil
+		}
+		model.sitDown(session, seat)
+	} else if strings.EqualFold(user, UserSpectate) {
+		model.gameList = gameList{active: true}
+	} else if strings.EqualFold(user, UserLeaderboard) {
+		model.leaderboard = newLeaderboardScreen(model.rules, model.playerID)
+	} else if !model.returning && persistent(model.playerID) && playerDB != nil {
+		// a key user who's new here, the lobby's there once they've had
+		// the tutorial or skipped it
+		model = model.startTutorial()
+	} else {
+		// Let them pick how to find an opponent
+		model.lobby.active = true
 	}
 
-	model := initialModel()
+	opts := []tea.ProgramOption{
+		tea.WithInput(s),
+		tea.WithOutput(s),
+	}
+	if model.lowBandwidth {
+		opts = append(opts, tea.WithFPS(LowBandwidthFPS))
+	}
+	// `ssh -t host inline` renders in place so the final board stays in scrollback
+	if !slices.Contains(s.Command(), "inline") {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	return model, opts
+}
 
-	// Set up player based on session manager
-	sessionManager.mutex.Lock()
-	if sessionManager.waitingSession == nil {
-		// First player
-		model.playerSymbol = PlayerX
-		model.isMyTurn = true
-		model.waitingForPlayer = true
-
-		// Create new session
-		sessionManager.waitingSession = &GameSession{
-			Board:         createEmptyBoard(),
-			CurrentPlayer: 0,
-			PlayerCount:   1,
-		}
-		model.gameSession = sessionManager.waitingSession
-	} else {
-		// Second player or reconnecting player
-		model.playerSymbol = PlayerO
-		model.isMyTurn = false
-		model.waitingForPlayer = false
+// newProgram starts a session's game, reading its keys through a sessionInput
+// so they can be handed to a peer, see peerVisit. That input goes last: wish's
+// own would read the session too, and they'd take turns losing keys.
+func newProgram(s ssh.Session) *tea.Program {
+	m, opts := handleSSHSession(s)
+	if m == nil {
+		return nil
+	}
+	opts = append(bubbletea.MakeOptions(s), opts...)
+	return tea.NewProgram(m, append(opts, tea.WithInput(newSessionInput(s, s.Context().Done())))...)
+}
 
-		// Join existing session and reset disconnect state
-		sessionManager.waitingSession.PlayerCount = 2
-		sessionManager.waitingSession.PlayerDisconnected = false
-		sessionManager.waitingSession.RestartRequested = false
-		model.gameSession = sessionManager.waitingSession
-		// Don't set waitingSession to nil - keep it for future games
+// hostKeyPath is the server's SSH host key, set with -hostkey. It's made on
+// first start if it isn't there. Keep it out of the repository: anyone with
+// the key can pose as the server.
+var hostKeyPath = defaultHostKeyPath()
+
+// defaultHostKeyPath keeps the host key with the user's config, empty if
+// there's no config directory to keep it in
+func defaultHostKeyPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
 	}
-	sessionManager.mutex.Unlock()
+	return filepath.Join(dir, "tictactui", "host_ed25519")
+}
 
-	// Set up disconnect detection
-	go func() {
-		// Simple disconnect detection - if session ends, mark as disconnected
-		<-s.Context().Done()
-		if model.gameSession != nil {
-			model.gameSession.mutex.Lock()
-			model.gameSession.PlayerDisconnected = true
-			model.gameSession.mutex.Unlock()
+// SSHPort is the port the SSH server listens on
+const SSHPort = "2222"
+
+// runSSHServer serves the game over SSH until the server fails
+func runSSHServer() {
+	if hostKeyPath == "" {
+		log.Fatalln("no config directory to keep the host key in, set one with -hostkey")
+	}
+	server, err := wish.NewServer(
+		wish.WithAddress(":"+SSHPort),
+		wish.WithHostKeyPath(hostKeyPath),
+		wish.WithPublicKeyAuth(func(ctx ssh.Context, key ssh.PublicKey) bool {
+			return true // Allow all connections
+		}),
+		wish.WithPasswordAuth(func(ctx ssh.Context, password string) bool {
+			return true // Allow all connections
+		}),
+		wish.WithMiddleware(
+			bubbletea.MiddlewareWithProgramHandler(newProgram, termenv.Ascii),
+			requirePTY,
+			serveExport, // needs no terminal, it's for redirecting to a file
+			serveNames,
+			servePeers,
+		),
+		wish.WithSubsystem("sftp", serveSFTP),
+	)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Println("Starting SSH Tic-Tac-Toe server on :" + SSHPort)
+	fmt.Println("Players can connect with: " + connectCommand())
+	fmt.Println("Host key: " + hostKeyPath)
+
+	if backupEvery > 0 {
+		go scheduleBackups()
+	}
+
+	if newsPath != "" {
+		go watchNews()
+	}
+
+	if pushURL != "" || federating() {
+		if err := loadResultSigner(); err != nil {
+			log.Fatalf("reading the host key to sign results with: %v", err)
 		}
-	}()
+	}
+	if pushURL != "" {
+		fmt.Println("Pushing rated results to " + pushURL)
+	}
+	if federating() {
+		trustSelf()
+		fmt.Printf("Federated with %d peers\n", len(peers))
+	}
 
-	return model, []tea.ProgramOption{
-		tea.WithInput(s),
-		tea.WithOutput(s),
-		tea.WithAltScreen(),
+	if httpAddr != "" {
+		go serveHTTP(httpAddr, playerDB)
+	}
+
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalln(err)
 	}
 }
 
 func main() {
+	flag.StringVar(&journalDir, "journal", "", "directory to record a journal of each multiplayer game's events in (off if empty)")
+	flag.StringVar(&httpAddr, "http", "", "address to serve the web leaderboard on, like :8080, in the server modes (off if empty, needs -db)")
+	flag.StringVar(&dbPath, "db", "", "database file to remember key-authenticated players and their records in across restarts (off if empty)")
+	games := flag.String("games", strings.Join(gameNames(boardGames), ","), "games players can pick from on the server, comma separated, the first is the default")
+	flag.BoolVar(&swapRuleEnabled, "swap-rule", true, "let rooms be opened with the swap rule, in the games it suits")
+	flag.StringVar(&newsPath, "news", "", "text file of news for the lobby, up to 6 lines, read again whenever it changes (none if empty)")
+	flag.StringVar(&artDir, "art", "", "directory of custom title, win and draw art, one subdirectory per game (built-in art if empty)")
+	flag.DurationVar(&backupEvery, "backup-every", 0, "how often the server backs up its player database and journals, e.g. 24h (never if 0)")
+	flag.StringVar(&backupDir, "backup-dir", "", "directory scheduled backups go in, the last 7 are kept (off if empty)")
+	flag.StringVar(&backupS3, "backup-s3", "", "S3-compatible bucket URL backups are uploaded to as well, like https://s3.example.com/bucket/prefix, with keys from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (off if empty)")
+	flag.StringVar(&backupS3Region, "backup-s3-region", backupS3Region, "region backups uploaded to S3 are signed for")
+	flag.StringVar(&webhookURL, "webhook", "", "URL tournament events (created, round paired, match finished, champion, cancelled) are posted to as JSON, e.g. for a Discord bot (off if empty)")
+	flag.StringVar(&webhookSecret, "webhook-secret", "", "secret webhook posts are signed with, as an HMAC-SHA256 in X-Tictactui-Signature (unsigned if empty)")
+	flag.StringVar(&pushURL, "push-results", "", "URL of a central leaderboard's /api/results to push rated games to, signed with the host key (off if empty)")
+	flag.StringVar(&trustedServersPath, "trusted-servers", "", "authorized_keys file of the host keys whose pushed results the web leaderboard takes and adds up on /api/network (off if empty)")
+	flag.StringVar(&peersPath, "peers", "", "file of the servers to federate with, a `host:port ssh-ed25519 AAAA...` line each: players waiting for a quick match are matched across them, and their rated games add up on a combined leaderboard (off if empty)")
+	flag.StringVar(&hostKeyPath, "hostkey", hostKeyPath, "SSH host key file for the server, made if it doesn't exist")
+	flag.StringVar(&hostName, "host", hostName, "host name players reach the server at, for the commands in invitations")
+	flag.DurationVar(&acceptTimeout, "accept", AcceptTimeout, "how long quick match players have to accept a match before going back in the queue (0 starts matches straight away)")
+	flag.IntVar(&autoTournamentSize, "auto-tournament", 0, "number of players waiting in the quick match queue at once that calls an impromptu tournament, lobby players get a minute to sign up (off if 0)")
+	flag.DurationVar(&checkInTimeout, "check-in", CheckInTimeout, "how long tournament players have to check in for each bout before forfeiting it (0 starts bouts straight away)")
+	flag.IntVar(&ratingDecay, "rating-decay", 0, "rating points players lose for each week without a rated game once they've been away two weeks, down to the starting rating (off if 0)")
+	flag.DurationVar(&abortAfter, "abort-after", AbortAfter, "how long a quick match game can go without anybody moving before it's called off, with no result, and both players go back in the queue (never if 0)")
+	flag.DurationVar(&spectatorDelay, "spectator-delay", 0, "how far behind the game spectators watch, e.g. 30s, so nobody can relay it to a player as it happens (live if 0)")
+	flag.BoolVar(&mergeSpectatorChat, "merge-spectator-chat", false, "show spectators' chat, which players can't see during their game, to the players once it's over")
+	flag.DurationVar(&reconnectGrace, "reconnect", ReconnectGrace, "how long a key user who disconnects mid-game has to reconnect and carry on before the game ends")
+	flag.DurationVar(&shotClock, "move-clock", 0, "time limit for each move in multiplayer games, the player who runs out loses (off if 0)")
+	flag.DurationVar(&gameClock, "game-clock", 0, "time limit for all of a player's moves in a multiplayer game, the player who runs out loses (off if 0)")
+	flag.DurationVar(&slowLink, "slow-link", SlowLink, "round trip time from which players get plain, low bandwidth rendering (0 never switches)")
+	flag.DurationVar(&tickInterval, "tick", TickerInterval, "how often live counters like the thinking timer refresh (slower links refresh less often)")
+	fontName := flag.String("font", figlet.Default.Name, "font for win/draw banners ("+strings.Join(figlet.Names(), ", ")+")")
+	altScreen := flag.Bool("altscreen", false, "use the alternate screen in standalone mode instead of rendering inline")
+	players := flag.Int("players", matchmaking.MinTournamentSize, "number of players in a tournament, in tournament mode")
+	flag.Parse()
+
+	font, ok := figlet.Lookup(*fontName)
+	if !ok {
+		log.Fatalf("unknown font %q, pick one of: %s", *fontName, strings.Join(figlet.Names(), ", "))
+	}
+	bannerFont = font
+
+	if err := enableGames(*games); err != nil {
+		log.Fatalln(err)
+	}
+
+	if artDir != "" {
+		if err := loadArt(artDir); err != nil {
+			log.Fatalf("loading art: %v", err)
+		}
+	}
+	if newsPath != "" {
+		if err := loadNews(); err != nil {
+			log.Fatalf("reading the news: %v", err)
+		}
+	}
+
+	if httpAddr != "" && dbPath == "" {
+		log.Fatalln("the web leaderboard needs a player database, pass -db too")
+	}
+	if backupEvery > 0 && backupDir == "" && backupS3 == "" {
+		log.Fatalln("scheduled backups need somewhere to go, pass -backup-dir or -backup-s3")
+	}
+	if trustedServersPath != "" {
+		if httpAddr == "" {
+			log.Fatalln("taking results from other servers needs the web leaderboard, pass -http too")
+		}
+		if err := loadTrustedServers(trustedServersPath); err != nil {
+			log.Fatalf("reading trusted servers: %v", err)
+		}
+	}
+	if peersPath != "" {
+		if err := loadPeers(peersPath); err != nil {
+			log.Fatalf("reading peers: %v", err)
+		}
+	}
+	if dbPath != "" {
+		store, err := openPlayerStore(dbPath)
+		if err != nil {
+			log.Fatalf("opening player database: %v", err)
+		}
+		playerDB = store
+		sessionManager.queue.SetStore(store)
+	}
+	sessionManager.queue.SetAcceptTimeout(acceptTimeout)
+	sessionManager.queue.SetRatingDecay(ratingDecay)
+
 	// Check if we should run in SSH mode or standalone
-	if len(os.Args) > 1 && os.Args[1] == "ssh" {
+	if flag.Arg(0) == "ssh" {
 		// SSH server mode
-		server, err := wish.NewServer(
-			wish.WithAddress(":2222"),
-			wish.WithPublicKeyAuth(func(ctx ssh.Context, key ssh.PublicKey) bool {
-				return true // Allow all connections
-			}),
-			wish.WithPasswordAuth(func(ctx ssh.Context, password string) bool {
-				return true // Allow all connections
-			}),
-			wish.WithMiddleware(
-				bubbletea.Middleware(handleSSHSession),
-			),
-		)
+		runSSHServer()
+	} else if flag.Arg(0) == "replay" {
+		// Step through the games in a recorded journal
+		m, err := replayFile(flag.Arg(1))
 		if err != nil {
 			log.Fatalln(err)
 		}
-
-		fmt.Println("Starting SSH Tic-Tac-Toe server on :2222")
-		fmt.Println("Players can connect with: ssh -p 2222 localhost")
-		fmt.Println("Note: Using temporary host keys (more secure)")
-
-		if err := server.ListenAndServe(); err != nil {
+		var opts []tea.ProgramOption
+		if *altScreen {
+			opts = append(opts, tea.WithAltScreen())
+		}
+		if _, err := tea.NewProgram(m, opts...).Run(); err != nil {
+			fmt.Printf("Alas, there's been an error: %v", err)
+			os.Exit(1)
+		}
+	} else if flag.Arg(0) == "analytics" {
+		// Summarize the recorded game journals
+		if err := runAnalytics(os.Stdout); err != nil {
 			log.Fatalln(err)
 		}
-	} else if len(os.Args) > 1 && os.Args[1] == "matchmaking" {
-		// Matchmaking server mode - use EXACT same code as SSH mode
-		server, err := wish.NewServer(
-			wish.WithAddress(":2222"),
-			wish.WithPublicKeyAuth(func(ctx ssh.Context, key ssh.PublicKey) bool {
-				return true // Allow all connections
-			}),
-			wish.WithPasswordAuth(func(ctx ssh.Context, password string) bool {
-				return true // Allow all connections
-			}),
-			wish.WithMiddleware(
-				bubbletea.Middleware(handleSSHSession),
-			),
-		)
-		if err != nil {
+	} else if flag.Arg(0) == "delete" {
+		// Delete a player's data, for admins; the server has to be stopped
+		// as it keeps the database locked
+		if err := runDelete(flag.Arg(1)); err != nil {
 			log.Fatalln(err)
 		}
-
-		fmt.Println("Starting SSH Tic-Tac-Toe server on :2222")
-		fmt.Println("Players can connect with: ssh -p 2222 localhost")
-		fmt.Println("Note: Using temporary host keys (more secure)")
-
-		if err := server.ListenAndServe(); err != nil {
+	} else if flag.Arg(0) == "backup" {
+		// Back up the database and journals, for admins; the server has to
+		// be stopped as it keeps the database locked
+		if err := runBackup(flag.Arg(1)); err != nil {
+			log.Fatalln(err)
+		}
+	} else if flag.Arg(0) == "restore" {
+		// Put a backup back, over the database and journals there are
+		if err := runRestore(flag.Arg(1)); err != nil {
 			log.Fatalln(err)
 		}
+	} else if flag.Arg(0) == "matchmaking" {
+		// Matchmaking server mode - use EXACT same code as SSH mode
+		runSSHServer()
+	} else if flag.Arg(0) == "tournament" {
+		// Tournament server mode - players are signed up for a bracket instead of the lobby
+		if *players < matchmaking.MinTournamentSize {
+			log.Fatalf("a tournament needs at least %d players", matchmaking.MinTournamentSize)
+		}
+		sessionManager.tournamentSize = *players
+		runSSHServer()
 	} else {
 		// Standalone mode - original working version
-		p := tea.NewProgram(initialModel())
+		var opts []tea.ProgramOption
+		if *altScreen {
+			opts = append(opts, tea.WithAltScreen())
+		}
+		m := initialModel()
+		m.opponentMenu.active = true
+		p := tea.NewProgram(m, opts...)
 		if _, err := p.Run(); err != nil {
 			fmt.Printf("Alas, there's been an error: %v", err)
 			os.Exit(1)
@@ -0,0 +1,191 @@
+package matchmaking
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// SetAcceptTimeout makes players the queue pairs accept their match within
+// d before it's confirmed. If either doesn't the match falls through: a
+// player who accepted goes back to their place in the queue, one who didn't
+// gets a strike for dodging it (see Penalize) and goes to the end of the
+// queue, unless that put them on a cooldown. 0, the default, confirms
+// matches straight away.
+func (q *Queue) SetAcceptTimeout(d time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.acceptTimeout = d
+}
+
+// pair fills a waiting match with p, giving the players until the accept
+// timeout to accept it. Call with q locked.
+func (q *Queue) pair(m *Match, p Player) {
+	if q.acceptTimeout > 0 {
+		m.mutex.Lock()
+		m.acceptBy = time.Now().Add(q.acceptTimeout)
+		m.mutex.Unlock()
+		time.AfterFunc(q.acceptTimeout, func() {
+			// nobody's waiting on this to hand the error back to
+			if err := q.fallThrough(m, ""); err != nil {
+				log.Printf("matchmaking: match %s: %v", m.ID, err)
+			}
+		})
+	}
+	m.fill(p)
+}
+
+// AcceptBy is when both players have to have accepted the match by. It's
+// zero if they don't need to.
+func (m *Match) AcceptBy() time.Time {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.acceptBy
+}
+
+// Accept confirms a player wants to play the match. Once both have,
+// Confirmed is closed.
+func (m *Match) Accept(playerID string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	seat := m.seat(playerID)
+	if seat < 0 || m.settled() {
+		return
+	}
+	m.accepted[seat] = true
+	if m.accepted[0] && m.accepted[1] {
+		close(m.confirmed)
+	}
+}
+
+// HasAccepted reports whether a player accepted the match
+func (m *Match) HasAccepted(playerID string) bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	seat := m.seat(playerID)
+	return seat >= 0 && m.accepted[seat]
+}
+
+// Confirmed is closed once the match is ready and both players accepted
+// it, or straight away when they didn't need to
+func (m *Match) Confirmed() <-chan struct{} {
+	return m.confirmed
+}
+
+// IsConfirmed reports whether the match is confirmed, see Confirmed
+func (m *Match) IsConfirmed() bool {
+	select {
+	case <-m.confirmed:
+		return true
+	default:
+		return false
+	}
+}
+
+// FellThrough is closed if a player declined the match or it wasn't
+// accepted in time. See Requeued for where its players went.
+func (m *Match) FellThrough() <-chan struct{} {
+	return m.fellThrough
+}
+
+// Requeued returns the match a player was put back in the queue in once
+// the match fell through or was aborted, nil if they weren't: they declined
+// it, or dodging it put them on a cooldown
+func (m *Match) Requeued(playerID string) *Match {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if seat := m.seat(playerID); seat >= 0 {
+		return m.requeued[seat]
+	}
+	return nil
+}
+
+// settled reports whether the match has been confirmed or fell through.
+// Call with m locked.
+func (m *Match) settled() bool {
+	select {
+	case <-m.confirmed:
+		return true
+	case <-m.fellThrough:
+		return true
+	default:
+		return false
+	}
+}
+
+// giveUp makes a match that hasn't been confirmed fall through, without
+// putting its players back in any queue. It returns which players accepted
+// it, and false if it was already settled.
+func (m *Match) giveUp() ([2]bool, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.settled() {
+		return m.accepted, false
+	}
+	close(m.fellThrough)
+	return m.accepted, true
+}
+
+// Decline gives up on a match that hasn't been confirmed yet, e.g. because
+// the player disconnected, and gives them a strike for dodging it. It does
+// nothing once the match is confirmed.
+func (q *Queue) Decline(m *Match, playerID string) error {
+	return q.fallThrough(m, playerID)
+}
+
+// Abort calls off a match nobody played in, without a result or strikes.
+// Both players go back to their places in the queue, if they aren't
+// cooling down, and the two aren't paired again. See Requeued for where
+// they went.
+func (q *Queue) Abort(m *Match) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for seat, p := range m.Players {
+		if m.requeued[seat] != nil || time.Now().Before(q.penaltiesOf(p.ID).CooldownUntil) {
+			continue
+		}
+		m.requeued[seat] = q.enqueue(p, m.created, m.Players[1-seat].ID)
+	}
+}
+
+// fallThrough gives up on a match that wasn't accepted. The player who
+// declined it leaves, a player who accepted goes back to their place in the
+// queue and one who didn't goes to the end, if they aren't cooling down.
+// The two aren't paired again. Players who didn't accept get a strike.
+func (q *Queue) fallThrough(m *Match, declinedID string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.acceptBy.IsZero() || m.settled() {
+		return nil
+	}
+
+	// players who accepted go first, they could be paired again straight away
+	seats := []int{0, 1}
+	if m.accepted[1] && !m.accepted[0] {
+		seats = []int{1, 0}
+	}
+	var errs []error
+	for _, seat := range seats {
+		p := m.Players[seat]
+		if p.ID == declinedID || !m.accepted[seat] {
+			_, err := q.penalize(p.ID, Dodged)
+			errs = append(errs, err)
+		}
+		if p.ID == declinedID || time.Now().Before(q.penaltiesOf(p.ID).CooldownUntil) {
+			continue
+		}
+		created := time.Now()
+		if m.accepted[seat] {
+			created = m.created
+		}
+		m.requeued[seat] = q.enqueue(p, created, m.Players[1-seat].ID)
+	}
+	close(m.fellThrough)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,109 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccept(t *testing.T) {
+	tests := []struct {
+		name         string
+		accept       [2]bool // whether a and b accept
+		decline      bool    // b declines instead of letting it time out
+		wantRequeued [2]bool
+		wantDodged   [2]int
+	}{
+		{"both accept", [2]bool{true, true}, false, [2]bool{}, [2]int{}},
+		{"one times out", [2]bool{true, false}, false, [2]bool{true, true}, [2]int{0, 1}},
+		{"neither accepts", [2]bool{false, false}, false, [2]bool{true, true}, [2]int{1, 1}},
+		{"one declines", [2]bool{true, false}, true, [2]bool{true, false}, [2]int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue()
+			q.SetAcceptTimeout(20 * time.Millisecond)
+			m, err := q.Join(Player{ID: "a"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := q.Join(Player{ID: "b"}); err != nil {
+				t.Fatal(err)
+			}
+			if !m.IsReady() || m.IsConfirmed() {
+				t.Fatalf("ready %v, confirmed %v, want a match waiting to be accepted", m.IsReady(), m.IsConfirmed())
+			}
+			for seat, accept := range tt.accept {
+				if accept {
+					m.Accept(m.Players[seat].ID)
+				}
+			}
+			if tt.decline {
+				if err := q.Decline(m, "b"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tt.accept == [2]bool{true, true} {
+				if !m.IsConfirmed() {
+					t.Fatal("both accepted but the match isn't confirmed")
+				}
+			} else {
+				select {
+				case <-m.FellThrough():
+				case <-time.After(time.Second):
+					t.Fatal("the match didn't fall through")
+				}
+			}
+			for seat, id := range []string{"a", "b"} {
+				if requeued := m.Requeued(id) != nil; requeued != tt.wantRequeued[seat] {
+					t.Errorf("%s requeued = %v, want %v", id, requeued, tt.wantRequeued[seat])
+				}
+				if got := q.Record(id, "").Dodged; got != tt.wantDodged[seat] {
+					t.Errorf("%s dodged %d, want %d", id, got, tt.wantDodged[seat])
+				}
+			}
+		})
+	}
+}
+
+func TestAbort(t *testing.T) {
+	q := NewQueue()
+	m, err := q.Join(Player{ID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Join(Player{ID: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	// b is cooling down after abandoning another game in the meantime
+	if _, err := q.Penalize("b", Abandoned); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Penalize("b", Abandoned); err != nil {
+		t.Fatal(err)
+	}
+	q.Abort(m)
+
+	if m.Requeued("a") == nil {
+		t.Error("a wasn't put back in the queue")
+	}
+	if m.Requeued("b") != nil {
+		t.Error("b was put back in the queue while cooling down")
+	}
+	if r := q.Record("a", ""); r.Strikes != 0 || r.Played() != 0 {
+		t.Errorf("a's record = %+v, want no strikes or games", r)
+	}
+
+	// a is paired with whoever joins next
+	q.Abort(m) // aborting twice doesn't queue a twice
+	if q.Waiting() != 1 {
+		t.Errorf("Waiting() = %d, want 1", q.Waiting())
+	}
+	next, err := q.Join(Player{ID: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != m.Requeued("a") || !next.IsReady() {
+		t.Error("a wasn't paired with c")
+	}
+}
@@ -0,0 +1,196 @@
+package matchmaking
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrOnHill is returned when a player who's already on the hill joins again
+var ErrOnHill = errors.New("matchmaking: player is already on the hill")
+
+// HallOfFameSize is how many of the longest reigns a Hill remembers
+const HallOfFameSize = 5
+
+// Hill is king of the hill: the first player to join holds the hill, and
+// everyone after them lines up to challenge whoever holds it, one at a time.
+// The winner of each bout stays on and the loser leaves the hill; the king
+// keeps the hill on a draw. The longest reigns go in the hall of fame.
+type Hill struct {
+	king    Player   // empty ID while nobody holds the hill
+	streak  int      // bouts the king has won in a row
+	line    []Player // challengers, next first
+	bout    *Match   // the king against the next challenger, nil between bouts
+	hall    []Reign  // longest first
+	changed chan struct{}
+	mutex   sync.Mutex
+}
+
+// Reign is how long a king held the hill
+type Reign struct {
+	King   Player
+	Streak int       // bouts won
+	Ended  time.Time // when they lost the hill or left it
+}
+
+// NewHill opens an empty hill
+func NewHill() *Hill {
+	return &Hill{changed: make(chan struct{})}
+}
+
+// Changed is closed the next time anything on the hill changes, call it
+// again afterwards to keep watching
+func (h *Hill) Changed() <-chan struct{} {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.changed
+}
+
+// Join puts a player on the hill: they hold it if nobody does, otherwise
+// they join the end of the line of challengers
+func (h *Hill) Join(p Player) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.on(p.ID) {
+		return ErrOnHill
+	}
+	if h.king.ID == "" {
+		h.king = p
+	} else {
+		h.line = append(h.line, p)
+	}
+	h.update()
+	return nil
+}
+
+// Leave takes a player off the hill. A challenger leaves the line, a king
+// hands the hill to the next in line, and a player leaving in the middle of
+// a bout loses it.
+func (h *Hill) Leave(playerID string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.bout != nil && h.bout.seat(playerID) >= 0 {
+		h.settle(h.bout.Opponent(playerID).ID)
+	}
+	if h.king.ID == playerID {
+		h.crown(Player{})
+	}
+	h.line = slices.DeleteFunc(h.line, func(c Player) bool { return c.ID == playerID })
+	h.update()
+}
+
+// Report records the result of a bout, winnerID is empty for a draw. It
+// does nothing for a match that isn't the bout under way.
+func (h *Hill) Report(m *Match, winnerID string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if m != h.bout {
+		return nil // settled already, one of them left
+	}
+	if winnerID != "" && m.seat(winnerID) < 0 {
+		return ErrNotInMatch
+	}
+	h.settle(winnerID)
+	h.update()
+	return nil
+}
+
+// settle ends the bout under way, the king keeps the hill unless the
+// challenger won. Call with h locked.
+func (h *Hill) settle(winnerID string) {
+	challenger := h.bout.Opponent(h.king.ID)
+	h.bout = nil
+	switch winnerID {
+	case h.king.ID:
+		h.streak++
+	case challenger.ID:
+		h.crown(challenger)
+		h.streak = 1
+	}
+}
+
+// crown hands the hill to a new king, or to nobody, and puts the old
+// king's reign in the hall of fame if it's one of the longest. Call with h
+// locked.
+func (h *Hill) crown(p Player) {
+	if h.king.ID != "" && h.streak > 0 {
+		h.hall = append(h.hall, Reign{King: h.king, Streak: h.streak, Ended: time.Now()})
+		slices.SortStableFunc(h.hall, func(a, b Reign) int { return cmp.Compare(b.Streak, a.Streak) })
+		h.hall = h.hall[:min(len(h.hall), HallOfFameSize)]
+	}
+	h.king, h.streak = p, 0
+}
+
+// update hands an empty hill to the next in line and starts the next bout
+// if there's a challenger, then lets everyone watching know. Call with h
+// locked.
+func (h *Hill) update() {
+	if h.king.ID == "" && len(h.line) > 0 {
+		h.king, h.line = h.line[0], h.line[1:]
+	}
+	if h.bout == nil && h.king.ID != "" && len(h.line) > 0 {
+		h.bout = newMatch(newMatchID(), h.king)
+		h.bout.fill(h.line[0])
+		h.line = h.line[1:]
+	}
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// NextMatch returns the bout a player's in, if they're in the one under way
+func (h *Hill) NextMatch(playerID string) (*Match, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.bout == nil || h.bout.seat(playerID) < 0 {
+		return nil, false
+	}
+	return h.bout, true
+}
+
+// King returns who holds the hill and how many bouts they've won in a row,
+// false if nobody does
+func (h *Hill) King() (Player, int, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.king, h.streak, h.king.ID != ""
+}
+
+// Challenger returns who's playing the king right now, false between bouts
+func (h *Hill) Challenger() (Player, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.bout == nil {
+		return Player{}, false
+	}
+	return h.bout.Opponent(h.king.ID), true
+}
+
+// Line returns the challengers waiting their turn, next first
+func (h *Hill) Line() []Player {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return slices.Clone(h.line)
+}
+
+// On reports whether a player is on the hill: holding it, playing for it
+// or in line
+func (h *Hill) On(playerID string) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.on(playerID)
+}
+
+// on is On. Call with h locked.
+func (h *Hill) on(playerID string) bool {
+	return h.king.ID == playerID || (h.bout != nil && h.bout.seat(playerID) >= 0) ||
+		slices.ContainsFunc(h.line, func(c Player) bool { return c.ID == playerID })
+}
+
+// HallOfFame returns the longest reigns, longest first
+func (h *Hill) HallOfFame() []Reign {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return slices.Clone(h.hall)
+}
@@ -0,0 +1,81 @@
+package matchmaking
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHill(t *testing.T) {
+	a, b, c := Player{ID: "a"}, Player{ID: "b"}, Player{ID: "c"}
+	tests := []struct {
+		name       string
+		play       func(t *testing.T, h *Hill) // after a, b and c joined in that order
+		wantKing   string
+		wantStreak int
+		wantLine   []string // challengers after whoever's playing the king
+		wantHall   []int    // streaks in the hall of fame
+	}{
+		{"nothing played", func(t *testing.T, h *Hill) {}, "a", 0, []string{"c"}, nil},
+		{"king wins", func(t *testing.T, h *Hill) {
+			report(t, h, "a", "a")
+		}, "a", 1, nil, nil},
+		{"king keeps it on a draw", func(t *testing.T, h *Hill) {
+			report(t, h, "a", "")
+		}, "a", 0, nil, nil},
+		{"challenger wins", func(t *testing.T, h *Hill) {
+			report(t, h, "a", "a")
+			report(t, h, "a", "c")
+		}, "c", 1, nil, []int{1}},
+		{"king leaves mid-bout", func(t *testing.T, h *Hill) {
+			h.Leave("a")
+		}, "b", 1, nil, nil},
+		{"challenger leaves the line", func(t *testing.T, h *Hill) {
+			h.Leave("c")
+		}, "a", 0, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHill()
+			for _, p := range []Player{a, b, c} {
+				if err := h.Join(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := h.Join(b); err != ErrOnHill {
+				t.Errorf("joining again: err = %v, want %v", err, ErrOnHill)
+			}
+			tt.play(t, h)
+
+			king, streak, ok := h.King()
+			if !ok || king.ID != tt.wantKing || streak != tt.wantStreak {
+				t.Errorf("King() = %s, %d, %v, want %s, %d", king.ID, streak, ok, tt.wantKing, tt.wantStreak)
+			}
+			var line []string
+			for _, p := range h.Line() {
+				line = append(line, p.ID)
+			}
+			if !slices.Equal(line, tt.wantLine) {
+				t.Errorf("Line() = %v, want %v", line, tt.wantLine)
+			}
+			var hall []int
+			for _, r := range h.HallOfFame() {
+				hall = append(hall, r.Streak)
+			}
+			if !slices.Equal(hall, tt.wantHall) {
+				t.Errorf("HallOfFame() streaks = %v, want %v", hall, tt.wantHall)
+			}
+		})
+	}
+}
+
+// report ends the bout under way on the hill, which playerID is in
+func report(t *testing.T, h *Hill, playerID, winnerID string) {
+	t.Helper()
+	m, ok := h.NextMatch(playerID)
+	if !ok {
+		t.Fatalf("%s isn't playing", playerID)
+	}
+	if err := h.Report(m, winnerID); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package matchmaking
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNoSuchInvite is returned when an invitation token is unknown, was
+// already used or has expired
+var ErrNoSuchInvite = errors.New("matchmaking: that invitation is used up or has expired")
+
+// tokenAlphabet is codeAlphabet in lower case, tokens go in commands
+const tokenAlphabet = "acdefghjkmnpqrtuvwxyz234679"
+
+// TokenLength is the number of characters in an invitation token. Unlike a
+// join code nobody types it in, so it can be long enough not to be guessed.
+const TokenLength = 10
+
+// invite lets whoever holds its token into a room, once
+type invite struct {
+	code    string // the room's
+	expires time.Time
+}
+
+// Invite makes a single-use token that seats whoever redeems it in the room
+// with the given code, until ttl is up. It replaces the room's earlier
+// invitation, if any.
+func (r *Rooms) Invite(code string, ttl time.Duration) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expire()
+
+	if _, ok := r.rooms[code]; !ok {
+		return "", ErrNoSuchRoom
+	}
+	r.revoke(code)
+	token := newToken()
+	for _, taken := r.invites[token]; taken; _, taken = r.invites[token] {
+		token = newToken()
+	}
+	r.invites[token] = invite{code: code, expires: time.Now().Add(ttl)}
+	return token, nil
+}
+
+// Redeem seats p in the room an invitation token is for, and uses it up
+func (r *Rooms) Redeem(token string, p Player) (*Match, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expire()
+
+	inv, ok := r.invites[strings.ToLower(strings.TrimSpace(token))]
+	if !ok {
+		return nil, ErrNoSuchInvite
+	}
+	m, err := r.join(inv.code, p)
+	if err != nil {
+		return nil, err
+	}
+	r.revoke(inv.code)
+	return m, nil
+}
+
+// revoke drops the invitation to a room. Call with r locked.
+func (r *Rooms) revoke(code string) {
+	for token, inv := range r.invites {
+		if inv.code == code {
+			delete(r.invites, token)
+		}
+	}
+}
+
+// newToken makes an invitation token, like "k7qfmx3ad9"
+func newToken() string {
+	b := make([]byte, TokenLength)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = tokenAlphabet[int(b[i])%len(tokenAlphabet)]
+	}
+	return string(b)
+}
@@ -0,0 +1,76 @@
+package matchmaking
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInvite(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration // how long the invitation's good for, 0 for a minute
+		// redeem does whatever happens between inviting and bob redeeming
+		// the invitation, and returns the token bob redeems
+		redeem  func(t *testing.T, r *Rooms, code, token string) string
+		wantErr error
+	}{
+		{"redeemed", 0, func(t *testing.T, r *Rooms, code, token string) string { return token }, nil},
+		{"typed in upper case", 0, func(t *testing.T, r *Rooms, code, token string) string {
+			return " " + strings.ToUpper(token) + " "
+		}, nil},
+		{"unknown", 0, func(t *testing.T, r *Rooms, code, token string) string { return "aaaaaaaaaa" }, ErrNoSuchInvite},
+		{"expired", 10 * time.Millisecond, func(t *testing.T, r *Rooms, code, token string) string {
+			time.Sleep(30 * time.Millisecond)
+			return token
+		}, ErrNoSuchInvite},
+		{"replaced by a new one", 0, func(t *testing.T, r *Rooms, code, token string) string {
+			if _, err := r.Invite(code, time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}, ErrNoSuchInvite},
+		{"room closed", 0, func(t *testing.T, r *Rooms, code, token string) string {
+			r.Close(code)
+			return token
+		}, ErrNoSuchInvite},
+		{"already used", 0, func(t *testing.T, r *Rooms, code, token string) string {
+			if _, err := r.Redeem(token, Player{ID: "carol"}); err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}, ErrNoSuchInvite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRooms(time.Minute)
+			m := r.Create(Player{ID: "alice"})
+			t.Cleanup(func() { Release(m.ID) })
+			ttl := tt.ttl
+			if ttl == 0 {
+				ttl = time.Minute
+			}
+			token, err := r.Invite(m.ID, ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(token) != TokenLength {
+				t.Errorf("token %q is %d characters, want %d", token, len(token), TokenLength)
+			}
+
+			got, err := r.Redeem(tt.redeem(t, r, m.ID, token), Player{ID: "bob"})
+			if err != tt.wantErr {
+				t.Fatalf("Redeem() err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (got != m || got.Players[1].ID != "bob") {
+				t.Errorf("bob wasn't seated in alice's room")
+			}
+		})
+	}
+}
+
+func TestInviteNoSuchRoom(t *testing.T) {
+	if _, err := NewRooms(time.Minute).Invite("ZZZZ", time.Minute); err != ErrNoSuchRoom {
+		t.Errorf("err = %v, want %v", err, ErrNoSuchRoom)
+	}
+}
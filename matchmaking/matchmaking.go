@@ -0,0 +1,645 @@
+// Package matchmaking pairs up players who want a game and keeps track of how
+// they do. It knows nothing about the game being played or how players
+// connect, so any multiplayer TUI can sit on top of it.
+//
+// A player joins the Queue and gets back a Match. If somebody was already
+// waiting the match is ready straight away, otherwise it becomes ready when
+// the next player joins. Players who'd rather pick their opponent open a room
+// in Rooms instead and hand its join code to a friend. Once a game is over
+// its result is reported back to the Queue, which keeps a win/loss/draw
+// record and an Elo rating per player and game, and pairs players of similar
+// rating when it can. A Queue can also ask paired players to accept their match
+// before it starts, see Queue.SetAcceptTimeout, and keeps players who dodge
+// matches or abandon games out for a while, see Queue.Penalize.
+package matchmaking
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrAlreadyQueued is returned when a player who is already waiting joins again
+	ErrAlreadyQueued = errors.New("matchmaking: player is already in the queue")
+
+	// ErrNotInMatch is returned when a result names a player who wasn't in the match
+	ErrNotInMatch = errors.New("matchmaking: player is not in this match")
+)
+
+// Player is anyone who can be matched. ID must be unique and stable for as
+// long as results should be tracked against it, Name is only for display.
+type Player struct {
+	ID   string
+	Name string
+	Game string // what they want to play, the queue only pairs players who want the same game
+
+	// Casual players want a game that doesn't count towards their rating.
+	// The queue only pairs casual players with each other, and keeps their
+	// results apart from their rated ones.
+	Casual bool
+}
+
+// wantsSame reports whether two players want the same kind of game
+func wantsSame(a, b Player) bool {
+	return a.Game == b.Game && a.Casual == b.Casual
+}
+
+// Match is a pairing of two players
+type Match struct {
+	ID string
+
+	// Players[0] is the player who waited, Players[1] the one who joined
+	// them. Players[1] is empty until the match is ready.
+	Players [2]Player
+
+	created  time.Time // when the first player started waiting
+	avoid    string    // a player the first player mustn't be paired with, see fallThrough
+	ready    chan struct{}
+	waited   chan struct{} // closed once the first player stops waiting in the queue, or in their room
+	movedTo  *Match        // where the queue moved the first player, if it did
+	released bool          // its ID was let go of already, see Released
+
+	// accepting a match the queue paired, see accept.go
+	acceptBy    time.Time     // zero if the players needn't accept
+	accepted    [2]bool       // indexed like Players
+	confirmed   chan struct{} // closed once both players accepted
+	fellThrough chan struct{} // closed if they didn't, in time
+	requeued    [2]*Match     // where each player went back in the queue after that
+
+	mutex sync.RWMutex
+}
+
+// newMatch starts a match with one player waiting in it
+func newMatch(id string, p Player) *Match {
+	return &Match{
+		ID:          id,
+		Players:     [2]Player{p},
+		created:     time.Now(),
+		ready:       make(chan struct{}),
+		waited:      make(chan struct{}),
+		confirmed:   make(chan struct{}),
+		fellThrough: make(chan struct{}),
+	}
+}
+
+// fill seats the second player, making the match ready. Unless the players
+// have to accept it first, it's confirmed too.
+func (m *Match) fill(p Player) {
+	m.mutex.Lock()
+	m.Players[1] = p
+	if m.acceptBy.IsZero() {
+		close(m.confirmed)
+	}
+	m.mutex.Unlock()
+	close(m.ready)
+	close(m.waited)
+}
+
+// Versus starts a match between two players straight away, outside the
+// queue and rooms, e.g. against a player who isn't a person
+func Versus(a, b Player) *Match {
+	m := newMatch(newMatchID(), a)
+	m.fill(b)
+	return m
+}
+
+// moveTo gives up on the match, its player has been paired in another one.
+// Nobody will play it, so its ID is free for a new match straight away.
+func (m *Match) moveTo(other *Match) {
+	m.mutex.Lock()
+	m.movedTo = other
+	m.mutex.Unlock()
+	m.release()
+	close(m.waited)
+}
+
+// release frees the match's ID for a new match, before its player has left
+func (m *Match) release() {
+	m.mutex.Lock()
+	m.released = true
+	m.mutex.Unlock()
+	Release(m.ID)
+}
+
+// Released reports whether the match's ID was freed while its player was
+// still in it, because the queue moved them or their room expired. The ID
+// may be a new match's by now, so it mustn't be released again.
+func (m *Match) Released() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.released
+}
+
+// Waited is closed once the player waiting in a match stops waiting: the
+// match filled, they left the queue or closed their room, the queue moved
+// them to a match with a closer rated opponent (see MovedTo), or their room
+// expired (see Released)
+func (m *Match) Waited() <-chan struct{} {
+	return m.waited
+}
+
+// MovedTo returns the match the queue moved this match's player to, once
+// Waited is closed. It's nil if they weren't moved.
+func (m *Match) MovedTo() *Match {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.movedTo
+}
+
+// Ready is closed once both players are in the match
+func (m *Match) Ready() <-chan struct{} {
+	return m.ready
+}
+
+// IsReady reports whether both players are in the match
+func (m *Match) IsReady() bool {
+	select {
+	case <-m.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// Seat returns which of Players the given player is (0 or 1), or -1 if
+// they're not in the match
+func (m *Match) Seat(playerID string) int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.seat(playerID)
+}
+
+// seat is Seat. Call with m locked.
+func (m *Match) seat(playerID string) int {
+	for i, p := range m.Players {
+		if p.ID == playerID && p.ID != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// Opponent returns the other player in the match
+func (m *Match) Opponent(playerID string) Player {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if m.Players[0].ID == playerID {
+		return m.Players[1]
+	}
+	return m.Players[0]
+}
+
+// Results are a player's results in one game so far. Each game has its own
+// rating, a strong checkers player can be new to Connect Four.
+type Results struct {
+	Wins   int
+	Losses int
+	Draws  int
+	Rating int // Elo rating, InitialRating until they've played
+
+	LastPlayed time.Time // when they last played a rated game, their rating decays once they've been away a while, see Queue.SetRatingDecay
+
+	// casual games, kept apart from the rated ones above
+	CasualWins   int
+	CasualLosses int
+	CasualDraws  int
+}
+
+// Penalties are what a player's been penalized for dodging matches and
+// abandoning games, see Queue.Penalize. They hold whatever game they play.
+type Penalties struct {
+	Dodged        int       // matches declined or not accepted in time
+	Abandoned     int       // games left before they were over
+	Strikes       int       // offences in a row, each makes the next cooldown longer
+	LastStrike    time.Time // strikes are forgiven StrikeMemory after this
+	CooldownUntil time.Time // barred from the queue until then
+}
+
+// Record is a player's results in one game, and their penalties
+type Record struct {
+	Results
+	Penalties
+}
+
+// Played is the number of rated games the results cover
+func (r Results) Played() int {
+	return r.Wins + r.Losses + r.Draws
+}
+
+// Placed reports whether the player's played their placement matches, so
+// their rating's public
+func (r Results) Placed() bool {
+	return r.Played() >= PlacementMatches
+}
+
+// PlayedCasual is the number of casual games the results cover
+func (r Results) PlayedCasual() int {
+	return r.CasualWins + r.CasualLosses + r.CasualDraws
+}
+
+// RecordStore keeps records somewhere that outlives the Queue, like a
+// database, so players keep their results across server restarts
+type RecordStore interface {
+	// LoadResults returns a player's stored results in a game, false if
+	// there are none
+	LoadResults(playerID, game string) (Results, bool)
+
+	// SaveResults stores a player's updated results in a game
+	SaveResults(playerID, game string, r Results) error
+
+	// LoadPenalties returns a player's stored penalties, false if there
+	// are none
+	LoadPenalties(playerID string) (Penalties, bool)
+
+	// SavePenalties stores a player's updated penalties
+	SavePenalties(playerID string, p Penalties) error
+}
+
+// resultsKey is whose results, in which game
+type resultsKey struct {
+	playerID string
+	game     string
+}
+
+// Queue pairs players with the closest rated player waiting, as long as
+// they're within the waiting player's rating window. Players outside every
+// window wait too, and as windows widen with time waiting players get paired
+// with each other: the newer one is moved into the older one's match.
+type Queue struct {
+	waiting       []*Match // matches with one player, oldest first
+	results       map[resultsKey]Results
+	penalties     map[string]Penalties
+	store         RecordStore           // nil keeps records in memory only
+	acceptTimeout time.Duration         // how long paired players have to accept, 0 if they needn't
+	pairing       *time.Timer           // runs pairWaiting, nil until someone's had to wait
+	decay         int                   // rating points lost per week away, see SetRatingDecay
+	prefer        map[string]preference // players who'd like to play the same opponent again, see PreferSame
+	mutex         sync.Mutex
+}
+
+// NewQueue creates an empty queue
+func NewQueue() *Queue {
+	return &Queue{results: map[resultsKey]Results{}, penalties: map[string]Penalties{}}
+}
+
+// SetStore makes the queue load records from and save them to store
+func (q *Queue) SetStore(store RecordStore) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.store = store
+}
+
+// Join adds a player to the queue. If someone is already waiting the two are
+// paired and the returned match is ready, otherwise the player waits in a new
+// match that becomes ready when the next player joins.
+func (q *Queue) Join(p Player) (*Match, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, m := range q.waiting {
+		if m.Players[0].ID == p.ID {
+			return nil, ErrAlreadyQueued
+		}
+	}
+	if time.Now().Before(q.penaltiesOf(p.ID).CooldownUntil) {
+		return nil, ErrCoolingDown
+	}
+	return q.enqueue(p, time.Now(), ""), nil
+}
+
+// enqueue pairs p with the closest rated waiting player whose window they're
+// in, or has them wait in a new match as if they'd been waiting since
+// created. They're never paired with the player avoid. Call with q locked.
+func (q *Queue) enqueue(p Player, created time.Time, avoid string) *Match {
+	now := time.Now()
+	rating := q.rating(p.ID, p.Game)
+	partner, _, held := q.partner(p.ID, now)
+	best, bestGap := -1, 0
+	for i, m := range q.waiting {
+		if m.Players[0].ID == avoid || m.avoid == p.ID || !wantsSame(m.Players[0], p) {
+			continue
+		}
+		if _, _, taken := q.partner(m.Players[0].ID, now); held || taken {
+			// kept for a rematch, with each other or nobody
+			if held && m.Players[0].ID == partner {
+				best = i
+				break
+			}
+			continue
+		}
+		g := gap(rating, q.rating(m.Players[0].ID, p.Game))
+		if g <= window(m, now) && (best < 0 || g < bestGap) {
+			best, bestGap = i, g
+		}
+	}
+	if best >= 0 {
+		m := q.waiting[best]
+		q.waiting = slices.Delete(q.waiting, best, best+1)
+		if held {
+			q.rematched(p.ID, partner)
+		}
+		q.pair(m, p)
+		return m
+	}
+
+	m := newMatch(newMatchID(), p)
+	m.created, m.avoid = created, avoid
+	// keep the queue oldest first, players who waited before keep their place
+	i := slices.IndexFunc(q.waiting, func(w *Match) bool { return w.created.After(created) })
+	if i < 0 {
+		i = len(q.waiting)
+	}
+	q.waiting = slices.Insert(q.waiting, i, m)
+	q.schedulePairing(now)
+	return m
+}
+
+// pairWaiting pairs up waiting players whose ratings have come within the
+// older one's window, moving the newer one into the older one's match
+func (q *Queue) pairWaiting() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(q.waiting); i++ {
+		older := q.waiting[i]
+		rating := q.rating(older.Players[0].ID, older.Players[0].Game)
+		best, bestGap := -1, 0
+		for j := i + 1; j < len(q.waiting); j++ {
+			if avoids(older, q.waiting[j]) || q.heldFor(older, q.waiting[j], now) > 0 {
+				continue
+			}
+			g := gap(rating, q.rating(q.waiting[j].Players[0].ID, older.Players[0].Game))
+			if g <= window(older, now) && (best < 0 || g < bestGap) {
+				best, bestGap = j, g
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		newer := q.waiting[best]
+		q.waiting = slices.Delete(q.waiting, best, best+1)
+		q.waiting = slices.Delete(q.waiting, i, i+1)
+		i--
+		q.pair(older, newer.Players[0])
+		newer.moveTo(older)
+	}
+	q.schedulePairing(now)
+}
+
+// schedulePairing runs pairWaiting once the next two waiting players come
+// within range of each other, rescheduling rather than adding to any run
+// already due. Call with q locked.
+func (q *Queue) schedulePairing(now time.Time) {
+	var next time.Duration
+	found := false
+	for i, older := range q.waiting {
+		rating := q.rating(older.Players[0].ID, older.Players[0].Game)
+		for _, newer := range q.waiting[i+1:] {
+			if avoids(older, newer) {
+				continue
+			}
+			wait := untilWithin(older, gap(rating, q.rating(newer.Players[0].ID, older.Players[0].Game)), now)
+			// players kept for a rematch are free again once its window closes
+			wait = max(wait, q.heldFor(older, newer, now))
+			if !found || wait < next {
+				next, found = wait, true
+			}
+		}
+	}
+	switch {
+	case !found:
+		if q.pairing != nil {
+			q.pairing.Stop()
+		}
+	case q.pairing == nil:
+		q.pairing = time.AfterFunc(next, q.pairWaiting)
+	default:
+		q.pairing.Reset(next)
+	}
+}
+
+// avoids reports whether the players waiting in two matches mustn't be
+// paired: one is avoiding the other, or they want different games
+func avoids(a, b *Match) bool {
+	return a.avoid == b.Players[0].ID || b.avoid == a.Players[0].ID || !wantsSame(a.Players[0], b.Players[0])
+}
+
+// Leave takes a player who is still waiting out of the queue, e.g. because
+// they disconnected. It does nothing if they've already been matched.
+func (q *Queue) Leave(p Player) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for i, m := range q.waiting {
+		if m.Players[0].ID == p.ID {
+			q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+			close(m.waited)
+			return
+		}
+	}
+}
+
+// Waiting is the number of players waiting for an opponent
+func (q *Queue) Waiting() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.waiting)
+}
+
+// WaitingFor is the number of players waiting for an opponent in a game
+func (q *Queue) WaitingFor(game string) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	n := 0
+	for _, m := range q.waiting {
+		if m.Players[0].Game == game {
+			n++
+		}
+	}
+	return n
+}
+
+// Report records the result of a game played in m. winnerID is the winning
+// player's ID, or empty for a draw. A match can host several games (rematches),
+// each is reported on its own. It returns how much the game moved each
+// player's rating in the game they played, in the order they're in
+// m.Players. Casual games don't move ratings, they only count towards the
+// players' casual results.
+func (q *Queue) Report(m *Match, winnerID string) ([2]int, error) {
+	m.mutex.RLock()
+	players := m.Players
+	m.mutex.RUnlock()
+
+	if winnerID != "" && winnerID != players[0].ID && winnerID != players[1].ID {
+		return [2]int{}, ErrNotInMatch
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	game := players[0].Game
+	ratings := [2]int{q.rating(players[0].ID, game), q.rating(players[1].ID, game)}
+	var changes [2]int
+	var errs []error
+	for i, p := range players {
+		if p.ID == "" {
+			continue // match never filled
+		}
+		r := q.resultsOf(p.ID, game)
+		if p.Casual {
+			switch winnerID {
+			case "":
+				r.CasualDraws++
+			case p.ID:
+				r.CasualWins++
+			default:
+				r.CasualLosses++
+			}
+			errs = append(errs, q.saveResults(p.ID, game, r))
+			continue
+		}
+		k := r.k() // a placement match moves it further, even the last one
+		score := 0.5
+		switch winnerID {
+		case "":
+			r.Draws++
+		case p.ID:
+			r.Wins++
+			score = 1
+		default:
+			r.Losses++
+			score = 0
+		}
+		changes[i] = ratingChange(ratings[i], ratings[1-i], k, score)
+		r.Rating = ratings[i] + changes[i] // any decay's kept from here on
+		r.LastPlayed = time.Now()
+		errs = append(errs, q.saveResults(p.ID, game, r))
+	}
+	return changes, errors.Join(errs...)
+}
+
+// Record returns a player's results in a game so far, with their rating as
+// it's decayed, and their penalties
+func (q *Queue) Record(playerID, game string) Record {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	r := Record{Results: q.resultsOf(playerID, game), Penalties: q.penaltiesOf(playerID)}
+	r.Rating = q.rating(playerID, game)
+	return r
+}
+
+// Forget drops a player's results in every game from memory, for players
+// who've asked to be deleted; delete them from the store first or they'll
+// be loaded again. A cooldown still running is kept, deleting isn't a way
+// out of one.
+func (q *Queue) Forget(playerID string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for k := range q.results {
+		if k.playerID == playerID {
+			delete(q.results, k)
+		}
+	}
+	cooldown := q.penalties[playerID].CooldownUntil
+	delete(q.penalties, playerID)
+	if time.Now().Before(cooldown) {
+		q.penalties[playerID] = Penalties{CooldownUntil: cooldown}
+	}
+}
+
+// resultsOf looks a player's results in a game up in memory, then in the
+// store. Call with q locked.
+func (q *Queue) resultsOf(playerID, game string) Results {
+	k := resultsKey{playerID, game}
+	r, ok := q.results[k]
+	if !ok && q.store != nil {
+		r, _ = q.store.LoadResults(playerID, game)
+		q.results[k] = r
+	}
+	if r.Rating == 0 {
+		// new, or stored before there were ratings
+		r.Rating = InitialRating
+	}
+	return r
+}
+
+// penaltiesOf looks a player's penalties up in memory, then in the store.
+// Call with q locked.
+func (q *Queue) penaltiesOf(playerID string) Penalties {
+	p, ok := q.penalties[playerID]
+	if !ok && q.store != nil {
+		p, _ = q.store.LoadPenalties(playerID)
+		q.penalties[playerID] = p
+	}
+	return p
+}
+
+// rating is a player's rating in a game as it's decayed, the one they're
+// paired and rated by. Call with q locked.
+func (q *Queue) rating(playerID, game string) int {
+	r := q.resultsOf(playerID, game)
+	return Decayed(r.Rating, r.LastPlayed, time.Now(), q.decay)
+}
+
+// saveResults keeps a player's updated results in a game, in memory and in
+// the store. Call with q locked.
+func (q *Queue) saveResults(playerID, game string, r Results) error {
+	q.results[resultsKey{playerID, game}] = r
+	if q.store == nil {
+		return nil
+	}
+	return q.store.SaveResults(playerID, game, r)
+}
+
+// savePenalties keeps a player's updated penalties, in memory and in the
+// store. Call with q locked.
+func (q *Queue) savePenalties(playerID string, p Penalties) error {
+	q.penalties[playerID] = p
+	if q.store == nil {
+		return nil
+	}
+	return q.store.SavePenalties(playerID, p)
+}
+
+// newMatchID makes a short id players can quote ("game 3f9a2c ended wrong"),
+// one that isn't in use
+func newMatchID() string {
+	return claimID(func() string {
+		b := make([]byte, 3)
+		_, _ = rand.Read(b)
+		return hex.EncodeToString(b)
+	})
+}
+
+// liveIDs are the match IDs and room codes in use. They're short, so a new
+// one could easily repeat one still in use, and mix two games up.
+var liveIDs = struct {
+	ids   map[string]bool
+	mutex sync.Mutex
+}{ids: map[string]bool{}}
+
+// claimID makes IDs with next until it makes one that isn't in use, and
+// claims it
+func claimID(next func() string) string {
+	liveIDs.mutex.Lock()
+	defer liveIDs.mutex.Unlock()
+	id := next()
+	for liveIDs.ids[id] {
+		id = next()
+	}
+	liveIDs.ids[id] = true
+	return id
+}
+
+// Release frees a match's ID, or a room's code, for a new match to use.
+// Call it once the game is over and nothing refers to the match by its ID
+// any more.
+func Release(id string) {
+	liveIDs.mutex.Lock()
+	defer liveIDs.mutex.Unlock()
+	delete(liveIDs.ids, id)
+}
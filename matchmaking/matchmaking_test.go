@@ -0,0 +1,173 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWantsSame(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Player
+		want bool
+	}{
+		{"same game, rated", Player{Game: "tic-tac-toe"}, Player{Game: "tic-tac-toe"}, true},
+		{"same game, casual", Player{Game: "checkers", Casual: true}, Player{Game: "checkers", Casual: true}, true},
+		{"different games", Player{Game: "tic-tac-toe"}, Player{Game: "connect-four"}, false},
+		{"casual and rated", Player{Game: "tic-tac-toe", Casual: true}, Player{Game: "tic-tac-toe"}, false},
+		{"no game picked", Player{}, Player{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wantsSame(tt.a, tt.b); got != tt.want {
+				t.Errorf("wantsSame(%+v, %+v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := wantsSame(tt.b, tt.a); got != tt.want {
+				t.Errorf("wantsSame(%+v, %+v) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoin(t *testing.T) {
+	tests := []struct {
+		name       string
+		first      Player
+		second     Player
+		ratings    [2]int // 0 is InitialRating
+		wantPaired bool
+	}{
+		{"same game", Player{ID: "a", Game: "tic-tac-toe"}, Player{ID: "b", Game: "tic-tac-toe"}, [2]int{}, true},
+		{"different games", Player{ID: "a", Game: "tic-tac-toe"}, Player{ID: "b", Game: "checkers"}, [2]int{}, false},
+		{"casual and rated", Player{ID: "a", Casual: true}, Player{ID: "b"}, [2]int{}, false},
+		{"both casual", Player{ID: "a", Casual: true}, Player{ID: "b", Casual: true}, [2]int{}, true},
+		{"ratings inside the window", Player{ID: "a"}, Player{ID: "b"}, [2]int{1250, 1250 + RatingWindow}, true},
+		{"ratings outside the window", Player{ID: "a"}, Player{ID: "b"}, [2]int{1250, 1251 + RatingWindow}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue()
+			for i, id := range []string{tt.first.ID, tt.second.ID} {
+				if tt.ratings[i] != 0 {
+					q.results[resultsKey{id, ""}] = Results{Rating: tt.ratings[i]}
+				}
+			}
+			first, err := q.Join(tt.first)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := q.Join(tt.second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if paired := second == first && first.IsReady(); paired != tt.wantPaired {
+				t.Fatalf("paired = %v, want %v", paired, tt.wantPaired)
+			}
+			if tt.wantPaired && first.Players != [2]Player{tt.first, tt.second} {
+				t.Errorf("Players = %+v, want %+v then %+v", first.Players, tt.first, tt.second)
+			}
+			want := 2 // both still waiting
+			if tt.wantPaired {
+				want = 0
+			}
+			if q.Waiting() != want {
+				t.Errorf("Waiting() = %d, want %d", q.Waiting(), want)
+			}
+		})
+	}
+}
+
+func TestJoinTwice(t *testing.T) {
+	q := NewQueue()
+	if _, err := q.Join(Player{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Join(Player{ID: "a"}); err != ErrAlreadyQueued {
+		t.Errorf("joining again: err = %v, want %v", err, ErrAlreadyQueued)
+	}
+}
+
+func TestPreferSame(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefer  [2]bool // whether a and b, who just played, want each other again
+		wantFor string  // who a, back in the queue first, is paired with
+	}{
+		{"both want a rematch", [2]bool{true, true}, "b"},
+		{"only one does", [2]bool{true, false}, "c"},
+		{"neither does", [2]bool{false, false}, "c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue()
+			a, b, c := Player{ID: "a"}, Player{ID: "b"}, Player{ID: "c"}
+			played := Versus(a, b)
+			q.PreferSame(played, "a", tt.prefer[0])
+			q.PreferSame(played, "b", tt.prefer[1])
+
+			m, err := q.Join(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// c joins before b, and only gets a if a isn't kept for b
+			if _, err := q.Join(c); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := q.Join(b); err != nil {
+				t.Fatal(err)
+			}
+			if !m.IsReady() {
+				t.Fatal("a wasn't paired")
+			}
+			if got := m.Players[1].ID; got != tt.wantFor {
+				t.Errorf("a was paired with %s, want %s", got, tt.wantFor)
+			}
+		})
+	}
+}
+
+func TestReportNotInMatch(t *testing.T) {
+	q := NewQueue()
+	m := Versus(Player{ID: "a"}, Player{ID: "b"})
+	if _, err := q.Report(m, "c"); err != ErrNotInMatch {
+		t.Errorf("err = %v, want %v", err, ErrNotInMatch)
+	}
+	if _, err := q.Report(m, ""); err != nil {
+		t.Errorf("reporting a draw: %v", err)
+	}
+}
+
+func TestMoveReleases(t *testing.T) {
+	q := NewQueue()
+	q.results[resultsKey{"a", ""}] = Results{Rating: 1250}
+	q.results[resultsKey{"b", ""}] = Results{Rating: 1251 + RatingWindow}
+	older, err := q.Join(Player{ID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newer, err := q.Join(Player{ID: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Release(older.ID) })
+
+	// a's window has had time to widen
+	q.mutex.Lock()
+	older.created = older.created.Add(-time.Minute)
+	q.mutex.Unlock()
+	q.pairWaiting()
+
+	<-newer.Waited()
+	if newer.MovedTo() != older || !newer.Released() {
+		t.Fatalf("b's match moved to %v, released %v, want a's and released", newer.MovedTo(), newer.Released())
+	}
+	if older.Released() {
+		t.Error("a's match was released, they're still playing it")
+	}
+	liveIDs.mutex.Lock()
+	defer liveIDs.mutex.Unlock()
+	if liveIDs.ids[newer.ID] {
+		t.Errorf("the moved match's ID %s is still in use", newer.ID)
+	}
+}
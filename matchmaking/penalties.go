@@ -0,0 +1,66 @@
+package matchmaking
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrCoolingDown is returned when a player who's been penalized joins the
+// queue before their cooldown is over, see Queue.Cooldown
+var ErrCoolingDown = errors.New("matchmaking: player is cooling down after dodging or abandoning games")
+
+// Offence is something that earns a player a strike
+type Offence int
+
+const (
+	// Dodged is declining a match, or not accepting it in time
+	Dodged Offence = iota
+
+	// Abandoned is leaving a game before it's over and not coming back
+	Abandoned
+)
+
+// PenaltyCooldowns is how long each strike in a row keeps a player out of
+// the queue. The first is just a warning, the last applies from then on.
+var PenaltyCooldowns = []time.Duration{0, time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour}
+
+// StrikeMemory is how long a player has to go without an offence for their
+// strikes to be forgiven, so the next cooldown starts from the bottom again
+const StrikeMemory = 24 * time.Hour
+
+// Penalize gives a player a strike for an offence and bars them from the
+// queue for a cooldown that grows with every strike. It returns the
+// cooldown.
+func (q *Queue) Penalize(playerID string, o Offence) (time.Duration, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.penalize(playerID, o)
+}
+
+// penalize is Penalize. Call with q locked.
+func (q *Queue) penalize(playerID string, o Offence) (time.Duration, error) {
+	r := q.penaltiesOf(playerID)
+	now := time.Now()
+	if now.Sub(r.LastStrike) > StrikeMemory {
+		r.Strikes = 0
+	}
+	r.Strikes++
+	r.LastStrike = now
+	switch o {
+	case Dodged:
+		r.Dodged++
+	case Abandoned:
+		r.Abandoned++
+	}
+	cooldown := PenaltyCooldowns[min(r.Strikes, len(PenaltyCooldowns))-1]
+	r.CooldownUntil = now.Add(cooldown)
+	return cooldown, q.savePenalties(playerID, r)
+}
+
+// Cooldown is how much longer a player is barred from the queue, 0 if
+// they're free to join
+func (q *Queue) Cooldown(playerID string) time.Duration {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return max(time.Until(q.penaltiesOf(playerID).CooldownUntil), 0)
+}
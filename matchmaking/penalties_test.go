@@ -0,0 +1,48 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPenalize(t *testing.T) {
+	q := NewQueue()
+	for strike, want := range PenaltyCooldowns {
+		got, err := q.Penalize("a", Abandoned)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("strike %d: cooldown %v, want %v", strike+1, got, want)
+		}
+	}
+	// the last cooldown applies from then on
+	if got, _ := q.Penalize("a", Dodged); got != PenaltyCooldowns[len(PenaltyCooldowns)-1] {
+		t.Errorf("strike %d: cooldown %v, want %v", len(PenaltyCooldowns)+1, got, PenaltyCooldowns[len(PenaltyCooldowns)-1])
+	}
+	r := q.Record("a", "")
+	if r.Abandoned != len(PenaltyCooldowns) || r.Dodged != 1 {
+		t.Errorf("abandoned %d and dodged %d, want %d and 1", r.Abandoned, r.Dodged, len(PenaltyCooldowns))
+	}
+
+	if q.Cooldown("a") <= 0 {
+		t.Error("no cooldown after the last strike")
+	}
+	if _, err := q.Join(Player{ID: "a"}); err != ErrCoolingDown {
+		t.Errorf("joining while cooling down: err = %v, want %v", err, ErrCoolingDown)
+	}
+
+	// a day later all is forgiven
+	r.LastStrike = time.Now().Add(-StrikeMemory - time.Minute)
+	r.CooldownUntil = time.Time{}
+	q.penalties["a"] = r.Penalties
+	if q.Cooldown("a") != 0 {
+		t.Errorf("Cooldown() = %v once it's over, want 0", q.Cooldown("a"))
+	}
+	if got, _ := q.Penalize("a", Dodged); got != PenaltyCooldowns[0] {
+		t.Errorf("first strike after a clean day: cooldown %v, want %v", got, PenaltyCooldowns[0])
+	}
+	if _, err := q.Join(Player{ID: "a"}); err != nil {
+		t.Errorf("joining after a warning: %v", err)
+	}
+}
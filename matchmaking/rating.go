@@ -0,0 +1,117 @@
+package matchmaking
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// InitialRating is every player's rating before their first game
+	InitialRating = 1200
+
+	// RatingK is how far a single game can move a rating
+	RatingK = 32
+
+	// PlacementMatches is how many rated games a new player plays before
+	// their rating's public. Until then it's provisional and moves by up to
+	// PlacementK a game, to find their level quickly.
+	PlacementMatches = 5
+	PlacementK       = 2 * RatingK
+
+	// RatingWindow is how far apart two players' ratings can be for the
+	// queue to pair them straight away
+	RatingWindow = 100
+
+	// RatingWindowGrowth widens the window for every second a player has
+	// been waiting, so nobody waits forever for a close match
+	RatingWindowGrowth = 10
+
+	// RatingDecayGrace is how long a player can go without a rated game
+	// before their rating starts to decay, see Queue.SetRatingDecay
+	RatingDecayGrace = 2 * week
+)
+
+const week = 7 * 24 * time.Hour
+
+// SetRatingDecay makes players who haven't played a rated game for longer
+// than RatingDecayGrace lose perWeek rating points for every week after
+// that, down to InitialRating, so the top of the leaderboard is players
+// who still play. Their rating's back to decaying from scratch once they
+// play again. 0, the default, turns decay off.
+func (q *Queue) SetRatingDecay(perWeek int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.decay = perWeek
+}
+
+// Decayed is a rating last played for at lastPlayed as it's decayed by now,
+// losing perWeek points a week once RatingDecayGrace is up. Ratings don't
+// decay below InitialRating, nor do ones from before there was decay.
+func Decayed(rating int, lastPlayed, now time.Time, perWeek int) int {
+	away := now.Sub(lastPlayed) - RatingDecayGrace
+	if perWeek <= 0 || lastPlayed.IsZero() || away < week || rating <= InitialRating {
+		return rating
+	}
+	return max(rating-int(away/week)*perWeek, InitialRating)
+}
+
+// expected is the score (1 for a win, 0.5 for a draw, 0 for a loss) a
+// player rated r is expected to get against one rated opponent
+func expected(r, opponent int) float64 {
+	return 1 / (1 + math.Pow(10, float64(opponent-r)/400))
+}
+
+// ratingChange is how much a player rated r gains (or loses, if negative)
+// by scoring score against one rated opponent, at most k
+func ratingChange(r, opponent, k int, score float64) int {
+	return int(math.Round(float64(k) * (score - expected(r, opponent))))
+}
+
+// k is how far a player's next game can move their rating
+func (r Results) k() int {
+	if !r.Placed() {
+		return PlacementK
+	}
+	return RatingK
+}
+
+// Stakes is what a game means for a player's rating: how much they'd gain
+// by winning and drawing, and lose by losing
+type Stakes struct {
+	Win, Draw, Loss int
+}
+
+// Stakes works out what's at stake for a player in a game of game against
+// opponent
+func (q *Queue) Stakes(playerID, opponentID, game string) Stakes {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	r, rating, opponent := q.resultsOf(playerID, game), q.rating(playerID, game), q.rating(opponentID, game)
+	return Stakes{
+		Win:  ratingChange(rating, opponent, r.k(), 1),
+		Draw: ratingChange(rating, opponent, r.k(), 0.5),
+		Loss: ratingChange(rating, opponent, r.k(), 0),
+	}
+}
+
+// window is how far from a waiting match's player's rating an opponent
+// can be, given how long they've waited
+func window(m *Match, now time.Time) int {
+	return RatingWindow + int(now.Sub(m.created).Seconds())*RatingWindowGrowth
+}
+
+// untilWithin is how long a match has to wait before its window covers a
+// rating gap of g
+func untilWithin(m *Match, g int, now time.Time) time.Duration {
+	// whole seconds, window only widens once a second
+	seconds := math.Ceil(float64(g-RatingWindow) / RatingWindowGrowth)
+	return max(m.created.Add(time.Duration(seconds)*time.Second).Sub(now), 0)
+}
+
+// gap is how far apart two ratings are
+func gap(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,185 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecayed(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		rating  int
+		away    time.Duration // since they last played
+		perWeek int
+		want    int
+	}{
+		{"decay off", 1500, 10 * week, 0, 1500},
+		{"never played", 1500, 0, 10, 1500},
+		{"inside the grace", 1500, RatingDecayGrace, 10, 1500},
+		{"less than a week past the grace", 1500, RatingDecayGrace + week - time.Second, 10, 1500},
+		{"a week past the grace", 1500, RatingDecayGrace + week, 10, 1490},
+		{"three and a half weeks past", 1500, RatingDecayGrace + 3*week + 3*24*time.Hour, 10, 1470},
+		{"down to the initial rating", 1250, RatingDecayGrace + 10*week, 10, InitialRating},
+		{"at the initial rating", InitialRating, RatingDecayGrace + 10*week, 10, InitialRating},
+		{"below the initial rating", 1100, RatingDecayGrace + 10*week, 10, 1100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lastPlayed := now.Add(-tt.away)
+			if tt.away == 0 {
+				lastPlayed = time.Time{}
+			}
+			if got := Decayed(tt.rating, lastPlayed, now, tt.perWeek); got != tt.want {
+				t.Errorf("Decayed(%d, %v ago, %d a week) = %d, want %d", tt.rating, tt.away, tt.perWeek, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatingChange(t *testing.T) {
+	tests := []struct {
+		name             string
+		rating, opponent int
+		k                int
+		score            float64
+		want             int
+	}{
+		{"even win", 1200, 1200, RatingK, 1, 16},
+		{"even draw", 1200, 1200, RatingK, 0.5, 0},
+		{"even loss", 1200, 1200, RatingK, 0, -16},
+		{"placement win", 1200, 1200, PlacementK, 1, 32},
+		{"upset win", 1200, 1600, RatingK, 1, 29},
+		{"expected win", 1600, 1200, RatingK, 1, 3},
+		{"draw with a stronger player", 1200, 1600, RatingK, 0.5, 13},
+		{"loss to a weaker player", 1600, 1200, RatingK, 0, -29},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ratingChange(tt.rating, tt.opponent, tt.k, tt.score); got != tt.want {
+				t.Errorf("ratingChange(%d, %d, %d, %v) = %d, want %d", tt.rating, tt.opponent, tt.k, tt.score, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultsK(t *testing.T) {
+	tests := []struct {
+		name    string
+		results Results
+		want    int
+	}{
+		{"new", Results{}, PlacementK},
+		{"one placement match to go", Results{Wins: 2, Losses: 1, Draws: 1}, PlacementK},
+		{"placed", Results{Wins: 3, Losses: 2}, RatingK},
+		{"casual games don't place", Results{CasualWins: 10}, PlacementK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.results.k(); got != tt.want {
+				t.Errorf("k() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		records     [2]Results // a's and b's before the game
+		casual      bool
+		winner      string
+		wantChanges [2]int
+		wantRecords [2]Results // without LastPlayed
+	}{
+		{
+			name:        "placement win",
+			winner:      "a",
+			wantChanges: [2]int{32, -32},
+			wantRecords: [2]Results{{Wins: 1, Rating: 1232}, {Losses: 1, Rating: 1168}},
+		},
+		{
+			name:        "placed draw",
+			records:     [2]Results{{Wins: 5, Rating: 1300}, {Wins: 5, Rating: 1300}},
+			wantChanges: [2]int{0, 0},
+			wantRecords: [2]Results{{Wins: 5, Draws: 1, Rating: 1300}, {Wins: 5, Draws: 1, Rating: 1300}},
+		},
+		{
+			name:        "the last placement match still moves it further",
+			records:     [2]Results{{Wins: 4, Rating: 1400}, {Wins: 5, Rating: 1400}},
+			winner:      "b",
+			wantChanges: [2]int{-32, 16},
+			wantRecords: [2]Results{{Wins: 4, Losses: 1, Rating: 1368}, {Wins: 6, Rating: 1416}},
+		},
+		{
+			name:        "casual",
+			casual:      true,
+			winner:      "b",
+			wantRecords: [2]Results{{CasualLosses: 1, Rating: InitialRating}, {CasualWins: 1, Rating: InitialRating}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := NewQueue()
+			ids := [2]string{"a", "b"}
+			for i, id := range ids {
+				if tt.records[i] != (Results{}) {
+					q.results[resultsKey{id, ""}] = tt.records[i]
+				}
+			}
+			m := Versus(Player{ID: "a", Casual: tt.casual}, Player{ID: "b", Casual: tt.casual})
+			changes, err := q.Report(m, tt.winner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changes != tt.wantChanges {
+				t.Errorf("changes = %v, want %v", changes, tt.wantChanges)
+			}
+			for i, id := range ids {
+				got := q.Record(id, "").Results
+				// only rated games hold off decay
+				if played := !got.LastPlayed.IsZero(); played == tt.casual {
+					t.Errorf("%s: LastPlayed set = %v, want %v", id, played, !tt.casual)
+				}
+				got.LastPlayed = time.Time{}
+				if got != tt.wantRecords[i] {
+					t.Errorf("%s: record = %+v, want %+v", id, got, tt.wantRecords[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRatingsPerGame(t *testing.T) {
+	q := NewQueue()
+	m := Versus(Player{ID: "a", Game: "checkers"}, Player{ID: "b", Game: "checkers"})
+	if _, err := q.Report(m, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if r := q.Record("a", "checkers"); r.Wins != 1 || r.Rating <= InitialRating {
+		t.Errorf("a's checkers record = %+v, want a win and a rating above %d", r.Results, InitialRating)
+	}
+	if r := q.Record("a", "connect-four"); r.Played() != 0 || r.Rating != InitialRating {
+		t.Errorf("a's connect-four record = %+v, want a fresh one", r.Results)
+	}
+
+	// penalties hold in every game
+	if _, err := q.Penalize("b", Abandoned); err != nil {
+		t.Fatal(err)
+	}
+	if q.Record("b", "connect-four").Abandoned != 1 {
+		t.Error("b's abandoned checkers game isn't on their connect-four record")
+	}
+
+	for _, p := range []Player{{ID: "c", Game: "checkers"}, {ID: "d", Game: "checkers"}, {ID: "e", Game: "connect-four"}} {
+		if _, err := q.Join(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := q.WaitingFor("checkers"); got != 0 {
+		t.Errorf("WaitingFor(checkers) = %d, want 0, c and d were paired", got)
+	}
+	if got := q.WaitingFor("connect-four"); got != 1 {
+		t.Errorf("WaitingFor(connect-four) = %d, want 1", got)
+	}
+}
@@ -0,0 +1,85 @@
+package matchmaking
+
+import "time"
+
+// RematchWindow is how long two players who'd both like to play each other
+// again have to get back in the queue. While it's open whichever of them
+// is waiting is kept for the other, after it they're paired like anyone.
+const RematchWindow = time.Minute
+
+// preference is a player's wish to be paired with the same opponent again
+type preference struct {
+	opponent string
+	until    time.Time // when the window closes
+}
+
+// PreferSame sets whether a player in m would like to play the same
+// opponent again. If both do, the next time they're both in the queue within
+// RematchWindow of this they're paired with each other, whatever their
+// ratings, and nobody else gets either of them in the meantime.
+func (q *Queue) PreferSame(m *Match, playerID string, prefer bool) {
+	m.mutex.RLock()
+	seat := m.seat(playerID)
+	players := m.Players
+	m.mutex.RUnlock()
+	if seat < 0 {
+		return
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	now := time.Now()
+	for id, p := range q.prefer {
+		if !now.Before(p.until) {
+			delete(q.prefer, id)
+		}
+	}
+	if !prefer {
+		delete(q.prefer, playerID)
+	} else {
+		if q.prefer == nil {
+			q.prefer = map[string]preference{}
+		}
+		q.prefer[playerID] = preference{opponent: players[1-seat].ID, until: now.Add(RematchWindow)}
+	}
+	// a waiting player may have just been spoken for, or let go
+	q.schedulePairing(now)
+}
+
+// partner returns who a player is kept for, if they and another player
+// both want to play each other again and neither window has closed. Call
+// with q locked.
+func (q *Queue) partner(playerID string, now time.Time) (string, time.Time, bool) {
+	p, ok := q.prefer[playerID]
+	if !ok || !now.Before(p.until) {
+		return "", time.Time{}, false
+	}
+	back, ok := q.prefer[p.opponent]
+	if !ok || back.opponent != playerID || !now.Before(back.until) {
+		return "", time.Time{}, false
+	}
+	if back.until.Before(p.until) {
+		return p.opponent, back.until, true
+	}
+	return p.opponent, p.until, true
+}
+
+// heldFor is how long the players waiting in two matches are kept apart
+// because one of them is waiting for a rematch with someone else, 0 if
+// they aren't. Call with q locked.
+func (q *Queue) heldFor(a, b *Match, now time.Time) time.Duration {
+	var held time.Duration
+	for _, m := range []*Match{a, b} {
+		if _, until, ok := q.partner(m.Players[0].ID, now); ok {
+			held = max(held, until.Sub(now))
+		}
+	}
+	return held
+}
+
+// rematched forgets two players' wish to play each other again, now that
+// they have. Call with q locked.
+func (q *Queue) rematched(a, b string) {
+	delete(q.prefer, a)
+	delete(q.prefer, b)
+}
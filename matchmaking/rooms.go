@@ -0,0 +1,140 @@
+package matchmaking
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoSuchRoom is returned when a join code doesn't match an open room
+	ErrNoSuchRoom = errors.New("matchmaking: no room with that code")
+
+	// ErrRoomFull is returned when someone already took the seat in a room
+	ErrRoomFull = errors.New("matchmaking: room is full")
+)
+
+// codeAlphabet leaves out characters that are easy to mix up when a code is
+// read out loud or copied by hand (0/O, 1/I/L, 5/S, 8/B)
+const codeAlphabet = "ACDEFGHJKMNPQRTUVWXYZ234679"
+
+// CodeLength is the number of characters in a join code
+const CodeLength = 4
+
+// Rooms lets a player open a private match and invite a friend into it with
+// a short join code, instead of playing whoever the Queue pairs them with.
+// The code doubles as the match ID.
+type Rooms struct {
+	ttl     time.Duration
+	rooms   map[string]room   // by code
+	invites map[string]invite // by token, see Invite
+	mutex   sync.Mutex
+}
+
+type room struct {
+	match   *Match
+	created time.Time
+}
+
+// NewRooms creates an empty room registry. Rooms nobody joins within ttl
+// expire and their codes are free for new rooms, rooms that were joined keep
+// their code until they're closed.
+func NewRooms(ttl time.Duration) *Rooms {
+	return &Rooms{ttl: ttl, rooms: map[string]room{}, invites: map[string]invite{}}
+}
+
+// Create opens a room with p waiting in it. The match becomes ready when
+// someone joins with its ID as the code.
+func (r *Rooms) Create(p Player) *Match {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expire()
+
+	code := claimID(newJoinCode)
+	m := newMatch(code, p)
+	r.rooms[code] = room{match: m, created: time.Now()}
+	return m
+}
+
+// Join seats p in the room with the given code. Codes are case-insensitive
+// and surrounding spaces are ignored.
+func (r *Rooms) Join(code string, p Player) (*Match, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expire()
+	return r.join(strings.ToUpper(strings.TrimSpace(code)), p)
+}
+
+// Open reports whether there's a room waiting for someone to join with
+// code. Codes are case-insensitive and surrounding spaces are ignored.
+func (r *Rooms) Open(code string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.expire()
+	rm, ok := r.rooms[strings.ToUpper(strings.TrimSpace(code))]
+	return ok && !rm.match.IsReady()
+}
+
+// join is Join, for a code that's been cleaned up. Call with r locked.
+func (r *Rooms) join(code string, p Player) (*Match, error) {
+	rm, ok := r.rooms[code]
+	if !ok {
+		return nil, ErrNoSuchRoom
+	}
+	if rm.match.IsReady() {
+		return nil, ErrRoomFull
+	}
+	if rm.match.Players[0].ID == p.ID {
+		return nil, ErrAlreadyQueued
+	}
+	rm.match.fill(p)
+	return rm.match, nil
+}
+
+// Close takes down a room, once everyone has left or its creator gave up
+// waiting. Its code can't be used again until it's released, see Release.
+func (r *Rooms) Close(code string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if rm, ok := r.rooms[code]; ok && !rm.match.IsReady() {
+		close(rm.match.waited)
+	}
+	delete(r.rooms, code)
+	r.revoke(code)
+}
+
+// expire drops rooms nobody joined in time, releasing their codes, and
+// invitations nobody used in time. Call with r locked.
+func (r *Rooms) expire() {
+	for code, rm := range r.rooms {
+		if !rm.match.IsReady() && time.Since(rm.created) > r.ttl {
+			delete(r.rooms, code)
+			r.revoke(code)
+			rm.match.release()
+			close(rm.match.waited)
+		}
+	}
+	for token, inv := range r.invites {
+		if time.Now().After(inv.expires) {
+			delete(r.invites, token)
+		}
+	}
+}
+
+// NewCode claims a join code no room or match is using, for something else
+// players join by code. Release it once it's done with.
+func NewCode() string {
+	return claimID(newJoinCode)
+}
+
+// newJoinCode makes a code that's quick to type, like "K7QF"
+func newJoinCode() string {
+	b := make([]byte, CodeLength)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b)
+}
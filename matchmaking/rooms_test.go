@@ -0,0 +1,34 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoomExpires(t *testing.T) {
+	r := NewRooms(10 * time.Millisecond)
+	expired := r.Create(Player{ID: "alice"})
+	closed := r.Create(Player{ID: "bob"})
+	t.Cleanup(func() { Release(closed.ID) })
+
+	// bob gave up waiting before it expired, their code's released once they've left
+	r.Close(closed.ID)
+	<-closed.Waited()
+	if closed.Released() {
+		t.Error("a closed room's code was released")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if r.Open(expired.ID) {
+		t.Fatal("the room's still open")
+	}
+	<-expired.Waited()
+	if !expired.Released() || expired.IsReady() {
+		t.Errorf("released %v, ready %v, want released and not ready", expired.Released(), expired.IsReady())
+	}
+	liveIDs.mutex.Lock()
+	defer liveIDs.mutex.Unlock()
+	if liveIDs.ids[expired.ID] {
+		t.Errorf("the expired room's code %s is still in use", expired.ID)
+	}
+}
@@ -0,0 +1,382 @@
+package matchmaking
+
+import (
+	"errors"
+	"math/bits"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTournamentStarted is returned when registering after the bracket was drawn
+	ErrTournamentStarted = errors.New("matchmaking: tournament has already started")
+
+	// ErrAlreadyRegistered is returned when a player registers twice
+	ErrAlreadyRegistered = errors.New("matchmaking: player is already registered")
+
+	// ErrTooFewPlayers is returned when registration closes before there's
+	// a field worth running a bracket for
+	ErrTooFewPlayers = errors.New("matchmaking: too few players registered")
+
+	// ErrTournamentCancelled is returned when registering for a tournament
+	// that was called off
+	ErrTournamentCancelled = errors.New("matchmaking: tournament was cancelled")
+)
+
+// MinTournamentSize is the smallest field worth running a bracket for
+const MinTournamentSize = 4
+
+// Tournament is a single-elimination bracket. Players register until the
+// field is full, then they're seeded at random and play their way through
+// the rounds: winners advance, losers are out, the last one left is champion.
+// When the field isn't a power of two the first seeds get a bye.
+//
+// Games that can end in a draw just get replayed, only a win is reported.
+type Tournament struct {
+	Size int // players needed before the bracket is drawn
+
+	checkIn   time.Duration // how long a bout's players have to check in, 0 if they needn't
+	seed      uint64        // what the bracket's drawn from, see SetSeed
+	seeded    bool          // seed was set rather than picked at random
+	players   []Player
+	cancelled bool            // registration closed with too few players, see Close
+	withdrawn map[string]bool // players who left, they forfeit every bout
+	rounds    [][]*Bout
+	changed   chan struct{}
+	mutex     sync.Mutex
+}
+
+// Bout is one pairing in the bracket
+type Bout struct {
+	Match *Match // nil until both players are known
+	Bye   bool   // the player in Players[0] advances without playing
+
+	// Players is who meets in this bout, an empty ID means they're still to
+	// be decided by the previous round
+	Players [2]Player
+
+	// Winner is the ID of the player who advances, empty until decided
+	Winner string
+
+	// NoShow is set if neither player checked in, see SetCheckIn
+	NoShow bool
+}
+
+// Decided reports whether the bout has a winner
+func (b Bout) Decided() bool {
+	return b.Winner != ""
+}
+
+// NewTournament opens registration for a bracket of size players
+func NewTournament(size int) *Tournament {
+	return &Tournament{
+		Size:      max(size, MinTournamentSize),
+		withdrawn: map[string]bool{},
+		changed:   make(chan struct{}),
+	}
+}
+
+// SetSeed makes the bracket be drawn from seed instead of a random one, so
+// a tournament's draw can be made again exactly, see Seed
+func (t *Tournament) SetSeed(seed uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.seed, t.seeded = seed, true
+}
+
+// Seed is what the bracket was drawn from, 0 until it's drawn
+func (t *Tournament) Seed() uint64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.rounds) == 0 {
+		return 0
+	}
+	return t.seed
+}
+
+// SetCheckIn makes the players of each bout check in, by accepting its
+// match (see Match.Accept), within d of it being paired. A player who
+// doesn't forfeits the bout, and if neither does both are out: the first
+// goes through only to forfeit their next bout. 0, the default, starts
+// bouts straight away.
+func (t *Tournament) SetCheckIn(d time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.checkIn = d
+}
+
+// Changed is closed the next time anything in the tournament changes, call
+// it again afterwards to keep watching
+func (t *Tournament) Changed() <-chan struct{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.changed
+}
+
+// Register adds a player to the field. The bracket is drawn as soon as the
+// field is full.
+func (t *Tournament) Register(p Player) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.cancelled {
+		return ErrTournamentCancelled
+	}
+	if t.rounds != nil {
+		return ErrTournamentStarted
+	}
+	for _, registered := range t.players {
+		if registered.ID == p.ID {
+			return ErrAlreadyRegistered
+		}
+	}
+	t.players = append(t.players, p)
+	if len(t.players) == t.Size {
+		t.draw()
+	}
+	t.update()
+	return nil
+}
+
+// Close stops registration before the field is full, drawing the bracket
+// with whoever has registered. With fewer than MinTournamentSize of them
+// the tournament is cancelled instead, and ErrTooFewPlayers returned.
+func (t *Tournament) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.rounds != nil || t.cancelled {
+		return nil
+	}
+	defer t.update()
+	if len(t.players) < MinTournamentSize {
+		t.cancelled = true
+		return ErrTooFewPlayers
+	}
+	t.Size = len(t.players)
+	t.draw()
+	return nil
+}
+
+// Cancelled reports whether registration closed with too few players, see
+// Close
+func (t *Tournament) Cancelled() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.cancelled
+}
+
+// Withdraw takes a player out, e.g. because they disconnected. Before the
+// bracket is drawn they just lose their spot, afterwards they forfeit.
+func (t *Tournament) Withdraw(playerID string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.rounds == nil {
+		for i, p := range t.players {
+			if p.ID == playerID {
+				t.players = append(t.players[:i], t.players[i+1:]...)
+				break
+			}
+		}
+	} else {
+		t.withdrawn[playerID] = true
+	}
+	t.update()
+}
+
+// Report records the winner of a bout's match. Draws (an empty winnerID)
+// don't decide anything, the players go again. Reports for bouts that are
+// already decided, e.g. by a forfeit, are ignored.
+func (t *Tournament) Report(m *Match, winnerID string) error {
+	if winnerID == "" {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, round := range t.rounds {
+		for _, b := range round {
+			if b.Match != m || b.Decided() {
+				continue
+			}
+			if winnerID != b.Players[0].ID && winnerID != b.Players[1].ID {
+				return ErrNotInMatch
+			}
+			b.Winner = winnerID
+			t.update()
+			return nil
+		}
+	}
+	return nil
+}
+
+// NextMatch returns the match a player should be playing now, or false if
+// they're waiting on other bouts, knocked out, or the tournament is over
+func (t *Tournament) NextMatch(playerID string) (*Match, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(t.rounds) == 0 {
+		return nil, false
+	}
+	for _, b := range t.rounds[len(t.rounds)-1] {
+		if b.Match != nil && !b.Decided() && b.Match.Seat(playerID) >= 0 {
+			return b.Match, true
+		}
+	}
+	return nil, false
+}
+
+// Players lists the field, in seeded order once the bracket is drawn
+func (t *Tournament) Players() []Player {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]Player(nil), t.players...)
+}
+
+// Rounds is a snapshot of the bracket so far, first round first. It's empty
+// until the bracket is drawn and gets a round longer as each one finishes.
+func (t *Tournament) Rounds() [][]Bout {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	rounds := make([][]Bout, len(t.rounds))
+	for i, round := range t.rounds {
+		for _, b := range round {
+			rounds[i] = append(rounds[i], *b)
+		}
+	}
+	return rounds
+}
+
+// TotalRounds is how many rounds it takes to crown a champion
+func (t *Tournament) TotalRounds() int {
+	return bits.Len(uint(t.Size - 1))
+}
+
+// Champion returns the winner of the final, or false while it's still being played
+func (t *Tournament) Champion() (Player, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.champion()
+}
+
+// champion is Champion for callers holding the lock
+func (t *Tournament) champion() (Player, bool) {
+	if len(t.rounds) == 0 {
+		return Player{}, false
+	}
+	final := t.rounds[len(t.rounds)-1]
+	if len(final) != 1 || !final[0].Decided() {
+		return Player{}, false
+	}
+	return t.player(final[0].Winner), true
+}
+
+// draw seeds the field at random, from the tournament's seed, and pairs up
+// the first round, top seeds meeting bottom seeds. Call with t locked.
+func (t *Tournament) draw() {
+	if !t.seeded {
+		t.seed = rand.Uint64()
+	}
+	rand.New(rand.NewPCG(t.seed, 0)).Shuffle(len(t.players), func(i, j int) {
+		t.players[i], t.players[j] = t.players[j], t.players[i]
+	})
+	slots := 1 << t.TotalRounds()
+	round := make([]*Bout, slots/2)
+	for i := range round {
+		b := &Bout{Players: [2]Player{t.players[i]}}
+		if opponent := slots - 1 - i; opponent < len(t.players) {
+			b.Players[1] = t.players[opponent]
+		} else {
+			b.Bye = true
+		}
+		round[i] = b
+	}
+	t.rounds = [][]*Bout{round}
+}
+
+// update settles byes and forfeits, starts matches that have both players,
+// moves on to the next round once the current one is done, then lets
+// everyone watching know. Call with t locked.
+func (t *Tournament) update() {
+	for len(t.rounds) > 0 {
+		round := t.rounds[len(t.rounds)-1]
+		for _, b := range round {
+			if b.Decided() {
+				continue
+			}
+			switch {
+			case b.Bye:
+				b.Winner = b.Players[0].ID
+			case t.withdrawn[b.Players[0].ID]:
+				b.Winner = b.Players[1].ID
+			case t.withdrawn[b.Players[1].ID]:
+				b.Winner = b.Players[0].ID
+			case b.Match == nil:
+				b.Match = newMatch(newMatchID(), b.Players[0])
+				if t.checkIn > 0 {
+					b.Match.acceptBy = time.Now().Add(t.checkIn)
+					time.AfterFunc(t.checkIn, func() { t.noShow(b) })
+				}
+				b.Match.fill(b.Players[1])
+			}
+			if b.Decided() && b.Match != nil {
+				// a forfeit, the player who's left needn't wait to check in
+				b.Match.giveUp()
+			}
+		}
+
+		done := true
+		for _, b := range round {
+			done = done && b.Decided()
+		}
+		if !done || len(round) == 1 {
+			break
+		}
+		next := make([]*Bout, len(round)/2)
+		for i := range next {
+			next[i] = &Bout{Players: [2]Player{
+				t.player(round[2*i].Winner),
+				t.player(round[2*i+1].Winner),
+			}}
+		}
+		t.rounds = append(t.rounds, next)
+	}
+
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// noShow settles a bout whose players didn't both check in in time,
+// whoever did goes through
+func (t *Tournament) noShow(b *Bout) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	accepted, ok := b.Match.giveUp()
+	if !ok || b.Decided() {
+		return
+	}
+	switch {
+	case accepted[0]:
+		b.Winner = b.Players[0].ID
+	case accepted[1]:
+		b.Winner = b.Players[1].ID
+	default:
+		t.withdrawn[b.Players[0].ID] = true
+		t.withdrawn[b.Players[1].ID] = true
+		b.Winner, b.NoShow = b.Players[0].ID, true
+	}
+	t.update()
+}
+
+// player looks up a registered player by ID. Call with t locked.
+func (t *Tournament) player(id string) Player {
+	for _, p := range t.players {
+		if p.ID == id {
+			return p
+		}
+	}
+	return Player{}
+}
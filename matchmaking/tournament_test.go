@@ -0,0 +1,120 @@
+package matchmaking
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// waitFor waits for the tournament to change until cond holds, failing the
+// test if it doesn't in time
+func waitFor(t *testing.T, tour *Tournament, cond func() bool) {
+	t.Helper()
+	deadline := time.After(time.Second)
+	for {
+		changed := tour.Changed()
+		if cond() {
+			return
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			t.Fatal("timed out waiting for the tournament")
+		}
+	}
+}
+
+func TestCheckIn(t *testing.T) {
+	tests := []struct {
+		name       string
+		accept     [2]bool // which of the first bout's players check in
+		wantWinner int     // seat of who goes through, -1 if the bout's still on
+		wantNoShow bool
+	}{
+		{"both check in", [2]bool{true, true}, -1, false},
+		{"only the first does", [2]bool{true, false}, 0, false},
+		{"only the second does", [2]bool{false, true}, 1, false},
+		{"neither does", [2]bool{false, false}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tour := NewTournament(4)
+			tour.SetCheckIn(20 * time.Millisecond)
+			for _, id := range []string{"a", "b", "c", "d"} {
+				if err := tour.Register(Player{ID: id}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			first, second := tour.Rounds()[0][0], tour.Rounds()[0][1]
+			for _, p := range second.Players {
+				second.Match.Accept(p.ID)
+			}
+			if first.Match.AcceptBy().IsZero() {
+				t.Fatal("the bout starts without waiting for check-in")
+			}
+			for seat, accept := range tt.accept {
+				if accept {
+					first.Match.Accept(first.Players[seat].ID)
+				}
+			}
+			if tt.accept == [2]bool{true, true} {
+				if !first.Match.IsConfirmed() {
+					t.Error("both checked in but the bout isn't confirmed")
+				}
+				time.Sleep(50 * time.Millisecond)
+				if b := tour.Rounds()[0][0]; b.Decided() {
+					t.Errorf("the bout was decided by check-in, winner %s", b.Winner)
+				}
+				return
+			}
+
+			waitFor(t, tour, func() bool { return tour.Rounds()[0][0].Decided() })
+			b := tour.Rounds()[0][0]
+			if want := first.Players[tt.wantWinner].ID; b.Winner != want {
+				t.Errorf("Winner = %s, want %s", b.Winner, want)
+			}
+			if b.NoShow != tt.wantNoShow {
+				t.Errorf("NoShow = %v, want %v", b.NoShow, tt.wantNoShow)
+			}
+			if !tt.wantNoShow {
+				return
+			}
+			// the other bout finishes and whoever goes through to the
+			// final meets nobody who turned up
+			if err := tour.Report(second.Match, second.Players[0].ID); err != nil {
+				t.Fatal(err)
+			}
+			champion, ok := tour.Champion()
+			if !ok || champion.ID != second.Players[0].ID {
+				t.Errorf("Champion() = %s, %v, want %s", champion.ID, ok, second.Players[0].ID)
+			}
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	bracket := func(seed uint64) []string {
+		tour := NewTournament(8)
+		tour.SetSeed(seed)
+		if tour.Seed() != 0 {
+			t.Error("Seed() isn't 0 before the draw")
+		}
+		for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			if err := tour.Register(Player{ID: id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if tour.Seed() != seed {
+			t.Errorf("Seed() = %d, want %d", tour.Seed(), seed)
+		}
+		var ids []string
+		for _, b := range tour.Rounds()[0] {
+			ids = append(ids, b.Players[0].ID, b.Players[1].ID)
+		}
+		return ids
+	}
+	first := bracket(42)
+	if again := bracket(42); !slices.Equal(again, first) {
+		t.Errorf("drawn from the same seed: %v, then %v", first, again)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	bolt "go.etcd.io/bbolt"
+)
+
+// namesBucket holds who claimed each display name, the owner's key
+// fingerprint by the name in lower case
+var namesBucket = []byte("names")
+
+// MaxNamesPerKey is how many names a key can keep for itself. Past that,
+// names it connects under are only held while it's connected.
+const MaxNamesPerKey = 5
+
+// nameRegistry keeps display names for whoever claimed them first. A key
+// user's names are theirs until they let them go or hand them over, kept in
+// the database with -db and for the server's run without. Anyone's name,
+// guest or not, is theirs for as long as they're connected.
+type nameRegistry struct {
+	owners map[string]string   // who claimed each name, by the name in lower case, when there's no database
+	held   map[string]nameHold // who's connected under each name, by the name in lower case
+	mutex  sync.Mutex
+}
+
+// nameHold is a player connected under a name, on one or more sessions
+type nameHold struct {
+	playerID string
+	sessions int
+}
+
+// nameClaims are every player's names
+var nameClaims = &nameRegistry{owners: map[string]string{}, held: map[string]nameHold{}}
+
+// Errors from changing who a name belongs to
+var (
+	ErrNotYourName  = errors.New("that name isn't yours")
+	ErrTooManyNames = fmt.Errorf("they already have %d names", MaxNamesPerKey)
+)
+
+// owner is the fingerprint of whoever claimed a name, empty if nobody has.
+// Call with the registry locked.
+func (nr *nameRegistry) owner(key string) string {
+	if playerDB != nil {
+		return playerDB.nameOwner(key)
+	}
+	return nr.owners[key]
+}
+
+// setOwner gives a name to a player, or to nobody. Call with the registry
+// locked.
+func (nr *nameRegistry) setOwner(key, playerID string) error {
+	if playerDB != nil {
+		return playerDB.setNameOwner(key, playerID)
+	}
+	if playerID == "" {
+		delete(nr.owners, key)
+	} else {
+		nr.owners[key] = playerID
+	}
+	return nil
+}
+
+// owned lists the names a player has claimed, in lower case. Call with the
+// registry locked.
+func (nr *nameRegistry) owned(playerID string) []string {
+	if playerDB != nil {
+		return playerDB.namesOwnedBy(playerID)
+	}
+	var names []string
+	for key, owner := range nr.owners {
+		if owner == playerID {
+			names = append(names, key)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// free reports whether a player can go by a name: nobody else has claimed
+// it or is connected under it. Call with the registry locked.
+func (nr *nameRegistry) free(key, playerID string) bool {
+	if h, ok := nr.held[key]; ok && h.playerID != playerID {
+		return false
+	}
+	owner := nr.owner(key)
+	return owner == "" || owner == playerID
+}
+
+// available reports whether a player can go by a name
+func (nr *nameRegistry) available(name, playerID string) bool {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+	return nr.free(strings.ToLower(name), playerID)
+}
+
+// hold puts a player under a name for a session, claiming it for them if
+// they have a key and nobody has yet. It reports false if the name is
+// someone else's. Let go of it when the session ends.
+func (nr *nameRegistry) hold(name, playerID string) bool {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+	key := strings.ToLower(name)
+	if !nr.free(key, playerID) {
+		return false
+	}
+	if persistent(playerID) && nr.owner(key) == "" && len(nr.owned(playerID)) < MaxNamesPerKey {
+		if err := nr.setOwner(key, playerID); err != nil {
+			// they can still go by it for now
+			log.Printf("names: %v", err)
+		}
+	}
+	h := nr.held[key]
+	h.playerID = playerID
+	h.sessions++
+	nr.held[key] = h
+	return true
+}
+
+// letGo ends one of a player's sessions under a name
+func (nr *nameRegistry) letGo(name, playerID string) {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+	key := strings.ToLower(name)
+	h, ok := nr.held[key]
+	if !ok || h.playerID != playerID {
+		return
+	}
+	if h.sessions--; h.sessions > 0 {
+		nr.held[key] = h
+	} else {
+		delete(nr.held, key)
+	}
+}
+
+// release gives up a player's claim on a name, for anyone to take
+func (nr *nameRegistry) release(name, playerID string) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+	key := strings.ToLower(name)
+	if nr.owner(key) != playerID {
+		return ErrNotYourName
+	}
+	return nr.setOwner(key, "")
+}
+
+// transfer hands a player's name over to another key
+func (nr *nameRegistry) transfer(name, playerID, to string) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+	key := strings.ToLower(name)
+	switch {
+	case nr.owner(key) != playerID:
+		return ErrNotYourName
+	case !isFingerprint(to):
+		return fmt.Errorf("%q isn't a key fingerprint, like SHA256:...", to)
+	case len(nr.owned(to)) >= MaxNamesPerKey:
+		return ErrTooManyNames
+	}
+	return nr.setOwner(key, to)
+}
+
+// isFingerprint reports whether s is a key's SHA256 fingerprint, the way
+// ssh-keygen -l shows it
+func isFingerprint(s string) bool {
+	hash, ok := strings.CutPrefix(s, "SHA256:")
+	if !ok {
+		return false
+	}
+	sum, err := base64.RawStdEncoding.DecodeString(hash)
+	return err == nil && len(sum) == sha256.Size
+}
+
+// forget gives up every name a player claimed
+func (nr *nameRegistry) forget(playerID string) error {
+	nr.mutex.Lock()
+	defer nr.mutex.Unlock()
+	var errs []error
+	for _, key := range nr.owned(playerID) {
+		errs = append(errs, nr.setOwner(key, ""))
+	}
+	return errors.Join(errs...)
+}
+
+// claimStoredNames fills a new names bucket from a database made before
+// there was one: each stored player's last name goes to whoever was seen
+// first under it
+func claimStoredNames(players, names *bolt.Bucket) error {
+	var recs []PlayerRecord
+	err := players.ForEach(func(_, data []byte) error {
+		var rec PlayerRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return err
+		}
+		// key- names are only ever their own key's, see chosenName
+		if rec.Name != "" && !strings.HasPrefix(strings.ToLower(rec.Name), "key-") && persistent(rec.Fingerprint) {
+			recs = append(recs, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	slices.SortStableFunc(recs, func(a, b PlayerRecord) int { return a.FirstSeen.Compare(b.FirstSeen) })
+	for _, rec := range recs {
+		key := []byte(strings.ToLower(rec.Name))
+		if names.Get(key) != nil {
+			continue
+		}
+		if err := names.Put(key, []byte(rec.Fingerprint)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nameOwner is who claimed a name, by the name in lower case
+func (ps *playerStore) nameOwner(key string) string {
+	var owner string
+	err := ps.db.View(func(tx *bolt.Tx) error {
+		owner = string(tx.Bucket(namesBucket).Get([]byte(key)))
+		return nil
+	})
+	if err != nil {
+		log.Printf("names: %v", err)
+	}
+	return owner
+}
+
+// setNameOwner gives a name to a player, or to nobody
+func (ps *playerStore) setNameOwner(key, playerID string) error {
+	return ps.db.Update(func(tx *bolt.Tx) error {
+		if playerID == "" {
+			return tx.Bucket(namesBucket).Delete([]byte(key))
+		}
+		return tx.Bucket(namesBucket).Put([]byte(key), []byte(playerID))
+	})
+}
+
+// namesOwnedBy lists the names a player has claimed, in lower case
+func (ps *playerStore) namesOwnedBy(playerID string) []string {
+	var names []string
+	err := ps.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(namesBucket).ForEach(func(key, owner []byte) error {
+			if string(owner) == playerID {
+				names = append(names, string(key))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("names: %v", err)
+	}
+	return names
+}
+
+// namesStarting finds the players who've claimed a name starting with
+// prefix, in any case, going through the names from it rather than every
+// player. It's who claimed them by fingerprint.
+func (ps *playerStore) namesStarting(prefix string) map[string]bool {
+	found := map[string]bool{}
+	key := []byte(strings.ToLower(prefix))
+	err := ps.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(namesBucket).Cursor()
+		for name, owner := c.Seek(key); name != nil && bytes.HasPrefix(name, key); name, owner = c.Next() {
+			found[string(owner)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("names: %v", err)
+	}
+	return found
+}
+
+// serveNames answers `ssh host names`, listing the names the player's key
+// has claimed, `names release <name>` and `names give <name> <fingerprint>`
+func serveNames(next ssh.Handler) ssh.Handler {
+	return func(s ssh.Session) {
+		cmd := s.Command()
+		if len(cmd) == 0 || cmd[0] != "names" {
+			next(s)
+			return
+		}
+		playerID := playerID(s)
+		if !persistent(playerID) {
+			wish.Errorln(s, "Connect with an SSH key to keep a name, guests only have theirs while they're connected.")
+			_ = s.Exit(1)
+			return
+		}
+		var err error
+		switch {
+		case len(cmd) == 1:
+			nameClaims.mutex.Lock()
+			names := nameClaims.owned(playerID)
+			nameClaims.mutex.Unlock()
+			if len(names) == 0 {
+				wish.Println(s, "You haven't claimed a name yet. Connect as one to claim it, like ssh alice@"+hostName)
+			}
+			for _, name := range names {
+				wish.Println(s, name)
+			}
+		case len(cmd) == 3 && cmd[1] == "release":
+			if err = nameClaims.release(cmd[2], playerID); err == nil {
+				wish.Println(s, "Released "+cmd[2]+", anyone can take it now.")
+			}
+		case len(cmd) == 4 && cmd[1] == "give":
+			if err = nameClaims.transfer(cmd[2], playerID, cmd[3]); err == nil {
+				wish.Println(s, "Gave "+cmd[2]+" to "+cmd[3]+".")
+			}
+		default:
+			err = errors.New("use names, names release <name> or names give <name> <fingerprint>")
+		}
+		if err != nil {
+			wish.Errorln(s, "Couldn't change your names:", err)
+			_ = s.Exit(1)
+			return
+		}
+		_ = s.Exit(0)
+	}
+}
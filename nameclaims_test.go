@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestNameRegistry(t *testing.T) {
+	const guest = "session:guest"
+	alice, bob := testFingerprint("alice"), testFingerprint("bob")
+	tests := []struct {
+		name string
+		// steps run against a fresh registry and say what went wrong, if
+		// anything
+		steps func(nr *nameRegistry) error
+	}{
+		{"first key keeps it", func(nr *nameRegistry) error {
+			if !nr.hold("alice", alice) {
+				return errors.New("alice couldn't take an unclaimed name")
+			}
+			nr.letGo("alice", alice)
+			if nr.available("Alice", bob) {
+				return errors.New("bob can go by alice's name after they left")
+			}
+			if !nr.available("alice", alice) {
+				return errors.New("alice can't go by their own name")
+			}
+			return nil
+		}},
+		{"guests only hold it while connected", func(nr *nameRegistry) error {
+			if !nr.hold("otter", guest) {
+				return errors.New("a guest couldn't take an unclaimed name")
+			}
+			if nr.hold("otter", bob) {
+				return errors.New("bob took a name a guest is connected under")
+			}
+			nr.letGo("otter", guest)
+			if !nr.hold("otter", bob) {
+				return errors.New("bob couldn't take a name the guest left")
+			}
+			return nil
+		}},
+		{"held until the last session ends", func(nr *nameRegistry) error {
+			nr.hold("otter", guest)
+			nr.hold("otter", guest)
+			nr.letGo("otter", guest)
+			if nr.available("otter", bob) {
+				return errors.New("the name was let go with a session still on")
+			}
+			nr.letGo("otter", guest)
+			if !nr.available("otter", bob) {
+				return errors.New("the name wasn't let go")
+			}
+			return nil
+		}},
+		{"release", func(nr *nameRegistry) error {
+			nr.hold("alice", alice)
+			nr.letGo("alice", alice)
+			if err := nr.release("alice", bob); !errors.Is(err, ErrNotYourName) {
+				return fmt.Errorf("bob releasing alice's name: %v, want %v", err, ErrNotYourName)
+			}
+			if err := nr.release("ALICE", alice); err != nil {
+				return err
+			}
+			if !nr.available("alice", bob) {
+				return errors.New("released name isn't free")
+			}
+			return nil
+		}},
+		{"transfer", func(nr *nameRegistry) error {
+			nr.hold("alice", alice)
+			for _, to := range []string{guest, "SHA256:nope", "alice"} {
+				if err := nr.transfer("alice", alice, to); err == nil {
+					return fmt.Errorf("gave a name to %q", to)
+				}
+			}
+			if err := nr.transfer("alice", bob, bob); !errors.Is(err, ErrNotYourName) {
+				return fmt.Errorf("bob taking alice's name: %v, want %v", err, ErrNotYourName)
+			}
+			if err := nr.transfer("alice", alice, bob); err != nil {
+				return err
+			}
+			nr.letGo("alice", alice)
+			if nr.available("alice", alice) || !nr.available("alice", bob) {
+				return errors.New("the name didn't change hands")
+			}
+			return nil
+		}},
+		{"only so many names a key", func(nr *nameRegistry) error {
+			for i := range MaxNamesPerKey + 1 {
+				name := fmt.Sprintf("alice%d", i)
+				nr.hold(name, alice)
+				nr.letGo(name, alice)
+			}
+			if got := len(nr.owned(alice)); got != MaxNamesPerKey {
+				return fmt.Errorf("alice owns %d names, want %d", got, MaxNamesPerKey)
+			}
+			if err := nr.transfer("alice0", alice, bob); err != nil {
+				return err
+			}
+			for i := range MaxNamesPerKey {
+				nr.hold(fmt.Sprintf("bob%d", i), bob)
+			}
+			if err := nr.transfer("alice1", alice, bob); !errors.Is(err, ErrTooManyNames) {
+				return fmt.Errorf("giving bob another name: %v, want %v", err, ErrTooManyNames)
+			}
+			return nil
+		}},
+		{"forget", func(nr *nameRegistry) error {
+			nr.hold("alice", alice)
+			nr.letGo("alice", alice)
+			if err := nr.forget(alice); err != nil {
+				return err
+			}
+			if len(nr.owned(alice)) != 0 || !nr.available("alice", bob) {
+				return errors.New("alice's name is still theirs")
+			}
+			return nil
+		}},
+	}
+	for _, tt := range tests {
+		for _, db := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/db=%v", tt.name, db), func(t *testing.T) {
+				old := playerDB
+				t.Cleanup(func() { playerDB = old })
+				playerDB = nil
+				if db {
+					ps, err := openPlayerStore(filepath.Join(t.TempDir(), "players.db"))
+					if err != nil {
+						t.Fatal(err)
+					}
+					t.Cleanup(func() { ps.db.Close() })
+					playerDB = ps
+				}
+				nr := &nameRegistry{owners: map[string]string{}, held: map[string]nameHold{}}
+				if err := tt.steps(nr); err != nil {
+					t.Error(err)
+				}
+			})
+		}
+	}
+}
+
+// TestClaimStoredNames checks a database from before the names bucket gives
+// each name to whoever went by it first
+func TestClaimStoredNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "players.db")
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	err = db.Update(func(tx *bolt.Tx) error {
+		players, err := tx.CreateBucket(playersBucket)
+		if err != nil {
+			return err
+		}
+		for i, rec := range []PlayerRecord{
+			{Fingerprint: "SHA256:late", Name: "Alice", FirstSeen: start.Add(time.Hour)},
+			{Fingerprint: "SHA256:early", Name: "alice", FirstSeen: start},
+			{Fingerprint: "SHA256:bob", Name: "bob", FirstSeen: start},
+		} {
+			data, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			if err := players.Put([]byte(rec.Fingerprint), data); err != nil {
+				return fmt.Errorf("player %d: %w", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	ps, err := openPlayerStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.db.Close()
+	for name, want := range map[string]string{"alice": "SHA256:early", "bob": "SHA256:bob"} {
+		if got := ps.nameOwner(name); got != want {
+			t.Errorf("%s belongs to %q, want %q", name, got, want)
+		}
+	}
+}
+
+// testFingerprint is a made-up key fingerprint for a player
+func testFingerprint(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
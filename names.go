@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// word lists for guest names, kept short so names fit next to the board
+var (
+	guestAdjectives = []string{
+		"brave", "calm", "clever", "cosmic", "daring", "eager", "fuzzy", "gentle",
+		"happy", "jolly", "lucky", "mighty", "nimble", "quiet", "rapid", "sneaky",
+		"sunny", "swift", "tiny", "witty",
+	}
+	guestAnimals = []string{
+		"badger", "beaver", "falcon", "ferret", "gecko", "heron", "koala", "lemur",
+		"lynx", "marmot", "moose", "narwhal", "otter", "panda", "puffin", "quokka",
+		"raven", "tapir", "walrus", "yak",
+	}
+)
+
+// guestName generates a memorable name like "brave-otter-42"
+func guestName() string {
+	return fmt.Sprintf("%s-%s-%d",
+		guestAdjectives[rand.IntN(len(guestAdjectives))],
+		guestAnimals[rand.IntN(len(guestAnimals))],
+		rand.IntN(90)+10,
+	)
+}
+
+// playerID identifies a player to the matchmaker. Key-authenticated players
+// keep their results across connections, everyone else is a new player on
+// every connection. A player a peer sent here is who the peer says.
+func playerID(s ssh.Session) string {
+	if v, ok := visiting(s); ok {
+		return v.id
+	}
+	if key := s.PublicKey(); key != nil {
+		return gossh.FingerprintSHA256(key)
+	}
+	return "session:" + s.Context().SessionID()
+}
+
+// Special usernames take the player straight somewhere rather than being
+// their name, as does a room's join code (`ssh K7QF@host`)
+const (
+	UserSpectate    = "spectate"    // the games in progress
+	UserLeaderboard = "leaderboard" // the top players
+)
+
+// genericUsers are usernames people get by default rather than choose, so
+// they make poor display names ("root vs root")
+var genericUsers = []string{"", "root", "admin", "administrator", "user", "guest", "ubuntu", "pi", "ec2-user"}
+
+// displayName picks the name opponents see for this session. The SSH username
+// (`ssh alice@host`) wins if it looks chosen and isn't someone else's, then
+// the name a returning player went by last time, otherwise key-authenticated
+// players are recognisable by a short fingerprint and everyone else gets a
+// guest name that lasts for the session.
+func displayName(s ssh.Session) string {
+	id := playerID(s)
+	return pickName(s, func(name string) bool { return nameClaims.available(name, id) })
+}
+
+// holdDisplayName picks the session's name like displayName and holds it
+// for them until the session ends, claiming it if it's nobody's yet, see
+// nameRegistry
+func holdDisplayName(s ssh.Session) string {
+	id := playerID(s)
+	name := pickName(s, func(name string) bool { return nameClaims.hold(name, id) })
+	go func() {
+		<-s.Context().Done()
+		nameClaims.letGo(name, id)
+	}()
+	return name
+}
+
+// GuestNameTries is how many guest names are tried for one nobody's using
+// before settling for one that is
+const GuestNameTries = 10
+
+// pickName is the first name the player can have, in displayName's order.
+// take reports whether they can have a name, and takes it if it holds it.
+func pickName(s ssh.Session, take func(name string) bool) string {
+	if user := sanitize(s.User(), MaxNameWidth); chosenName(user) && take(user) {
+		return user
+	}
+	if rec, ok := playerDB.lookup(playerID(s)); ok && rec.Name != "" && take(rec.Name) {
+		return rec.Name // what they went by last time
+	}
+	if id := playerID(s); persistent(id) {
+		fingerprint := strings.TrimPrefix(id, "SHA256:")
+		return "key-" + fingerprint[:8] // only they can go by it, see chosenName
+	}
+	name := guestName()
+	for range GuestNameTries {
+		if take(name) {
+			break
+		}
+		name = guestName()
+	}
+	return name
+}
+
+// chosenName reports whether a username can be a player's name: it isn't
+// one people get by default, or one of the special usernames, and it can't
+// be mistaken for another player's
+func chosenName(user string) bool {
+	lower := strings.ToLower(user)
+	switch {
+	case slices.Contains(genericUsers, lower), lower == UserSpectate, lower == UserLeaderboard:
+		return false
+	case strings.HasPrefix(lower, "key-"):
+		// what key users without a name go by, anyone could claim to be them
+		return false
+	case sessionManager.rooms.Open(user):
+		// here to join the room, see handleSSHSession
+		return false
+	}
+	return true
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Independent servers can add their results up on one central leaderboard:
+// each server pushes its rated games there with -push-results, signed with
+// its SSH host key, and the central server takes results only from the
+// host keys listed in its -trusted-servers file. Players are known by their
+// key fingerprint, which is the same on every server they use that key on.
+
+// pushURL is where finished rated games are pushed, set with -push-results.
+// Empty means they aren't.
+var pushURL string
+
+// trustedServersPath lists the host keys, in authorized_keys format, whose
+// results this server's web leaderboard takes, set with -trusted-servers.
+// Empty means it takes none.
+var trustedServersPath string
+
+// resultSigner signs pushed results with the host key, nil when they aren't
+// pushed
+var resultSigner gossh.Signer
+
+// trustedServers are the host keys results are taken from, by fingerprint
+var trustedServers map[string]gossh.PublicKey
+
+// resultsBucket holds one networkResult per game pushed from another
+// server, checked when it came in, keyed by resultKey
+var resultsBucket = []byte("results")
+
+// PushAttempts is how many times a result is pushed before giving up on it
+const PushAttempts = 3
+
+// MaxResultSize is the most a pushed result can be, anything bigger isn't
+// one
+const MaxResultSize = 16 << 10
+
+// networkResult is a rated game as it's pushed to the central leaderboard
+type networkResult struct {
+	Server  string           `json:"server"` // the pushing server's host key, authorized_keys format
+	Game    string           `json:"game"`
+	ID      string           `json:"id"` // the game on the pushing server
+	Ended   time.Time        `json:"ended"`
+	Players [2]networkPlayer `json:"players"` // X first
+	Winner  string           `json:"winner"`  // "X", "O" or "draw"
+}
+
+// networkPlayer is a player in a pushed result
+type networkPlayer struct {
+	Fingerprint string `json:"fingerprint"`
+	Name        string `json:"name"`
+}
+
+// signedResult is a result exactly as it was signed, and the signature
+type signedResult struct {
+	Result    json.RawMessage `json:"result"`
+	Signature []byte          `json:"signature"` // the host key's signature of Result, SSH wire format
+}
+
+// loadResultSigner reads the host key to sign pushed results with. Run it
+// after the SSH server's made the key.
+func loadResultSigner() error {
+	pem, err := os.ReadFile(hostKeyPath)
+	if err != nil {
+		return err
+	}
+	resultSigner, err = gossh.ParsePrivateKey(pem)
+	return err
+}
+
+// loadTrustedServers reads the host keys results are taken from
+func loadTrustedServers(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	trustedServers = map[string]gossh.PublicKey{}
+	for len(bytes.TrimSpace(data)) > 0 {
+		key, _, _, rest, err := gossh.ParseAuthorizedKey(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		trustedServers[gossh.FingerprintSHA256(key)] = key
+		data = rest
+	}
+	if len(trustedServers) == 0 {
+		return fmt.Errorf("%s: no host keys", path)
+	}
+	return nil
+}
+
+// pushResult sends a finished game to the central leaderboard, if there is
+// one. Only rated games between key users go: the others don't count on a
+// leaderboard, and nor do players who'd rather be left off them. Call with
+// the session locked.
+func (gs *GameSession) pushResult() {
+	if resultSigner == nil {
+		return
+	}
+	r := networkResult{
+		Server: strings.TrimSpace(string(gossh.MarshalAuthorizedKey(resultSigner.PublicKey()))),
+		Game:   gs.match.Players[0].Game,
+		ID:     gs.ID,
+		Ended:  gs.GameEnded,
+		Winner: gs.Winner,
+	}
+	for i, p := range gs.match.Players {
+		if p.Casual || !persistent(p.ID) || privacyOf(p.ID).Unranked {
+			return
+		}
+		r.Players[i] = networkPlayer{Fingerprint: p.ID, Name: gs.PlayerNames[i]}
+	}
+	go func() {
+		body, err := sign(r)
+		if err != nil {
+			log.Printf("signing result of game %s: %v", r.ID, err)
+			return
+		}
+		if pushURL != "" {
+			if err := push(body); err != nil {
+				log.Printf("pushing result of game %s: %v", r.ID, err)
+			}
+		}
+		shareResult(r, body)
+	}()
+}
+
+// sign signs a result with the host key, for pushing
+func sign(r networkResult) ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := resultSigner.Sign(rand.Reader, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(signedResult{Result: data, Signature: gossh.Marshal(sig)})
+}
+
+// push posts a signed result to pushURL
+func push(body []byte) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return deliver(func() error { return postResult(client, body) })
+}
+
+// deliver sends a result, trying again a few times if the server it's for
+// can't be reached or has trouble
+func deliver(send func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := send()
+		var rejected rejectedError
+		if err == nil || errors.As(err, &rejected) || attempt == PushAttempts {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * 10 * time.Second)
+	}
+}
+
+// rejectedError is the server a result's for turning it down, which trying
+// again won't change
+type rejectedError string
+
+func (e rejectedError) Error() string {
+	return "rejected: " + string(e)
+}
+
+// postResult posts one signed result
+func postResult(client *http.Client, body []byte) error {
+	resp, err := client.Post(pushURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	why, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+	if resp.StatusCode < 500 {
+		return rejectedError(strings.TrimSpace(string(why)))
+	}
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(why)))
+}
+
+// verify checks a pushed result came from a trusted server, unchanged, and
+// reads it
+func (s signedResult) verify() (networkResult, error) {
+	var r networkResult
+	if err := json.Unmarshal(s.Result, &r); err != nil {
+		return r, err
+	}
+	key, _, _, _, err := gossh.ParseAuthorizedKey([]byte(r.Server))
+	if err != nil {
+		return r, fmt.Errorf("server key: %w", err)
+	}
+	trusted, ok := trustedServers[gossh.FingerprintSHA256(key)]
+	if !ok || !bytes.Equal(trusted.Marshal(), key.Marshal()) {
+		return r, errors.New("not a trusted server")
+	}
+	var sig gossh.Signature
+	if err := gossh.Unmarshal(s.Signature, &sig); err != nil {
+		return r, fmt.Errorf("signature: %w", err)
+	}
+	if err := key.Verify(s.Result, &sig); err != nil {
+		return r, errors.New("bad signature")
+	}
+	switch {
+	case r.ID == "" || r.Ended.IsZero():
+		return r, errors.New("no game")
+	case r.Winner != PlayerX && r.Winner != PlayerO && r.Winner != Draw:
+		return r, fmt.Errorf("no such result %q", r.Winner)
+	case r.Players[0].Fingerprint == "" || r.Players[1].Fingerprint == "":
+		return r, errors.New("missing a player")
+	}
+	return r, nil
+}
+
+// resultKey keeps one copy of each game: a server pushing it twice just
+// overwrites it
+func resultKey(r networkResult, server gossh.PublicKey) []byte {
+	return []byte(gossh.FingerprintSHA256(server) + " " + r.ID + " " + r.Ended.UTC().Format(time.RFC3339Nano))
+}
+
+// addResult stores a verified result pushed from another server, or
+// shared with this server's peers
+func (ps *playerStore) addResult(r networkResult) error {
+	if ps == nil {
+		return nil
+	}
+	key, _, _, _, err := gossh.ParseAuthorizedKey([]byte(r.Server))
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return ps.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(resultsBucket).Put(resultKey(r, key), data)
+	})
+}
+
+// unsignResults turns results stored signed, before they were checked only
+// on the way in, into the results themselves. They were checked when they
+// came in too.
+func unsignResults(bucket *bolt.Bucket) error {
+	unsigned := map[string][]byte{}
+	err := bucket.ForEach(func(key, data []byte) error {
+		var s signedResult
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if len(s.Signature) > 0 {
+			unsigned[string(key)] = s.Result
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// a bucket can't change while it's being gone through
+	for key, data := range unsigned {
+		if err := bucket.Put([]byte(key), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forgetResults takes every pushed game a player was in off the central
+// leaderboard
+func (ps *playerStore) forgetResults(playerID string) error {
+	if ps == nil || !persistent(playerID) {
+		return nil
+	}
+	return ps.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(resultsBucket)
+		var keys [][]byte
+		err := bucket.ForEach(func(key, data []byte) error {
+			var r networkResult
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+			if r.Players[0].Fingerprint == playerID || r.Players[1].Fingerprint == playerID {
+				keys = append(keys, bytes.Clone(key))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// networkPlayerRecord is a player's record on the central leaderboard, over
+// every server that pushes to it
+type networkPlayerRecord struct {
+	Rank        int     `json:"rank"`
+	Fingerprint string  `json:"fingerprint"`
+	Name        string  `json:"name"` // the name in their latest game
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	Draws       int     `json:"draws"`
+	WinRate     float64 `json:"win_rate"` // percentage of their games won
+	Servers     int     `json:"servers"`  // how many servers they've played on
+
+	named   time.Time
+	servers map[string]bool
+}
+
+// networkLeaderboard adds up the pushed results by player, most wins first,
+// at most limit of them. A server taken off the trusted list stops counting.
+func (ps *playerStore) networkLeaderboard(limit int) ([]networkPlayerRecord, error) {
+	players := map[string]*networkPlayerRecord{}
+	err := ps.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(resultsBucket).ForEach(func(key, data []byte) error {
+			server, _, _ := bytes.Cut(key, []byte(" ")) // its fingerprint, see resultKey
+			if _, ok := trustedServers[string(server)]; !ok {
+				return nil
+			}
+			var r networkResult
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+			for i, p := range r.Players {
+				rec, ok := players[p.Fingerprint]
+				if !ok {
+					rec = &networkPlayerRecord{Fingerprint: p.Fingerprint, servers: map[string]bool{}}
+					players[p.Fingerprint] = rec
+				}
+				if r.Ended.After(rec.named) {
+					rec.Name, rec.named = p.Name, r.Ended
+				}
+				rec.servers[r.Server] = true
+				switch r.Winner {
+				case Draw:
+					rec.Draws++
+				case []string{PlayerX, PlayerO}[i]:
+					rec.Wins++
+				default:
+					rec.Losses++
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	board := []networkPlayerRecord{}
+	for _, rec := range players {
+		rec.Servers = len(rec.servers)
+		rec.WinRate = 100 * float64(rec.Wins) / float64(rec.Wins+rec.Losses+rec.Draws)
+		board = append(board, *rec)
+	}
+	slices.SortFunc(board, func(a, b networkPlayerRecord) int {
+		return cmp.Or(b.Wins-a.Wins, a.Losses-b.Losses, strings.Compare(a.Name, b.Name))
+	})
+	board = board[:min(limit, len(board))]
+	for i := range board {
+		board[i].Rank = i + 1
+	}
+	return board, nil
+}
+
+// serveResults takes results pushed from trusted servers
+func serveResults(ps *playerStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var s signedResult
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxResultSize)).Decode(&s); err != nil {
+			http.Error(w, "not a signed result", http.StatusBadRequest)
+			return
+		}
+		result, err := s.verify()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err := ps.addResult(result); err != nil {
+			httpError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
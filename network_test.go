@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// testServerKey makes a host key for a server pushing results
+func testServerKey(t *testing.T) gossh.Signer {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := gossh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+// signResult signs a result like push does, with the server's key
+func signResult(t *testing.T, server gossh.Signer, r networkResult) signedResult {
+	t.Helper()
+	r.Server = strings.TrimSpace(string(gossh.MarshalAuthorizedKey(server.PublicKey())))
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := server.Sign(rand.Reader, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signedResult{Result: data, Signature: gossh.Marshal(sig)}
+}
+
+func TestVerifyResult(t *testing.T) {
+	trusted, stranger := testServerKey(t), testServerKey(t)
+	old := trustedServers
+	t.Cleanup(func() { trustedServers = old })
+	trustedServers = map[string]gossh.PublicKey{gossh.FingerprintSHA256(trusted.PublicKey()): trusted.PublicKey()}
+
+	game := networkResult{
+		Game:    GameTicTacToe,
+		ID:      "abc123",
+		Ended:   time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		Players: [2]networkPlayer{{"SHA256:a", "alice"}, {"SHA256:b", "bob"}},
+		Winner:  PlayerX,
+	}
+	tests := []struct {
+		name    string
+		signed  func() signedResult
+		wantErr bool
+	}{
+		{"trusted server", func() signedResult { return signResult(t, trusted, game) }, false},
+		{"untrusted server", func() signedResult { return signResult(t, stranger, game) }, true},
+		{"changed after signing", func() signedResult {
+			s := signResult(t, trusted, game)
+			s.Result = bytes.Replace(s.Result, []byte(`"winner":"X"`), []byte(`"winner":"O"`), 1)
+			return s
+		}, true},
+		{"signed by another key", func() signedResult {
+			s := signResult(t, trusted, game)
+			forged := signResult(t, stranger, game)
+			s.Signature = forged.Signature
+			return s
+		}, true},
+		{"not a result", func() signedResult {
+			r := game
+			r.Winner = "nobody"
+			return signResult(t, trusted, r)
+		}, true},
+		{"missing a player", func() signedResult {
+			r := game
+			r.Players[1] = networkPlayer{}
+			return signResult(t, trusted, r)
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := tt.signed().verify()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verify() err = %v, want an error: %v", err, tt.wantErr)
+			}
+			if err == nil && (r.ID != game.ID || r.Players != game.Players || r.Winner != game.Winner) {
+				t.Errorf("verify() = %+v, want %+v", r, game)
+			}
+		})
+	}
+}
+
+func TestServeResults(t *testing.T) {
+	trusted, stranger := testServerKey(t), testServerKey(t)
+	oldServers, oldStore := trustedServers, playerDB
+	t.Cleanup(func() { trustedServers, playerDB = oldServers, oldStore })
+	trustedServers = map[string]gossh.PublicKey{gossh.FingerprintSHA256(trusted.PublicKey()): trusted.PublicKey()}
+	ps, err := openPlayerStore(filepath.Join(t.TempDir(), "players.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ps.db.Close() })
+	playerDB = ps
+
+	post := func(s signedResult) int {
+		body, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		serveResults(ps)(w, httptest.NewRequest(http.MethodPost, "/api/results", bytes.NewReader(body)))
+		return w.Code
+	}
+	ended := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	games := []networkResult{
+		{ID: "1", Ended: ended, Players: [2]networkPlayer{{"SHA256:a", "alice"}, {"SHA256:b", "bob"}}, Winner: PlayerX},
+		{ID: "2", Ended: ended, Players: [2]networkPlayer{{"SHA256:b", "bob"}, {"SHA256:c", "carol"}}, Winner: PlayerX},
+		{ID: "3", Ended: ended, Players: [2]networkPlayer{{"SHA256:c", "carol"}, {"SHA256:a", "alice"}}, Winner: Draw},
+	}
+	for _, g := range games {
+		if code := post(signResult(t, trusted, g)); code != http.StatusOK {
+			t.Fatalf("posting game %s: %d", g.ID, code)
+		}
+	}
+	// pushed twice, it counts once
+	if code := post(signResult(t, trusted, games[0])); code != http.StatusOK {
+		t.Fatalf("posting game 1 again: %d", code)
+	}
+	if code := post(signResult(t, stranger, networkResult{ID: "4", Ended: ended, Players: games[0].Players, Winner: PlayerX})); code != http.StatusForbidden {
+		t.Errorf("posting from an untrusted server: %d, want %d", code, http.StatusForbidden)
+	}
+
+	board := func() map[string][3]int {
+		t.Helper()
+		recs, err := ps.networkLeaderboard(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string][3]int{}
+		for _, rec := range recs {
+			got[rec.Name] = [3]int{rec.Wins, rec.Losses, rec.Draws}
+		}
+		return got
+	}
+	want := map[string][3]int{"alice": {1, 0, 1}, "bob": {1, 1, 0}, "carol": {0, 1, 1}}
+	if got := board(); !maps.Equal(got, want) {
+		t.Errorf("leaderboard = %v, want %v", got, want)
+	}
+
+	// deleting bob's data takes the games they were in with it
+	if err := ps.forgetResults("SHA256:b"); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string][3]int{"alice": {0, 0, 1}, "carol": {0, 0, 1}}
+	if got := board(); !maps.Equal(got, want) {
+		t.Errorf("leaderboard without bob = %v, want %v", got, want)
+	}
+
+	// a server taken off the trusted list stops counting
+	trustedServers = map[string]gossh.PublicKey{}
+	if got := board(); len(got) != 0 {
+		t.Errorf("leaderboard without trusted servers = %v, want nobody", got)
+	}
+}
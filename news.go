@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// newsPath is a text file of news for the lobby, like new features,
+// upcoming tournaments or rule changes, set with -news. Empty means there's
+// none. It's read again whenever it changes, so operators can edit it while
+// the server's running.
+var newsPath string
+
+const (
+	// MaxNewsLines is as long as the news can be
+	MaxNewsLines = 6
+
+	// NewsCheckInterval is how often the news file is checked for changes
+	NewsCheckInterval = 10 * time.Second
+)
+
+// news is what the lobby shows from newsPath
+var news struct {
+	lines    []string
+	modified time.Time // the file's, when it was last read
+	mutex    sync.RWMutex
+}
+
+// loadNews reads the news file if it's changed since it was last read.
+// The news goes if the file does.
+func loadNews() error {
+	info, err := os.Stat(newsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		setNews(nil, time.Time{})
+		return nil
+	} else if err != nil {
+		return err
+	}
+	news.mutex.RLock()
+	same := info.ModTime().Equal(news.modified)
+	news.mutex.RUnlock()
+	if same {
+		return nil
+	}
+	// the same clean up as custom art, so nothing in it can mess with
+	// players' terminals
+	text, err := readArt(newsPath, MaxNewsLines)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	if text != "" {
+		lines = strings.Split(text, "\n")
+	}
+	setNews(lines, info.ModTime())
+	return nil
+}
+
+// setNews replaces the lobby's news
+func setNews(lines []string, modified time.Time) {
+	news.mutex.Lock()
+	defer news.mutex.Unlock()
+	news.lines, news.modified = lines, modified
+}
+
+// watchNews keeps the news up to date with the file, until the server stops
+func watchNews() {
+	for range time.Tick(NewsCheckInterval) {
+		if err := loadNews(); err != nil {
+			log.Printf("reading the news: %v", err)
+		}
+	}
+}
+
+// newsView draws the news panel in the lobby, if there's any news
+func (m model) newsView() string {
+	news.mutex.RLock()
+	defer news.mutex.RUnlock()
+	if len(news.lines) == 0 {
+		return ""
+	}
+	s := headerStyle.Render("  News") + "\n"
+	for _, line := range news.lines {
+		s += m.fit("  "+line) + "\n"
+	}
+	return s + "\n"
+}
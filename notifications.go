@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// notifications are how a player wants to hear that something's happened
+// while they're looking at another window, kept in their profile
+type notifications struct {
+	TurnBell  bool `json:"turn_bell"`  // the bell rings when the opponent has moved
+	MatchBell bool `json:"match_bell"` // the bell rings when a match is found
+	ChatPings bool `json:"chat_pings"` // the bell rings when the opponent says something
+	Desktop   bool `json:"desktop"`    // each of those is a desktop notification too, with OSC 9
+}
+
+// defaultNotifications are what players who haven't picked get: the bell
+// for a match found, which has a time limit to accept
+var defaultNotifications = notifications{MatchBell: true}
+
+// notificationSettings are the player's notifications, the defaults if
+// they've never changed them
+func (rec PlayerRecord) notificationSettings() notifications {
+	if rec.Notifications == nil {
+		return defaultNotifications
+	}
+	return *rec.Notifications
+}
+
+// setNotifications changes the player's notifications and saves them to
+// their profile
+func (m *model) setNotifications(n notifications) {
+	m.notifications = n
+	err := playerDB.change(m.playerID, func(rec *PlayerRecord) {
+		rec.Notifications = &n
+	})
+	if err != nil {
+		log.Printf("players: %v", err)
+	}
+}
+
+// alert gets the player's attention with the bell, if they want it for
+// this, and a desktop notification saying what happened if they want those
+// too. It's written to the terminal between frames, each write to an SSH
+// channel goes out whole.
+func (m model) alert(wanted bool, message string) tea.Cmd {
+	if !wanted || m.alerts == nil {
+		return nil
+	}
+	seq := "\a"
+	if m.notifications.Desktop {
+		// OSC 9, terminals that don't know it ignore it
+		seq += "\x1b]9;" + strings.ReplaceAll(sanitize(message, MaxChatLength), ";", ",") + "\x1b\\"
+	}
+	out := m.alerts
+	return func() tea.Msg {
+		_, _ = io.WriteString(out, seq)
+		return nil
+	}
+}
+
+// alertChanges tells the player what happened in the session since they
+// last caught up with it: their turn came round, or the opponent said
+// something after lastChat
+func (m model) alertChanges(wasMyTurn bool, lastChat time.Time) tea.Cmd {
+	if m.spectating || m.gameSession == nil {
+		return nil
+	}
+	var cmds []tea.Cmd
+	if !wasMyTurn && m.isMyTurn && m.winner == Empty && m.moves > 0 {
+		cmds = append(cmds, m.alert(m.notifications.TurnBell, "Your move against "+m.opponentName))
+	}
+	for _, msg := range m.chat {
+		if msg.At.After(lastChat) && msg.Player != m.playerSymbol {
+			cmds = append(cmds, m.alert(m.notifications.ChatPings, msg.Name+": "+msg.Text))
+			break // one's enough for a burst of them
+		}
+	}
+	return tea.Batch(cmds...)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAlerts rings the bells a player wants, and only those
+func TestAlerts(t *testing.T) {
+	var out strings.Builder
+	m := initialModel()
+	m.alerts = &out
+	m.gameSession = &GameSession{}
+	m.playerSymbol, m.opponentName = PlayerX, "bea"
+	said := time.Now()
+
+	tests := []struct {
+		name          string
+		notifications notifications
+		wasMyTurn     bool
+		chat          []chatMessage
+		want          string
+	}{
+		{"their move, no bell", notifications{}, false, nil, ""},
+		{"their move", notifications{TurnBell: true}, false, nil, "\a"},
+		{"still our move", notifications{TurnBell: true}, true, nil, ""},
+		{"their move, on the desktop too", notifications{TurnBell: true, Desktop: true}, false, nil, "\a\x1b]9;Your move against bea\x1b\\"},
+		{"they said something", notifications{ChatPings: true}, true, []chatMessage{{Player: PlayerO, Name: "bea", Text: "gg; \x1b[31mwp", At: said}}, "\a"},
+		{"they said something, on the desktop", notifications{ChatPings: true, Desktop: true}, true, []chatMessage{{Player: PlayerO, Name: "bea", Text: "gg; wp", At: said}}, "\a\x1b]9;bea: gg, wp\x1b\\"},
+		{"we said something", notifications{ChatPings: true}, true, []chatMessage{{Player: PlayerX, Name: "ann", Text: "gg", At: said}}, ""},
+		{"they said it before", notifications{ChatPings: true}, true, []chatMessage{{Player: PlayerO, Name: "bea", Text: "hi", At: said.Add(-time.Second)}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			m.notifications, m.chat = tt.notifications, tt.chat
+			m.isMyTurn, m.moves = true, 1
+			if cmd := m.alertChanges(tt.wasMyTurn, said.Add(-time.Millisecond)); cmd != nil {
+				cmd()
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// StreamKeepAlive is how often a quiet game stream gets a comment, so
+// proxies don't take it for dead and the stream notices the game's gone
+const StreamKeepAlive = 15 * time.Second
+
+// overlay is a game as JSON for stream overlays, what a spectator sees of it
+type overlay struct {
+	ID         string           `json:"id"`
+	Game       string           `json:"game"`
+	Board      [][]string       `json:"board"` // rows top to bottom, "" for empty cells
+	Turn       string           `json:"turn"`  // "X" or "O"
+	Winner     string           `json:"winner,omitempty"`
+	Moves      int              `json:"moves"`
+	Spectators int              `json:"spectators"`
+	MoveClock  float64          `json:"move_clock,omitempty"` // seconds the player to move has left, with a shot clock
+	Players    [2]overlayPlayer `json:"players"`              // X then O
+}
+
+// overlayPlayer is one side of the game in an overlay
+type overlayPlayer struct {
+	Side   string  `json:"side"`
+	Name   string  `json:"name"`
+	Title  string  `json:"title,omitempty"`
+	Rating int     `json:"rating,omitempty"`
+	Wins   int     `json:"wins"`
+	Losses int     `json:"losses"`
+	Draws  int     `json:"draws"`
+	Clock  float64 `json:"clock,omitempty"` // seconds left on their game clock, if there is one
+}
+
+// overlayFor is the game as spectators see it right now, with the
+// -spectator-delay if there is one
+func overlayFor(gs *GameSession) overlay {
+	gs.mutex.RLock()
+	defer gs.mutex.RUnlock()
+	f, ok := gs.delayedFrame()
+	if !ok {
+		f = gs.frame()
+	}
+	o := overlay{
+		ID:         gs.ID,
+		Game:       gs.Rules.Name(),
+		Board:      f.board,
+		Turn:       []string{PlayerX, PlayerO}[f.currentPlayer],
+		Winner:     f.winner,
+		Moves:      f.moves,
+		Spectators: gs.Spectators,
+	}
+	running := f.winner == Empty
+	if gs.TimeControl.Move > 0 && running && !gs.Casual {
+		o.MoveClock = max(gs.TimeControl.Move-turnTime(f.turnStarted), 0).Seconds()
+	}
+	names := gs.shownNames()
+	for i, side := range []string{PlayerX, PlayerO} {
+		p := overlayPlayer{Side: side, Name: names[i]}
+		if f.winner == gs.Winner && !gs.Anonymous[i] {
+			// their records would give a delayed result away, or an
+			// anonymous player's name
+			r := sessionManager.queue.Record(gs.match.Players[i].ID, gs.Rules.Name())
+			p.Title = rankTitle(r.Rating, r.Played())
+			if r.Placed() {
+				p.Rating = r.Rating
+			}
+			p.Wins, p.Losses, p.Draws = r.Wins, r.Losses, r.Draws
+		}
+		if gs.TimeControl.Game > 0 && !gs.Casual {
+			left := f.clocks[i]
+			if i == f.currentPlayer && running {
+				left -= turnTime(f.turnStarted)
+			}
+			p.Clock = max(left, 0).Seconds()
+		}
+		o.Players[i] = p
+	}
+	return o
+}
+
+// gameInProgress finds a game that's being played by its ID, one that
+// spectators could watch
+func gameInProgress(id string) (*GameSession, bool) {
+	for _, gs := range sessionManager.watchable() {
+		if gs.ID == id {
+			return gs, true
+		}
+	}
+	return nil, false
+}
+
+// serveOverlay answers /api/game/{id} with a game in progress as JSON, for
+// streamers to poll from an OBS browser source
+func serveOverlay(w http.ResponseWriter, r *http.Request) {
+	gs, ok := gameInProgress(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "no such game in progress", http.StatusNotFound)
+		return
+	}
+	// overlays are pages of their own, on another origin
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "no-store") // they poll it
+	writeJSON(w, overlayFor(gs))
+}
+
+// serveGameStream answers /api/game/{id}/stream with the game as a stream
+// of server-sent events, for sites to embed a live board without polling:
+// a "game" event with the overlay JSON straight away and after every move,
+// and an "end" event once the players have left. Like spectators, it's
+// -spectator-delay behind.
+func serveGameStream(w http.ResponseWriter, r *http.Request) {
+	gs, ok := gameInProgress(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "no such game in progress", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	rc := http.NewResponseController(w)
+	send := func(event string, data []byte) bool {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	updates := gs.subscribe()
+	defer gs.unsubscribe(updates)
+	keepAlive := time.NewTicker(StreamKeepAlive)
+	defer keepAlive.Stop()
+	// a delayed game catches up without telling anyone, so look every second
+	var catchUp <-chan time.Time
+	if spectatorDelay > 0 {
+		t := time.NewTicker(time.Second)
+		defer t.Stop()
+		catchUp = t.C
+	}
+
+	var last overlay
+	sent := false
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": still here\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		case <-updates:
+		case <-catchUp:
+		}
+		if _, ok := gameInProgress(gs.ID); !ok {
+			send("end", []byte(`{}`))
+			return
+		}
+		o := overlayFor(gs)
+		if sent && sameBoard(o, last) {
+			// just the clocks ticking, which embeds can count down themselves
+			continue
+		}
+		data, err := json.Marshal(o)
+		if err != nil {
+			log.Printf("http: %v", err)
+			return
+		}
+		if !send("game", data) {
+			return
+		}
+		last, sent = o, true
+	}
+}
+
+// sameBoard reports whether two overlays show the same position and
+// players, whatever their clocks say
+func sameBoard(a, b overlay) bool {
+	a.MoveClock, b.MoveClock = 0, 0
+	for i := range a.Players {
+		a.Players[i].Clock, b.Players[i].Clock = 0, 0
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"tictactui/matchmaking"
+)
+
+// ForfeitWait is how long a player whose opponent left a rated game waits
+// for it to be settled as a forfeit before going back to the lobby anyway
+const ForfeitWait = 2 * time.Second
+
+// abandon settles a game a player left before it was over, unless they
+// came back in time or the game ended without them. A rated game is lost,
+// and leaving a quick match game earns a strike too. It runs once their
+// time to reconnect is up. Their seat is the one they left.
+func (sm *SessionManager) abandon(session *GameSession, p matchmaking.Player, seat int) {
+	session.mutex.Lock()
+	left, opponentLeft := session.leftAt[seat], session.leftAt[1-seat]
+	gone := !left.IsZero() && (opponentLeft.IsZero() || opponentLeft.After(left)) &&
+		!time.Now().Before(session.ReconnectBy) && session.Winner == Empty && !session.Aborted && !session.MatchStarted.IsZero()
+	strike := gone && session.abandoning()
+	if gone && !session.Casual {
+		session.Forfeited = true
+		session.finish([]string{PlayerX, PlayerO}[1-seat])
+		session.notify()
+	}
+	session.mutex.Unlock()
+	if !strike {
+		return
+	}
+	if _, err := sm.queue.Penalize(p.ID, matchmaking.Abandoned); err != nil {
+		log.Printf("penalizing %s for abandoning game %s: %v", p.Name, session.ID, err)
+	}
+}
+
+// abandoning reports whether a player leaving now would be abandoning a
+// quick match game. Rooms are among friends, and tournament and hill bouts
+// are forfeited instead. Call with the session locked.
+func (gs *GameSession) abandoning() bool {
+	return !gs.Private && !gs.Casual && gs.tournament == nil && gs.hill == nil && !gs.Aborted && !gs.MatchStarted.IsZero() && gs.Winner == Empty
+}
+
+// penaltiesView tells a player about the matches they dodged and the games
+// they abandoned, and how long they're kept out of the queue for it
+func (m model) penaltiesView() string {
+	r := sessionManager.queue.Record(m.playerID, m.rules.Name())
+	if r.Dodged+r.Abandoned == 0 {
+		return ""
+	}
+	s := fmt.Sprintf("  Dodged %d, abandoned %d", r.Dodged, r.Abandoned)
+	if cooldown := sessionManager.queue.Cooldown(m.playerID); cooldown > 0 {
+		s += ", no quick match for " + formatCooldown(cooldown)
+	}
+	return footerStyle.Render(s) + "\n"
+}
+
+// formatCooldown says how long is left of a cooldown
+func formatCooldown(d time.Duration) string {
+	return formatDuration(d.Round(time.Second))
+}
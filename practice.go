@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// EvalBarWidth is how many cells wide the evaluation bar is
+const EvalBarWidth = 20
+
+// practice is a Tic-Tac-Toe sandbox against the perfect computer: moves can
+// be taken back as often as the player likes, the evaluation bar says who
+// wins with best play, and positions can be set up by hand
+type practice struct {
+	active    bool
+	history   []position // the position before each move, for takebacks
+	eval      bool       // showing the evaluation bar
+	settingUp bool       // placing marks by hand instead of playing
+	err       string     // why the position set up can't be played from
+}
+
+// position is a practice game as it stood before a move
+type position struct {
+	board [][]string
+	turn  string
+	moves int
+}
+
+// startPractice sets up a practice game, the player is X and the computer
+// answers as O
+func (m model) startPractice() (tea.Model, tea.Cmd) {
+	if !gameEnabled(enabledGames, GameTicTacToe) {
+		m.lobby.err = "Practice is Tic-Tac-Toe, which this server doesn't have on"
+		return m, nil
+	}
+	m.lobby.active = false
+	m.opponentMenu = opponentMenu{}
+	m.practice = practice{active: true}
+	m.computer, m.difficulty = PlayerO, AIHard
+	m.rules = ticTacToe // the computer only knows Tic-Tac-Toe
+	m.resetGame()
+	return m, m.clearScreen()
+}
+
+// updatePractice handles the practice keys, ok is false for keys that
+// aren't practice keys
+func (m model) updatePractice(msg tea.KeyMsg) (next tea.Model, cmd tea.Cmd, ok bool) {
+	switch msg.String() {
+	case "u":
+		next, cmd = m.takeBack()
+	case "e":
+		m.practice.eval = !m.practice.eval
+		next = m
+	case "p":
+		m.practice.settingUp = true
+		m.practice.err = ""
+		m.winner, m.winningCells = Empty, nil
+		m.confetti = m.confetti.Stop()
+		m.game++ // the computer's move on its way is for the old position
+		next, cmd = m, m.clearScreen()
+	case "esc":
+		if m.done == nil {
+			// a standalone game, there's no lobby to go back to
+			return m, nil, false
+		}
+		next, cmd = m.fresh().backToLobby()
+	default:
+		return m, nil, false
+	}
+	return next, cmd, true
+}
+
+// takeBack undoes moves back to the player's last turn, the computer's
+// answer goes too
+func (m model) takeBack() (tea.Model, tea.Cmd) {
+	h := m.practice.history
+	if len(h) == 0 {
+		return m, nil
+	}
+	p := h[len(h)-1]
+	h = h[:len(h)-1]
+	for p.turn == m.computer && len(h) > 0 {
+		p = h[len(h)-1]
+		h = h[:len(h)-1]
+	}
+	m.practice.history = h
+	m.board, m.currentPlayer, m.moves = p.board, p.turn, p.moves
+	m.winner, m.winningCells = Empty, nil
+	m.held, m.chain = nil, nil
+	m.confetti = m.confetti.Stop()
+	m.game++ // the computer's move on its way is for the position taken back
+	// a position set up with the computer to move, it plays again
+	return m, tea.Batch(m.clearScreen(), m.computerTurn())
+}
+
+// record keeps the position before a practice move, to take it back to
+func (m *model) record() {
+	if m.practice.active {
+		m.practice.history = append(m.practice.history, position{copyBoard(m.board), m.currentPlayer, m.moves})
+	}
+}
+
+// updateSetUp handles keys while setting up a practice position
+func (m model) updateSetUp(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "up", "k":
+		m.cursorY = max(m.cursorY-1, 0)
+	case "down", "j":
+		m.cursorY = min(m.cursorY+1, len(m.board)-1)
+	case "left", "h":
+		m.cursorX = max(m.cursorX-1, 0)
+	case "right", "l":
+		m.cursorX = min(m.cursorX+1, len(m.board[m.cursorY])-1)
+	case "enter", " ":
+		// empty, X, O and round again
+		cell := &m.board[m.cursorY][m.cursorX]
+		switch *cell {
+		case Empty:
+			*cell = PlayerX
+		case PlayerX:
+			*cell = PlayerO
+		default:
+			*cell = Empty
+		}
+		m.practice.err = ""
+	case "c":
+		m.board = m.rules.newBoard()
+		m.practice.err = ""
+	case "p", "esc":
+		return m.playFromHere()
+	}
+	return m, nil
+}
+
+// playFromHere starts playing from the position set up, if it's one a game
+// could have reached that isn't over already
+func (m model) playFromHere() (tea.Model, tea.Cmd) {
+	xCount, oCount := 0, 0
+	for _, row := range m.board {
+		for _, cell := range row {
+			switch cell {
+			case PlayerX:
+				xCount++
+			case PlayerO:
+				oCount++
+			}
+		}
+	}
+	switch {
+	case xCount != oCount && xCount != oCount+1:
+		m.practice.err = "X moves first, so there's one X more than Os or as many of each"
+		return m, nil
+	case ticTacToe.checkWinner(m.board, PlayerX) != nil || ticTacToe.checkWinner(m.board, PlayerO) != nil:
+		m.practice.err = "That game's already won"
+		return m, nil
+	case isDraw(m.board):
+		m.practice.err = "That game's already over, the board's full"
+		return m, nil
+	}
+	m.currentPlayer = PlayerX
+	if xCount > oCount {
+		m.currentPlayer = PlayerO
+	}
+	m.moves = xCount + oCount
+	m.practice.settingUp = false
+	m.practice.history = nil
+	return m, tea.Batch(m.clearScreen(), m.computerTurn())
+}
+
+// evaluate scores the position for X with perfect play from both sides:
+// positive if X wins, negative if O does, 0 for a draw. The sooner the
+// win, the further from 0.
+func (m model) evaluate() int {
+	return minimax(copyBoard(m.board), PlayerX, m.currentPlayer, 0)
+}
+
+// practiceView draws the evaluation bar or the set up instructions
+func (m model) practiceView() string {
+	if m.practice.settingUp {
+		s := "\n" + headerStyle.Render("Setting up a position") + "\n"
+		if m.practice.err != "" {
+			s += lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render(m.practice.err) + "\n"
+		}
+		return s
+	}
+	if !m.practice.eval || m.winner != Empty {
+		return ""
+	}
+	score := m.evaluate()
+	verdict := "Draw with best play"
+	switch {
+	case score > 0:
+		verdict = fmt.Sprintf("X wins in %d with best play", 10-score)
+	case score < 0:
+		verdict = fmt.Sprintf("O wins in %d with best play", 10+score)
+	}
+	// the bar's X share goes from none to all of it as the score goes from
+	// O winning straight away to X doing so
+	xCells := (score + 10) * EvalBarWidth / 20
+	bar := xStyle.Render(strings.Repeat("█", xCells)) + oStyle.Render(strings.Repeat("█", EvalBarWidth-xCells))
+	return "\n" + styledPlayer(PlayerX) + " " + bar + " " + styledPlayer(PlayerO) + footerStyle.Render("  "+verdict) + "\n"
+}
+
+// practiceKeys says what the keys do in a practice game
+func (m model) practiceKeys() string {
+	if m.practice.settingUp {
+		return "Press enter to change a cell, c to clear the board, p to play from here, q to quit"
+	}
+	keys := "Press u to undo, e for the eval bar, p to set up, r to restart"
+	if m.done != nil {
+		keys += ", esc for the lobby"
+	}
+	return keys + ", q to quit"
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// DeletedName stands in for a deleted player's name in the games they
+// played, their opponents keep the games
+const DeletedName = "deleted player"
+
+// DeleteConfirmation is what a player types to delete their data
+const DeleteConfirmation = "DELETE"
+
+// AnonymousName stands in for the name of a player who'd rather spectators
+// didn't see it
+const AnonymousName = "anonymous"
+
+// privacy is what a player would rather keep to themselves, from their
+// profile. Key users only, guests aren't on leaderboards and their games
+// aren't kept under their name.
+type privacy struct {
+	NoReplays bool `json:"no_replays,omitempty"` // their games aren't on the /replay pages
+	Unranked  bool `json:"unranked,omitempty"`   // they're left off the leaderboards and /api/player, and their games aren't pushed
+	Anonymous bool `json:"anonymous,omitempty"`  // spectators, overlays and replay pages see AnonymousName
+}
+
+// privacyOf is a player's privacy settings, none for guests
+func privacyOf(playerID string) privacy {
+	rec, _ := playerDB.lookup(playerID)
+	return rec.Privacy
+}
+
+// setPrivacy changes the player's privacy settings and saves them to their
+// profile
+func (m *model) setPrivacy(p privacy) {
+	m.privacy = p
+	err := playerDB.change(m.playerID, func(rec *PlayerRecord) {
+		rec.Privacy = p
+	})
+	if err != nil {
+		log.Printf("players: %v", err)
+	}
+}
+
+// shownNames are the players' names as spectators see them, AnonymousName
+// for those who'd rather not be seen. Call with the session locked.
+func (gs *GameSession) shownNames() [2]string {
+	names := gs.PlayerNames
+	for i, anonymous := range gs.Anonymous {
+		if anonymous {
+			names[i] = AnonymousName
+		}
+	}
+	return names
+}
+
+// deleteData forgets everything kept about a player: their record, rating,
+// macros and penalties, the names they claimed, their games to rewatch and
+// on the central leaderboard, and their name, key and chat in the journals.
+// Games stay for their opponents, played by DeletedName, except on the
+// central leaderboard, which only has the results.
+func deleteData(playerID string) error {
+	if err := playerDB.forget(playerID); err != nil {
+		return err
+	}
+	if err := playerDB.forgetAnnotations(playerID); err != nil {
+		return err
+	}
+	if err := playerDB.forgetRivalries(playerID); err != nil {
+		return err
+	}
+	if err := nameClaims.forget(playerID); err != nil {
+		return err
+	}
+	if err := playerDB.forgetResults(playerID); err != nil {
+		return err
+	}
+	sessionManager.queue.Forget(playerID)
+	recentReplays.forget(playerID)
+	return scrubJournals(playerID)
+}
+
+// scrubJournals takes a player out of every journal they're in
+func scrubJournals(playerID string) error {
+	if journalDir == "" {
+		return nil
+	}
+	paths, err := filepath.Glob(filepath.Join(journalDir, "*.jsonl"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, path := range paths {
+		errs = append(errs, scrubJournal(path, playerID))
+	}
+	return errors.Join(errs...)
+}
+
+// scrubJournal takes a player out of one journal, leaving the file alone if
+// they're not in it. The scrubbed journal replaces the old one in one go, so
+// a crash can't leave half of it behind.
+func scrubJournal(path, playerID string) error {
+	events, err := readJournal(path)
+	if err != nil {
+		return err
+	}
+	changed := false
+	side := "" // the player's seat, X or O, while they're sitting in it
+	scrubbed := events[:0]
+	for _, e := range events {
+		switch {
+		case e.Type == EventJoin && e.ID == playerID:
+			side = e.Player
+			e.Name, e.ID = DeletedName, ""
+			changed = true
+		case e.Type == EventJoin && e.Player == side:
+			side = "" // someone else in their seat
+		case e.Type == EventChat && e.Player == side && side != "",
+			e.Type == EventSpectatorChat && e.ID == playerID:
+			changed = true
+			continue // what they said goes with them
+		}
+		scrubbed = append(scrubbed, e)
+	}
+	if !changed {
+		return nil
+	}
+
+	var b strings.Builder
+	for _, e := range scrubbed {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// runDelete deletes a player's data for an admin, by key fingerprint, for
+// the delete command
+func runDelete(fingerprint string) error {
+	if fingerprint == "" {
+		return errors.New("usage: tictactui -db players.db [-journal dir] delete SHA256:<fingerprint>")
+	}
+	if !strings.HasPrefix(fingerprint, "SHA256:") {
+		fingerprint = "SHA256:" + fingerprint
+	}
+	if _, ok := playerDB.lookup(fingerprint); !ok && playerDB != nil {
+		fmt.Println("No record for", fingerprint+", scrubbing the journals anyway")
+	}
+	if err := deleteData(fingerprint); err != nil {
+		return err
+	}
+	fmt.Println("Deleted", fingerprint)
+	return nil
+}
+
+// deleteScreen is where players delete their own data
+type deleteScreen struct {
+	active bool
+	typed  string // the confirmation typed so far
+	done   bool   // their data's gone
+	err    string // why deleting it didn't work
+}
+
+// openDelete asks the player to confirm they want their data deleted
+func (m model) openDelete() (tea.Model, tea.Cmd) {
+	m.lobby.active = false
+	m.deleting = deleteScreen{active: true}
+	return m, m.clearScreen()
+}
+
+// updateDelete handles keys while confirming
+func (m model) updateDelete(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	d := &m.deleting
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	case tea.KeyEsc:
+		m.deleting = deleteScreen{}
+		return m.backToLobby()
+	case tea.KeyBackspace:
+		if d.typed != "" {
+			d.typed = d.typed[:len(d.typed)-1]
+		}
+	case tea.KeyEnter:
+		if d.done || d.typed != DeleteConfirmation || !persistent(m.playerID) {
+			break
+		}
+		if err := deleteData(m.playerID); err != nil {
+			d.err = "Couldn't delete everything: " + err.Error()
+			break
+		}
+		d.done, d.err = true, ""
+		m.returning, m.macros, m.avatar, m.theme, m.notifications, m.privacy = false, nil, "", themes[0], defaultNotifications, privacy{}
+	case tea.KeyRunes:
+		for _, r := range msg.Runes {
+			if !d.done && len(d.typed) < len(DeleteConfirmation) {
+				d.typed += strings.ToUpper(string(r))
+			}
+		}
+	}
+	return m, nil
+}
+
+// deleteView draws the confirmation
+func (m model) deleteView() string {
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+	s += headerStyle.Render("  Delete my data") + "\n\n"
+
+	switch {
+	case !persistent(m.playerID):
+		s += "  Guests aren't remembered, there's nothing to delete\n"
+	case m.deleting.done:
+		s += "  Done, your record, rating, macros and games are gone. Your games stay\n"
+		s += "  for your opponents, played by \"" + DeletedName + "\".\n"
+	default:
+		s += "  This forgets your record, rating, macros and games for good, and takes\n"
+		s += "  your name and chat out of the games you played.\n\n"
+		typed := m.deleting.typed + strings.Repeat("_", len(DeleteConfirmation)-len(m.deleting.typed))
+		s += "  Type " + DeleteConfirmation + " and press enter: " + headerStyle.Render(typed) + "\n"
+	}
+	if m.deleting.err != "" {
+		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render("  "+m.deleting.err) + "\n"
+	}
+	s += footerStyle.Render("\n  Press esc to go back, ctrl+c to quit") + "\n"
+	return s
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"tictactui/matchmaking"
+)
+
+// TestPrivacy keeps ann off the leaderboard and her games off the web, and
+// bea's name from spectators
+func TestPrivacy(t *testing.T) {
+	ps, err := openPlayerStore(filepath.Join(t.TempDir(), "players.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.db.Close()
+	oldDB, oldJournals := playerDB, journalDir
+	t.Cleanup(func() { playerDB, journalDir = oldDB, oldJournals })
+	playerDB, journalDir = ps, t.TempDir()
+
+	settings := map[string]privacy{
+		"SHA256:a": {Unranked: true, NoReplays: true},
+		"SHA256:b": {Anonymous: true},
+		"SHA256:c": {},
+	}
+	for id, p := range settings {
+		if err := ps.SaveResults(id, GameTicTacToe, matchmaking.Results{Wins: matchmaking.PlacementMatches, Rating: 1500}); err != nil {
+			t.Fatal(err)
+		}
+		if err := ps.change(id, func(rec *PlayerRecord) { rec.Privacy = p }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the leaderboards
+	board, err := ps.ranking(GameTicTacToe, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := slices.IndexFunc(board, func(p publicPlayer) bool { return p.Fingerprint == "SHA256:a" }); i >= 0 || len(board) != 2 {
+		t.Errorf("ranked %d players, ann at %d, want bea and cat without ann", len(board), i)
+	}
+
+	// spectators
+	gs := &GameSession{PlayerNames: [2]string{"bea", "cat"}, Anonymous: [2]bool{true, false}}
+	if got := gs.shownNames(); got != [2]string{AnonymousName, "cat"} {
+		t.Errorf("spectators see %v", got)
+	}
+
+	// replay pages
+	play := func(id string, x, o string) {
+		t.Helper()
+		j := openJournal(id)
+		for _, e := range []Event{
+			{Type: EventJoin, Player: PlayerX, Name: strings.ToLower(x[7:]) + "-name", ID: x, Game: GameTicTacToe},
+			{Type: EventJoin, Player: PlayerO, Name: strings.ToLower(o[7:]) + "-name", ID: o, Game: GameTicTacToe},
+			{Type: EventMove, Player: PlayerX, Cell: "B2"},
+			{Type: EventResult, Result: PlayerX, Reason: ReasonAbandoned},
+		} {
+			j.Record(e)
+		}
+	}
+	play("ac", "SHA256:a", "SHA256:c")
+	play("bc", "SHA256:b", "SHA256:c")
+	page := func(id string) (int, string) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/replay/"+id, nil)
+		r.SetPathValue("id", id)
+		serveReplayPage(w, r)
+		return w.Code, w.Body.String()
+	}
+	if code, _ := page("ac"); code != http.StatusNotFound {
+		t.Errorf("ann's game is %d, want it not found", code)
+	}
+	code, body := page("bc")
+	if code != http.StatusOK || strings.Contains(body, "b-name") || !strings.Contains(body, AnonymousName) || !strings.Contains(body, "c-name") {
+		t.Errorf("bea's game is %d, want it with her anonymous:\n%s", code, body)
+	}
+}
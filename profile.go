@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// Profile fields
+const (
+	profileName = iota
+	profileAvatar
+	profileTheme
+	profileMacros
+	profileTurnBell
+	profileMatchBell
+	profileChatPings
+	profileDesktop
+	profileReplays
+	profileLeaderboard
+	profileSpectators
+)
+
+var profileLabels = []string{
+	profileName:        "Name",
+	profileAvatar:      "Avatar",
+	profileTheme:       "Board",
+	profileMacros:      "Macros",
+	profileTurnBell:    "Your move",
+	profileMatchBell:   "Match found",
+	profileChatPings:   "Chat",
+	profileDesktop:     "Desktop",
+	profileReplays:     "Replays",
+	profileLeaderboard: "Leaderboards",
+	profileSpectators:  "Spectators",
+}
+
+// profileSections are the profile's pages, tab goes on to the next. The
+// first has the player's stats under its fields.
+var profileSections = []struct {
+	title  string
+	fields []int
+}{
+	{"Profile", []int{profileName, profileAvatar, profileTheme, profileMacros}},
+	{"Notifications", []int{profileTurnBell, profileMatchBell, profileChatPings, profileDesktop}},
+	{"Privacy", []int{profileReplays, profileLeaderboard, profileSpectators}},
+}
+
+// profile is the player's own profile, from the lobby: who they are, how
+// things look, what their keys do, which bells ring and what they keep to
+// themselves, changed in place and saved with their record if they have a
+// key
+type profile struct {
+	active  bool
+	section int    // the page on screen, index into profileSections
+	choice  int    // highlighted field, index into the section's fields
+	editing bool   // typing a new name
+	text    string // the name typed so far
+	err     string // why the last change didn't take
+	puzzles int    // how many puzzles they've solved, from their record
+}
+
+// Errors from picking a new name
+var (
+	ErrNameNotChosen = errors.New("pick a name of your own, not that one")
+	ErrNameTaken     = errors.New("that name is someone else's")
+)
+
+// openProfile shows the player's profile, from the lobby
+func (m model) openProfile() (tea.Model, tea.Cmd) {
+	m.lobby.active = false
+	rec, _ := playerDB.lookup(m.playerID)
+	m.profile = profile{active: true, puzzles: len(rec.Puzzles)}
+	return m, m.clearScreen()
+}
+
+// updateProfile handles keys on the profile
+func (m model) updateProfile(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.profile.editing {
+		switch msg.Type {
+		case tea.KeyCtrlC:
+			return m, tea.Quit
+		case tea.KeyEsc:
+			m.profile.editing, m.profile.err = false, ""
+		case tea.KeyEnter:
+			if err := m.rename(m.profile.text); err != nil {
+				m.profile.err = "Can't go by that name, " + err.Error()
+				break
+			}
+			m.profile.editing, m.profile.err = false, ""
+		case tea.KeyBackspace:
+			if text := []rune(m.profile.text); len(text) > 0 {
+				m.profile.text = string(text[:len(text)-1])
+			}
+		case tea.KeySpace:
+			m.profile.text += " "
+		case tea.KeyRunes:
+			for _, r := range msg.Runes {
+				if len([]rune(m.profile.text)) < MaxNameWidth && unicode.IsPrint(r) {
+					m.profile.text += string(r)
+				}
+			}
+		}
+		return m, nil
+	}
+
+	step, fields := 1, len(profileSections[m.profile.section].fields)
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.profile = profile{}
+		return m.backToLobby()
+	case "tab", "shift+tab":
+		turn := 1
+		if msg.String() == "shift+tab" {
+			turn = len(profileSections) - 1
+		}
+		m.profile.section = (m.profile.section + turn) % len(profileSections)
+		m.profile.choice, m.profile.err = 0, ""
+		return m, m.clearScreen()
+	case "up", "k":
+		m.profile.choice = (m.profile.choice + fields - 1) % fields
+	case "down", "j":
+		m.profile.choice = (m.profile.choice + 1) % fields
+	case "left", "h":
+		step = -1
+		fallthrough
+	case "right", "l", "enter", " ":
+		m.profile.err = ""
+		n, p := m.notifications, m.privacy
+		switch profileSections[m.profile.section].fields[m.profile.choice] {
+		case profileName:
+			if step > 0 {
+				m.profile.editing, m.profile.text = true, m.playerName
+			}
+		case profileAvatar:
+			if !persistent(m.playerID) {
+				m.profile.err = avatarNeedsKey
+				break
+			}
+			m.setAvatar(nextAvatar(m.avatar, step))
+		case profileTheme:
+			m.setTheme(m.theme.next(step))
+		case profileMacros:
+			if step > 0 {
+				return m.openMacroEditor()
+			}
+		case profileTurnBell:
+			n.TurnBell = !n.TurnBell
+			m.setNotifications(n)
+		case profileMatchBell:
+			n.MatchBell = !n.MatchBell
+			m.setNotifications(n)
+		case profileChatPings:
+			n.ChatPings = !n.ChatPings
+			m.setNotifications(n)
+		case profileDesktop:
+			n.Desktop = !n.Desktop
+			m.setNotifications(n)
+		case profileReplays, profileLeaderboard, profileSpectators:
+			if !persistent(m.playerID) {
+				m.profile.err = "Guests are already off the leaderboards, and their games aren't kept under their name"
+				break
+			}
+			switch profileSections[m.profile.section].fields[m.profile.choice] {
+			case profileReplays:
+				p.NoReplays = !p.NoReplays
+			case profileLeaderboard:
+				p.Unranked = !p.Unranked
+			case profileSpectators:
+				p.Anonymous = !p.Anonymous
+			}
+			m.setPrivacy(p)
+		}
+	}
+	return m, nil
+}
+
+// rename changes the player's display name for the rest of the session,
+// and for next time if they have a key. The new name is held for them like
+// the one they connected under, see holdDisplayName.
+func (m *model) rename(name string) error {
+	name = sanitize(name, MaxNameWidth)
+	switch {
+	case name == m.playerName:
+		return nil
+	case !chosenName(name):
+		return ErrNameNotChosen
+	case !nameClaims.hold(name, m.playerID):
+		return ErrNameTaken
+	}
+	if done, id := m.done, m.playerID; done != nil {
+		go func() {
+			<-done
+			nameClaims.letGo(name, id)
+		}()
+	}
+	m.playerName = name
+	err := playerDB.change(m.playerID, func(rec *PlayerRecord) {
+		rec.Name = name
+	})
+	if err != nil {
+		log.Printf("players: %v", err)
+	}
+	return nil
+}
+
+// setTheme picks the player's board theme and saves it to their profile
+func (m *model) setTheme(theme boardTheme) {
+	m.theme = theme
+	err := playerDB.change(m.playerID, func(rec *PlayerRecord) {
+		rec.Theme = theme.name
+	})
+	if err != nil {
+		log.Printf("players: %v", err)
+	}
+}
+
+// profileValue is what a field's set to, as it's shown
+func (m model) profileValue(field int) string {
+	switch field {
+	case profileName:
+		if m.profile.editing {
+			return m.profile.text + "_"
+		}
+		return m.playerName
+	case profileAvatar:
+		if m.avatar == "" {
+			return footerStyle.Render("none")
+		}
+		return m.avatar
+	case profileTheme:
+		preview := m.theme.mark(PlayerX).Render("[X]") + m.theme.mark(PlayerO).Render("[O]") + m.theme.cell.Render("[ ]")
+		return m.theme.name + "  " + preview
+	case profileMacros:
+		if len(m.macros) == 0 {
+			return footerStyle.Render("none bound")
+		}
+		keys := make([]string, 0, len(m.macros))
+		for _, key := range macroKeys {
+			if m.macros[string(key)] != "" {
+				keys = append(keys, string(key))
+			}
+		}
+		return "on " + strings.Join(keys, ", ")
+	case profileTurnBell:
+		return "bell " + onOff(m.notifications.TurnBell) + footerStyle.Render(", when the opponent has moved")
+	case profileMatchBell:
+		return "bell " + onOff(m.notifications.MatchBell) + footerStyle.Render(", to accept it in time")
+	case profileChatPings:
+		return "bell " + onOff(m.notifications.ChatPings) + footerStyle.Render(", when the opponent says something")
+	case profileDesktop:
+		return onOff(m.notifications.Desktop) + footerStyle.Render(", a notification for each bell, in terminals with OSC 9")
+	case profileReplays:
+		if m.privacy.NoReplays {
+			return "hidden" + footerStyle.Render(", your games aren't on the web")
+		}
+		return "public" + footerStyle.Render(", your games are on the web's replay pages")
+	case profileLeaderboard:
+		if m.privacy.Unranked {
+			return "left off" + footerStyle.Render(", here, on the web and across servers")
+		}
+		return "listed" + footerStyle.Render(", with your rating")
+	case profileSpectators:
+		if m.privacy.Anonymous {
+			return "see " + AnonymousName + footerStyle.Render(", as do overlays and replay pages")
+		}
+		return "see your name"
+	}
+	return ""
+}
+
+// profileView draws the profile: who they are, the fields they can change
+// and their stats
+func (m model) profileView() string {
+	s := "\n  "
+	for i, section := range profileSections {
+		if i > 0 {
+			s += footerStyle.Render(" · ")
+		}
+		if i == m.profile.section {
+			s += headerStyle.Render(section.title)
+		} else {
+			s += footerStyle.Render(section.title)
+		}
+	}
+	s += "\n"
+	if persistent(m.playerID) {
+		s += footerStyle.Render("  Key "+m.playerID) + "\n\n"
+	} else {
+		s += footerStyle.Render("  No key, changes last until you disconnect") + "\n\n"
+	}
+
+	for i, field := range profileSections[m.profile.section].fields {
+		line := fmt.Sprintf("%-13s", profileLabels[field]) + m.profileValue(field)
+		if i == m.profile.choice {
+			s += headerStyle.Render("> ") + line + "\n"
+		} else {
+			s += "  " + line + "\n"
+		}
+	}
+
+	if m.profile.section == 0 {
+		s += "\n" + headerStyle.Render("  Stats") + "\n"
+		for _, game := range enabledGames {
+			s += fmt.Sprintf("  %-13s", game.Title()) + formatRecord(m.playerID, game.Name()) + formatCasualRecord(m.playerID, game.Name()) + "\n"
+		}
+		if persistent(m.playerID) && gameEnabled(enabledGames, GameTicTacToe) {
+			s += fmt.Sprintf("  %-13s", "Puzzles") + footerStyle.Render(fmt.Sprintf(" %d of %d solved", m.profile.puzzles, len(puzzles))) + "\n"
+		}
+	}
+
+	if m.profile.err != "" {
+		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render("  "+m.profile.err) + "\n"
+	}
+	if m.profile.editing {
+		s += footerStyle.Render("\n  Press enter to go by this name, esc to cancel") + "\n"
+	} else {
+		s += footerStyle.Render("\n  Press enter or ←/→ to change, tab for the next page, esc for the lobby, q to quit") + "\n"
+	}
+	return s
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// TestProfile changes a key user's name, avatar, board and macros from
+// their profile, and finds them saved
+func TestProfile(t *testing.T) {
+	ps, err := openPlayerStore(filepath.Join(t.TempDir(), "players.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.db.Close()
+	old, oldClaims := playerDB, nameClaims
+	t.Cleanup(func() { playerDB, nameClaims = old, oldClaims })
+	playerDB = ps
+	nameClaims = &nameRegistry{owners: map[string]string{}, held: map[string]nameHold{}}
+	if !nameClaims.hold("bea", "SHA256:b") {
+		t.Fatal("bea couldn't go by bea")
+	}
+
+	m := initialModel()
+	m.playerID, m.playerName = "SHA256:a", "ann"
+	next, _ := m.openProfile()
+	m = next.(model)
+	special := map[string]tea.KeyType{"enter": tea.KeyEnter, "esc": tea.KeyEsc, "down": tea.KeyDown, "left": tea.KeyLeft, "backspace": tea.KeyBackspace, "tab": tea.KeyTab}
+	press := func(keys ...string) {
+		t.Helper()
+		for _, key := range keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+			if k, ok := special[key]; ok {
+				msg = tea.KeyMsg{Type: k}
+			}
+			var next tea.Model
+			if m.macroEditor.active {
+				next, _ = m.updateMacroEditor(msg)
+			} else {
+				next, _ = m.updateProfile(msg)
+			}
+			m = next.(model)
+			if height := lip.Height(m.view()); height > MinTerminalHeight {
+				t.Fatalf("the profile is %d lines, taller than a %d line terminal", height, MinTerminalHeight)
+			}
+		}
+	}
+
+	// someone else's name won't do, a name of their own will
+	press("enter", "backspace", "backspace", "backspace", "bea", "enter")
+	if m.playerName != "ann" || m.profile.err == "" || !m.profile.editing {
+		t.Errorf("took bea's name: name %q, error %q", m.playerName, m.profile.err)
+	}
+	press("backspace", "ckie", "enter")
+	if m.playerName != "beckie" || m.profile.editing {
+		t.Errorf("went by %q, want beckie", m.playerName)
+	}
+
+	// an avatar and a board, the board back round the list the other way
+	press("down", "enter", "down", "left")
+	if m.avatar != avatars[1] || m.theme.name != themes[len(themes)-1].name {
+		t.Errorf("avatar %q and board %q, want %q and %q", m.avatar, m.theme.name, avatars[1], themes[len(themes)-1].name)
+	}
+
+	// macros are edited from here, and come back here
+	press("down", "enter", "enter", "B2 enter", "enter", "esc")
+	if !m.profile.active || m.macros["1"] != "B2 enter" {
+		t.Errorf("after the macro editor: profile %v, macros %v", m.profile.active, m.macros)
+	}
+
+	// the match bell's on to begin with, off it goes and the turn bell on
+	if m.notifications != defaultNotifications {
+		t.Errorf("notifications %+v to begin with, want the defaults", m.notifications)
+	}
+	press("tab", "enter", "down", "enter")
+	want := notifications{TurnBell: true}
+	if m.notifications != want {
+		t.Errorf("notifications %+v, want %+v", m.notifications, want)
+	}
+
+	// and on to privacy, off the leaderboards
+	press("tab", "down", "enter")
+	if m.privacy != (privacy{Unranked: true}) {
+		t.Errorf("privacy %+v, want off the leaderboards", m.privacy)
+	}
+
+	rec, _ := ps.lookup("SHA256:a")
+	if rec.Privacy != m.privacy {
+		t.Errorf("saved privacy %+v, want %+v", rec.Privacy, m.privacy)
+	}
+	if rec.notificationSettings() != want {
+		t.Errorf("saved notifications %+v, want %+v", rec.notificationSettings(), want)
+	}
+	if rec.Name != "beckie" || rec.Avatar != avatars[1] || rec.Theme != themes[len(themes)-1].name || rec.Macros["1"] != "B2 enter" {
+		t.Errorf("saved %+v", rec)
+	}
+	if themeNamed(rec.Theme).name != m.theme.name || themeNamed("gone").name != themes[0].name {
+		t.Error("themes don't come back by name")
+	}
+
+	press("esc")
+	if m.profile.active || !m.lobby.active {
+		t.Error("esc didn't go back to the lobby")
+	}
+}
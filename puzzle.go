@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Puzzles are Tic-Tac-Toe positions with X to move and a win there for the
+// taking, against the perfect computer. They go from wins in one to forks,
+// and on to forks set up a move ahead; each has the one winning move, so
+// finding it is the lesson. Key users' progress is kept with their record.
+
+// puzzleSpec is a position to solve
+type puzzleSpec struct {
+	name  string            // what progress is kept by, it stays the same once a puzzle's out
+	title string            // what it's about
+	hint  string            // a nudge towards the winning move
+	rows  [BoardSize]string // the board a row at a time, . for an empty cell
+}
+
+// puzzles are the positions to solve, in the order they're played
+var puzzles = []puzzleSpec{
+	{"first-line", "Win in one", "Three in a row wins, look along the top", [BoardSize]string{"XX.", "OO.", "..."}},
+	{"win-and-block", "Win in one", "O is about to win, but you get there first", [BoardSize]string{"X.O", "XO.", "..."}},
+	{"first-fork", "Fork", "Make two threats at once, O can only block one", [BoardSize]string{"XXO", "O..", "..."}},
+	{"edge-fork", "Fork", "The corner between your marks makes two lines", [BoardSize]string{"..X", "XOO", "..."}},
+	{"corner-fork", "Fork", "A row and a diagonal, both through one corner", [BoardSize]string{"X.O", "O..", "X.."}},
+	{"block-and-fork", "Block and fork", "O threatens a diagonal, block it where it makes two threats of your own", [BoardSize]string{"X.O", ".O.", "..X"}},
+	{"forced-block", "Two moves ahead", "Block first, the block sets up the fork", [BoardSize]string{"OX.", "O.X", "..."}},
+	{"make-them-block", "Two moves ahead", "Block, and the fork comes next", [BoardSize]string{".XO", "X.O", "..."}},
+	{"quiet-block", "Two moves ahead", "Your block starts a line O has to answer", [BoardSize]string{"OO.", "X..", ".X."}},
+}
+
+// board sets out the puzzle's position
+func (p puzzleSpec) board() [][]string {
+	return boardFromRows(p.rows)
+}
+
+// boardFromRows sets out a Tic-Tac-Toe board written a row at a time, X, O
+// or . for an empty cell
+func boardFromRows(rows [BoardSize]string) [][]string {
+	board := ticTacToe.newBoard()
+	for y, row := range rows {
+		for x, cell := range row {
+			if cell != '.' {
+				board[y][x] = string(cell)
+			}
+		}
+	}
+	return board
+}
+
+// puzzleMode is a player working through the puzzles
+type puzzleMode struct {
+	active bool
+	index  int             // the puzzle on the board
+	solved map[string]bool // by name, what's in their record for key users
+	hint   bool            // showing the puzzle's hint
+}
+
+// next is the first puzzle after the one on the board that they haven't
+// solved, round to the start, or just the one after if they've solved them
+// all
+func (p puzzleMode) next() int {
+	for i := 1; i <= len(puzzles); i++ {
+		if j := (p.index + i) % len(puzzles); !p.solved[puzzles[j].name] {
+			return j
+		}
+	}
+	return (p.index + 1) % len(puzzles)
+}
+
+// startPuzzles puts up the first puzzle the player hasn't solved, they're X
+// and the computer defends as O
+func (m model) startPuzzles() (tea.Model, tea.Cmd) {
+	if !gameEnabled(enabledGames, GameTicTacToe) {
+		m.lobby.err = "Puzzles are Tic-Tac-Toe, which this server doesn't have on"
+		return m, nil
+	}
+	m.lobby.active = false
+	m.opponentMenu = opponentMenu{}
+	solved := map[string]bool{}
+	rec, _ := playerDB.lookup(m.playerID)
+	for _, name := range rec.Puzzles {
+		solved[name] = true
+	}
+	m.puzzle = puzzleMode{active: true, index: len(puzzles) - 1, solved: solved}
+	m.puzzle.index = m.puzzle.next()
+	m.computer, m.difficulty = PlayerO, AIHard
+	m.rules = ticTacToe // the computer only knows Tic-Tac-Toe
+	m.setPuzzle()
+	return m, m.clearScreen()
+}
+
+// setPuzzle puts the puzzle's position on the board, for a first go or
+// another one
+func (m *model) setPuzzle() {
+	m.resetGame()
+	p := puzzles[m.puzzle.index]
+	m.board = p.board()
+	m.moves = BoardSize*BoardSize - len(freeCells(m.board))
+	m.puzzle.hint = false
+}
+
+// solvePuzzle notes that the player won the puzzle on the board
+func (m *model) solvePuzzle() {
+	name := puzzles[m.puzzle.index].name
+	if m.puzzle.solved[name] {
+		return
+	}
+	m.puzzle.solved[name] = true
+	err := playerDB.change(m.playerID, func(rec *PlayerRecord) {
+		if !slices.Contains(rec.Puzzles, name) {
+			rec.Puzzles = append(rec.Puzzles, name)
+		}
+	})
+	if err != nil {
+		log.Printf("players: %v", err)
+	}
+}
+
+// updatePuzzle handles the puzzle keys, ok is false for keys that aren't
+// puzzle keys
+func (m model) updatePuzzle(msg tea.KeyMsg) (next tea.Model, cmd tea.Cmd, ok bool) {
+	switch msg.String() {
+	case "r":
+		m.setPuzzle()
+	case "n":
+		m.puzzle.index = m.puzzle.next()
+		m.setPuzzle()
+	case "?":
+		m.puzzle.hint = !m.puzzle.hint
+		return m, nil, true
+	case "esc":
+		if m.done == nil {
+			// a standalone game, there's no lobby to go back to
+			return m, nil, false
+		}
+		next, cmd = m.fresh().backToLobby()
+		return next, cmd, true
+	default:
+		return m, nil, false
+	}
+	return m, m.clearScreen(), true
+}
+
+// puzzleView says which puzzle is on the board and how far along they are
+func (m model) puzzleView() string {
+	p := puzzles[m.puzzle.index]
+	s := "\n" + headerStyle.Render(fmt.Sprintf("Puzzle %d of %d: %s", m.puzzle.index+1, len(puzzles), p.title))
+	if m.puzzle.solved[p.name] {
+		s += footerStyle.Render(" (solved)")
+	}
+	solved := 0
+	for _, spec := range puzzles {
+		if m.puzzle.solved[spec.name] {
+			solved++
+		}
+	}
+	s += "\n" + footerStyle.Render(fmt.Sprintf("X to move and win, solved %d of %d", solved, len(puzzles))) + "\n"
+	if m.puzzle.hint {
+		s += footerStyle.Render("Hint: "+p.hint) + "\n"
+	}
+	return s
+}
+
+// puzzleKeys says what the keys do in a puzzle
+func (m model) puzzleKeys() string {
+	keys := "Press r to try again, n for the next puzzle"
+	if m.winner == Empty {
+		keys = "Press ? for a hint, r to start over, n for the next puzzle"
+	}
+	if m.done != nil {
+		keys += ", esc for the lobby"
+	}
+	return keys + ", q to quit"
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// TestPuzzles checks each puzzle is a position a game could reach, with X
+// to move and just the one move that wins against best defence
+func TestPuzzles(t *testing.T) {
+	names := map[string]bool{}
+	for _, p := range puzzles {
+		t.Run(p.name, func(t *testing.T) {
+			if names[p.name] {
+				t.Fatalf("another puzzle's called %s too, progress is kept by name", p.name)
+			}
+			names[p.name] = true
+			board := p.board()
+			xs, os := 0, 0
+			for _, row := range board {
+				xs += strings.Count(strings.Join(row, ""), PlayerX)
+				os += strings.Count(strings.Join(row, ""), PlayerO)
+			}
+			if xs != os {
+				t.Fatalf("%d Xs and %d Os, it isn't X to move", xs, os)
+			}
+			if ticTacToe.checkWinner(board, PlayerX) != nil || ticTacToe.checkWinner(board, PlayerO) != nil {
+				t.Fatal("it's already won")
+			}
+			var wins []coord
+			for _, cell := range freeCells(board) {
+				board[cell.row][cell.col] = PlayerX
+				if minimax(board, PlayerX, PlayerO, 1) > 0 {
+					wins = append(wins, cell)
+				}
+				board[cell.row][cell.col] = Empty
+			}
+			if len(wins) != 1 {
+				t.Errorf("winning moves %v, want just the one", wins)
+			}
+		})
+	}
+}
+
+// TestSolvePuzzle solves the first puzzle and comes back to the second
+func TestSolvePuzzle(t *testing.T) {
+	ps, err := openPlayerStore(filepath.Join(t.TempDir(), "players.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.db.Close()
+	old := playerDB
+	t.Cleanup(func() { playerDB = old })
+	playerDB = ps
+
+	start := func() model {
+		t.Helper()
+		m := initialModel()
+		m.playerID = "SHA256:a"
+		next, _ := m.startPuzzles()
+		return next.(model)
+	}
+	m := start()
+	if m.puzzle.index != 0 || !slices.Equal(m.board[0], []string{PlayerX, PlayerX, Empty}) {
+		t.Fatalf("started on puzzle %d, board %v, want the first", m.puzzle.index, m.board)
+	}
+	if height := lip.Height(m.view()); height > MinTerminalHeight {
+		t.Errorf("a puzzle is %d lines, taller than a %d line terminal", height, MinTerminalHeight)
+	}
+
+	// a wrong move can be taken back to the start
+	m.playLocal(move{coord{2, 0}, coord{2, 0}})
+	next, _, ok := m.updatePuzzle(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	m = next.(model)
+	if !ok || m.board[2][0] != Empty || m.moves != 4 {
+		t.Errorf("after r the board is %v with %d moves, want the puzzle again", m.board, m.moves)
+	}
+
+	m.playLocal(move{coord{0, 2}, coord{0, 2}})
+	if m.winner != PlayerX || m.winnerLine() != "Solved!" {
+		t.Fatalf("winner %q, %q, want the puzzle solved", m.winner, m.winnerLine())
+	}
+	if rec, _ := ps.lookup("SHA256:a"); !slices.Equal(rec.Puzzles, []string{puzzles[0].name}) {
+		t.Errorf("solved %v, want the first", rec.Puzzles)
+	}
+
+	// next time, they're on to the second
+	if m = start(); m.puzzle.index != 1 {
+		t.Errorf("came back to puzzle %d, want the second", m.puzzle.index)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+
+	"tictactui/matchmaking"
+)
+
+// After a game either player can ask to play the same opponent again the
+// next time they queue. If both do, whichever of them gets back in the queue
+// first is kept for the other for matchmaking.RematchWindow, and they're
+// paired as soon as the other joins; otherwise they're paired like anyone.
+
+// requeueable reports whether the player can go back in the queue from the
+// game that just finished, the games that are part of something else stay
+// where they are
+func (m model) requeueable() bool {
+	return m.gameSession != nil && !m.spectating && m.tournament == nil && m.hill == nil && !m.crowd && m.winner != Empty
+}
+
+// togglePreferSame sets whether a player would like to play the same
+// opponent again, once the game's over
+func (gs *GameSession) togglePreferSame(player string) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if gs.Winner == Empty {
+		return
+	}
+	i := playerIndex(player)
+	gs.PreferSame[i] = !gs.PreferSame[i]
+	sessionManager.queue.PreferSame(gs.match, gs.match.Players[i].ID, gs.PreferSame[i])
+	gs.notify()
+}
+
+// opponentLeftWhy tells a player whose opponent left why they're back in
+// the lobby, and how to find them again if they both wanted to
+func (m model) opponentLeftWhy() string {
+	if m.forfeited && m.winner == m.playerSymbol {
+		return "Your opponent left and didn't come back, the win's yours"
+	}
+	if m.winner == Empty || !m.preferSame[0] || !m.preferSame[1] {
+		return "Your opponent left, the game's over"
+	}
+	queue := "Quick match"
+	if m.casual {
+		queue = "Casual match"
+	}
+	return fmt.Sprintf("Your opponent's gone to play you again, pick %s within %s to join them", queue, formatDuration(matchmaking.RematchWindow))
+}
+
+// preferSameView says who'd like to play each other again, and how to ask
+func (m model) preferSameView() string {
+	if !m.requeueable() {
+		return ""
+	}
+	mine, theirs := m.preferSame[playerIndex(m.playerSymbol)], m.preferSame[playerIndex(opponentOf(m.playerSymbol))]
+	switch {
+	case mine && theirs:
+		return headerStyle.Render(fmt.Sprintf("You both want to play each other again, press n within %s to be paired", formatDuration(matchmaking.RematchWindow))) + "\n"
+	case theirs:
+		return headerStyle.Render("Your opponent would like to play you again, press p if you would too") + "\n"
+	case mine:
+		return footerStyle.Render("Waiting to see if your opponent would like to play you again too, p to change your mind") + "\n"
+	}
+	return footerStyle.Render("Press p to play the same opponent next time you queue, if they'd like to too") + "\n"
+}
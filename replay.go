@@ -0,0 +1,415 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// MaxReplays is how many of their last games a player can rewatch
+const MaxReplays = 10
+
+// replay is one game, move by move
+type replay struct {
+	id     string    // the game session's, several games can share it
+	rules  gameRules // which game it was
+	names  [2]string // X and O
+	ids    [2]string // X and O's key fingerprints, empty for guests and older journals
+	moves  []Event
+	result Event     // zero if the game never finished
+	played time.Time // when the first move was made
+	seed   uint64    // what its random choices were made from, 0 in older journals
+	draw   uint64    // what its tournament's bracket was drawn from, 0 outside tournaments
+}
+
+// replaysIn splits a game session's journal into its games, oldest first.
+// Games nobody moved in are left out.
+func replaysIn(id string, events []Event) []replay {
+	var replays []replay
+	game := replay{id: id, rules: ticTacToe}
+	done := func() {
+		if len(game.moves) > 0 {
+			replays = append(replays, game)
+		}
+		game = replay{id: id, rules: game.rules, names: game.names, ids: game.ids, draw: game.draw}
+	}
+	for _, e := range events {
+		switch e.Type {
+		case EventJoin:
+			game.names[playerIndex(e.Player)] = e.Name
+			game.ids[playerIndex(e.Player)] = e.ID
+			game.rules = rulesFor(e.Game)
+		case EventMove, EventSwap:
+			if len(game.moves) == 0 {
+				game.played = e.Time
+			}
+			game.moves = append(game.moves, e)
+		case EventResult:
+			game.result = e
+			done()
+		case EventRestart:
+			done()
+		case EventSeed:
+			if e.Tournament {
+				game.draw = e.Seed
+			} else {
+				game.seed = e.Seed
+			}
+		case EventTakeback:
+			if len(game.moves) > 0 {
+				game.moves = game.moves[:len(game.moves)-1]
+			}
+		}
+	}
+	done()
+	return replays
+}
+
+// board is the position after the first step moves
+func (r replay) board(step int) [][]string {
+	board := r.rules.newBoard()
+	rows, cols := r.rules.Size()
+	for _, e := range r.moves[:step] {
+		mv, ok := parseMove(e.Cell, rows, cols)
+		switch {
+		case !ok:
+		case e.Type == EventSwap:
+			board[mv.to.row][mv.to.col] = e.Player // X's first move is O's
+		default:
+			r.rules.play(board, e.Player, mv)
+		}
+	}
+	return board
+}
+
+// notation lists the game's moves in the order they were made, X's first
+func (r replay) notation() string {
+	cells := make([]string, len(r.moves))
+	for i, e := range r.moves {
+		cells[i] = e.Cell
+		if e.Type == EventSwap {
+			cells[i] = "swap"
+		}
+	}
+	return strings.Join(cells, " ")
+}
+
+// outcome says how the game ended
+func (r replay) outcome() string {
+	switch r.result.Result {
+	case "":
+		return "unfinished"
+	case Draw:
+		return "draw"
+	}
+	won := r.names[playerIndex(r.result.Result)] + " won"
+	if r.result.Reason == ReasonTime {
+		won += " on time"
+	}
+	return won
+}
+
+// replayLog keeps each key user's last few games to rewatch
+type replayLog struct {
+	replays map[string][]replay // by player ID, newest last
+	mutex   sync.Mutex
+}
+
+// recentReplays is every key user's last games on the server. Guests get a
+// new ID every time, so theirs would never be watched.
+var recentReplays = &replayLog{replays: map[string][]replay{}}
+
+// add remembers a game for the players who played it, forgetting their
+// oldest once they have enough
+func (l *replayLog) add(playerIDs []string, r replay) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, id := range playerIDs {
+		if !persistent(id) {
+			continue
+		}
+		l.replays[id] = append(l.replays[id], r)
+		if len(l.replays[id]) > MaxReplays {
+			l.replays[id] = l.replays[id][1:]
+		}
+	}
+}
+
+// list returns a player's games, newest first
+func (l *replayLog) list(playerID string) []replay {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	replays := slices.Clone(l.replays[playerID])
+	slices.Reverse(replays)
+	return replays
+}
+
+// forget drops a player's games, and their name from the games their
+// opponents keep
+func (l *replayLog) forget(playerID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.replays, playerID)
+	for _, replays := range l.replays {
+		for i := range replays {
+			if seat := slices.Index(replays[i].ids[:], playerID); seat >= 0 {
+				replays[i].names[seat] = DeletedName
+				replays[i].ids[seat] = ""
+			}
+		}
+	}
+}
+
+// saveReplay keeps the game that just finished for its players to rewatch.
+// Call with the session locked.
+func (gs *GameSession) saveReplay() {
+	replays := replaysIn(gs.ID, gs.journal.Events())
+	if len(replays) == 0 {
+		return
+	}
+	ids := []string{gs.match.Players[0].ID, gs.match.Players[1].ID}
+	recentReplays.add(ids, replays[len(replays)-1])
+}
+
+// replayViewer is where players pick one of their games and step through it
+type replayViewer struct {
+	active   bool
+	replays  []replay
+	choice   int  // highlighted game
+	watching bool // stepping through the highlighted game
+	step     int  // how many of its moves are on the board
+	file     bool // playing a journal from the command line, there's nowhere to go back to
+}
+
+// openReplays shows the player's last games
+func (m model) openReplays() (tea.Model, tea.Cmd) {
+	m.lobby.active = false
+	m.replays = replayViewer{active: true, replays: recentReplays.list(m.playerID)}
+	return m, m.clearScreen()
+}
+
+// rewatch leaves the game that just finished to step through it
+func (m model) rewatch() (tea.Model, tea.Cmd) {
+	replays := replaysIn(m.gameSession.ID, m.gameSession.journal.Events())
+	close(m.left)
+	f := m.fresh()
+	if len(replays) == 0 {
+		return f.backToLobby()
+	}
+	f.replays = replayViewer{active: true, replays: replays[len(replays)-1:], watching: true}
+	f.loadAnnotations()
+	return f, f.clearScreen()
+}
+
+// journalID is the ID of the game a journal file is for, they're named
+// <game id>-<start time>.jsonl
+func journalID(path string) string {
+	id, _, _ := strings.Cut(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "-")
+	return id
+}
+
+// replayFile sets up the model to play back the games in a journal file,
+// for the replay command
+func replayFile(path string) (model, error) {
+	events, err := readJournal(path)
+	if err != nil {
+		return model{}, err
+	}
+	replays := replaysIn(journalID(path), events)
+	if len(replays) == 0 {
+		return model{}, fmt.Errorf("%s: no moves to replay", path)
+	}
+	m := initialModel()
+	// straight into the game if there's only the one
+	m.replays = replayViewer{active: true, replays: replays, file: true, watching: len(replays) == 1}
+	if m.replays.watching {
+		m.loadAnnotations()
+	}
+	return m, nil
+}
+
+// updateReplays handles keys while picking a game to rewatch, or stepping
+// through it
+func (m model) updateReplays(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	rv := &m.replays
+	if msg.String() == "ctrl+c" || msg.String() == "q" {
+		return m, tea.Quit
+	}
+
+	if rv.watching && m.annotating {
+		return m.updateAnnotation(msg)
+	}
+	if rv.watching {
+		moves := len(rv.replays[rv.choice].moves)
+		switch msg.String() {
+		case "esc":
+			if rv.file && len(rv.replays) == 1 {
+				return m, tea.Quit
+			}
+			rv.watching = false
+			return m, m.clearScreen()
+		case "right", "l", " ", "n":
+			rv.step = min(rv.step+1, moves)
+		case "left", "h", "backspace", "p":
+			rv.step = max(rv.step-1, 0)
+		case "home", "g":
+			rv.step = 0
+		case "end", "G":
+			rv.step = moves
+		case "a":
+			if m.canAnnotate() {
+				m = m.startAnnotating()
+			}
+		case "w":
+			if m.done != nil {
+				rr := openReplayRoom(rv.replays[rv.choice], rv.step, m.playerID, m.playerName)
+				return m.enterReplayRoom(rr)
+			}
+		}
+		return m, nil
+	}
+
+	games := len(rv.replays)
+	switch msg.String() {
+	case "esc":
+		if rv.file {
+			return m, tea.Quit
+		}
+		m.replays = replayViewer{}
+		return m.backToLobby()
+	case "up", "k":
+		if games > 0 {
+			rv.choice = (rv.choice + games - 1) % games
+		}
+	case "down", "j":
+		if games > 0 {
+			rv.choice = (rv.choice + 1) % games
+		}
+	case "enter", " ":
+		if games > 0 {
+			rv.watching = true
+			rv.step = 0
+			m.loadAnnotations()
+			return m, m.clearScreen()
+		}
+	}
+	return m, nil
+}
+
+// replaysView draws the list of games to rewatch, or the game being
+// rewatched
+func (m model) replaysView() string {
+	if m.replays.watching {
+		r := m.replays.replays[m.replays.choice]
+		// headed by the game's title, whichever game is picked in the lobby
+		watching := m
+		watching.rules = r.rules
+		return "\n" + headerStyle.Render(watching.art().Title) + "\n\n" + m.replayView(r, m.replays.step)
+	}
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+
+	s += headerStyle.Render("  Your last games") + "\n\n"
+	if len(m.replays.replays) == 0 {
+		if persistent(m.playerID) {
+			s += "  None yet, games show up here once they're over\n"
+		} else {
+			s += "  Connect with an SSH key to keep your games to rewatch\n"
+		}
+	}
+	for i, r := range m.replays.replays {
+		line := fmt.Sprintf("%s  %s %s vs %s %s", r.id, styledPlayer(PlayerX), r.names[0], styledPlayer(PlayerO), r.names[1])
+		line += footerStyle.Render(fmt.Sprintf("  %s, %s, %d moves, %s", r.rules.Title(), r.outcome(), len(r.moves), formatAgo(time.Since(r.played))))
+		if i == m.replays.choice {
+			s += headerStyle.Render("> ") + line + "\n"
+		} else {
+			s += "  " + line + "\n"
+		}
+	}
+	s += footerStyle.Render("\n  Press enter to watch, esc to go back, q to quit") + "\n"
+	return s
+}
+
+// replayView draws a game as it stood after the first step moves
+func (m model) replayView(r replay, step int) string {
+	board := r.board(step)
+	var winning []coord
+	if step == len(r.moves) && r.result.Result != "" && r.result.Result != Draw {
+		_, winning = r.rules.result(board, r.result.Result)
+	}
+
+	// the move that was just made stands out, where the mark or piece went
+	last := coord{-1, -1}
+	if step > 0 {
+		rows, cols := r.rules.Size()
+		if mv, ok := parseMove(r.moves[step-1].Cell, rows, cols); ok {
+			last = mv.to
+		}
+	}
+
+	s := boardIndent
+	for x := range board[0] {
+		s += footerStyle.Render(" " + columnLabel(x) + " ")
+	}
+	s += "\n"
+	for y, row := range board {
+		s += footerStyle.Render(fmt.Sprintf("%*d ", len(boardIndent)-1, y+1))
+		for x, cell := range row {
+			left, right := "[", "]"
+			if crowned(cell) {
+				left, right = "{", "}"
+			}
+			switch {
+			case !r.rules.playable(y, x):
+				s += "   "
+			case slices.Contains(winning, coord{row: y, col: x}):
+				s += m.theme.win.Render(left + owner(cell) + right)
+			case cell == Empty:
+				s += m.theme.cell.Render("[ ]")
+			case last == coord{y, x}:
+				s += m.theme.mark(owner(cell)).Underline(true).Render(left + owner(cell) + right)
+			default:
+				s += m.theme.cell.Render(left) + m.theme.player(owner(cell)) + m.theme.cell.Render(right)
+			}
+		}
+		s += "\n"
+	}
+
+	s += "\n"
+	if step == 0 {
+		s += footerStyle.Render(fmt.Sprintf("Start, %d moves to go", len(r.moves))) + "\n"
+	} else {
+		move := r.moves[step-1]
+		s += footerStyle.Render(fmt.Sprintf("Move %d of %d: ", step, len(r.moves))) + m.theme.player(move.Player) + " " + move.Cell
+		if move.Type == EventSwap {
+			s += footerStyle.Render(" (swap)")
+		}
+		s += footerStyle.Render(" after "+formatDuration(move.Time.Sub(r.played))) + "\n"
+	}
+	if step == len(r.moves) {
+		s += headerStyle.Render("Result: "+r.outcome()) + "\n"
+	} else {
+		s += "\n"
+	}
+	s += m.annotationsView()
+	s += footerStyle.Render("\nReplay of game "+r.id) + "\n"
+	s += m.theme.player(PlayerX) + " " + r.names[0] + footerStyle.Render("  vs  ") + m.theme.player(PlayerO) + " " + r.names[1] + "\n"
+	if m.replayRoom != nil {
+		s += m.replayRoomView()
+	} else if m.replays.file && len(m.replays.replays) == 1 {
+		s += footerStyle.Render("\nPress ←/→ to step, home/end to jump, q to quit") + "\n"
+	} else if m.replays.file {
+		s += footerStyle.Render("\nPress ←/→ to step, home/end to jump, esc to go back, q to quit") + "\n"
+	} else if m.canAnnotate() {
+		s += footerStyle.Render("\nPress ←/→ to step, home/end to jump, a to add a note, w to watch together, esc to go back, q to quit") + "\n"
+	} else {
+		s += footerStyle.Render("\nPress ←/→ to step, home/end to jump, w to watch together, esc to go back, q to quit") + "\n"
+	}
+	return s
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"tictactui/matchmaking"
+)
+
+func TestReplaysIn(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	joins := []Event{
+		{Type: EventJoin, Player: PlayerX, Name: "alice", ID: "SHA256:a", Game: GameConnectFour},
+		{Type: EventJoin, Player: PlayerO, Name: "bob", Game: GameConnectFour},
+	}
+	type game struct {
+		rules    string
+		notation string
+		outcome  string
+		played   time.Time
+	}
+	tests := []struct {
+		name   string
+		events []Event
+		want   []game
+	}{
+		{
+			name: "one game",
+			events: append(joins[:2:2],
+				Event{Time: at(1), Type: EventMove, Player: PlayerX, Cell: "D6"},
+				Event{Time: at(2), Type: EventMove, Player: PlayerO, Cell: "D5"},
+				Event{Time: at(3), Type: EventResult, Result: PlayerO},
+			),
+			want: []game{{GameConnectFour, "D6 D5", "bob won", at(1)}},
+		},
+		{
+			name:   "nobody moved",
+			events: append(joins[:2:2], Event{Type: EventResult, Result: PlayerX, Reason: ReasonAbandoned}),
+		},
+		{
+			name: "unfinished",
+			events: append(joins[:2:2],
+				Event{Time: at(1), Type: EventMove, Player: PlayerX, Cell: "A6"},
+			),
+			want: []game{{GameConnectFour, "A6", "unfinished", at(1)}},
+		},
+		{
+			name: "restart starts the next game",
+			events: append(joins[:2:2],
+				Event{Time: at(1), Type: EventMove, Player: PlayerX, Cell: "A6"},
+				Event{Time: at(2), Type: EventRestart},
+				Event{Time: at(3), Type: EventMove, Player: PlayerX, Cell: "B6"},
+				Event{Time: at(4), Type: EventResult, Result: PlayerX, Reason: ReasonTime},
+				Event{Time: at(5), Type: EventMove, Player: PlayerX, Cell: "C6"},
+				Event{Time: at(6), Type: EventResult, Result: Draw},
+			),
+			want: []game{
+				{GameConnectFour, "A6", "unfinished", at(1)},
+				{GameConnectFour, "B6", "alice won on time", at(3)},
+				{GameConnectFour, "C6", "draw", at(5)},
+			},
+		},
+		{
+			name: "takebacks",
+			events: append(joins[:2:2],
+				Event{Time: at(1), Type: EventMove, Player: PlayerX, Cell: "A6"},
+				Event{Time: at(2), Type: EventMove, Player: PlayerO, Cell: "B6"},
+				Event{Time: at(3), Type: EventTakeback},
+				Event{Time: at(4), Type: EventMove, Player: PlayerO, Cell: "C6"},
+			),
+			want: []game{{GameConnectFour, "A6 C6", "unfinished", at(1)}},
+		},
+		{
+			name: "swap",
+			events: []Event{
+				{Type: EventJoin, Player: PlayerX, Name: "alice"},
+				{Type: EventJoin, Player: PlayerO, Name: "bob"},
+				{Time: at(1), Type: EventMove, Player: PlayerX, Cell: "B2"},
+				{Time: at(2), Type: EventSwap, Player: PlayerO, Cell: "B2"},
+			},
+			want: []game{{GameTicTacToe, "B2 swap", "unfinished", at(1)}},
+		},
+		{
+			name: "a journal from before games could be picked",
+			events: []Event{
+				{Time: at(1), Type: EventMove, Player: PlayerX, Cell: "A1"},
+			},
+			want: []game{{GameTicTacToe, "A1", "unfinished", at(1)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replays := replaysIn("abc123", tt.events)
+			if len(replays) != len(tt.want) {
+				t.Fatalf("got %d games, want %d", len(replays), len(tt.want))
+			}
+			for i, r := range replays {
+				got := game{r.rules.Name(), r.notation(), r.outcome(), r.played}
+				if got != tt.want[i] {
+					t.Errorf("game %d = %+v, want %+v", i+1, got, tt.want[i])
+				}
+				if r.id != "abc123" {
+					t.Errorf("game %d: id = %q, want abc123", i+1, r.id)
+				}
+			}
+		})
+	}
+}
+
+func TestReplaysInPlayers(t *testing.T) {
+	events := []Event{
+		{Type: EventJoin, Player: PlayerX, Name: "alice", ID: "SHA256:a"},
+		{Type: EventJoin, Player: PlayerO, Name: "bob"},
+		{Type: EventMove, Player: PlayerX, Cell: "A1"},
+		{Type: EventResult, Result: PlayerX},
+		// bob left and carol took their seat
+		{Type: EventJoin, Player: PlayerO, Name: "carol", ID: "SHA256:c"},
+		{Type: EventMove, Player: PlayerX, Cell: "B2"},
+	}
+	replays := replaysIn("abc123", events)
+	want := []struct{ names, ids [2]string }{
+		{[2]string{"alice", "bob"}, [2]string{"SHA256:a", ""}},
+		{[2]string{"alice", "carol"}, [2]string{"SHA256:a", "SHA256:c"}},
+	}
+	if len(replays) != len(want) {
+		t.Fatalf("got %d games, want %d", len(replays), len(want))
+	}
+	for i, r := range replays {
+		if r.names != want[i].names || r.ids != want[i].ids {
+			t.Errorf("game %d: names %q ids %q, want %q and %q", i+1, r.names, r.ids, want[i].names, want[i].ids)
+		}
+	}
+}
+
+func TestReplaysInSeeds(t *testing.T) {
+	events := []Event{
+		{Type: EventSeed, Seed: 7, Tournament: true},
+		{Type: EventSeed, Seed: 1},
+		{Type: EventMove, Player: PlayerX, Cell: "A1"},
+		{Type: EventRestart},
+		{Type: EventSeed, Seed: 2},
+		{Type: EventMove, Player: PlayerX, Cell: "B2"},
+	}
+	replays := replaysIn("abc123", events)
+	if len(replays) != 2 {
+		t.Fatalf("got %d games, want 2", len(replays))
+	}
+	for i, r := range replays {
+		// each game has its own seed, the tournament's the same for both
+		if r.seed != uint64(i+1) || r.draw != 7 {
+			t.Errorf("game %d: seed %d draw %d, want %d and 7", i+1, r.seed, r.draw, i+1)
+		}
+	}
+
+	// seeds are written as strings, a uint64 doesn't fit in a JSON number
+	// everywhere
+	data, err := json.Marshal(events[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"seed":"1"`) {
+		t.Errorf("seed event = %s, want the seed as a string", data)
+	}
+}
+
+func TestReplayBoard(t *testing.T) {
+	r := replay{
+		rules: ticTacToe,
+		moves: []Event{
+			{Type: EventMove, Player: PlayerX, Cell: "B2"},
+			{Type: EventSwap, Player: PlayerO, Cell: "B2"},
+			{Type: EventMove, Player: PlayerX, Cell: "A1"},
+			{Type: EventMove, Player: PlayerO, Cell: "Z9"}, // unreadable, skipped
+		},
+	}
+	tests := []struct {
+		step int
+		want []string
+	}{
+		{0, []string{"...", "...", "..."}},
+		{1, []string{"...", ".X.", "..."}},
+		{2, []string{"...", ".O.", "..."}},
+		{3, []string{"X..", ".O.", "..."}},
+		{4, []string{"X..", ".O.", "..."}},
+	}
+	for _, tt := range tests {
+		got, want := r.board(tt.step), lineBoard(tt.want...)
+		for y := range want {
+			for x := range want[y] {
+				if got[y][x] != want[y][x] {
+					t.Errorf("step %d: %s = %q, want %q", tt.step, cellName(y, x), got[y][x], want[y][x])
+				}
+			}
+		}
+	}
+}
+
+// TestSummaryRecord checks the post-game summary's record is the game's
+// moves, read from the journal once when the game ends
+func TestSummaryRecord(t *testing.T) {
+	gs := &GameSession{
+		ID:      "abc123",
+		Rules:   ticTacToe,
+		Board:   ticTacToe.newBoard(),
+		match:   matchmaking.Versus(matchmaking.Player{ID: "x"}, matchmaking.Player{ID: "o"}),
+		journal: &Journal{},
+	}
+	for i, cell := range []string{"A1", "B2", "A2", "C3", "A3"} {
+		gs.journal.Record(Event{Type: EventMove, Player: []string{PlayerX, PlayerO}[i%2], Cell: cell})
+	}
+	m := model{gameSession: gs, playerSymbol: PlayerX}
+	m.syncSession()
+	if m.notation != "" {
+		t.Fatalf("record %q while the game's on", m.notation)
+	}
+
+	gs.Winner = PlayerX
+	m.syncSession()
+	if want := "A1 B2 A2 C3 A3"; m.notation != want || !strings.Contains(m.summary(), "Record: "+want) {
+		t.Errorf("record %q, want %q in the summary", m.notation, want)
+	}
+	// it isn't read again on later updates
+	gs.journal.Record(Event{Type: EventMove, Player: PlayerO, Cell: "C1"})
+	if m.syncSession(); m.notation != "A1 B2 A2 C3 A3" {
+		t.Errorf("record %q after another update", m.notation)
+	}
+
+	// a restart clears it for the next game
+	gs.Winner = Empty
+	if m.syncSession(); m.notation != "" {
+		t.Errorf("record %q after a restart", m.notation)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// replayPageGame is one game on a replay page
+type replayPageGame struct {
+	Title  string
+	X, O   string
+	Played time.Time
+	Result string
+	Start  []annotation // notes from before the first move
+	Moves  []replayPageMove
+	Board  string // how it ended, as plain text
+}
+
+// replayPageMove is a move on a replay page, with its notes
+type replayPageMove struct {
+	Number int
+	Player string
+	Cell   string
+	Notes  []annotation
+}
+
+// replayPage shows a game session's games move by move, with the notes
+// players left on them, for browsers
+var replayPage = template.Must(template.New("replay").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>tictactui game {{.ID}}</title>
+<style>
+body { background: #282a36; color: #f8f8f2; font-family: monospace; margin: 2em auto; max-width: 50em; }
+h1 { color: #f1fa8c; }
+h2 { color: #ff79c6; font-size: 1.1em; margin-top: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 0.3em 0.8em; text-align: left; vertical-align: top; }
+th { color: #6272a4; border-bottom: 1px solid #6272a4; }
+tr:nth-child(even) { background: #343746; }
+pre { color: #bd93f9; }
+.x { color: #8be9fd; }
+.o { color: #ff79c6; }
+.note { color: #f1fa8c; }
+.author { color: #6272a4; }
+</style>
+</head>
+<body>
+<h1>Game {{.ID}}</h1>
+{{range .Games}}
+<h2>{{.Title}}: <span class="x">X</span> {{.X}} vs <span class="o">O</span> {{.O}}</h2>
+<p>{{.Played.UTC.Format "2006-01-02 15:04 UTC"}}, {{.Result}}</p>
+{{range .Start}}<p class="note">{{.Text}} <span class="author">({{.Author}})</span></p>{{end}}
+<table>
+<tr><th>#</th><th>Move</th><th>Notes</th></tr>
+{{range .Moves}}<tr><td>{{.Number}}</td><td><span class="{{if eq .Player "X"}}x{{else}}o{{end}}">{{.Player}}</span> {{.Cell}}</td><td>{{range .Notes}}<div class="note">{{.Text}} <span class="author">({{.Author}})</span></div>{{end}}</td></tr>
+{{end}}</table>
+<pre>{{.Board}}</pre>
+{{end}}
+</body>
+</html>
+`))
+
+// serveReplayPage shows a game from the journals, with its notes
+func serveReplayPage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	// IDs are join codes or hex, and go in a glob
+	if id == "" || strings.ContainsFunc(id, func(c rune) bool { return !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') }) {
+		http.Error(w, "no such game", http.StatusNotFound)
+		return
+	}
+	paths, err := filepath.Glob(filepath.Join(journalDir, id+"-*.jsonl"))
+	if err != nil {
+		httpError(w, err)
+		return
+	}
+	var games []replayPageGame
+	for _, path := range paths {
+		events, err := readJournal(path)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		for _, rp := range replaysIn(id, events) {
+			x, o := privacyOf(rp.ids[0]), privacyOf(rp.ids[1])
+			if x.NoReplays || o.NoReplays {
+				continue
+			}
+			if x.Anonymous {
+				rp.names[0] = AnonymousName
+			}
+			if o.Anonymous {
+				rp.names[1] = AnonymousName
+			}
+			notes, err := playerDB.annotations(rp)
+			if err != nil {
+				httpError(w, err)
+				return
+			}
+			for i, note := range notes {
+				if privacyOf(note.AuthorID).Anonymous {
+					notes[i].Author = AnonymousName
+				}
+			}
+			game := replayPageGame{
+				Title:  rp.rules.Title(),
+				X:      rp.names[0],
+				O:      rp.names[1],
+				Played: rp.played,
+				Result: rp.outcome(),
+				Start:  notesOn(notes, 0),
+				Board:  plainBoard(rp.rules, rp.board(len(rp.moves))),
+			}
+			for i, e := range rp.moves {
+				cell := e.Cell
+				if e.Type == EventSwap {
+					cell = "swap"
+				}
+				game.Moves = append(game.Moves, replayPageMove{Number: i + 1, Player: e.Player, Cell: cell, Notes: notesOn(notes, i+1)})
+			}
+			games = append(games, game)
+		}
+	}
+	if len(games) == 0 {
+		http.Error(w, "no such game", http.StatusNotFound)
+		return
+	}
+	err = replayPage.Execute(w, struct {
+		ID    string
+		Games []replayPageGame
+	}{id, games})
+	if err != nil {
+		log.Printf("http: %v", err)
+	}
+}
+
+// plainBoard draws a board as text, kings in braces like on the terminal
+func plainBoard(rules gameRules, board [][]string) string {
+	var s strings.Builder
+	s.WriteString("   ")
+	for x := range board[0] {
+		s.WriteString(" " + columnLabel(x) + " ")
+	}
+	s.WriteString("\n")
+	for y, row := range board {
+		fmt.Fprintf(&s, "%2d ", y+1)
+		for x, cell := range row {
+			switch {
+			case !rules.playable(y, x):
+				s.WriteString("   ")
+			case cell == Empty:
+				s.WriteString("[ ]")
+			case crowned(cell):
+				s.WriteString("{" + owner(cell) + "}")
+			default:
+				s.WriteString("[" + owner(cell) + "]")
+			}
+		}
+		s.WriteString("\n")
+	}
+	return s.String()
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"tictactui/matchmaking"
+)
+
+// A replay room is a replay several players watch together, like a
+// tournament's final gone over afterwards. Whoever opens it steps through
+// the game and everyone in it sees the same move, with the room's own
+// chat. It has a join code like a game room's, and closes when the last
+// player leaves.
+
+// replayRoom is a replay being watched together
+type replayRoom struct {
+	Code    string
+	Replay  replay
+	Step    int          // how many of its moves are on the board
+	Driver  string       // the player ID of whoever steps through it
+	Members []roomMember // in the order they came in
+	Chat    []chatMessage
+
+	subscribers []chan struct{} // signalled whenever the room changes
+	mutex       sync.RWMutex
+}
+
+// roomMember is someone in a replay room
+type roomMember struct {
+	ID   string
+	Name string
+}
+
+// replayRooms are the open replay rooms, by join code
+var replayRooms = struct {
+	rooms map[string]*replayRoom
+	mutex sync.Mutex
+}{rooms: map[string]*replayRoom{}}
+
+// openReplayRoom opens a room on a replay, at the step it's been watched
+// to, with the player who opened it driving
+func openReplayRoom(r replay, step int, playerID, name string) *replayRoom {
+	rr := &replayRoom{Code: matchmaking.NewCode(), Replay: r, Step: step, Driver: playerID}
+	rr.Members = []roomMember{{ID: playerID, Name: name}}
+	replayRooms.mutex.Lock()
+	replayRooms.rooms[rr.Code] = rr
+	replayRooms.mutex.Unlock()
+	return rr
+}
+
+// joinReplayRoom lets a player into the replay room with the given code,
+// if there is one. Codes are case-insensitive.
+func joinReplayRoom(code, playerID, name string) (*replayRoom, bool) {
+	replayRooms.mutex.Lock()
+	defer replayRooms.mutex.Unlock()
+	rr, ok := replayRooms.rooms[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return nil, false
+	}
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	rr.Members = append(rr.Members, roomMember{ID: playerID, Name: name})
+	rr.notify()
+	return rr, true
+}
+
+// leave lets a player out of the room. If they were driving the next
+// player in takes over, and the room closes once it's empty.
+func (rr *replayRoom) leave(playerID string) {
+	// the rooms before the room, like joinReplayRoom, so an emptied room
+	// can't be joined on its way out
+	replayRooms.mutex.Lock()
+	defer replayRooms.mutex.Unlock()
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	if i := slices.IndexFunc(rr.Members, func(rm roomMember) bool { return rm.ID == playerID }); i >= 0 {
+		rr.Members = slices.Delete(rr.Members, i, i+1)
+	}
+	if len(rr.Members) == 0 {
+		delete(replayRooms.rooms, rr.Code)
+		matchmaking.Release(rr.Code)
+		return
+	}
+	if !rr.member(rr.Driver) {
+		rr.Driver = rr.Members[0].ID
+	}
+	rr.notify()
+}
+
+// member reports whether a player is in the room. Call with the room locked.
+func (rr *replayRoom) member(playerID string) bool {
+	return slices.ContainsFunc(rr.Members, func(rm roomMember) bool { return rm.ID == playerID })
+}
+
+// seek moves everyone to the position after the first step moves, if the
+// player's the one driving
+func (rr *replayRoom) seek(playerID string, step int) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	if playerID != rr.Driver {
+		return
+	}
+	rr.Step = min(max(step, 0), len(rr.Replay.moves))
+	rr.notify()
+}
+
+// handOver passes the driving on to the next player in the room, if the
+// player's the one driving
+func (rr *replayRoom) handOver(playerID string) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	if playerID != rr.Driver {
+		return
+	}
+	i := slices.IndexFunc(rr.Members, func(rm roomMember) bool { return rm.ID == playerID })
+	for _, next := range slices.Concat(rr.Members[i+1:], rr.Members[:i]) {
+		if next.ID != playerID {
+			rr.Driver = next.ID
+			rr.notify()
+			return
+		}
+	}
+}
+
+// say adds a message to the room's chat, sanitized since it's going on
+// everyone else's screen
+func (rr *replayRoom) say(name, text string) {
+	text = sanitize(text, MaxChatLength)
+	if text == "" {
+		return
+	}
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	rr.Chat = append(rr.Chat, chatMessage{Name: name, Text: text, At: time.Now()})
+	if len(rr.Chat) > MaxChatHistory {
+		rr.Chat = rr.Chat[len(rr.Chat)-MaxChatHistory:]
+	}
+	rr.notify()
+}
+
+// annotated lets everyone know the notes on the game changed
+func (rr *replayRoom) annotated() {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	rr.notify()
+}
+
+// subscribe returns a channel that's signalled whenever the room changes.
+// It starts out signalled so a new subscriber syncs straight away.
+func (rr *replayRoom) subscribe() chan struct{} {
+	ch := make(chan struct{}, 1)
+	ch <- struct{}{}
+	rr.mutex.Lock()
+	rr.subscribers = append(rr.subscribers, ch)
+	rr.mutex.Unlock()
+	return ch
+}
+
+// unsubscribe stops updates to ch and closes it
+func (rr *replayRoom) unsubscribe(ch chan struct{}) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+	rr.subscribers = slices.DeleteFunc(rr.subscribers, func(c chan struct{}) bool { return c == ch })
+	close(ch)
+}
+
+// notify tells every subscriber the room changed. Call with the room locked.
+func (rr *replayRoom) notify() {
+	for _, ch := range rr.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// replayRoomMsg says the replay room changed
+type replayRoomMsg struct {
+	updates <-chan struct{} // the subscription it came from
+}
+
+// waitForRoom waits for the next change to the replay room
+func waitForRoom(updates <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-updates; !ok {
+			return nil
+		}
+		return replayRoomMsg{updates: updates}
+	}
+}
+
+// enterReplayRoom follows a replay room the player's in until they leave
+// or disconnect
+func (m model) enterReplayRoom(rr *replayRoom) (tea.Model, tea.Cmd) {
+	m.lobby = lobby{}
+	m.replayRoom = rr
+	m.replays = replayViewer{active: true, replays: []replay{rr.Replay}, watching: true}
+	m.left = make(chan struct{})
+	m.roomUpdates = rr.subscribe()
+
+	playerID, updates, done, left := m.playerID, m.roomUpdates, m.done, m.left
+	go func() {
+		select {
+		case <-done:
+		case <-left:
+		}
+		rr.unsubscribe(updates)
+		rr.leave(playerID)
+	}()
+	return m, tea.Batch(m.clearScreen(), waitForRoom(m.roomUpdates))
+}
+
+// syncReplayRoom copies the room as it is now into the model
+func (m model) syncReplayRoom(msg replayRoomMsg) (tea.Model, tea.Cmd) {
+	if m.replayRoom == nil || msg.updates != m.roomUpdates {
+		return m, nil // from a room we've left
+	}
+	rr := m.replayRoom
+	rr.mutex.RLock()
+	m.replays.step = rr.Step
+	m.driving = rr.Driver == m.playerID
+	m.roomMembers = nil
+	for _, rm := range rr.Members {
+		name := rm.Name
+		if rm.ID == rr.Driver {
+			name += " (driving)"
+		}
+		m.roomMembers = append(m.roomMembers, name)
+	}
+	m.chat = slices.Clone(rr.Chat)
+	rr.mutex.RUnlock()
+	m.loadAnnotations()
+	return m, waitForRoom(m.roomUpdates)
+}
+
+// updateReplayRoom handles keys in a replay room: the driver steps through
+// the game for everyone, anyone can chat
+func (m model) updateReplayRoom(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.chatting {
+		return m.updateChat(msg)
+	}
+	if m.annotating {
+		return m.updateAnnotation(msg)
+	}
+	rr, step := m.replayRoom, m.replays.step
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		close(m.left)
+		return m.fresh().backToLobby()
+	case "t":
+		m.chatting = true
+	case "d":
+		rr.handOver(m.playerID)
+	case "a":
+		if m.canAnnotate() {
+			m = m.startAnnotating()
+		}
+	case "right", "l", " ", "n":
+		rr.seek(m.playerID, step+1)
+	case "left", "h", "backspace", "p":
+		rr.seek(m.playerID, step-1)
+	case "home", "g":
+		rr.seek(m.playerID, 0)
+	case "end", "G":
+		rr.seek(m.playerID, len(rr.Replay.moves))
+	}
+	return m, nil
+}
+
+// replayRoomView draws who's in the replay room, its chat, and the keys
+func (m model) replayRoomView() string {
+	s := "\n" + headerStyle.Render("Watching together, join code "+m.replayRoom.Code) + "\n"
+	s += m.fit(footerStyle.Render("In the room: ")+strings.Join(m.roomMembers, ", ")) + "\n"
+	s += m.chatView()
+	if m.canAnnotate() {
+		s += footerStyle.Render("\nPress ←/→ to step, home/end to jump, a to add a note, d to hand over, t to chat, esc to leave") + "\n"
+	} else if m.driving {
+		s += footerStyle.Render("\nPress ←/→ to step, home/end to jump, d to hand over, t to chat, esc to leave") + "\n"
+	} else {
+		s += footerStyle.Render("\nPress t to chat, esc to leave") + "\n"
+	}
+	return s
+}
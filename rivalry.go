@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// rivalriesBucket holds one JSON headToHead per pair of key users who've
+// played each other, by rivalryKey
+var rivalriesBucket = []byte("rivalries")
+
+// headToHead is how two players have done against each other, the player
+// whose fingerprint sorts first first
+type headToHead struct {
+	Wins  [2]int `json:"wins"`
+	Draws int    `json:"draws"`
+}
+
+// rivalryKey is what a pair of players' record against each other is kept
+// under, and whether they're the other way round in it
+func rivalryKey(playerID, opponentID string) ([]byte, bool) {
+	if opponentID < playerID {
+		return []byte(opponentID + " " + playerID), true
+	}
+	return []byte(playerID + " " + opponentID), false
+}
+
+// recordMeeting adds a finished game to two players' record against each
+// other. winner is the index in ids of whoever won, -1 for a draw.
+func (ps *playerStore) recordMeeting(ids [2]string, winner int) error {
+	if ps == nil || !persistent(ids[0]) || !persistent(ids[1]) || ids[0] == ids[1] {
+		return nil
+	}
+	key, swapped := rivalryKey(ids[0], ids[1])
+	return ps.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(rivalriesBucket)
+		var h headToHead
+		if data := bucket.Get(key); data != nil {
+			if err := json.Unmarshal(data, &h); err != nil {
+				return err
+			}
+		}
+		switch {
+		case winner < 0:
+			h.Draws++
+		case (winner == 1) != swapped:
+			h.Wins[1]++
+		default:
+			h.Wins[0]++
+		}
+		data, err := json.Marshal(h)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+// headToHead returns how a player has done against an opponent: their wins,
+// losses and draws
+func (ps *playerStore) headToHead(playerID, opponentID string) (won, lost, drawn int) {
+	if ps == nil || !persistent(playerID) || !persistent(opponentID) {
+		return 0, 0, 0
+	}
+	key, swapped := rivalryKey(playerID, opponentID)
+	var h headToHead
+	err := ps.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(rivalriesBucket).Get(key)
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &h)
+	})
+	if err != nil {
+		log.Printf("rivalries: %v", err)
+		return 0, 0, 0
+	}
+	if swapped {
+		return h.Wins[1], h.Wins[0], h.Draws
+	}
+	return h.Wins[0], h.Wins[1], h.Draws
+}
+
+// forgetRivalries takes a player out of everyone's records against them
+func (ps *playerStore) forgetRivalries(playerID string) error {
+	if ps == nil || !persistent(playerID) {
+		return nil
+	}
+	return ps.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(rivalriesBucket)
+		var keys [][]byte
+		err := bucket.ForEach(func(key, _ []byte) error {
+			first, second, _ := bytes.Cut(key, []byte(" "))
+			if string(first) == playerID || string(second) == playerID {
+				keys = append(keys, bytes.Clone(key))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// a bucket can't change while it's being gone through
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// rivalryView sums up how the player has done against their opponent, like
+// "You lead 7-5", empty if they've never played each other
+func rivalryView(playerID, opponentID string) string {
+	won, lost, drawn := playerDB.headToHead(playerID, opponentID)
+	var s string
+	switch {
+	case won+lost+drawn == 0:
+		return ""
+	case won > lost:
+		s = fmt.Sprintf("You lead %d-%d", won, lost)
+	case won < lost:
+		s = fmt.Sprintf("They lead %d-%d", lost, won)
+	default:
+		s = fmt.Sprintf("You're level at %d-%d", won, lost)
+	}
+	switch drawn {
+	case 0:
+	case 1:
+		s += ", with 1 draw"
+	default:
+		s += fmt.Sprintf(", with %d draws", drawn)
+	}
+	return s
+}
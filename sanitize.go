@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// MaxNameWidth is how many terminal cells a display name may take up
+const MaxNameWidth = 20
+
+// sanitize makes a string from a remote client safe to draw: escape sequences
+// and control characters are removed so nobody can repaint an opponent's
+// screen, whitespace is collapsed, and the result is cut to maxWidth cells.
+// Everything a client sends us (usernames, and later names and messages)
+// goes through here before it's rendered.
+func sanitize(s string, maxWidth int) string {
+	s = ansi.Strip(s)
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r), unicode.Is(unicode.Bidi_Control, r), r == unicode.ReplacementChar:
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.Join(strings.Fields(s), " ")
+	return ansi.Truncate(s, maxWidth, "…")
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		maxWidth int
+		want     string
+	}{
+		{"plain", "alice", 20, "alice"},
+		{"colours", "\x1b[31mred\x1b[0m", 20, "red"},
+		{"clearing the screen", "\x1b[2J\x1b[Hhi", 20, "hi"},
+		{"window title", "\x1b]0;pwned\x07bob", 20, "bob"},
+		{"control characters", "a\x00b\x07c\x7fd", 20, "abcd"},
+		{"bidi overrides", "abc\u202edef\u2066", 20, "abcdef"},
+		{"invalid UTF-8", "a\xffb", 20, "ab"},
+		{"whitespace collapses", "  hello \t\n  world  ", 20, "hello world"},
+		{"only whitespace", " \t\r\n ", 20, ""},
+		{"fits exactly", "abcde", 5, "abcde"},
+		{"cut to fit", "abcdefgh", 5, "abcd…"},
+		{"wide characters", "日本語です", 5, "日本…"},
+		{"cut after stripping", "\x1b[1mabc\x1b[0m", 3, "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitize(tt.in, tt.maxWidth); got != tt.want {
+				t.Errorf("sanitize(%q, %d) = %q, want %q", tt.in, tt.maxWidth, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	"github.com/pkg/sftp"
+)
+
+// Key users can download their replays and exports with an SFTP client, on
+// the game's port. What they see is read only and made up as they connect:
+//
+//	/replays/<game id>-<played>.jsonl  each of their games, as a journal the replay command plays back
+//	/tictactui.json, /matches.csv, /stats.csv  their export, in each format
+
+// ReplaysDir is the directory a player's replays are downloaded from
+const ReplaysDir = "replays"
+
+// sftpCommand is what a player runs to download their replays
+func sftpCommand() string {
+	return "sftp -P " + SSHPort + " " + hostName + ":" + ReplaysDir + "/"
+}
+
+// sftpFile is a file or directory a player can download
+type sftpFile struct {
+	name    string
+	data    []byte // empty for directories
+	modTime time.Time
+	dir     bool
+}
+
+func (f *sftpFile) Name() string       { return f.name }
+func (f *sftpFile) Size() int64        { return int64(len(f.data)) }
+func (f *sftpFile) ModTime() time.Time { return f.modTime }
+func (f *sftpFile) IsDir() bool        { return f.dir }
+func (f *sftpFile) Sys() any           { return nil }
+
+func (f *sftpFile) Mode() os.FileMode {
+	if f.dir {
+		return os.ModeDir | 0o555
+	}
+	return 0o444
+}
+
+// sftpFiles is everything a player can download, by path, "/" being the
+// top. It answers their SFTP client's requests, turning down any that would
+// change something.
+type sftpFiles map[string]*sftpFile
+
+// filesFor gathers what a player can download
+func filesFor(playerID, name string) (sftpFiles, error) {
+	now := time.Now()
+	files := sftpFiles{
+		"/":              {name: "/", modTime: now, dir: true},
+		"/" + ReplaysDir: {name: ReplaysDir, modTime: now, dir: true},
+	}
+	replays, err := playerReplays(playerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range replays {
+		data, err := replayJournal(r)
+		if err != nil {
+			return nil, err
+		}
+		file := r.id + "-" + r.played.UTC().Format("20060102T150405Z") + ".jsonl"
+		files["/"+ReplaysDir+"/"+file] = &sftpFile{name: file, data: data, modTime: r.played}
+	}
+
+	export, err := exportFor(playerID, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range exportFiles {
+		var buf bytes.Buffer
+		if err := writeExport(&buf, f.format, export); err != nil {
+			return nil, err
+		}
+		files["/"+f.file] = &sftpFile{name: f.file, data: buf.Bytes(), modTime: now}
+	}
+	return files, nil
+}
+
+// replayJournal writes out a game as a journal of its own, with the players'
+// joins, its seeds, moves and result; the chat stays out of it
+func replayJournal(r replay) ([]byte, error) {
+	events := []Event{
+		{Time: r.played, Type: EventJoin, Player: PlayerX, Name: r.names[0], ID: r.ids[0], Game: r.rules.Name()},
+		{Time: r.played, Type: EventJoin, Player: PlayerO, Name: r.names[1], ID: r.ids[1], Game: r.rules.Name()},
+	}
+	if r.draw != 0 {
+		events = append(events, Event{Time: r.played, Type: EventSeed, Seed: r.draw, Tournament: true})
+	}
+	if r.seed != 0 {
+		events = append(events, Event{Time: r.played, Type: EventSeed, Seed: r.seed})
+	}
+	events = append(events, r.moves...)
+	if r.result.Type == EventResult {
+		events = append(events, r.result)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Fileread opens a file to download
+func (files sftpFiles) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	f, ok := files[r.Filepath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	if f.dir {
+		return nil, sftp.ErrSSHFxFailure
+	}
+	return bytes.NewReader(f.data), nil
+}
+
+// Filewrite turns down uploads
+func (files sftpFiles) Filewrite(*sftp.Request) (io.WriterAt, error) {
+	return nil, sftp.ErrSSHFxPermissionDenied
+}
+
+// Filecmd turns down renames, removals and the like
+func (files sftpFiles) Filecmd(*sftp.Request) error {
+	return sftp.ErrSSHFxPermissionDenied
+}
+
+// Filelist lists a directory, or stats a file
+func (files sftpFiles) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	f, ok := files[r.Filepath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	switch r.Method {
+	case "List":
+		if !f.dir {
+			return sftpListing{f}, nil
+		}
+		var listing sftpListing
+		for p, child := range files {
+			if p != "/" && path.Dir(p) == r.Filepath {
+				listing = append(listing, child)
+			}
+		}
+		slices.SortFunc(listing, func(a, b os.FileInfo) int { return strings.Compare(a.Name(), b.Name()) })
+		return listing, nil
+	case "Stat":
+		return sftpListing{f}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+// sftpListing is a directory's files, or the one file stat asked about
+type sftpListing []os.FileInfo
+
+// ListAt copies files out from offset on, io.EOF once there are no more
+func (l sftpListing) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// serveSFTP answers the sftp subsystem with the player's files, for key
+// users only: guests start afresh every time, so there's nothing of theirs
+// to download
+func serveSFTP(s ssh.Session) {
+	playerID := playerID(s)
+	if !persistent(playerID) {
+		wish.Errorln(s, "Connect with an SSH key to download your games, guests start afresh every time.")
+		_ = s.Exit(1)
+		return
+	}
+	files, err := filesFor(playerID, displayName(s))
+	if err != nil {
+		wish.Errorln(s, "Couldn't read your games:", err)
+		_ = s.Exit(1)
+		return
+	}
+	server := sftp.NewRequestServer(s, sftp.Handlers{FileGet: files, FilePut: files, FileCmd: files, FileList: files})
+	if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("sftp: %v", err)
+	}
+	_ = server.Close()
+}
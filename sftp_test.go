@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+func TestServeSFTP(t *testing.T) {
+	old := recentReplays
+	t.Cleanup(func() { recentReplays = old })
+	recentReplays = &replayLog{replays: map[string][]replay{}}
+	played := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	game := replay{
+		id:     "abc123",
+		rules:  ticTacToe,
+		names:  [2]string{"alice", "bob"},
+		ids:    [2]string{"SHA256:a", "SHA256:b"},
+		played: played,
+		seed:   42,
+		moves: []Event{
+			{Time: played, Type: EventMove, Player: PlayerX, Cell: "B2"},
+			{Time: played, Type: EventMove, Player: PlayerO, Cell: "A1"},
+		},
+		result: Event{Time: played, Type: EventResult, Result: Draw},
+	}
+	recentReplays.add([]string{"SHA256:a"}, game)
+
+	files, err := filesFor("SHA256:a", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the client and server talk over a pair of pipes, like they would over
+	// the subsystem's channel
+	toServer, fromClient := io.Pipe()
+	toClient, fromServer := io.Pipe()
+	server := sftp.NewRequestServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{toServer, fromServer}, sftp.Handlers{FileGet: files, FilePut: files, FileCmd: files, FileList: files})
+	go server.Serve()
+	client, err := sftp.NewClientPipe(toClient, fromClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		server.Close() // hangs up on the client, or it waits for the server forever
+		client.Close()
+	})
+
+	list := func(dir string) []string {
+		t.Helper()
+		infos, err := client.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("listing %s: %v", dir, err)
+		}
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		return names
+	}
+	if got, want := list("/"), []string{"matches.csv", ReplaysDir, "stats.csv", "tictactui.json"}; !slices.Equal(got, want) {
+		t.Errorf("/ = %v, want %v", got, want)
+	}
+	name := "abc123-20250601T120000Z.jsonl"
+	if got := list(ReplaysDir); !slices.Equal(got, []string{name}) {
+		t.Fatalf("%s = %v, want %v", ReplaysDir, got, []string{name})
+	}
+
+	// a downloaded replay plays back as the game it was
+	f, err := client.Open(filepath.Join(ReplaysDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	path := filepath.Join(t.TempDir(), name)
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	events, err := readJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := replaysIn(journalID(path), events)
+	if len(got) != 1 || got[0].id != game.id || got[0].names != game.names || got[0].ids != game.ids ||
+		got[0].seed != game.seed || got[0].notation() != game.notation() || got[0].outcome() != game.outcome() {
+		t.Errorf("replayed %+v, want %+v", got, game)
+	}
+
+	export, err := client.Open("/tictactui.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer export.Close()
+	var e playerExport
+	if err := json.NewDecoder(export).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Matches) != 1 || e.Matches[0].ID != game.id {
+		t.Errorf("exported %+v, want the one match", e.Matches)
+	}
+
+	// it's read only
+	if _, err := client.Create("/notes.txt"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("uploading: %v, want permission denied", err)
+	}
+	if err := client.Remove("/stats.csv"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("removing: %v, want permission denied", err)
+	}
+	if _, err := client.Stat("/secrets"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stat of a missing file: %v, want it not to exist", err)
+	}
+}
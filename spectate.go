@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"tictactui/figlet"
+)
+
+// gameListMsg says a game started or ended
+type gameListMsg struct{}
+
+// gameList is where spectators pick a game to watch
+type gameList struct {
+	active bool
+	choice int            // highlighted game
+	games  []*GameSession // games in progress, by ID
+}
+
+// refreshGameList looks at the games in progress straight away
+func refreshGameList() tea.Msg {
+	return gameListMsg{}
+}
+
+// watchGameList waits for the next game to start or end
+func watchGameList() tea.Cmd {
+	changed := sessionManager.watchSessions()
+	return func() tea.Msg {
+		<-changed
+		return gameListMsg{}
+	}
+}
+
+// watchers says how many are watching, if anyone is
+func watchers(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" · %d watching", n)
+}
+
+// fresh starts over for the same connection, keeping who the player is and
+// their settings
+func (m model) fresh() model {
+	f := initialModel()
+	f.playerID = m.playerID
+	f.playerName = m.playerName
+	f.returning = m.returning
+	f.visitor = m.visitor
+	f.macros = m.macros
+	f.avatar = m.avatar
+	f.theme = m.theme
+	f.notifications = m.notifications
+	f.privacy = m.privacy
+	f.alerts = m.alerts
+	f.casual = m.casual // for "find next opponent"
+	f.swapRule = m.swapRule
+	f.roomClock = m.roomClock
+	f.roomHandicap = m.roomHandicap
+	f.done = m.done
+	f.tournament = m.tournament
+	f.hill = m.hill
+	f.tickInterval = m.tickInterval
+	f.reducedMotion = m.reducedMotion
+	f.lowBandwidth = m.lowBandwidth
+	f.font = m.font
+	f.rules, f.board = m.rules, m.rules.newBoard()
+	f.width, f.height = m.width, m.height
+	return f
+}
+
+// openGameList shows the games that can be watched
+func (m model) openGameList() (tea.Model, tea.Cmd) {
+	m.lobby.active = false
+	m.gameList = gameList{active: true}
+	return m, tea.Batch(m.clearScreen(), refreshGameList)
+}
+
+// updateGameListGames refreshes the list, keeping the same game highlighted
+// if it's still going
+func (m model) updateGameListGames() (tea.Model, tea.Cmd) {
+	if !m.gameList.active {
+		return m, nil
+	}
+	// start watching before looking so no change slips through in between
+	watch := watchGameList()
+	var highlighted *GameSession
+	if m.gameList.choice < len(m.gameList.games) {
+		highlighted = m.gameList.games[m.gameList.choice]
+	}
+	m.gameList.games = sessionManager.watchable()
+	m.gameList.choice = 0
+	for i, game := range m.gameList.games {
+		if game == highlighted {
+			m.gameList.choice = i
+		}
+	}
+	return m, watch
+}
+
+// updateGameList handles keys while picking a game to watch
+func (m model) updateGameList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	games := len(m.gameList.games)
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.gameList = gameList{}
+		// back to wherever they came from
+		if m.tournament == nil && m.hill == nil {
+			return m.backToLobby()
+		}
+		return m, m.clearScreen()
+	case "up", "k":
+		if games > 0 {
+			m.gameList.choice = (m.gameList.choice + games - 1) % games
+		}
+	case "down", "j":
+		if games > 0 {
+			m.gameList.choice = (m.gameList.choice + 1) % games
+		}
+	case "enter", " ":
+		if games > 0 {
+			return m.watchGame(m.gameList.games[m.gameList.choice])
+		}
+	}
+	return m, nil
+}
+
+// watchGame starts following a game as a spectator
+func (m model) watchGame(session *GameSession) (tea.Model, tea.Cmd) {
+	m.gameList = gameList{}
+	m.spectating = true
+	m.left = make(chan struct{})
+	m.gameSession = session
+	m.rules, m.board = session.Rules, session.Rules.newBoard()
+	m.updates = session.subscribe()
+	session.addSpectator(1)
+
+	updates, done, left := m.updates, m.done, m.left
+	go func() {
+		select {
+		case <-done:
+		case <-left:
+		}
+		session.unsubscribe(updates)
+		session.addSpectator(-1)
+	}()
+
+	return m, tea.Batch(m.clearScreen(), waitForUpdate(m.updates))
+}
+
+// stopWatching goes back to the list of games
+func (m model) stopWatching() (tea.Model, tea.Cmd) {
+	close(m.left)
+	return m.fresh().openGameList()
+}
+
+// updateSpectating handles keys while watching, spectators can look but
+// not touch
+func (m model) updateSpectating(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.chatting {
+		return m.updateChat(msg)
+	}
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		return m.stopWatching()
+	case "f":
+		m.font = figlet.Next(m.font)
+	case "m":
+		m.reducedMotion = !m.reducedMotion
+	case "b":
+		m.lowBandwidth = !m.lowBandwidth
+		return m, tea.Batch(tea.ClearScreen, m.startTicking())
+	case "t":
+		m.chatting = true
+	case "up", "k", "down", "j", "right", "l", "left", "h":
+		if m.voting() {
+			m.moveCursor(msg.String())
+		}
+	case "enter", " ":
+		if m.voting() {
+			if mv, ok := m.pick(); ok {
+				m.gameSession.castVote(m.playerID, mv)
+			}
+		}
+	}
+	return m, m.startTicking()
+}
+
+// gameListView draws the games that can be watched
+func (m model) gameListView() string {
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+	s += headerStyle.Render("  Games in progress") + "\n\n"
+
+	if len(m.gameList.games) == 0 {
+		s += "  None right now, games show up here as they start\n"
+	}
+	for i, game := range m.gameList.games {
+		game.mutex.RLock()
+		names := game.shownNames()
+		line := fmt.Sprintf("%s  %s %s vs %s %s%s", game.ID,
+			styledPlayer(PlayerX), names[0], styledPlayer(PlayerO), names[1], watchers(game.Spectators))
+		line += footerStyle.Render(" · " + game.Rules.Title())
+		if game.TimeControl.on() && !game.Casual {
+			line += footerStyle.Render(" · " + game.TimeControl.String())
+		}
+		if game.Crowd {
+			line += footerStyle.Render(" · the crowd votes on O's moves")
+		}
+		game.mutex.RUnlock()
+		if i == m.gameList.choice {
+			s += headerStyle.Render("> ") + line + "\n"
+		} else {
+			s += "  " + line + "\n"
+		}
+	}
+	s += footerStyle.Render("\n  Press enter to watch, esc to go back, q to quit") + "\n"
+	return s
+}
+
+// spectatorDelay holds spectators this far behind the game, so a friend
+// watching can't relay what's happening to a player as it happens. Set with
+// -spectator-delay, 0 shows games live.
+var spectatorDelay time.Duration
+
+// frame is what spectators see of a game at one moment
+type frame struct {
+	at            time.Time
+	board         [][]string
+	currentPlayer int
+	winner        string
+	winningCells  []coord
+	chain         *coord
+	moves         int
+	turnStarted   time.Time
+	clocks        [2]time.Duration
+}
+
+// frame is the game as it is now. Call with gs locked for reading.
+func (gs *GameSession) frame() frame {
+	return frame{
+		at:            time.Now(),
+		board:         copyBoard(gs.Board),
+		currentPlayer: gs.CurrentPlayer,
+		winner:        gs.Winner,
+		winningCells:  gs.WinningCells,
+		chain:         gs.Chain,
+		moves:         gs.Moves,
+		turnStarted:   gs.TurnStarted,
+		clocks:        gs.Clocks,
+	}
+}
+
+// recordFrame keeps what the game looks like now for the delayed spectator
+// feed, and drops what no spectator will see again. Call with gs locked.
+func (gs *GameSession) recordFrame() {
+	if spectatorDelay == 0 {
+		return
+	}
+	now := time.Now()
+	gs.frames = append(gs.frames, gs.frame())
+	// the newest frame that's old enough is on spectators' screens, the
+	// ones before it are done with
+	shown := 0
+	for i, f := range gs.frames {
+		if now.Sub(f.at) >= spectatorDelay {
+			shown = i
+		}
+	}
+	gs.frames = gs.frames[shown:]
+}
+
+// delayedFrame is what spectators see of the game, spectatorDelay behind.
+// Call with gs locked for reading.
+func (gs *GameSession) delayedFrame() (frame, bool) {
+	// the crowd plays live, it can only vote on what's there
+	if spectatorDelay == 0 || len(gs.frames) == 0 || gs.Crowd {
+		return frame{}, false
+	}
+	// the game's first frame until it's been going for the delay
+	shown := gs.frames[0]
+	for _, f := range gs.frames {
+		if time.Since(f.at) >= spectatorDelay {
+			shown = f
+		}
+	}
+	// the turn's as far along as it was then
+	shown.turnStarted = shown.turnStarted.Add(spectatorDelay)
+	return shown, true
+}
+
+// showFrame puts what spectators see of the game on a spectator's board
+func (m *model) showFrame(f frame) {
+	m.board = copyBoard(f.board)
+	m.currentPlayer = PlayerX
+	if f.currentPlayer == 1 {
+		m.currentPlayer = PlayerO
+	}
+	m.winner = f.winner
+	m.winningCells = f.winningCells
+	m.chain = f.chain
+	m.moves = f.moves
+	m.turnStarted = f.turnStarted
+	m.clocks = f.clocks
+}
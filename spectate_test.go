@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"tictactui/matchmaking"
+)
+
+// TestPrivateGames checks games in rooms can't be found to watch, in the
+// list, the attract mode or the overlay API, while other games can
+func TestPrivateGames(t *testing.T) {
+	room := func(host, guest string) *GameSession {
+		t.Helper()
+		session, _, err := sessionManager.createRoom(matchmaking.Player{ID: "session:" + host, Name: host, Game: GameTicTacToe}, false, timeControl{}, handicapNone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			sessionManager.mutex.Lock()
+			delete(sessionManager.sessions, session.ID)
+			sessionManager.mutex.Unlock()
+		})
+		if _, _, err := sessionManager.joinRoom(session.ID, matchmaking.Player{ID: "session:" + guest, Name: guest, Game: GameTicTacToe}); err != nil {
+			t.Fatal(err)
+		}
+		return session
+	}
+	private, public := room("ann", "bea"), room("cat", "dan")
+	public.mutex.Lock()
+	public.Private = false // as if the queue had paired them
+	public.mutex.Unlock()
+
+	games := sessionManager.watchable()
+	if slices.Contains(games, private) || !slices.Contains(games, public) {
+		t.Errorf("spectators can pick from %v, want %s and not %s", games, public.ID, private.ID)
+	}
+	m := initialModel()
+	m.gameList.active = true
+	next, _ := m.updateGameListGames()
+	if slices.Contains(next.(model).gameList.games, private) {
+		t.Error("the room's game is in the list of games to watch")
+	}
+	if featuredGame() == private {
+		t.Error("the room's game is featured in the attract mode")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/game/{id}", serveOverlay)
+	for gs, want := range map[*GameSession]int{private: http.StatusNotFound, public: http.StatusOK} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/game/"+gs.ID, nil))
+		if w.Code != want {
+			t.Errorf("/api/game/%s answered %d, want %d", gs.ID, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"tictactui/matchmaking"
+)
+
+// dbPath is the database players are remembered in, set with -db. Empty
+// means nothing outlives the server.
+var dbPath string
+
+// playerDB remembers key-authenticated players, nil when -db is off
+var playerDB *playerStore
+
+// playersBucket holds one JSON PlayerRecord per key fingerprint
+var playersBucket = []byte("players")
+
+// PlayerRecord is what's remembered about a player between visits
+type PlayerRecord struct {
+	Fingerprint string                `json:"fingerprint"`
+	Name        string                `json:"name"`
+	Games       map[string]GameRecord `json:"games,omitempty"` // by game name, each game's rated apart
+	FirstSeen   time.Time             `json:"first_seen"`
+	LastSeen    time.Time             `json:"last_seen"`
+	Macros      map[string]string     `json:"macros,omitempty"`  // key to the steps it plays, see parseMacro
+	Puzzles     []string              `json:"puzzles,omitempty"` // the puzzles they've solved, by name, see puzzle.go
+	Avatar      string                `json:"avatar,omitempty"`  // shown next to their name, one of avatars
+	Theme       string                `json:"theme,omitempty"`   // the board's colours, by name, see themes
+
+	Notifications *notifications `json:"notifications,omitempty"` // nil for the defaults, see notificationSettings
+	Privacy       privacy        `json:"privacy,omitzero"`
+
+	// penalties, they hold in every game, see matchmaking.Queue.Penalize
+	Dodged        int       `json:"dodged,omitempty"`
+	Abandoned     int       `json:"abandoned,omitempty"`
+	Strikes       int       `json:"strikes,omitempty"`
+	LastStrike    time.Time `json:"last_strike,omitzero"`
+	CooldownUntil time.Time `json:"cooldown_until,omitzero"`
+}
+
+// GameRecord is a player's results in one game. It's laid out like
+// matchmaking.Results, so one converts to the other.
+type GameRecord struct {
+	Wins       int       `json:"wins"`
+	Losses     int       `json:"losses"`
+	Draws      int       `json:"draws"`
+	Rating     int       `json:"rating"`
+	LastPlayed time.Time `json:"last_played,omitzero"` // their last rated game, for rating decay
+
+	// casual games, kept apart from the rated ones above
+	CasualWins   int `json:"casual_wins,omitempty"`
+	CasualLosses int `json:"casual_losses,omitempty"`
+	CasualDraws  int `json:"casual_draws,omitempty"`
+}
+
+// played is how many rated games the record covers
+func (g GameRecord) played() int {
+	return g.Wins + g.Losses + g.Draws
+}
+
+// playerStore keeps PlayerRecords in a bbolt database, keyed by SSH public
+// key fingerprint. Players without a key only last for their session, so
+// they aren't stored. A nil store remembers nothing.
+type playerStore struct {
+	db *bolt.DB
+}
+
+// openPlayerStore opens (or creates) the database at path
+func openPlayerStore(path string) (*playerStore, error) {
+	// a second server on the same file would otherwise hang waiting for the lock
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{playersBucket, resultsBucket, annotationsBucket, rivalriesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if err := unsignResults(tx.Bucket(resultsBucket)); err != nil {
+			return err
+		}
+		if err := splitGameRecords(tx.Bucket(playersBucket)); err != nil {
+			return err
+		}
+		if tx.Bucket(namesBucket) != nil {
+			return nil
+		}
+		names, err := tx.CreateBucket(namesBucket)
+		if err != nil {
+			return err
+		}
+		return claimStoredNames(tx.Bucket(playersBucket), names)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &playerStore{db: db}, nil
+}
+
+// persistent reports whether a player ID is worth storing: only key
+// fingerprints are the same next time
+func persistent(playerID string) bool {
+	return !strings.HasPrefix(playerID, "session:")
+}
+
+// lookup returns a player's stored record, false if they've never been seen
+func (ps *playerStore) lookup(playerID string) (PlayerRecord, bool) {
+	if ps == nil || !persistent(playerID) {
+		return PlayerRecord{}, false
+	}
+	var rec PlayerRecord
+	found := false
+	err := ps.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(playersBucket).Get([]byte(playerID))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &rec)
+	})
+	if err != nil {
+		log.Printf("players: %v", err)
+		return PlayerRecord{}, false
+	}
+	return rec, found
+}
+
+// change applies fn to a player's record, starting a new one if needed, and
+// stores the result
+func (ps *playerStore) change(playerID string, fn func(*PlayerRecord)) error {
+	if ps == nil || !persistent(playerID) {
+		return nil
+	}
+	return ps.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(playersBucket)
+		rec := PlayerRecord{Fingerprint: playerID, FirstSeen: time.Now().UTC()}
+		if data := bucket.Get([]byte(playerID)); data != nil {
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return err
+			}
+		}
+		fn(&rec)
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(playerID), data)
+	})
+}
+
+// seen notes that a player connected under name, and returns what's
+// remembered about them along with whether they've been here before
+func (ps *playerStore) seen(playerID, name string) (PlayerRecord, bool) {
+	rec, returning := ps.lookup(playerID)
+	err := ps.change(playerID, func(rec *PlayerRecord) {
+		rec.Name = name
+		rec.LastSeen = time.Now().UTC()
+	})
+	if err != nil {
+		log.Printf("players: %v", err)
+	}
+	return rec, returning
+}
+
+// forget deletes a player's record
+func (ps *playerStore) forget(playerID string) error {
+	if ps == nil || !persistent(playerID) {
+		return nil
+	}
+	return ps.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(playersBucket).Delete([]byte(playerID))
+	})
+}
+
+// LoadResults implements matchmaking.RecordStore
+func (ps *playerStore) LoadResults(playerID, game string) (matchmaking.Results, bool) {
+	rec, _ := ps.lookup(playerID)
+	g, ok := rec.Games[game]
+	return matchmaking.Results(g), ok
+}
+
+// SaveResults implements matchmaking.RecordStore
+func (ps *playerStore) SaveResults(playerID, game string, r matchmaking.Results) error {
+	return ps.change(playerID, func(rec *PlayerRecord) {
+		g := GameRecord(r)
+		g.LastPlayed = g.LastPlayed.UTC()
+		if rec.Games == nil {
+			rec.Games = map[string]GameRecord{}
+		}
+		rec.Games[game] = g
+	})
+}
+
+// LoadPenalties implements matchmaking.RecordStore
+func (ps *playerStore) LoadPenalties(playerID string) (matchmaking.Penalties, bool) {
+	rec, ok := ps.lookup(playerID)
+	return matchmaking.Penalties{
+		Dodged:        rec.Dodged,
+		Abandoned:     rec.Abandoned,
+		Strikes:       rec.Strikes,
+		LastStrike:    rec.LastStrike,
+		CooldownUntil: rec.CooldownUntil,
+	}, ok
+}
+
+// SavePenalties implements matchmaking.RecordStore
+func (ps *playerStore) SavePenalties(playerID string, p matchmaking.Penalties) error {
+	return ps.change(playerID, func(rec *PlayerRecord) {
+		rec.Dodged, rec.Abandoned, rec.Strikes = p.Dodged, p.Abandoned, p.Strikes
+		rec.LastStrike, rec.CooldownUntil = p.LastStrike.UTC(), p.CooldownUntil.UTC()
+	})
+}
+
+// splitGameRecords moves the results of players stored before each game
+// was rated apart into their record for tic-tac-toe, the first game there
+// was, which most of them were played in
+func splitGameRecords(bucket *bolt.Bucket) error {
+	split := map[string][]byte{}
+	err := bucket.ForEach(func(key, data []byte) error {
+		// the results were kept next to the rest, where a GameRecord's are
+		var old struct {
+			PlayerRecord
+			GameRecord
+		}
+		if err := json.Unmarshal(data, &old); err != nil {
+			return err
+		}
+		if old.Games != nil || old.GameRecord == (GameRecord{}) {
+			return nil
+		}
+		old.Games = map[string]GameRecord{GameTicTacToe: old.GameRecord}
+		data, err := json.Marshal(old.PlayerRecord)
+		if err != nil {
+			return err
+		}
+		split[string(key)] = data
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// a bucket can't change while it's being gone through
+	for key, data := range split {
+		if err := bucket.Put([]byte(key), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
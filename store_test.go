@@ -0,0 +1,94 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+
+	"tictactui/matchmaking"
+)
+
+// TestSplitGameRecords opens a database from before each game was rated
+// apart
+func TestSplitGameRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "players.db")
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		players, err := tx.CreateBucket(playersBucket)
+		if err != nil {
+			return err
+		}
+		return players.Put([]byte("SHA256:a"), []byte(`{"fingerprint":"SHA256:a","name":"alice","wins":6,"losses":1,"draws":0,"rating":1350,"casual_wins":2,"dodged":1}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	ps, err := openPlayerStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.db.Close()
+	rec, _ := ps.lookup("SHA256:a")
+	want := GameRecord{Wins: 6, Losses: 1, Rating: 1350, CasualWins: 2}
+	if got := rec.Games[GameTicTacToe]; got != want || len(rec.Games) != 1 {
+		t.Errorf("games = %+v, want tic-tac-toe's %+v", rec.Games, want)
+	}
+	if rec.Name != "alice" || rec.Dodged != 1 {
+		t.Errorf("record = %+v, want alice's name and penalties kept", rec)
+	}
+}
+
+func TestLeaderboardPerGame(t *testing.T) {
+	ps, err := openPlayerStore(filepath.Join(t.TempDir(), "players.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ps.db.Close()
+	placed := matchmaking.Results{Wins: matchmaking.PlacementMatches, Rating: 1300}
+	for _, r := range []struct {
+		id, game string
+		results  matchmaking.Results
+	}{
+		{"SHA256:a", GameTicTacToe, placed},
+		{"SHA256:b", GameConnectFour, placed},
+		{"SHA256:b", GameTicTacToe, matchmaking.Results{Wins: 1, Rating: 1216}}, // still placing
+	} {
+		if err := ps.SaveResults(r.id, r.game, r.results); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		game string
+		want []string
+	}{
+		{GameTicTacToe, []string{"SHA256:a"}},
+		{GameConnectFour, []string{"SHA256:b"}},
+		{GameCheckers, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.game, func(t *testing.T) {
+			board, err := ps.leaderboard(tt.game, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, p := range board {
+				got = append(got, p.Fingerprint)
+				if p.Game != tt.game || p.Rating != 1300 {
+					t.Errorf("%s is on it with %+v, want their %s rating", p.Fingerprint, p, tt.game)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("leaderboard = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "fmt"
+
+// The swap ("pie") rule takes the edge off moving first in the bigger line
+// games: once X has made the first move, O can take it as their own
+// instead of replying, and then X moves. It's set per room by whoever
+// opens it.
+
+// swapRuleEnabled is whether rooms can be opened with the swap rule, set
+// with -swap-rule
+var swapRuleEnabled = true
+
+// swappable reports whether a game can be played with the swap rule
+func swappable(rules gameRules) bool {
+	g, ok := rules.(lineGame)
+	return ok && g.rows*g.cols > BoardSize*BoardSize && swapRuleEnabled
+}
+
+// canSwap reports whether O can swap now, straight after X's first move in
+// a game with the swap rule. Call with the session locked for reading.
+func (gs *GameSession) canSwap() bool {
+	return gs.Swap && gs.Moves == 1 && gs.CurrentPlayer == 1 && gs.Winner == Empty && gs.Chain == nil
+}
+
+// swap makes X's first move O's, and hands the turn back to X
+func (gs *GameSession) swap() {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+	if !gs.canSwap() {
+		return
+	}
+	for y, row := range gs.Board {
+		for x, cell := range row {
+			if cell == PlayerX {
+				gs.Board[y][x] = PlayerO
+				gs.journal.Record(Event{Type: EventSwap, Player: PlayerO, Cell: cellName(y, x)})
+			}
+		}
+	}
+	gs.Moves++
+	gs.RestartOffered = [2]bool{}
+	gs.chargeClock()
+	gs.passTurn()
+	gs.notify()
+}
+
+// swapView offers O the swap, or tells X it's on offer
+func (m model) swapView() string {
+	switch {
+	case !m.canSwap:
+		return ""
+	case m.playerSymbol == PlayerO:
+		return headerStyle.Render("Press s to swap: take X's first move as your own, and X moves next") + "\n"
+	}
+	return footerStyle.Render(fmt.Sprintf("%s can take your first move with the swap rule", m.opponentName)) + "\n"
+}
+
+// swapRuleView shows whether rooms the player opens use the swap rule, for
+// the games that can
+func (m model) swapRuleView() string {
+	if !swappable(m.rules) {
+		return ""
+	}
+	return footerStyle.Render("  Swap rule for new rooms: ") + onOff(m.swapRule) + footerStyle.Render(" (s to change)") + "\n"
+}
@@ -0,0 +1,86 @@
+package main
+
+import lip "github.com/charmbracelet/lipgloss"
+
+// boardTheme is the colours a player sees the board and the marks in,
+// picked in their profile. The rest of the screen keeps the dracula palette.
+type boardTheme struct {
+	name   string
+	x, o   lip.Style // each player's marks
+	cell   lip.Style // empty cells
+	win    lip.Style // the winning line
+	cursor lip.Style // the cell under the cursor, an X or O there keeps its colour
+}
+
+// themes are the board themes to pick from, the first is everyone's to
+// begin with
+var themes = []boardTheme{
+	{
+		name:   "Dracula",
+		x:      xStyle,
+		o:      oStyle,
+		cell:   cellStyle,
+		win:    winStyle,
+		cursor: lip.NewStyle().Background(lip.Color("#44475a")).Foreground(lip.Color("#f8f8f2")).Bold(true),
+	},
+	{
+		name:   "Solarized",
+		x:      lip.NewStyle().Foreground(lip.Color("#268BD2")), // blue
+		o:      lip.NewStyle().Foreground(lip.Color("#DC322F")), // red
+		cell:   lip.NewStyle().Foreground(lip.Color("#93A1A1")), // base1
+		win:    lip.NewStyle().Foreground(lip.Color("#859900")).Bold(true),
+		cursor: lip.NewStyle().Background(lip.Color("#073642")).Foreground(lip.Color("#EEE8D5")).Bold(true),
+	},
+	{
+		name:   "Amber",
+		x:      lip.NewStyle().Foreground(lip.Color("#FFB000")).Bold(true),
+		o:      lip.NewStyle().Foreground(lip.Color("#FFB000")),
+		cell:   lip.NewStyle().Foreground(lip.Color("#7F5800")),
+		win:    lip.NewStyle().Foreground(lip.Color("#FFCC66")).Bold(true).Underline(true),
+		cursor: lip.NewStyle().Background(lip.Color("#3D2A00")).Foreground(lip.Color("#FFB000")).Bold(true),
+	},
+	{
+		// no colours at all, for terminals and eyes that don't tell them apart
+		name:   "Plain",
+		x:      lip.NewStyle().Bold(true),
+		o:      lip.NewStyle(),
+		cell:   lip.NewStyle().Faint(true),
+		win:    lip.NewStyle().Bold(true).Underline(true),
+		cursor: lip.NewStyle().Reverse(true),
+	},
+}
+
+// themeNamed is the theme with that name, the first one if there isn't one
+// any more
+func themeNamed(name string) boardTheme {
+	for _, t := range themes {
+		if t.name == name {
+			return t
+		}
+	}
+	return themes[0]
+}
+
+// next is the theme step places on from this one, round the list either
+// way
+func (t boardTheme) next(step int) boardTheme {
+	for i, theme := range themes {
+		if theme.name == t.name {
+			return themes[((i+step)%len(themes)+len(themes))%len(themes)]
+		}
+	}
+	return themes[0]
+}
+
+// mark styles a player's mark, or anything of theirs
+func (t boardTheme) mark(player string) lip.Style {
+	if player == PlayerO {
+		return t.o
+	}
+	return t.x
+}
+
+// player is a player's mark in their colour, like styledPlayer
+func (t boardTheme) player(player string) string {
+	return t.mark(player).Render(player)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeControl is how long players have to make their moves in a game
+type timeControl struct {
+	Name      string        // the preset's, empty for the server's clocks
+	Game      time.Duration // each player's game clock, 0 if there isn't one
+	Move      time.Duration // the shot clock, 0 if there isn't one
+	Increment time.Duration // added to a player's game clock after each of their moves
+}
+
+// timeControlPresets are the time controls rooms can be opened with, besides
+// the server's clocks
+var timeControlPresets = []timeControl{
+	{Name: "Bullet", Game: 30 * time.Second},
+	{Name: "Bullet", Game: 30 * time.Second, Increment: time.Second},
+	{Name: "Blitz", Game: time.Minute},
+	{Name: "Blitz", Game: time.Minute, Increment: 2 * time.Second},
+	{Name: "Casual"}, // untimed
+}
+
+// serverTimeControl is the clocks set with -move-clock and -game-clock,
+// what quick matches and everything but rooms are played with
+func serverTimeControl() timeControl {
+	return timeControl{Game: gameClock, Move: shotClock}
+}
+
+// roomTimeControl is the time control a room is opened with, by the
+// player's pick in the lobby: 0 for the server's clocks, then the presets
+func roomTimeControl(pick int) timeControl {
+	if pick <= 0 || pick > len(timeControlPresets) {
+		return serverTimeControl()
+	}
+	return timeControlPresets[pick-1]
+}
+
+// on reports whether games with this time control are played against the
+// clock
+func (tc timeControl) on() bool {
+	return tc.Game > 0 || tc.Move > 0
+}
+
+// String is how the time control's shown, like "Blitz 1:00 +2s"
+func (tc timeControl) String() string {
+	var clocks []string
+	if tc.Game > 0 {
+		game := formatDuration(tc.Game)
+		if tc.Increment > 0 {
+			game += fmt.Sprintf(" +%ds", int(tc.Increment.Seconds()))
+		}
+		clocks = append(clocks, game)
+	}
+	if tc.Move > 0 {
+		clocks = append(clocks, formatDuration(tc.Move)+" a move")
+	}
+	if len(clocks) == 0 {
+		clocks = append(clocks, "untimed")
+	}
+	if tc.Name == "" {
+		return strings.Join(clocks, ", ")
+	}
+	return tc.Name + " " + strings.Join(clocks, ", ")
+}
+
+// timeControlView shows the time control rooms the player opens are played
+// with
+func (m model) timeControlView() string {
+	tc := roomTimeControl(m.roomClock)
+	name := tc.String()
+	if tc.Name == "" {
+		name = "the server's, " + name
+	}
+	return footerStyle.Render("  Clock for new rooms: ") + headerStyle.Render(name) +
+		footerStyle.Render(" (c to change)") + "\n"
+}
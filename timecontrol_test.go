@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeControlString(t *testing.T) {
+	tests := []struct {
+		tc   timeControl
+		want string
+	}{
+		{timeControl{Name: "Bullet", Game: 30 * time.Second}, "Bullet 0:30"},
+		{timeControl{Name: "Blitz", Game: time.Minute, Increment: 2 * time.Second}, "Blitz 1:00 +2s"},
+		{timeControl{Name: "Casual"}, "Casual untimed"},
+		{timeControl{Game: 5 * time.Minute, Move: 15 * time.Second}, "5:00, 0:15 a move"},
+		{timeControl{Move: 15 * time.Second, Increment: time.Second}, "0:15 a move"},
+		{timeControl{}, "untimed"},
+	}
+	for _, tt := range tests {
+		if got := tt.tc.String(); got != tt.want {
+			t.Errorf("%+v = %q, want %q", tt.tc, got, tt.want)
+		}
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	gs := &GameSession{
+		TimeControl: timeControl{Name: "Blitz", Game: time.Minute, Increment: 2 * time.Second},
+		TurnStarted: time.Now(),
+	}
+	gs.startClocks()
+	gs.chargeClock()
+	gs.passTurn()
+	// X took next to no time over their move and got the increment
+	if x := gs.Clocks[0]; x <= time.Minute || x > time.Minute+2*time.Second {
+		t.Errorf("X's clock = %v, want just under 1:02", x)
+	}
+	if o := gs.Clocks[1]; o != time.Minute {
+		t.Errorf("O's clock = %v, want 1:00", o)
+	}
+	if gs.CurrentPlayer != 1 {
+		t.Errorf("CurrentPlayer = %d, want O", gs.CurrentPlayer)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"tictactui/matchmaking"
+)
+
+// CheckInTimeout is how long a tournament bout's players have to check in
+// by default, a player who doesn't forfeits
+const CheckInTimeout = 30 * time.Second
+
+// checkInTimeout is how long a tournament bout's players have to check in,
+// set with -check-in
+var checkInTimeout = CheckInTimeout
+
+// bracketMsg says the tournament bracket may have changed
+type bracketMsg struct{}
+
+// checkBracket looks at the bracket straight away
+func checkBracket() tea.Msg {
+	return bracketMsg{}
+}
+
+// watchBracket waits for the next change to the bracket
+func watchBracket(t *matchmaking.Tournament) tea.Cmd {
+	changed := t.Changed()
+	return func() tea.Msg {
+		<-changed
+		return bracketMsg{}
+	}
+}
+
+// updateBracket sends the player to their next match once it's ready,
+// otherwise keeps waiting in the bracket
+func (m model) updateBracket() (tea.Model, tea.Cmd) {
+	if m.gameSession != nil && !m.spectating {
+		// playing, the bracket is checked again after the match
+		return m, nil
+	}
+	if m.tournament.Cancelled() {
+		// an impromptu tournament too few signed up for
+		f := m.fresh()
+		f.tournament = nil
+		f.lobby.err = "Not enough players signed up, the tournament's off"
+		return f.backToLobby()
+	}
+	// start watching before looking so no change slips through in between
+	watch := watchBracket(m.tournament)
+	if match, ok := m.tournament.NextMatch(m.playerID); ok {
+		if m.spectating {
+			// stop watching, it's their turn to play
+			close(m.left)
+			m = m.fresh()
+		}
+		return m.enterSession(sessionManager.playTournamentMatch(m.tournament, match, m.player()))
+	}
+	return m, watch
+}
+
+// updateBracketKeys handles keys while waiting in the bracket, there's no
+// board to play on until the next match starts
+func (m model) updateBracketKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "w":
+		return m.openGameList()
+	case "esc":
+		// an impromptu tournament was joined from the lobby, so there's
+		// one to go back to
+		if sessionManager.tournamentSize == 0 {
+			return m.leaveTournament()
+		}
+	}
+	return m, nil
+}
+
+// backToBracket leaves a settled tournament match and waits for the next one
+func (m model) backToBracket() (tea.Model, tea.Cmd) {
+	close(m.left)
+
+	return m.fresh(), tea.Batch(m.clearScreen(), checkBracket)
+}
+
+// roundName is what a round is called, counting from the final backwards
+func roundName(round, total int) string {
+	switch total - round {
+	case 1:
+		return "Final"
+	case 2:
+		return "Semi-finals"
+	}
+	return fmt.Sprintf("Round %d", round+1)
+}
+
+// bracketView draws the bracket and where the player stands in it
+func (m model) bracketView() string {
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+
+	t := m.tournament
+	rounds := t.Rounds()
+	if len(rounds) == 0 {
+		players := t.Players()
+		s += headerStyle.Render(fmt.Sprintf("  Tournament, waiting for players (%d of %d)", len(players), t.Size)) + "\n\n"
+		for _, p := range players {
+			s += "    " + p.Name + "\n"
+		}
+		if call, ok := sessionManager.tournamentCall(); ok && call.tournament == t {
+			left := int(max(time.Until(call.closes), 0).Seconds())
+			s += footerStyle.Render(fmt.Sprintf("\n  Sign ups close in %ds, or once %d players have joined", left, t.Size)) + "\n"
+		} else {
+			s += footerStyle.Render(fmt.Sprintf("\n  The bracket is drawn once %d players have joined", t.Size)) + "\n"
+		}
+		s += footerStyle.Render("\n  Press "+m.bracketKeys()) + "\n"
+		return s
+	}
+
+	s += headerStyle.Render(fmt.Sprintf("  Tournament, round %d of %d", len(rounds), t.TotalRounds())) + "\n"
+	knockedOut := false
+	for i, round := range rounds {
+		s += "\n" + footerStyle.Render("  "+roundName(i, t.TotalRounds())) + "\n"
+		for _, b := range round {
+			s += "    " + boutLine(b) + "\n"
+			if b.Decided() && b.Winner != m.playerID && (b.Players[0].ID == m.playerID || b.Players[1].ID == m.playerID) {
+				knockedOut = true
+			}
+		}
+	}
+
+	s += "\n"
+	registered := slices.ContainsFunc(t.Players(), func(p matchmaking.Player) bool { return p.ID == m.playerID })
+	if champion, ok := t.Champion(); ok {
+		if champion.ID == m.playerID {
+			s += headerStyle.Render("  You're the champion!") + "\n"
+		} else {
+			s += headerStyle.Render("  "+champion.Name+" is the champion!") + "\n"
+		}
+	} else if !registered {
+		s += footerStyle.Render("  The tournament is already under way, you're watching") + "\n"
+	} else if knockedOut {
+		s += footerStyle.Render("  You're out, stay to follow the rest of the bracket") + "\n"
+	} else {
+		s += footerStyle.Render("  Waiting for your next opponent...") + "\n"
+	}
+	s += footerStyle.Render("\n  Press "+m.bracketKeys()) + "\n"
+	return s
+}
+
+// bracketKeys says what the keys do while waiting in the bracket
+func (m model) bracketKeys() string {
+	if sessionManager.tournamentSize == 0 {
+		return "w to watch a game, esc to go back to the lobby, q to quit"
+	}
+	return "w to watch a game, q to quit"
+}
+
+// boutLine sums up one bout of the bracket
+func boutLine(b matchmaking.Bout) string {
+	if b.Bye {
+		return b.Players[0].Name + footerStyle.Render("  gets a bye")
+	}
+	names := make([]string, 2)
+	for i, p := range b.Players {
+		names[i] = p.Name
+		if p.ID == "" {
+			names[i] = "?"
+		}
+	}
+	line := names[0] + footerStyle.Render(" vs ") + names[1]
+	switch {
+	case b.NoShow:
+		return line + footerStyle.Render("  neither checked in, both are out")
+	case b.Decided():
+		winner := names[0]
+		if b.Winner == b.Players[1].ID {
+			winner = names[1]
+		}
+		return line + footerStyle.Render("  "+winner+" advances")
+	case b.Match != nil:
+		return line + footerStyle.Render("  playing")
+	}
+	return line
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// tutorialStep is one thing the tutorial teaches, on a Tic-Tac-Toe board of
+// its own
+type tutorialStep struct {
+	title  string
+	say    string            // what to do, a line or two
+	rows   [BoardSize]string // the board it starts from, a row at a time, . for an empty cell
+	target coord             // where the cursor has to get to, or the mark has to go
+	place  bool              // a mark to put down at target, rather than just the cursor to get there
+	wrong  string            // said when a mark goes anywhere else
+	done   string            // said once it's done
+}
+
+// tutorialSteps are the tutorial, in order
+var tutorialSteps = []tutorialStep{
+	{
+		title:  "Moving",
+		say:    "Move the cursor with the arrow keys, or h, j, k and l.\nTake it to the bottom right corner, C3.",
+		rows:   [BoardSize]string{"...", "...", "..."},
+		target: coord{2, 2},
+		done:   "That's it, the cursor is where your next mark goes.",
+	},
+	{
+		title:  "Placing a mark",
+		say:    "Move to the centre, B2, and press enter or space to put your X there.",
+		rows:   [BoardSize]string{"...", "...", "..."},
+		target: coord{1, 1},
+		place:  true,
+		wrong:  "Not there, the centre is B2.",
+		done:   "The centre is on four lines, more than any other cell.",
+	},
+	{
+		title:  "Blocking",
+		say:    "O has two in a row along the top and wins next move.\nPut your X where O would.",
+		rows:   [BoardSize]string{"OO.", ".X.", "..X"},
+		target: coord{0, 2},
+		place:  true,
+		wrong:  "O still wins there, look along the top row.",
+		done:   "Blocked. Look for the other player's threats before your own plans.",
+	},
+	{
+		title:  "Forks",
+		say:    "Make two threats at once, O can only block one of them.\nFind the cell that lines up with both your Xs.",
+		rows:   [BoardSize]string{"XXO", "O..", "..."},
+		target: coord{1, 1},
+		place:  true,
+		wrong:  "That's one threat at most, O blocks it. Try the centre.",
+		done:   "A fork: B3 and C3 both win, and O can only block one.",
+	},
+}
+
+// tutorial walks a player through the keys and the ideas of the game
+type tutorial struct {
+	active bool
+	step   int
+	done   bool      // the step's done, enter goes on to the next
+	wrong  string    // why the last mark didn't go down
+	rules  gameRules // the game they had picked, for when they're done
+}
+
+// startTutorial starts the tutorial from the top. It's played on a
+// Tic-Tac-Toe board, whatever the game picked.
+func (m model) startTutorial() model {
+	m.lobby.active = false
+	m.tutorial = tutorial{active: true, rules: m.rules}
+	m.rules = ticTacToe
+	return m.setTutorialStep(0)
+}
+
+// setTutorialStep sets out a step's board
+func (m model) setTutorialStep(step int) model {
+	m.tutorial.step, m.tutorial.done, m.tutorial.wrong = step, false, ""
+	m.board = boardFromRows(tutorialSteps[step].rows)
+	m.cursorX, m.cursorY = 0, 0
+	return m
+}
+
+// leaveTutorial goes to the lobby, back on the game they'd picked
+func (m model) leaveTutorial() (tea.Model, tea.Cmd) {
+	m.rules = m.tutorial.rules
+	m.board = m.rules.newBoard()
+	m.cursorX, m.cursorY = 0, 0
+	m.tutorial = tutorial{}
+	return m.backToLobby()
+}
+
+// updateTutorial handles keys in the tutorial
+func (m model) updateTutorial(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	step := tutorialSteps[m.tutorial.step]
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		return m.leaveTutorial()
+	case "up", "k", "down", "j", "right", "l", "left", "h":
+		if m.tutorial.done {
+			break
+		}
+		m.moveCursor(msg.String())
+		if !step.place && (coord{m.cursorY, m.cursorX}) == step.target {
+			m.tutorial.done = true
+		}
+	case "enter", " ":
+		switch {
+		case m.tutorial.done && m.tutorial.step == len(tutorialSteps)-1:
+			return m.leaveTutorial()
+		case m.tutorial.done:
+			return m.setTutorialStep(m.tutorial.step + 1), m.clearScreen()
+		case !step.place:
+		case (coord{m.cursorY, m.cursorX}) != step.target:
+			m.tutorial.wrong = step.wrong
+		default:
+			m.board[m.cursorY][m.cursorX] = PlayerX
+			m.tutorial.done, m.tutorial.wrong = true, ""
+		}
+	}
+	return m, nil
+}
+
+// tutorialView draws the step the tutorial's on
+func (m model) tutorialView() string {
+	step := tutorialSteps[m.tutorial.step]
+	s := "\n"
+	s += headerStyle.Render(m.art().Title)
+	s += "\n\n"
+	s += headerStyle.Render(fmt.Sprintf("  Tutorial, %d of %d: %s", m.tutorial.step+1, len(tutorialSteps), step.title)) + "\n\n"
+	s += m.boardView() + "\n"
+	s += lip.NewStyle().PaddingLeft(2).Render(step.say) + "\n"
+	switch {
+	case m.tutorial.done && m.tutorial.step == len(tutorialSteps)-1:
+		s += "\n" + headerStyle.Render("  "+step.done) + "\n"
+		s += footerStyle.Render("  You're ready. Puzzles in the lobby have more forks to find.") + "\n"
+		s += footerStyle.Render("\n  Press enter for the lobby, q to quit") + "\n"
+		return s
+	case m.tutorial.done:
+		s += "\n" + headerStyle.Render("  "+step.done) + "\n"
+		s += footerStyle.Render("\n  Press enter to go on, esc to skip the rest, q to quit") + "\n"
+		return s
+	case m.tutorial.wrong != "":
+		s += "\n" + lip.NewStyle().Foreground(lip.Color("#FF5555")).Bold(true).Render("  "+m.tutorial.wrong) + "\n"
+	}
+	s += footerStyle.Render("\n  Press esc to skip the tutorial, q to quit") + "\n"
+	return s
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	lip "github.com/charmbracelet/lipgloss"
+)
+
+// TestTutorial plays through the tutorial, a wrong move or two on the way
+func TestTutorial(t *testing.T) {
+	m := initialModel()
+	m.rules = connectFour // what they had picked, the tutorial's on Tic-Tac-Toe
+	m = m.startTutorial()
+
+	special := map[string]tea.KeyType{"enter": tea.KeyEnter, "down": tea.KeyDown, "right": tea.KeyRight}
+	press := func(keys ...string) {
+		t.Helper()
+		for _, key := range keys {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+			if k, ok := special[key]; ok {
+				msg = tea.KeyMsg{Type: k}
+			}
+			next, _ := m.updateTutorial(msg)
+			m = next.(model)
+			if height := lip.Height(m.view()); m.tutorial.active && height > MinTerminalHeight {
+				t.Fatalf("step %d is %d lines, taller than a %d line terminal", m.tutorial.step, height, MinTerminalHeight)
+			}
+		}
+	}
+	want := func(step int, done bool) {
+		t.Helper()
+		if m.tutorial.step != step || m.tutorial.done != done {
+			t.Fatalf("on step %d, done %v, want step %d, done %v", m.tutorial.step, m.tutorial.done, step, done)
+		}
+	}
+
+	// moving: enter does nothing, getting to C3 does it
+	press("enter", "down", "right")
+	want(0, false)
+	press("j", "l")
+	want(0, true)
+
+	// placing a mark, not in the corner
+	press("enter", "enter")
+	want(1, false)
+	if m.tutorial.wrong == "" || m.board[0][0] != Empty {
+		t.Errorf("a mark in A1 went down, or nothing said it was wrong")
+	}
+	press("down", "right", "enter")
+	want(1, true)
+	if m.board[1][1] != PlayerX {
+		t.Errorf("no X in the centre, board %v", m.board)
+	}
+
+	// blocking and forking
+	press("enter", "right", "right", "enter")
+	want(2, true)
+	press("enter", "down", "right", "enter")
+	want(3, true)
+
+	// and on to the lobby, on the game they'd picked
+	press("enter")
+	if m.tutorial.active || !m.lobby.active || m.rules != connectFour || len(m.board) != len(connectFour.newBoard()) {
+		t.Errorf("after the tutorial: tutorial %v, lobby %v, game %s, want the lobby on connect four", m.tutorial.active, m.lobby.active, m.rules.Name())
+	}
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"tictactui/matchmaking"
+)
+
+// httpAddr is where the web leaderboard listens, set with -http. Empty means
+// there's no web leaderboard.
+var httpAddr string
+
+// ratingDecay is how many rating points players lose for each week they
+// don't play once RatingDecayGrace is up, set with -rating-decay. 0 means
+// ratings don't decay.
+var ratingDecay int
+
+// LeaderboardSize is how many players the leaderboard shows unless asked
+// for more with ?limit=
+const LeaderboardSize = 50
+
+// StatsTTL is how long the web reuses the stats from the journals before
+// adding them up again
+const StatsTTL = time.Minute
+
+// publicPlayer is what the web shows about a player in one game, their
+// macros and first visit stay private
+type publicPlayer struct {
+	Rank        int       `json:"rank,omitempty"`
+	Fingerprint string    `json:"fingerprint"`
+	Name        string    `json:"name"`
+	Game        string    `json:"game"`             // the game the rating and results are in, like GameTicTacToe
+	Rating      int       `json:"rating,omitempty"` // left out until they've played their placement matches
+	Title       string    `json:"title"`            // the rank title shown by their name, see rankTitle
+	Wins        int       `json:"wins"`
+	Losses      int       `json:"losses"`
+	Draws       int       `json:"draws"`
+	WinRate     float64   `json:"win_rate"` // percentage of their games won
+	LastSeen    time.Time `json:"last_seen"`
+
+	// casual games, kept apart from the rated ones above
+	CasualWins   int `json:"casual_wins,omitempty"`
+	CasualLosses int `json:"casual_losses,omitempty"`
+	CasualDraws  int `json:"casual_draws,omitempty"`
+
+	// penalties, so players and admins can see who's been dodging
+	Dodged        int       `json:"dodged,omitempty"`
+	Abandoned     int       `json:"abandoned,omitempty"`
+	CooldownUntil time.Time `json:"cooldown_until,omitzero"`
+}
+
+// public is the part of a record that's shown on the web, with the
+// player's rating and results in game
+func (rec PlayerRecord) public(game string) publicPlayer {
+	g := rec.Games[game]
+	p := publicPlayer{
+		Fingerprint: rec.Fingerprint,
+		Name:        rec.Name,
+		Game:        game,
+		Rating:      cmp.Or(g.Rating, matchmaking.InitialRating), // stored before there were ratings
+		Wins:        g.Wins,
+		Losses:      g.Losses,
+		Draws:       g.Draws,
+		LastSeen:    rec.LastSeen,
+
+		CasualWins:   g.CasualWins,
+		CasualLosses: g.CasualLosses,
+		CasualDraws:  g.CasualDraws,
+
+		Dodged:    rec.Dodged,
+		Abandoned: rec.Abandoned,
+	}
+	games := g.played()
+	if games > 0 {
+		p.WinRate = 100 * float64(g.Wins) / float64(games)
+	}
+	p.Rating = matchmaking.Decayed(p.Rating, g.LastPlayed, time.Now(), ratingDecay)
+	p.Title = rankTitle(p.Rating, games)
+	if games < matchmaking.PlacementMatches {
+		p.Rating = 0 // provisional, not public yet
+	}
+	if time.Now().Before(rec.CooldownUntil) {
+		p.CooldownUntil = rec.CooldownUntil
+	}
+	return p
+}
+
+// all lists every stored player
+func (ps *playerStore) all() ([]PlayerRecord, error) {
+	var recs []PlayerRecord
+	err := ps.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(playersBucket).ForEach(func(_, data []byte) error {
+			var rec PlayerRecord
+			if err := json.Unmarshal(data, &rec); err != nil {
+				return err
+			}
+			recs = append(recs, rec)
+			return nil
+		})
+	})
+	return recs, err
+}
+
+// leaderboard ranks the players who've played their placement matches in
+// a game, best rating first, at most limit of them
+func (ps *playerStore) leaderboard(game string, limit int) ([]publicPlayer, error) {
+	board, err := ps.ranking(game, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return board[:min(limit, len(board))], nil
+}
+
+// ranking ranks every player who's played their placement matches in a
+// game, best rating first, bar those who'd rather not be. With since, only
+// those who've played a rated game in it since then are ranked, see
+// seasonStart.
+func (ps *playerStore) ranking(game string, since time.Time) ([]publicPlayer, error) {
+	recs, err := ps.all()
+	if err != nil {
+		return nil, err
+	}
+	board := []publicPlayer{}
+	for _, rec := range recs {
+		g := rec.Games[game]
+		if g.played() >= matchmaking.PlacementMatches && !g.LastPlayed.Before(since) && !rec.Privacy.Unranked {
+			board = append(board, rec.public(game))
+		}
+	}
+	slices.SortFunc(board, func(a, b publicPlayer) int {
+		return cmp.Or(b.Rating-a.Rating, b.Wins-a.Wins, strings.Compare(a.Name, b.Name))
+	})
+	for i := range board {
+		board[i].Rank = i + 1
+	}
+	return board, nil
+}
+
+// statsCache keeps the stats from the journals for a while, adding them up
+// reads every journal there is
+type statsCache struct {
+	stats *gameStats
+	at    time.Time
+	mutex sync.Mutex
+}
+
+// journalStats are the stats the web and the lobby show
+var journalStats = &statsCache{}
+
+// load returns the stats, adding them up again if they're older than
+// StatsTTL. There are none without -journal.
+func (c *statsCache) load() (*gameStats, error) {
+	if journalDir == "" {
+		return newGameStats(), nil
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.stats != nil && time.Since(c.at) < StatsTTL {
+		return c.stats, nil
+	}
+	st, err := analyzeJournals(journalDir)
+	if err != nil {
+		return nil, err
+	}
+	c.stats, c.at = st, time.Now()
+	return st, nil
+}
+
+// get returns each game's stats
+func (c *statsCache) get() ([]gameSummary, error) {
+	st, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return st.summaries(), nil
+}
+
+// leaderboardData is what the leaderboard page shows
+type leaderboardData struct {
+	Boards []gameBoard
+	Games  []gameSummary
+}
+
+// gameBoard is one game's leaderboard on the leaderboard page
+type gameBoard struct {
+	Title   string
+	Players []publicPlayer
+}
+
+// leaderboardPage is the leaderboard for browsers
+var leaderboardPage = template.Must(template.New("leaderboard").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>tictactui leaderboard</title>
+<style>
+body { background: #282a36; color: #f8f8f2; font-family: monospace; margin: 2em auto; max-width: 50em; }
+h1 { color: #f1fa8c; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 0.3em 0.8em; text-align: left; }
+th { color: #6272a4; border-bottom: 1px solid #6272a4; }
+tr:nth-child(even) { background: #343746; }
+a { color: #8be9fd; text-decoration: none; }
+.rating { color: #50fa7b; }
+.title { color: #6272a4; }
+h2 { color: #ff79c6; font-size: 1.1em; margin-top: 2em; }
+</style>
+</head>
+<body>
+<h1>tictactui leaderboard</h1>
+{{range .Boards}}
+<h2>Top {{.Title}} players</h2>
+{{if .Players}}
+<table>
+<tr><th>#</th><th>Player</th><th>Rating</th><th>W-L-D</th><th>Won</th><th>Last seen</th></tr>
+{{range .Players}}<tr><td>{{.Rank}}</td><td><a href="/api/player/{{.Fingerprint}}?game={{.Game}}">{{.Name}}</a> <span class="title">{{.Title}}</span></td><td class="rating">{{.Rating}}</td><td>{{.Wins}}-{{.Losses}}-{{.Draws}}</td><td>{{printf "%.0f" .WinRate}}%</td><td>{{.LastSeen.Format "2006-01-02"}}</td></tr>
+{{end}}</table>
+{{else}}
+<p>Nobody has finished a rated game yet. Be the first: <code>ssh -p 2222 &lt;host&gt;</code></p>
+{{end}}
+{{end}}
+{{range .Games}}
+<h2>{{.Game}}</h2>
+{{if .Finished}}<p>Of {{.Finished}} finished games, X won {{printf "%.1f" .XWins}}%, O won {{printf "%.1f" .OWins}}% and {{printf "%.1f" .Draws}}% were drawn.</p>{{end}}
+{{if .Openings}}<p>Most common first moves: {{range $i, $o := .Openings}}{{if $i}}, {{end}}{{$o.Move}} ({{$o.Games}}){{end}}</p>{{end}}
+{{end}}
+<p>Also as JSON: <a href="/api/leaderboard">/api/leaderboard</a>, <a href="/api/stats">/api/stats</a></p>
+</body>
+</html>
+`))
+
+// serveHTTP serves the web leaderboard until the listener fails
+func serveHTTP(addr string, ps *playerStore) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		var boards []gameBoard
+		for _, g := range enabledGames {
+			board, err := ps.leaderboard(g.Name(), LeaderboardSize)
+			if err != nil {
+				httpError(w, err)
+				return
+			}
+			boards = append(boards, gameBoard{Title: g.Title(), Players: board})
+		}
+		games, err := journalStats.get()
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		if err := leaderboardPage.Execute(w, leaderboardData{Boards: boards, Games: games}); err != nil {
+			log.Printf("http: %v", err)
+		}
+	})
+	mux.HandleFunc("GET /api/leaderboard", func(w http.ResponseWriter, r *http.Request) {
+		limit, ok := queryLimit(w, r)
+		if !ok {
+			return
+		}
+		game, ok := queryGame(w, r)
+		if !ok {
+			return
+		}
+		board, err := ps.leaderboard(game, limit)
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		writeJSON(w, board)
+	})
+	mux.HandleFunc("GET /api/stats", func(w http.ResponseWriter, r *http.Request) {
+		games, err := journalStats.get()
+		if err != nil {
+			httpError(w, err)
+			return
+		}
+		writeJSON(w, games)
+	})
+	// fingerprints are base64 so they can have slashes in them
+	mux.HandleFunc("GET /api/player/{fingerprint...}", func(w http.ResponseWriter, r *http.Request) {
+		fingerprint := r.PathValue("fingerprint")
+		if !strings.HasPrefix(fingerprint, "SHA256:") {
+			fingerprint = "SHA256:" + fingerprint
+		}
+		game, ok := queryGame(w, r)
+		if !ok {
+			return
+		}
+		rec, ok := ps.lookup(fingerprint)
+		if !ok || rec.Privacy.Unranked {
+			http.Error(w, "no such player", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, rec.public(game))
+	})
+
+	mux.HandleFunc("GET /api/game/{id}", serveOverlay)
+	mux.HandleFunc("GET /api/game/{id}/stream", serveGameStream)
+	if journalDir != "" {
+		mux.HandleFunc("GET /replay/{id}", serveReplayPage)
+	}
+
+	if trustedServers != nil {
+		// the central leaderboard for the servers that push to it
+		mux.HandleFunc("POST /api/results", serveResults(ps))
+		mux.HandleFunc("GET /api/network", func(w http.ResponseWriter, r *http.Request) {
+			limit, ok := queryLimit(w, r)
+			if !ok {
+				return
+			}
+			board, err := ps.networkLeaderboard(limit)
+			if err != nil {
+				httpError(w, err)
+				return
+			}
+			writeJSON(w, board)
+		})
+	}
+
+	fmt.Println("Serving the web leaderboard on", addr)
+	log.Fatalln(http.ListenAndServe(addr, mux))
+}
+
+// queryLimit reads how many players a leaderboard request wants with
+// ?limit=, LeaderboardSize if it doesn't say. It answers bad requests
+// itself and reports false.
+func queryLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	s := r.URL.Query().Get("limit")
+	if s == "" {
+		return LeaderboardSize, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+// queryGame reads which game a request wants ratings in with ?game=, the
+// default game if it doesn't say. It answers bad requests itself and
+// reports false.
+func queryGame(w http.ResponseWriter, r *http.Request) (string, bool) {
+	game := r.URL.Query().Get("game")
+	if game == "" {
+		return enabledGames[0].Name(), true
+	}
+	if !gameEnabled(boardGames, game) {
+		http.Error(w, "game must be one of "+strings.Join(gameNames(boardGames), ", "), http.StatusBadRequest)
+		return "", false
+	}
+	return game, true
+}
+
+// writeJSON sends v as the response
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("http: %v", err)
+	}
+}
+
+// httpError logs an internal error and tells the client something went wrong
+func httpError(w http.ResponseWriter, err error) {
+	log.Printf("http: %v", err)
+	http.Error(w, "something went wrong", http.StatusInternalServerError)
+}
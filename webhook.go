@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"tictactui/matchmaking"
+)
+
+// webhookURL is where tournament events are posted, e.g. for a Discord bot
+// to mirror the bracket, set with -webhook. Empty means they aren't.
+var webhookURL string
+
+// webhookSecret signs webhook posts, set with -webhook-secret. Empty means
+// they go unsigned.
+var webhookSecret string
+
+// Tournament events, see tournamentEvent
+const (
+	EventTournamentCreated   = "created"
+	EventTournamentCancelled = "cancelled"
+	EventRoundPaired         = "round_paired"
+	EventMatchFinished       = "match_finished"
+	EventChampion            = "champion"
+)
+
+// tournamentEvent is what a webhook gets posted as JSON. Every event has
+// the tournament and a one-line summary in Content; the rest depends on the
+// event.
+type tournamentEvent struct {
+	Event      string    `json:"event"`
+	Tournament string    `json:"tournament"` // the same for all of one tournament's events
+	Content    string    `json:"content"`
+	At         time.Time `json:"at"`
+	Size       int       `json:"size"`    // players in the field, or wanted for it before the draw
+	Rounds     int       `json:"rounds"`  // rounds it takes to crown a champion
+	Connect    string    `json:"connect"` // the command players join the server with
+
+	SignUpsClose time.Time    `json:"signups_close,omitzero"` // created, for an impromptu tournament
+	Round        *hookRound   `json:"round,omitempty"`        // round_paired, and the round a match_finished was in
+	Bout         *hookBout    `json:"bout,omitempty"`         // match_finished
+	Champion     *hookPlayer  `json:"champion,omitempty"`     // champion
+	Players      []hookPlayer `json:"players,omitempty"`      // champion, the field in seeded order
+}
+
+// hookRound is a round of the bracket in a webhook
+type hookRound struct {
+	Number int        `json:"number"` // from 1
+	Name   string     `json:"name"`   // like "Semi-finals"
+	Bouts  []hookBout `json:"bouts,omitempty"`
+}
+
+// hookBout is a pairing in a webhook
+type hookBout struct {
+	Players [2]hookPlayer `json:"players"`
+	Bye     bool          `json:"bye,omitempty"`     // Players[0] goes through without playing
+	Winner  *hookPlayer   `json:"winner,omitempty"`  // once it's decided
+	NoShow  bool          `json:"no_show,omitempty"` // neither checked in, both are out
+}
+
+// hookPlayer is a player in a webhook. Key users have their fingerprint,
+// for a bot to know them by from one tournament to the next.
+type hookPlayer struct {
+	Name        string `json:"name"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// newTournament opens a tournament, with the check in the server's set up
+// for, and posts its events to the webhook if there is one. closes is when
+// an impromptu tournament's sign ups close, zero otherwise.
+func newTournament(size int, closes time.Time) *matchmaking.Tournament {
+	t := matchmaking.NewTournament(size)
+	t.SetCheckIn(checkInTimeout)
+	if webhookURL != "" {
+		go postTournamentEvents(t, rand.Text(), closes)
+	}
+	return t
+}
+
+// postTournamentEvents posts a tournament's events as they happen, one at
+// a time and in order, until it crowns a champion or is called off
+func postTournamentEvents(t *matchmaking.Tournament, id string, closes time.Time) {
+	event := func(name, content string) tournamentEvent {
+		return tournamentEvent{
+			Event:      name,
+			Tournament: id,
+			Content:    content,
+			At:         time.Now(),
+			Size:       t.Size,
+			Rounds:     t.TotalRounds(),
+			Connect:    connectCommand(),
+		}
+	}
+
+	created := event(EventTournamentCreated, fmt.Sprintf("A %d player tournament is open, join with %s", t.Size, connectCommand()))
+	created.SignUpsClose = closes
+	postWebhook(created)
+
+	paired := 0                   // rounds posted so far
+	finished := map[[2]int]bool{} // bouts posted so far, by round and place in it
+	for {
+		// start watching before looking so no change slips through in between
+		changed := t.Changed()
+		if t.Cancelled() {
+			postWebhook(event(EventTournamentCancelled, "Not enough players signed up, the tournament's off"))
+			return
+		}
+		rounds := t.Rounds()
+		total := t.TotalRounds()
+		for i, round := range rounds {
+			r := hookRound{Number: i + 1, Name: roundName(i, total)}
+			if i >= paired {
+				for _, b := range round {
+					r.Bouts = append(r.Bouts, boutHook(b))
+				}
+				e := event(EventRoundPaired, r.Name+" is paired: "+pairingsText(round))
+				e.Round = &r
+				postWebhook(e)
+				paired = i + 1
+				r.Bouts = nil
+			}
+			for j, b := range round {
+				if !b.Decided() || b.Bye || finished[[2]int{i, j}] {
+					continue
+				}
+				finished[[2]int{i, j}] = true
+				bout := boutHook(b)
+				e := event(EventMatchFinished, r.Name+": "+ansi.Strip(boutLine(b)))
+				e.Round, e.Bout = &r, &bout
+				postWebhook(e)
+			}
+		}
+		if champion, ok := t.Champion(); ok {
+			p := playerHook(champion)
+			e := event(EventChampion, champion.Name+" is the champion!")
+			e.Champion = &p
+			for _, p := range t.Players() {
+				e.Players = append(e.Players, playerHook(p))
+			}
+			postWebhook(e)
+			return
+		}
+		<-changed
+	}
+}
+
+// pairingsText lists a round's pairings for an event's summary
+func pairingsText(round []matchmaking.Bout) string {
+	lines := make([]string, len(round))
+	for i, b := range round {
+		if b.Bye {
+			lines[i] = b.Players[0].Name + " gets a bye"
+		} else {
+			lines[i] = b.Players[0].Name + " vs " + b.Players[1].Name
+		}
+	}
+	return strings.Join(lines, "; ")
+}
+
+// boutHook is a bout as a webhook shows it
+func boutHook(b matchmaking.Bout) hookBout {
+	h := hookBout{Bye: b.Bye, NoShow: b.NoShow}
+	for i, p := range b.Players {
+		h.Players[i] = playerHook(p)
+	}
+	if b.Decided() {
+		winner := h.Players[0]
+		if b.Winner == b.Players[1].ID {
+			winner = h.Players[1]
+		}
+		h.Winner = &winner
+	}
+	return h
+}
+
+// playerHook is a player as a webhook shows them
+func playerHook(p matchmaking.Player) hookPlayer {
+	h := hookPlayer{Name: p.Name}
+	if p.ID != "" && persistent(p.ID) {
+		h.Fingerprint = p.ID
+	}
+	return h
+}
+
+// postWebhook posts one event. With a secret, the X-Tictactui-Signature
+// header has the body's HMAC-SHA256 as "sha256=<hex>" for the receiver to
+// check it came from this server.
+func postWebhook(e tournamentEvent) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("webhook: %v", err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("webhook: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if webhookSecret != "" {
+		req.Header.Set("X-Tictactui-Signature", "sha256="+hex.EncodeToString(hmacSHA256([]byte(webhookSecret), string(body))))
+	}
+	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	if err != nil {
+		log.Printf("webhook %s: %v", e.Event, err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		why, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		log.Printf("webhook %s: %s: %s", e.Event, resp.Status, strings.TrimSpace(string(why)))
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPostWebhook(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{"signed", "hunter2"},
+		{"unsigned", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			var signature string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				signature = r.Header.Get("X-Tictactui-Signature")
+			}))
+			defer srv.Close()
+			oldURL, oldSecret := webhookURL, webhookSecret
+			t.Cleanup(func() { webhookURL, webhookSecret = oldURL, oldSecret })
+			webhookURL, webhookSecret = srv.URL, tt.secret
+
+			postWebhook(tournamentEvent{Event: EventTournamentCreated, Tournament: "abc123", Content: "A tournament is starting"})
+
+			var e tournamentEvent
+			if err := json.Unmarshal(body, &e); err != nil {
+				t.Fatalf("posted %q: %v", body, err)
+			}
+			if e.Event != EventTournamentCreated || e.Tournament != "abc123" {
+				t.Errorf("posted %+v", e)
+			}
+			if tt.secret == "" {
+				if signature != "" {
+					t.Errorf("unsigned post has signature %q", signature)
+				}
+				return
+			}
+			// checked the way a receiver would
+			got, ok := strings.CutPrefix(signature, "sha256=")
+			sum, err := hex.DecodeString(got)
+			if !ok || err != nil {
+				t.Fatalf("signature %q isn't sha256=<hex>", signature)
+			}
+			mac := hmac.New(sha256.New, []byte(tt.secret))
+			mac.Write(body)
+			if !hmac.Equal(sum, mac.Sum(nil)) {
+				t.Errorf("signature %q doesn't match the body", signature)
+			}
+		})
+	}
+}
+
+// TestHMACSHA256 checks test case 2 of RFC 4231
+func TestHMACSHA256(t *testing.T) {
+	got := hex.EncodeToString(hmacSHA256([]byte("Jefe"), "what do ya want for nothing?"))
+	if want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"; got != want {
+		t.Errorf("hmacSHA256() = %s, want %s", got, want)
+	}
+}